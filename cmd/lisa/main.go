@@ -4,14 +4,25 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
+	"text/tabwriter"
+	"time"
 
+	"github.com/brainwhocodes/lisa-loop/internal/budget"
+	"github.com/brainwhocodes/lisa-loop/internal/checkpoint"
 	"github.com/brainwhocodes/lisa-loop/internal/circuit"
 	"github.com/brainwhocodes/lisa-loop/internal/codex"
+	"github.com/brainwhocodes/lisa-loop/internal/command"
+	"github.com/brainwhocodes/lisa-loop/internal/config"
 	"github.com/brainwhocodes/lisa-loop/internal/loop"
 	"github.com/brainwhocodes/lisa-loop/internal/project"
+	"github.com/brainwhocodes/lisa-loop/internal/runner"
+	"github.com/brainwhocodes/lisa-loop/internal/state"
 	"github.com/brainwhocodes/lisa-loop/internal/tui"
 	"github.com/charmbracelet/log"
 )
@@ -23,21 +34,30 @@ func main() {
 
 	var (
 		projectDir string
-		promptFile string
-		maxCalls   int
-		timeout    int
 		useMonitor bool
-		verbose    bool
 		logFormat  string
 
-		// Backend selection
-		backend string
+		// Configuration file settings
+		profile    string
+		configFile string
 
-		// OpenCode backend settings
-		opencodeServerURL string
-		opencodeUsername  string
-		opencodePassword  string
-		opencodeModelID   string
+		// Rollback settings
+		rollbackTo  int
+		rollbackRun string
+
+		// Sync settings
+		syncSince  string
+		syncDryRun bool
+
+		// State settings
+		stateAll bool
+
+		// Locking
+		force bool
+
+		// Run continuity
+		resume bool
+		fresh  bool
 
 		setupName   string
 		setupPrompt string
@@ -51,22 +71,27 @@ func main() {
 
 	fs := flag.NewFlagSet("lisa", flag.ExitOnError)
 	fs.BoolVar(&useMonitor, "monitor", false, "Enable integrated monitoring")
-	fs.BoolVar(&verbose, "verbose", false, "Verbose output")
 	fs.StringVar(&logFormat, "log-format", "", "Log format: text, json, or logfmt (enables CLI log mode)")
-
 	fs.StringVar(&projectDir, "project", ".", "Project directory")
-	fs.StringVar(&promptFile, "prompt", "PROMPT.md", "Prompt file")
-	fs.IntVar(&maxCalls, "calls", 3, "Max loop iterations (default: 3, 10 for opencode backend)")
-	fs.IntVar(&timeout, "timeout", 600, "Codex timeout (seconds)")
 
-	// Backend selection
-	fs.StringVar(&backend, "backend", "cli", "Backend: cli or opencode")
+	fs.StringVar(&profile, "profile", "", "Config profile to apply (env: LISA_PROFILE)")
+	fs.StringVar(&configFile, "config", "", "Project config file (default: lisa.yaml, searched upward)")
+	fs.BoolVar(&force, "force", false, "Take over a project lock left by a run that is no longer active")
+	fs.BoolVar(&resume, "resume", false, "Continue the interrupted run: same run ID, loop counter and sessions")
+	fs.BoolVar(&fresh, "fresh", false, "Start over: reset the circuit breaker and forget backend sessions")
+
+	// Settings that can also come from config files and the environment
+	registerConfigFlags(fs)
+
+	fs.IntVar(&rollbackTo, "to", 0, "Loop number to restore (for rollback command)")
+	fs.StringVar(&rollbackRun, "run", "", "Run ID to restore from (for rollback command, default: most recent)")
 
-	// OpenCode backend settings (with env fallbacks)
-	fs.StringVar(&opencodeServerURL, "opencode-url", "", "OpenCode server URL (env: OPENCODE_SERVER_URL)")
-	fs.StringVar(&opencodeUsername, "opencode-user", "", "OpenCode username (env: OPENCODE_SERVER_USERNAME)")
-	fs.StringVar(&opencodePassword, "opencode-pass", "", "OpenCode password (env: OPENCODE_SERVER_PASSWORD)")
-	fs.StringVar(&opencodeModelID, "opencode-model", "", "OpenCode model ID (env: OPENCODE_MODEL_ID, default: glm-4.7)")
+	// Sync settings
+	fs.StringVar(&syncSince, "since", "", "Git ref to diff against (for sync command, default: start of last run)")
+	fs.BoolVar(&syncDryRun, "dry-run", false, "Show proposed task marks without updating the plan (for sync command)")
+
+	// State settings
+	fs.BoolVar(&stateAll, "all", false, "Also remove checkpoint history and session archives (for state clean)")
 
 	fs.StringVar(&setupName, "name", "", "Project name (for setup command)")
 	fs.StringVar(&setupPrompt, "description", "", "Project description for Codex to generate customized templates")
@@ -81,76 +106,229 @@ func main() {
 	fs.Usage = printHelp
 
 	if err := fs.Parse(flagArgs); err != nil {
-		os.Exit(1)
+		exit(1)
+	}
+
+	// "config", "state" and "history" take a subcommand, which may come before or after the flags
+	var action string
+	if (command == "config" || command == "state" || command == "history") && fs.NArg() > 0 {
+		action = fs.Arg(0)
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			exit(1)
+		}
+	}
+
+	// "history show" takes a run ID, also accepted as --run
+	historyRun := rollbackRun
+	if command == "history" && fs.NArg() > 0 {
+		historyRun = fs.Arg(0)
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			exit(1)
+		}
 	}
 
-	// Apply environment variable fallbacks for OpenCode settings
-	opencodeServerURL = envFallback(opencodeServerURL, "OPENCODE_SERVER_URL", "")
-	opencodeUsername = envFallback(opencodeUsername, "OPENCODE_SERVER_USERNAME", "opencode")
-	opencodePassword = envFallback(opencodePassword, "OPENCODE_SERVER_PASSWORD", "")
-	opencodeModelID = envFallback(opencodeModelID, "OPENCODE_MODEL_ID", "glm-4.7")
+	switch command {
+	case "help", "version":
+		handleSubcommands(command)
+		return
+	}
+
+	resolved, err := config.Load(config.LoadOptions{
+		ProjectDir: projectDir,
+		ConfigFile: configFile,
+		Profile:    profile,
+		Flags:      setFlags(fs),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+		exit(1)
+	}
+	resolved.Config.ProjectPath = projectDir
+	cfg := resolved.Config
+	loop.SetPlanFile(cfg.PlanFile)
 
-	// Default max calls to 10 for opencode backend if not explicitly set
-	if backend == "opencode" && !isFlagSet(fs, "calls") {
-		maxCalls = 10
+	if command == "config" {
+		handleConfigCommand(action, resolved)
+		return
 	}
 
-	// Build OpenCode settings struct for passing to handlers
-	ocSettings := openCodeSettings{
-		serverURL: opencodeServerURL,
-		username:  opencodeUsername,
-		password:  opencodePassword,
-		modelID:   opencodeModelID,
+	if errs := config.Validate(cfg); len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		exit(1)
+	}
+
+	if needsLock(command, action, syncDryRun) {
+		lock, err := state.AcquireLock(projectDir, command, force)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+		projectLock = lock
+		defer lock.Release()
 	}
 
 	switch command {
 	case "init":
-		handleInitCommand(initMode, projectDir, maxCalls, timeout, verbose, backend, ocSettings, logFormat)
+		handleInitCommand(initMode, cfg, logFormat)
 	case "setup":
-		handleSetupCommand(setupName, setupPrompt, setupInit, withGit, verbose)
+		handleSetupCommand(setupName, setupPrompt, setupInit, withGit, cfg.Verbose)
 	case "import":
-		handleImportCommand(importSrc, importName, projectDir, verbose)
+		handleImportCommand(importSrc, importName, projectDir, cfg.Verbose)
 	case "status":
-		handleStatusCommand(projectDir)
+		handleStatusCommand(projectDir, cfg)
 	case "reset-circuit":
-		handleResetCircuitCommand(projectDir)
+		handleResetCircuitCommand(cfg)
 	case "sync":
-		handleSyncCommand(projectDir, verbose)
-	case "run", "help", "version":
-		handleSubcommands(command, projectDir, promptFile, maxCalls, timeout, useMonitor, verbose, backend, ocSettings, logFormat)
+		handleSyncCommand(projectDir, syncSince, syncDryRun, cfg.Verbose)
+	case "rollback":
+		handleRollbackCommand(projectDir, rollbackTo, rollbackRun)
+	case "state":
+		handleStateCommand(projectDir, action, stateAll)
+	case "history":
+		handleHistoryCommand(projectDir, action, historyRun, cfg.Verbose)
+	case "run":
+		handleRunCommand(cfg, useMonitor, logFormat, resume, fresh)
 	default:
 		fmt.Fprintf(os.Stderr, "Error: unknown command '%s'\n\n", command)
 		printHelp()
-		os.Exit(1)
+		exit(1)
 	}
 }
 
-// openCodeSettings holds OpenCode backend configuration
-type openCodeSettings struct {
-	serverURL string
-	username  string
-	password  string
-	modelID   string
+// projectLock is held while a command modifies the project
+var projectLock *state.Lock
+
+// exit releases the project lock before exiting, since os.Exit skips deferred calls
+func exit(code int) {
+	projectLock.Release()
+	os.Exit(code)
 }
 
-func handleSubcommands(command, projectDir, promptFile string, maxCalls, timeout int, useMonitor, verbose bool, backend string, ocSettings openCodeSettings, logFormat string) {
+// needsLock reports whether a command writes to the plan or state files
+// Read-only commands run alongside an active loop
+func needsLock(command, action string, dryRun bool) bool {
+	switch command {
+	case "run", "init", "reset-circuit", "rollback":
+		return true
+	case "sync":
+		return !dryRun
+	case "state":
+		return action == "clean"
+	}
+	return false
+}
+
+// registerConfigFlags adds a flag for every setting that has one
+// Values are read back through setFlags so unset flags do not mask config files
+func registerConfigFlags(fs *flag.FlagSet) {
+	defaults := config.Default()
+	for _, s := range config.Settings {
+		if s.Flag == "" {
+			continue
+		}
+		usage := s.Doc
+		if s.Env != "" {
+			usage += " (env: " + s.Env + ")"
+		}
+		switch v := s.Get(&defaults).(type) {
+		case string:
+			fs.String(s.Flag, v, usage)
+		case int:
+			fs.Int(s.Flag, v, usage)
+		case bool:
+			fs.Bool(s.Flag, v, usage)
+		case float64:
+			fs.Float64(s.Flag, v, usage)
+		}
+	}
+}
+
+// setFlags returns the flags given on the command line with their values
+func setFlags(fs *flag.FlagSet) map[string]string {
+	flags := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		flags[f.Name] = f.Value.String()
+	})
+	return flags
+}
+
+func handleSubcommands(command string) {
 	switch command {
 	case "help", "--help", "-h":
 		printHelp()
-		os.Exit(0)
+		exit(0)
 	case "version", "--version":
 		fmt.Println("Lisa Codex v1.0.0")
 		fmt.Println("Charm TUI scaffold - Complete")
-		os.Exit(0)
+		exit(0)
+	}
+}
+
+func handleConfigCommand(action string, resolved *config.Resolved) {
+	switch action {
+	case "", "show":
+		printConfig(os.Stdout, resolved)
+	case "validate":
+		if err := os.Chdir(resolved.Config.ProjectPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error changing to project directory: %v\n", err)
+			exit(1)
+		}
+		errs := config.Validate(resolved.Config)
+		if err := validateBackend(resolved.Config); err != nil {
+			errs = append(errs, err)
+		}
+		if len(errs) > 0 {
+			for _, err := range errs {
+				fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			}
+			exit(1)
+		}
+		fmt.Println("✅ Configuration is valid")
+		for _, path := range resolved.Files {
+			fmt.Printf("   Read: %s\n", path)
+		}
 	default:
-		handleRunCommand(projectDir, promptFile, maxCalls, timeout, useMonitor, verbose, backend, ocSettings, logFormat)
+		fmt.Fprintf(os.Stderr, "Error: unknown config action '%s' (want show or validate)\n", action)
+		exit(1)
 	}
 }
 
-func handleInitCommand(mode string, projectDir string, maxCalls int, timeout int, verbose bool, backend string, ocSettings openCodeSettings, logFormat string) {
-	if err := os.Chdir(projectDir); err != nil {
+// printConfig writes every setting with its effective value and where it came from
+func printConfig(w io.Writer, resolved *config.Resolved) {
+	if resolved.Profile != "" {
+		fmt.Fprintf(w, "Profile: %s\n", resolved.Profile)
+	}
+	if len(resolved.Files) == 0 {
+		fmt.Fprintln(w, "Config files: none")
+	} else {
+		fmt.Fprintln(w, "Config files:")
+		for _, path := range resolved.Files {
+			fmt.Fprintf(w, "  %s\n", path)
+		}
+	}
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	for _, s := range config.Settings {
+		value := fmt.Sprint(s.Get(&resolved.Config))
+		switch {
+		case s.Secret && value != "":
+			value = "********"
+		case value == "":
+			value = `""`
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Key, value, resolved.Source(s.Key))
+	}
+	tw.Flush()
+}
+
+func handleInitCommand(mode string, config loop.Config, logFormat string) {
+	if err := os.Chdir(config.ProjectPath); err != nil {
 		fmt.Fprintf(os.Stderr, "Error changing to project directory: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Determine init mode
@@ -167,7 +345,7 @@ func handleInitCommand(mode string, projectDir string, maxCalls int, timeout int
 		initMode = ""
 	default:
 		fmt.Fprintf(os.Stderr, "Error: unknown mode '%s'. Use: implementation, fix, or refactor\n", mode)
-		os.Exit(1)
+		exit(1)
 	}
 
 	opts := project.InitOptions{
@@ -182,12 +360,12 @@ func handleInitCommand(mode string, projectDir string, maxCalls int, timeout int
 	result, err := project.Init(opts)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "\n❌ Error: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	if !result.Success {
 		fmt.Fprintf(os.Stderr, "\n❌ Initialization failed\n")
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Print success message based on mode
@@ -221,22 +399,19 @@ func handleInitCommand(mode string, projectDir string, maxCalls int, timeout int
 	}
 
 	// Now launch the TUI
-	config := loop.Config{
-		Backend:            backend,
-		ProjectPath:        ".",
-		PromptPath:         "PROMPT.md",
-		MaxCalls:           maxCalls,
-		Timeout:            timeout,
-		Verbose:            verbose,
-		ResetCircuit:       false,
-		OpenCodeServerURL:  ocSettings.serverURL,
-		OpenCodeUsername:   ocSettings.username,
-		OpenCodePassword:   ocSettings.password,
-		OpenCodeModelID:    ocSettings.modelID,
-	}
-
-	rateLimiter := loop.NewRateLimiter(config.MaxCalls, 1)
-	breaker := circuit.NewBreaker(3, 5)
+	config.ProjectPath = "."
+
+	if err := validateBackend(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+
+	rateLimiter, err := newRateLimiter(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+	breaker := circuit.NewBreaker(config.CircuitNoProgressThreshold, config.CircuitSameErrorThreshold)
 	controller := loop.NewController(config, rateLimiter, breaker)
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -244,16 +419,16 @@ func handleInitCommand(mode string, projectDir string, maxCalls int, timeout int
 
 	// Use log mode if log format is specified, otherwise use TUI
 	if logFormat != "" {
-		runWithLogs(ctx, controller, config, verbose, logFormat)
+		runWithLogs(ctx, controller, config, config.Verbose, logFormat)
 	} else {
-		runWithMonitor(ctx, controller, config, verbose, loopMode)
+		runWithMonitor(ctx, controller, config, config.Verbose, loopMode)
 	}
 }
 
 func handleSetupCommand(projectName string, prompt string, init bool, withGit bool, verbose bool) {
 	if projectName == "" && !init {
 		fmt.Fprintln(os.Stderr, "Error: --name is required for setup command (or use --init for current directory)")
-		os.Exit(1)
+		exit(1)
 	}
 
 	// If --init is used without --name, use current directory name
@@ -261,7 +436,7 @@ func handleSetupCommand(projectName string, prompt string, init bool, withGit bo
 		wd, err := os.Getwd()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: could not get current directory: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 		projectName = wd
 	}
@@ -278,12 +453,12 @@ func handleSetupCommand(projectName string, prompt string, init bool, withGit bo
 	result, err := project.Setup(opts)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error setting up project: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	if !result.Success {
 		fmt.Fprintf(os.Stderr, "Project setup failed\n")
-		os.Exit(1)
+		exit(1)
 	}
 
 	fmt.Printf("✅ Project created successfully!\n")
@@ -300,13 +475,13 @@ func handleSetupCommand(projectName string, prompt string, init bool, withGit bo
 func handleImportCommand(sourcePath string, projectName string, outputDir string, verbose bool) {
 	if sourcePath == "" {
 		fmt.Fprintln(os.Stderr, "Error: --source is required for import command")
-		os.Exit(1)
+		exit(1)
 	}
 
 	if !project.IsSupportedFormat(sourcePath) {
 		fmt.Fprintf(os.Stderr, "Error: unsupported file format: %s\n", sourcePath)
 		fmt.Fprintln(os.Stderr, "Supported formats:", project.SupportedFormats())
-		os.Exit(1)
+		exit(1)
 	}
 
 	opts := project.ImportOptions{
@@ -320,12 +495,12 @@ func handleImportCommand(sourcePath string, projectName string, outputDir string
 	result, err := project.ImportPRD(opts)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error importing PRD: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	if !result.Success {
 		fmt.Fprintf(os.Stderr, "Import failed\n")
-		os.Exit(1)
+		exit(1)
 	}
 
 	fmt.Printf("✅ Import completed successfully!\n")
@@ -345,16 +520,16 @@ func handleImportCommand(sourcePath string, projectName string, outputDir string
 	fmt.Println("  ralph --monitor")
 }
 
-func handleStatusCommand(projectPath string) {
+func handleStatusCommand(projectPath string, config loop.Config) {
 	if err := os.Chdir(projectPath); err != nil {
 		fmt.Fprintf(os.Stderr, "Error changing to project directory: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	if err := project.ValidateProject(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		fmt.Fprintf(os.Stderr, "Run 'ralph setup' to create a new project\n")
-		os.Exit(1)
+		exit(1)
 	}
 
 	fmt.Println("✅ Valid Lisa Codex project")
@@ -362,35 +537,71 @@ func handleStatusCommand(projectPath string) {
 	projectRoot, err := project.GetProjectRoot()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error finding project root: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	fmt.Printf("   Project root: %s\n", projectRoot)
 
-	tasks, err := loop.LoadFixPlan()
+	doc, err := loop.LoadPlanDocument()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: could not load @fix_plan.md: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Warning: could not load plan: %v\n", err)
 	} else {
-		completed := 0
-		for _, task := range tasks {
-			if len(task) > 0 && task[0] == '[' {
-				completed++
+		completed, total := doc.Counts()
+		fmt.Printf("   Plan file: %s\n", doc.Path)
+		fmt.Printf("   Tasks: %d/%d completed\n", completed, total)
+		for _, t := range doc.Remaining() {
+			if t.Blocked != "" {
+				fmt.Printf("   BLOCKED: %s (%s)\n", t.Text, t.Blocked)
 			}
 		}
-		fmt.Printf("   Tasks: %d/%d completed\n", completed, len(tasks))
+	}
+
+	tracker := budget.NewTracker(budget.Limits{
+		DayTokens: config.BudgetDayTokens,
+		DayCost:   config.BudgetDayCost,
+	})
+	if err := tracker.LoadState(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not load budget usage: %v\n", err)
+	} else {
+		fmt.Printf("   Spent today: %s\n", tracker.Status().DaySummary())
+	}
+
+	breaker := circuit.NewBreaker(config.CircuitNoProgressThreshold, config.CircuitSameErrorThreshold)
+	breaker.SetRecovery(time.Duration(config.CircuitCooldown)*time.Second, config.CircuitHalfOpenProbes)
+	breaker, err = breaker.LoadState()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not load circuit breaker state: %v\n", err)
+		return
+	}
+	fmt.Printf("   Circuit: %s\n", breaker.GetState())
+	if at, ok := breaker.NextProbe(); ok {
+		fmt.Printf("   Next probe: %s\n", at.Format("2006-01-02 15:04:05"))
+	} else if breaker.IsProbing() {
+		fmt.Printf("   Probes left: %d\n", breaker.ProbesLeft())
+	}
+	groups := breaker.GetErrorGroups()
+	if len(groups) > 0 {
+		fmt.Printf("   Errors (%d distinct, circuit opens at %d of one):\n", len(groups), config.CircuitSameErrorThreshold*2)
+		for _, g := range groups {
+			sample := strings.Join(strings.Fields(g.Sample), " ")
+			if len(sample) > 80 {
+				sample = sample[:77] + "..."
+			}
+			fmt.Printf("     %3d× %s (last %s)\n", g.Count, sample, g.LastSeen.Format("2006-01-02 15:04:05"))
+		}
 	}
 }
 
-func handleResetCircuitCommand(projectPath string) {
-	if err := os.Chdir(projectPath); err != nil {
+func handleResetCircuitCommand(config loop.Config) {
+	if err := os.Chdir(config.ProjectPath); err != nil {
 		fmt.Fprintf(os.Stderr, "Error changing to project directory: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
-	breaker := circuit.NewBreaker(3, 5)
+	breaker := circuit.NewBreaker(config.CircuitNoProgressThreshold, config.CircuitSameErrorThreshold)
 	if err := breaker.Reset(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error resetting circuit breaker: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	fmt.Println("✅ Circuit breaker reset successfully")
@@ -400,99 +611,279 @@ func handleResetCircuitCommand(projectPath string) {
 	fmt.Println("  ralph --monitor")
 }
 
-func handleSyncCommand(projectPath string, verbose bool) {
+func handleStateCommand(projectPath, action string, all bool) {
+	if err := os.Chdir(projectPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error changing to project directory: %v\n", err)
+		exit(1)
+	}
+
+	switch action {
+	case "", "inspect":
+		meta, err := state.LoadMeta("")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading state directory: %v\n", err)
+			exit(1)
+		}
+		fmt.Printf("State directory: %s (layout v%d, created %s)\n", state.Dir, meta.Version, meta.CreatedAt.Format(time.RFC3339))
+		if len(meta.MigratedFrom) > 0 {
+			fmt.Printf("   Migrated: %s\n", strings.Join(meta.MigratedFrom, ", "))
+		}
+		if len(meta.Skipped) > 0 {
+			fmt.Printf("   ⚠️  Not migrated (unreadable): %s\n", strings.Join(meta.Skipped, ", "))
+		}
+
+		infos, err := state.Inspect("")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading state: %v\n", err)
+			exit(1)
+		}
+		if len(infos) == 0 {
+			fmt.Println("\nNo state recorded yet")
+		}
+		for _, info := range infos {
+			fmt.Printf("\n%s (v%d, %d bytes, updated %s)\n", info.Name, info.Version, info.Size, info.UpdatedAt.Format(time.RFC3339))
+			fmt.Println(info.Describe())
+		}
+		if owner, locked, err := state.ReadLock(""); err != nil {
+			fmt.Printf("\n⚠️  Unreadable lock: %v\n", err)
+		} else if locked {
+			fmt.Printf("\nLocked by PID %d on %s since %s (%s)\n", owner.PID, owner.Host, owner.StartedAt.Format(time.RFC3339), owner.Command)
+		}
+		if archives := state.Archives(""); len(archives) > 0 {
+			fmt.Printf("\n%s/ (%d archived sessions)\n", state.ArchiveDir, len(archives))
+		}
+		if legacy := state.LegacyFiles(""); len(legacy) > 0 {
+			fmt.Printf("\nLegacy files still present: %s\n", strings.Join(legacy, ", "))
+		}
+
+	case "clean":
+		removed, err := state.Clean("", all)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error cleaning state: %v\n", err)
+			exit(1)
+		}
+		if len(removed) == 0 {
+			fmt.Println("✅ Nothing to clean")
+			return
+		}
+		fmt.Println("✅ Removed:")
+		for _, path := range removed {
+			fmt.Printf("   %s\n", path)
+		}
+
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown state action '%s' (want inspect or clean)\n", action)
+		exit(1)
+	}
+}
+
+func handleHistoryCommand(projectPath, action, runID string, verbose bool) {
+	if err := os.Chdir(projectPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error changing to project directory: %v\n", err)
+		exit(1)
+	}
+
+	switch action {
+	case "", "list":
+		runs, err := loop.LoadRunHistory("")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading run journal: %v\n", err)
+			exit(1)
+		}
+		if len(runs) == 0 {
+			fmt.Println("No runs journaled yet")
+			return
+		}
+		fmt.Printf("%-16s  %-19s  %9s  %5s  %5s  %s\n", "RUN", "STARTED", "DURATION", "LOOPS", "TASKS", "EXIT")
+		for _, run := range runs {
+			fmt.Printf("%-16s  %-19s  %9s  %5d  %5d  %s\n",
+				run.RunID, run.Started.Format("2006-01-02 15:04:05"), run.Duration().Round(time.Second),
+				run.Iterations, run.TasksCompleted, run.ExitReason)
+		}
+
+	case "show":
+		run, entries, err := loop.LoadRunEvents("", runID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+		fmt.Printf("Run %s: %s, %d loops, %d tasks completed, %s\n",
+			run.RunID, run.Duration().Round(time.Second), run.Iterations, run.TasksCompleted, run.ExitReason)
+		hidden := 0
+		for _, entry := range entries {
+			if entry.Event.IsDetail() && !verbose {
+				hidden++
+				continue
+			}
+			lane := ""
+			if entry.Event.Worker > 0 {
+				lane = fmt.Sprintf(" w%d", entry.Event.Worker)
+			}
+			fmt.Printf("%s  loop %-3d%s  %-15s  %s\n",
+				entry.Time.Format("15:04:05.000"), entry.Loop, lane, entry.Event.Type, entry.Event.Describe())
+		}
+		if hidden > 0 {
+			fmt.Printf("\n(%d agent output and debug events hidden; use --verbose to show them)\n", hidden)
+		}
+
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown history action '%s' (want list or show)\n", action)
+		exit(1)
+	}
+}
+
+func handleSyncCommand(projectPath string, since string, dryRun bool, verbose bool) {
 	if err := os.Chdir(projectPath); err != nil {
 		fmt.Fprintf(os.Stderr, "Error changing to project directory: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
-	fmt.Println("🔄 Checking task status against filesystem...")
+	fmt.Println("🔄 Checking task status against project changes...")
 
-	result, err := loop.SyncTasksWithFilesystem(projectPath)
+	result, err := loop.SyncTasks(".", loop.SyncOptions{Since: since})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error syncing tasks: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	if len(result.Evidence) == 0 {
 		fmt.Println("   No task evidence found")
 		fmt.Println("\n   Note: Sync looks for:")
-		fmt.Println("   - Test config files (vitest.config.ts, jest.config.ts)")
-		fmt.Println("   - New files created by 'Add'/'Create' tasks")
+		fmt.Println("   - Changed files matching backticked paths in tasks (git)")
+		fmt.Println("   - Added identifiers matching backticked names in tasks (git)")
+		fmt.Println("   - New files created by 'Add'/'Create' tasks (without git)")
+		return
+	}
+
+	fmt.Printf("   Plan file: %s\n\n", result.PlanFile)
+	printSyncTable(result.Evidence, verbose)
+
+	if result.TasksUpdated == 0 {
+		fmt.Println("\n   No tasks auto-marked (use the plan file to manually mark tasks as [x])")
 		return
 	}
 
-	fmt.Printf("   Plan file: %s\n", result.PlanFile)
-	fmt.Println("\n   Evidence found:")
-	for _, ev := range result.Evidence {
-		status := "❓"
+	if dryRun {
+		fmt.Printf("\n   Dry run: %d task(s) would be marked complete\n", result.TasksUpdated)
+		return
+	}
+
+	fmt.Printf("\n   Auto-marking %d task(s) with high confidence...\n", result.TasksUpdated)
+	if err := loop.ApplySyncResult(result); err != nil {
+		fmt.Fprintf(os.Stderr, "Error updating plan file: %v\n", err)
+		exit(1)
+	}
+	fmt.Println("   ✅ Plan file updated")
+}
+
+// printSyncTable prints task evidence as a table of proposed marks
+func printSyncTable(evidence []loop.TaskEvidence, verbose bool) {
+	fmt.Printf("   %-4s  %-5s  %-50s  %s\n", "MARK", "CONF", "TASK", "EVIDENCE")
+	for _, ev := range evidence {
+		mark := "-"
 		if ev.ShouldMark {
-			status = "✅"
-		}
-		taskPreview := ev.TaskText
-		if len(taskPreview) > 60 {
-			taskPreview = taskPreview[:60] + "..."
+			mark = "✅"
 		}
-		fmt.Printf("   %s %s\n", status, taskPreview)
-		for _, f := range ev.FilesFound {
-			fmt.Printf("      └─ %s\n", f)
-		}
-		if ev.Reason != "" {
-			fmt.Printf("      └─ %s (%.0f%% confidence)\n", ev.Reason, ev.Confidence*100)
+
+		taskPreview := strings.TrimPrefix(ev.TaskText, "[ ] ")
+		if len(taskPreview) > 50 {
+			taskPreview = taskPreview[:47] + "..."
 		}
-	}
 
-	if result.TasksUpdated > 0 {
-		fmt.Printf("\n   Auto-marking %d task(s) with high confidence...\n", result.TasksUpdated)
-		if err := loop.ApplySyncResult(result); err != nil {
-			fmt.Fprintf(os.Stderr, "Error updating plan file: %v\n", err)
-			os.Exit(1)
+		fmt.Printf("   %-4s  %4.0f%%  %-50s  %s\n", mark, ev.Confidence*100, taskPreview, ev.Reason)
+		if verbose {
+			for _, f := range ev.FilesFound {
+				fmt.Printf("   %-4s  %-5s  └─ %s\n", "", "", f)
+			}
 		}
-		fmt.Println("   ✅ Plan file updated")
-	} else {
-		fmt.Println("\n   No tasks auto-marked (use the plan file to manually mark tasks as [x])")
 	}
 }
 
-func handleRunCommand(projectPath string, promptFile string, maxCalls int, timeout int, useMonitor bool, verbose bool, backend string, ocSettings openCodeSettings, logFormat string) {
+func handleRollbackCommand(projectPath string, loopNum int, runID string) {
 	if err := os.Chdir(projectPath); err != nil {
 		fmt.Fprintf(os.Stderr, "Error changing to project directory: %v\n", err)
-		os.Exit(1)
+		exit(1)
+	}
+
+	if loopNum <= 0 {
+		fmt.Fprintf(os.Stderr, "Error: --to <loop> is required\n")
+		exit(1)
+	}
+
+	cp, err := checkpoint.RestoreTo(".", loopNum, runID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error restoring checkpoint: %v\n", err)
+		exit(1)
+	}
+
+	fmt.Printf("✅ Restored checkpoint before loop %d\n", cp.Loop)
+	fmt.Printf("   Run: %s\n", cp.RunID)
+	fmt.Printf("   Branch: %s\n", cp.Branch)
+	fmt.Printf("   Commit: %s\n", cp.SHA)
+}
+
+func handleRunCommand(config loop.Config, useMonitor bool, logFormat string, resume, fresh bool) {
+	if resume && fresh {
+		fmt.Fprintf(os.Stderr, "Error: --resume and --fresh cannot be used together\n")
+		exit(1)
+	}
+
+	// Keep the project path valid after the chdir below
+	if abs, err := filepath.Abs(config.ProjectPath); err == nil {
+		config.ProjectPath = abs
+	}
+
+	if err := os.Chdir(config.ProjectPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error changing to project directory: %v\n", err)
+		exit(1)
 	}
 
 	if err := project.ValidateProject(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		fmt.Fprintf(os.Stderr, "Run 'ralph setup' to create a new project\n")
-		os.Exit(1)
+		exit(1)
 	}
 
-	config := loop.Config{
-		Backend:            backend,
-		ProjectPath:        projectPath,
-		PromptPath:         promptFile,
-		MaxCalls:           maxCalls,
-		Timeout:            timeout,
-		Verbose:            verbose,
-		ResetCircuit:       false,
-		OpenCodeServerURL:  ocSettings.serverURL,
-		OpenCodeUsername:   ocSettings.username,
-		OpenCodePassword:   ocSettings.password,
-		OpenCodeModelID:    ocSettings.modelID,
+	if err := validateBackend(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
 	}
 
-	rateLimiter := loop.NewRateLimiter(config.MaxCalls, 1)
-	breaker := circuit.NewBreaker(3, 5)
+	rateLimiter, err := newRateLimiter(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+	breaker := circuit.NewBreaker(config.CircuitNoProgressThreshold, config.CircuitSameErrorThreshold)
 	controller := loop.NewController(config, rateLimiter, breaker)
 
+	switch {
+	case resume:
+		rec, err := state.LoadRunRecord()
+		if err == nil {
+			err = controller.ResumeRun(rec)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: cannot resume: %v\n", err)
+			exit(1)
+		}
+		fmt.Printf("⏯️  Resuming run %s after loop %d\n", rec.ID, rec.Loop)
+	case fresh:
+		if err := controller.StartFresh(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	setupGracefulShutdown(cancel, controller)
 
 	if logFormat != "" {
-		runWithLogs(ctx, controller, config, verbose, logFormat)
+		runWithLogs(ctx, controller, config, config.Verbose, logFormat)
 	} else if useMonitor {
-		runWithMonitor(ctx, controller, config, verbose)
+		runWithMonitor(ctx, controller, config, config.Verbose)
 	} else {
-		runHeadless(ctx, controller, config, verbose)
+		runHeadless(ctx, controller, config, config.Verbose)
 	}
 }
 
@@ -515,9 +906,12 @@ func runWithMonitor(ctx context.Context, controller *loop.Controller, config loo
 	} else {
 		program = tui.NewProgram(tuiConfig, controller)
 	}
-	if err := program.Run(); err != nil {
+	// Quitting, or a signal cancelling ctx, waits for the loop to stop before the TUI exits
+	err := program.Run(ctx)
+	stopGracefully(controller)
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error running TUI: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 }
 
@@ -542,33 +936,32 @@ func runHeadless(ctx context.Context, controller *loop.Controller, config loop.C
 				levelEmoji = "✅"
 			}
 			fmt.Printf("%s %s\n", levelEmoji, event.LogMessage)
+		case "state_change":
+			fmt.Printf("🔌 Circuit %s → %s: %s\n", event.PrevCircuitState, event.CircuitState, event.LogMessage)
 		case "loop_update":
 			if verbose {
 				fmt.Printf("📊 Loop %d | Calls: %d | Status: %s | Circuit: %s\n",
 					event.LoopNumber, event.CallsUsed, event.Status, event.CircuitState)
 			}
+		case "usage":
+			if verbose && event.Budget != nil {
+				fmt.Printf("💰 Spent: %s\n", event.Budget.Summary())
+			}
 		}
 	})
 
-	errCh := make(chan error, 1)
-	go func() {
-		defer close(errCh)
-		if err := controller.Run(ctx); err != nil {
-			errCh <- err
-		}
-	}()
-
-	select {
-	case err := <-errCh:
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "\n❌ Loop error: %v\n", err)
-			os.Exit(1)
-		}
-		fmt.Println("\n✅ Lisa Codex loop completed successfully")
-	case <-ctx.Done():
+	// A signal cancels ctx; Run then stops the backend and records the run before returning
+	err := controller.Run(ctx)
+	switch {
+	case ctx.Err() != nil:
+		stopGracefully(controller)
 		fmt.Println("\n🛑 Lisa Codex stopped by user")
-		os.Exit(0)
+		exit(0)
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "\n❌ Loop error: %v\n", err)
+		exit(1)
 	}
+	fmt.Println("\n✅ Lisa Codex loop completed successfully")
 }
 
 func runWithLogs(ctx context.Context, controller *loop.Controller, config loop.Config, verbose bool, logFormat string) {
@@ -616,6 +1009,12 @@ func runWithLogs(ctx context.Context, controller *loop.Controller, config loop.C
 				logger.Debug(event.LogMessage)
 			}
 
+		case "state_change":
+			logger.Warn("Circuit breaker "+strings.ToLower(event.CircuitState),
+				"from", event.PrevCircuitState,
+				"reason", event.LogMessage,
+			)
+
 		case "loop_update":
 			logger.Info("Loop update",
 				"loop", event.LoopNumber,
@@ -624,6 +1023,16 @@ func runWithLogs(ctx context.Context, controller *loop.Controller, config loop.C
 				"circuit", event.CircuitState,
 			)
 
+		case "usage":
+			if event.Budget != nil {
+				logger.Debug("Usage",
+					"run_tokens", event.Budget.Run.Tokens(),
+					"run_cost", event.Budget.Run.Cost,
+					"day_tokens", event.Budget.Day.Tokens(),
+					"day_cost", event.Budget.Day.Cost,
+				)
+			}
+
 		case "codex_output":
 			if verbose {
 				logger.Debug("Output",
@@ -653,30 +1062,39 @@ func runWithLogs(ctx context.Context, controller *loop.Controller, config loop.C
 				"exit_signal", event.ExitSignal,
 				"confidence", event.ConfidenceScore,
 			)
-		}
-	})
 
-	errCh := make(chan error, 1)
-	go func() {
-		defer close(errCh)
-		if err := controller.Run(ctx); err != nil {
-			errCh <- err
+		case "verification":
+			if event.Verification != nil {
+				logger.Info("Verification result",
+					"command", event.Verification.Command,
+					"passed", event.Verification.Passed,
+					"exit_code", event.Verification.ExitCode,
+					"duration", event.Verification.Duration,
+				)
+			}
 		}
-	}()
+	})
 
-	select {
-	case err := <-errCh:
-		if err != nil {
-			logger.Error("Loop error", "error", err)
-			os.Exit(1)
-		}
-		logger.Info("Lisa Codex loop completed successfully")
-	case <-ctx.Done():
+	// A signal cancels ctx; Run then stops the backend and records the run before returning
+	err := controller.Run(ctx)
+	switch {
+	case ctx.Err() != nil:
+		stopGracefully(controller)
 		logger.Warn("Lisa Codex stopped by user")
-		os.Exit(0)
+		exit(0)
+	case err != nil:
+		logger.Error("Loop error", "error", err)
+		exit(1)
 	}
+	logger.Info("Lisa Codex loop completed successfully")
 }
 
+// shutdownTimeout bounds how long a signal waits for the loop to stop before exiting anyway
+const shutdownTimeout = 15 * time.Second
+
+// setupGracefulShutdown cancels the loop on SIGINT or SIGTERM
+// The code running the loop exits once Run has returned; if the loop doesn't stop in
+// time, the process exits here instead
 func setupGracefulShutdown(cancel context.CancelFunc, controller *loop.Controller) {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -687,15 +1105,25 @@ func setupGracefulShutdown(cancel context.CancelFunc, controller *loop.Controlle
 		fmt.Println("Performing graceful shutdown...")
 
 		cancel()
+		if controller.WaitForRun(shutdownTimeout) {
+			return
+		}
 
+		fmt.Fprintf(os.Stderr, "Loop did not stop within %s; exiting\n", shutdownTimeout)
 		if err := controller.GracefulExit(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error during graceful exit: %v\n", err)
 		}
-
-		os.Exit(0)
+		exit(1)
 	}()
 }
 
+// stopGracefully cleans up after Run returned because the loop was cancelled
+func stopGracefully(controller *loop.Controller) {
+	if err := controller.GracefulExit(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error during graceful exit: %v\n", err)
+	}
+}
+
 func isCommand(arg string) bool {
 	validCommands := map[string]bool{
 		"run":           true,
@@ -705,6 +1133,10 @@ func isCommand(arg string) bool {
 		"status":        true,
 		"sync":          true,
 		"reset-circuit": true,
+		"rollback":      true,
+		"config":        true,
+		"state":         true,
+		"history":       true,
 		"help":          true,
 		"version":       true,
 	}
@@ -741,26 +1173,73 @@ func printHelp() {
 	fmt.Println("  setup              Create a new Lisa-managed project")
 	fmt.Println("  import             Import PRD or specification document")
 	fmt.Println("  status             Show project status")
-	fmt.Println("  sync               Sync task status with filesystem (detect completed tasks)")
+	fmt.Println("  sync               Sync task status with git changes (detect completed tasks)")
 	fmt.Println("  reset-circuit      Reset circuit breaker state")
+	fmt.Println("  rollback           Restore the work tree to a loop checkpoint")
+	fmt.Println("  config show        Print the effective configuration and where each value came from")
+	fmt.Println("  config validate    Check config files, environment and flags for errors")
+	fmt.Println("  state inspect      Show the documents in the .lisa/ state directory")
+	fmt.Println("  state clean        Remove run state (rate limit, sessions, circuit breaker)")
+	fmt.Println("  history            List past runs with duration, loops, tasks and exit reason")
+	fmt.Println("  history show [run] Print the event timeline of a run (default: most recent)")
 	fmt.Println("  help               Show this help")
 	fmt.Println("  version            Show version")
 	fmt.Println("")
 	fmt.Println("Options:")
 	fmt.Println("  --project <path>        Project directory (default: .)")
 	fmt.Println("  --prompt <file>         Prompt file (default: PROMPT.md)")
+	fmt.Println("  --plan <file>           Plan file (default: detected from the project mode)")
 	fmt.Println("  --calls <number>        Max loop iterations (default: 3, 10 for opencode)")
 	fmt.Println("  --timeout <seconds>     Codex timeout (default: 600)")
 	fmt.Println("  --monitor               Enable integrated TUI monitoring")
 	fmt.Println("  --verbose               Verbose output")
 	fmt.Println("  --log-format <format>   Log format: text, json, or logfmt (enables CLI log mode)")
+	fmt.Println("  --force                 Take over a project lock left by a run that is no longer active")
+	fmt.Println("  --resume                Continue the interrupted run (run ID, loop counter, sessions)")
+	fmt.Println("  --fresh                 Start over: reset the circuit breaker and forget backend sessions")
+	fmt.Println("  --rate-limit <windows>  Calls allowed across all Lisa processes, e.g. \"40/1h,300/1d\" (env: LISA_RATE_LIMIT)")
+	fmt.Println("")
+	fmt.Println("Configuration options:")
+	fmt.Println("  --config <file>         Project config file (default: lisa.yaml or .lisa.yaml, searched upward)")
+	fmt.Println("  --profile <name>        Apply a profile from the config files (env: LISA_PROFILE)")
+	fmt.Println("")
+	fmt.Println("Verification options:")
+	fmt.Println("  --verify <command>      Command run after each loop, e.g. \"go test ./...\" (env: LISA_VERIFY_COMMAND)")
+	fmt.Println("  --verify-timeout <sec>  Verification timeout (default: 300)")
+	fmt.Println("  --verify-uncheck        Un-mark tasks ticked during a loop that fails verification")
+	fmt.Println("")
+	fmt.Println("Checkpoint options:")
+	fmt.Println("  --checkpoint            Commit each loop on a lisa/run-<id> git branch")
+	fmt.Println("  --rollback-on-failure   Roll back loops that fail verification or open the circuit (default: true)")
+	fmt.Println("")
+	fmt.Println("Scheduling options:")
+	fmt.Println("  --focus                 Work on one controller-selected task per loop")
+	fmt.Println("  --max-task-attempts <n> Failed attempts before a task is marked BLOCKED (default: 3)")
+	fmt.Println("  --parallel <n>          Run up to n ready tasks at once in separate git worktrees")
+	fmt.Println("")
+	fmt.Println("Budget options:")
+	fmt.Println("  --budget-tokens <n>     Tokens the run may use (default: unlimited; budget.day_tokens caps the day)")
+	fmt.Println("  --budget-cost <amount>  Cost the run may incur (default: unlimited; budget.day_cost caps the day)")
 	fmt.Println("")
 	fmt.Println("Backend options:")
-	fmt.Println("  --backend <name>        Backend: cli or opencode (default: cli)")
+	fmt.Println("  --backend <name>        Backend: cli, opencode, openai, command or replay (default: cli)")
 	fmt.Println("  --opencode-url <url>    OpenCode server URL (env: OPENCODE_SERVER_URL)")
 	fmt.Println("  --opencode-user <user>  OpenCode username (env: OPENCODE_SERVER_USERNAME, default: opencode)")
 	fmt.Println("  --opencode-pass <pass>  OpenCode password (env: OPENCODE_SERVER_PASSWORD)")
 	fmt.Println("  --opencode-model <id>   OpenCode model ID (env: OPENCODE_MODEL_ID, default: glm-4.7)")
+	fmt.Println("  --openai-url <url>      Chat completions base URL (env: OPENAI_BASE_URL)")
+	fmt.Println("  --openai-key <key>      API key (env: OPENAI_API_KEY)")
+	fmt.Println("  --openai-model <name>   Model name (env: OPENAI_MODEL)")
+	fmt.Println("  --command <cmdline>     Agent command for the command backend (env: LISA_COMMAND)")
+	fmt.Println("  --command-prompt <mode> Pass the prompt on stdin, as an arg or in a file (default: stdin)")
+	fmt.Println("  --command-output <fmt>  Output: text, codex, claude, or a JSONL field mapping (default: text)")
+	fmt.Println("  --command-resume <args> Arguments added when a session ID is saved, e.g. \"--resume {session_id}\"")
+	fmt.Println("")
+	fmt.Println("Record and replay options:")
+	fmt.Println("  --record <dir>          Record every prompt, event stream and output to dir")
+	fmt.Println("  --replay-dir <dir>      Recordings served by --backend replay")
+	fmt.Println("  --replay-match <mode>   Match calls by loop number or prompt hash: loop or hash (default: loop)")
+	fmt.Println("  --replay-timing         Replay events with their original timing")
 	fmt.Println("")
 	fmt.Println("Init command options:")
 	fmt.Println("  --mode <mode>           Mode: implementation, fix, or refactor (auto-detect)")
@@ -771,6 +1250,17 @@ func printHelp() {
 	fmt.Println("  --init                  Initialize in current directory (existing project)")
 	fmt.Println("  --git                   Initialize git (default: true)")
 	fmt.Println("")
+	fmt.Println("Sync command options:")
+	fmt.Println("  --since <ref>           Git ref to diff against (default: start of last checkpointed run, else HEAD)")
+	fmt.Println("  --dry-run               Show proposed task marks without updating the plan")
+	fmt.Println("")
+	fmt.Println("Rollback command options:")
+	fmt.Println("  --to <loop>             Loop whose starting checkpoint to restore (required)")
+	fmt.Println("  --run <id>              Run ID to restore from (default: most recent)")
+	fmt.Println("")
+	fmt.Println("State command options:")
+	fmt.Println("  --all                   Also remove checkpoint history and session archives (state clean)")
+	fmt.Println("")
 	fmt.Println("Import command options:")
 	fmt.Println("  --source <file>         Source file to import (required)")
 	fmt.Println("  --import-name <name>    Project name (auto-detect if empty)")
@@ -783,25 +1273,44 @@ func printHelp() {
 	fmt.Println("  ?            Show help")
 }
 
-// envFallback returns the flag value if set, otherwise checks the environment variable,
-// and finally returns the default value.
-func envFallback(flagValue, envName, defaultValue string) string {
-	if flagValue != "" {
-		return flagValue
-	}
-	if env := os.Getenv(envName); env != "" {
-		return env
+// validateBackend checks backend settings that would otherwise only fail on the first loop
+func validateBackend(config loop.Config) error {
+	switch config.Backend {
+	case "command":
+		return command.Validate(config)
+	case "openai":
+		if config.OpenAIBaseURL == "" {
+			return fmt.Errorf("openai backend needs a base URL (--openai-url or OPENAI_BASE_URL)")
+		}
+	case "replay":
+		if config.ReplayMatch != runner.ReplayMatchLoop && config.ReplayMatch != runner.ReplayMatchHash {
+			return fmt.Errorf("unknown --replay-match %q (want loop or hash)", config.ReplayMatch)
+		}
+		recs, err := runner.LoadRecordings(config.ReplayDir)
+		if err != nil {
+			return err
+		}
+		if len(recs) == 0 {
+			return fmt.Errorf("replay backend needs recordings (--replay-dir %q has none)", config.ReplayDir)
+		}
 	}
-	return defaultValue
+	return nil
 }
 
-// isFlagSet checks if a flag was explicitly set on the command line.
-func isFlagSet(fs *flag.FlagSet, name string) bool {
-	found := false
-	fs.Visit(func(f *flag.Flag) {
-		if f.Name == name {
-			found = true
-		}
-	})
-	return found
+// newRateLimiter limits every Lisa process of the user together to --calls per
+// window_hours and the --rate-limit windows, counting calls in the user's call log
+func newRateLimiter(cfg loop.Config) (*loop.RateLimiter, error) {
+	windows, err := config.ParseRateWindows(cfg.RateLimitWindows)
+	if err != nil {
+		return nil, err
+	}
+	rateLimiter := loop.NewRateLimiter(cfg.MaxCalls, cfg.RateLimitHours)
+	path, err := state.UserCallLogPath()
+	if err != nil {
+		return nil, err
+	}
+	if err := rateLimiter.ShareWindows(path, windows); err != nil {
+		return nil, fmt.Errorf("failed to read call log: %w", err)
+	}
+	return rateLimiter, nil
 }
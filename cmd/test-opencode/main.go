@@ -9,6 +9,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -103,7 +104,7 @@ func main() {
 		logger.Debug("Event received", "event", event["event"])
 	})
 
-	output, sid, err := runner.Run("What is 2 + 2? Reply with just the number.")
+	output, sid, err := runner.Run(context.Background(), "What is 2 + 2? Reply with just the number.")
 	if err != nil {
 		logger.Fatal("Runner failed", "error", err)
 	}
@@ -128,7 +129,7 @@ func main() {
 
 	// Test 5: Resume session
 	logger.Info("Test 5: Testing session resume...")
-	output2, sid2, err := runner.Run("What was the previous question I asked?")
+	output2, sid2, err := runner.Run(context.Background(), "What was the previous question I asked?")
 	if err != nil {
 		logger.Fatal("Failed to resume session", "error", err)
 	}
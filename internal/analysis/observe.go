@@ -0,0 +1,108 @@
+package analysis
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Observation is what a loop was seen to do, as opposed to what the agent claims it did
+type Observation struct {
+	FilesChanged []string // Paths changed in the work tree during the loop
+	FilesKnown   bool     // The work tree could be compared, so FilesChanged can be trusted
+
+	TasksChecked   int  // Plan tasks that went from unchecked to checked
+	TasksUnchecked int  // Plan tasks still unchecked after the loop
+	PlanKnown      bool // The plan could be read before and after the loop
+
+	ToolCalls  int  // Tool calls in the backend's event stream, not counting the status report
+	ToolsKnown bool // The backend streams its tool calls, so ToolCalls can be trusted
+}
+
+// discrepancyPenalty is how much each discrepancy lowers confidence
+const discrepancyPenalty = 0.25
+
+// ApplyObservation checks the agent's claims against what the loop was observed to do.
+// The observed file and task counts replace the claimed ones. Each discrepancy is
+// recorded in Discrepancies, cancels any exit signal and lowers confidence, which is
+// recomputed from the observation instead of completion keywords in the output.
+func (a *Analysis) ApplyObservation(obs Observation) {
+	if a == nil {
+		return
+	}
+	if a.Status == nil {
+		a.Status = &RALPHStatus{Status: "UNKNOWN", WorkType: "UNKNOWN"}
+	}
+	claimedFiles, claimedTasks := a.Status.FilesModified, a.Status.TasksCompleted
+
+	var found []string
+	if obs.FilesKnown {
+		if claimedFiles > 0 && len(obs.FilesChanged) == 0 {
+			found = append(found, fmt.Sprintf("claimed %d files modified, changed 0", claimedFiles))
+		} else if a.Report != nil {
+			if unchanged := unchangedPaths(a.Report.FilesTouched, obs.FilesChanged); len(unchanged) > 0 {
+				found = append(found, fmt.Sprintf("files_touched lists %s, which did not change", strings.Join(unchanged, ", ")))
+			}
+		}
+		a.Status.FilesModified = len(obs.FilesChanged)
+	}
+
+	if obs.PlanKnown {
+		if claimedTasks > obs.TasksChecked {
+			found = append(found, fmt.Sprintf("claimed %d tasks completed, %d checked off in the plan", claimedTasks, obs.TasksChecked))
+		}
+		if obs.TasksUnchecked > 0 {
+			if a.ExitSignal {
+				found = append(found, fmt.Sprintf("EXIT_SIGNAL with %d unchecked tasks", obs.TasksUnchecked))
+			} else if a.Status.Status == "COMPLETE" {
+				found = append(found, fmt.Sprintf("STATUS: COMPLETE with %d unchecked tasks", obs.TasksUnchecked))
+			}
+		}
+		a.Status.TasksCompleted = obs.TasksChecked
+	}
+
+	if obs.ToolsKnown && obs.ToolCalls == 0 && (claimedFiles > 0 || claimedTasks > 0) {
+		found = append(found, "claimed work without making any tool calls")
+	}
+
+	if len(found) > 0 {
+		a.Discrepancies = append(a.Discrepancies, found...)
+		a.ExitSignal = false
+		a.Status.ExitSignal = false
+	}
+
+	// Observed completion takes the place of completion keywords in the output
+	confidence := calculateConfidence(a.Status, 0, "")
+	if obs.PlanKnown && obs.TasksUnchecked == 0 {
+		confidence += 0.2
+	}
+	confidence -= discrepancyPenalty * float64(len(found))
+	if confidence > 1.0 {
+		confidence = 1.0
+	}
+	if confidence < 0.0 {
+		confidence = 0.0
+	}
+	a.ConfidenceScore = confidence
+}
+
+// unchangedPaths returns the claimed paths that match none of the changed ones
+// Paths match when one ends with the other, as they may be relative to different directories
+func unchangedPaths(claimed, changed []string) []string {
+	var unchanged []string
+	for _, claim := range claimed {
+		claim = path.Clean(strings.TrimPrefix(claim, "./"))
+		found := false
+		for _, p := range changed {
+			p = path.Clean(p)
+			if p == claim || strings.HasSuffix(p, "/"+claim) || strings.HasSuffix(claim, "/"+p) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			unchanged = append(unchanged, claim)
+		}
+	}
+	return unchanged
+}
@@ -0,0 +1,78 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplyObservation(t *testing.T) {
+	legacy := `---RALPH_STATUS---
+STATUS: COMPLETE
+TASKS_COMPLETED_THIS_LOOP: 2
+FILES_MODIFIED: 3
+TESTS_STATUS: PASSING
+EXIT_SIGNAL: true
+---END_RALPH_STATUS---
+All done, everything is complete and ready.`
+
+	tests := []struct {
+		name string
+		obs  Observation
+		want []string
+		exit bool
+	}{
+		{"claims hold", Observation{FilesChanged: []string{"a.go", "b.go", "c.go"}, FilesKnown: true, TasksChecked: 2, PlanKnown: true, ToolCalls: 4, ToolsKnown: true}, nil, true},
+		{"nothing observable", Observation{}, nil, true},
+		{"no files changed", Observation{FilesKnown: true}, []string{"claimed 3 files modified, changed 0"}, false},
+		{"tasks not checked", Observation{TasksChecked: 1, PlanKnown: true}, []string{"claimed 2 tasks completed, 1 checked off in the plan"}, false},
+		{"exit with open tasks", Observation{TasksChecked: 2, TasksUnchecked: 4, PlanKnown: true}, []string{"EXIT_SIGNAL with 4 unchecked tasks"}, false},
+		{"no tool calls", Observation{ToolsKnown: true}, []string{"claimed work without making any tool calls"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, _ := Analyze(legacy, nil)
+			result.ApplyObservation(tt.obs)
+
+			if strings.Join(result.Discrepancies, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("Discrepancies = %q, want %q", result.Discrepancies, tt.want)
+			}
+			if result.ExitSignal != tt.exit || result.Status.ExitSignal != tt.exit {
+				t.Errorf("ExitSignal = %v, want %v", result.ExitSignal, tt.exit)
+			}
+			if len(tt.want) > 0 && result.ConfidenceScore >= 0.9 {
+				t.Errorf("ConfidenceScore = %v, want below the completion threshold", result.ConfidenceScore)
+			}
+		})
+	}
+}
+
+func TestApplyObservation_ObservedCounts(t *testing.T) {
+	// Keywords alone no longer earn confidence once the loop is observed
+	result, _ := Analyze("Done. Finished, complete and ready.", nil)
+	if result.ConfidenceScore != 0.7 {
+		t.Fatalf("keyword confidence = %v, want 0.7", result.ConfidenceScore)
+	}
+	result.ApplyObservation(Observation{FilesChanged: []string{"main.go"}, FilesKnown: true, TasksChecked: 1, TasksUnchecked: 2, PlanKnown: true})
+
+	if result.ConfidenceScore != 0.5 {
+		t.Errorf("ConfidenceScore = %v, want 0.5", result.ConfidenceScore)
+	}
+	if result.Status.FilesModified != 1 || result.Status.TasksCompleted != 1 {
+		t.Errorf("Status = %+v, want the observed counts", result.Status)
+	}
+	if len(result.Discrepancies) != 0 {
+		t.Errorf("Discrepancies = %q, want none for under-reporting", result.Discrepancies)
+	}
+}
+
+func TestApplyObservation_FilesTouched(t *testing.T) {
+	output := "```lisa-status\n" + validReport + "\n```\n"
+	result, _ := Analyze(output, nil)
+	result.ApplyObservation(Observation{FilesChanged: []string{"internal/parse/parse.go"}, FilesKnown: true})
+
+	want := "files_touched lists parse_test.go, which did not change"
+	if len(result.Discrepancies) != 1 || result.Discrepancies[0] != want {
+		t.Errorf("Discrepancies = %q, want %q", result.Discrepancies, want)
+	}
+}
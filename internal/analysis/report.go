@@ -0,0 +1,279 @@
+package analysis
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// StatusReportVersion is the version of the status report contract in StatusSchema
+const StatusReportVersion = 1
+
+// StatusFence is the info string of the fenced code block that carries a status report
+const StatusFence = "lisa-status"
+
+// StatusTool is the tool an agent can call with its status report instead of writing the block
+const StatusTool = "lisa_report_status"
+
+// StatusSchema is the published JSON schema of the status report
+//
+//go:embed status.schema.json
+var StatusSchema []byte
+
+// Task outcomes in a status report
+const (
+	OutcomeCompleted  = "completed"
+	OutcomeInProgress = "in_progress"
+	OutcomeBlocked    = "blocked"
+	OutcomeSkipped    = "skipped"
+)
+
+// StatusReport is the structured status an agent sends at the end of a loop
+type StatusReport struct {
+	Version        int           `json:"version"`
+	Status         string        `json:"status"`                 // WORKING, COMPLETE or BLOCKED
+	CurrentTask    string        `json:"current_task,omitempty"` // Task ID
+	Tasks          []TaskOutcome `json:"tasks,omitempty"`
+	TestsStatus    string        `json:"tests_status"` // PASSING, FAILING or UNKNOWN
+	FilesTouched   []string      `json:"files_touched,omitempty"`
+	Blockers       []string      `json:"blockers,omitempty"`
+	FollowUpTasks  []string      `json:"follow_up_tasks,omitempty"`
+	ExitSignal     bool          `json:"exit_signal"`
+	Recommendation string        `json:"recommendation,omitempty"`
+}
+
+// TaskOutcome is what happened to one task during the loop
+type TaskOutcome struct {
+	ID      string `json:"id"`
+	Outcome string `json:"outcome"` // completed, in_progress, blocked or skipped
+	Note    string `json:"note,omitempty"`
+}
+
+// ReportError lists everything wrong with a rejected status report
+type ReportError struct {
+	Problems []string
+}
+
+func (e *ReportError) Error() string {
+	return "invalid status report: " + strings.Join(e.Problems, "; ")
+}
+
+var (
+	reportFields   = []string{"version", "status", "current_task", "tasks", "tests_status", "files_touched", "blockers", "follow_up_tasks", "exit_signal", "recommendation"}
+	reportRequired = []string{"version", "status", "tests_status", "exit_signal"}
+	taskFields     = []string{"id", "outcome", "note"}
+
+	reportStatuses = []string{"WORKING", "COMPLETE", "BLOCKED"}
+	testStatuses   = []string{"PASSING", "FAILING", "UNKNOWN"}
+	taskOutcomes   = []string{OutcomeCompleted, OutcomeInProgress, OutcomeBlocked, OutcomeSkipped}
+)
+
+// statusFenceRegex matches a fenced status report block
+var statusFenceRegex = regexp.MustCompile("(?s)```" + StatusFence + "[ \\t]*\\r?\\n(.*?)```")
+
+// ParseStatusReport decodes a status report and validates it against the schema
+// All problems found are returned together in a *ReportError
+func ParseStatusReport(data []byte) (*StatusReport, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, &ReportError{Problems: []string{"not a JSON object: " + err.Error()}}
+	}
+
+	var problems []string
+	problems = append(problems, unknownFields("", fields, reportFields)...)
+	for _, key := range reportRequired {
+		if _, ok := fields[key]; !ok {
+			problems = append(problems, fmt.Sprintf("missing required field %q", key))
+		}
+	}
+
+	var report StatusReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if !errors.As(err, &typeErr) {
+			return nil, &ReportError{Problems: append(problems, err.Error())}
+		}
+		problems = append(problems, fmt.Sprintf("%s: got %s, want %s", typeErr.Field, typeErr.Value, typeErr.Type))
+	}
+	var tasks []map[string]json.RawMessage
+	if json.Unmarshal(fields["tasks"], &tasks) == nil {
+		for i, task := range tasks {
+			problems = append(problems, unknownFields(fmt.Sprintf("tasks[%d].", i), task, taskFields)...)
+		}
+	}
+
+	if _, ok := fields["version"]; ok && report.Version != StatusReportVersion {
+		problems = append(problems, fmt.Sprintf("version: got %d, want %d", report.Version, StatusReportVersion))
+	}
+	if _, ok := fields["status"]; ok {
+		problems = append(problems, checkOneOf("status", report.Status, reportStatuses)...)
+	}
+	if _, ok := fields["tests_status"]; ok {
+		problems = append(problems, checkOneOf("tests_status", report.TestsStatus, testStatuses)...)
+	}
+	for i, task := range report.Tasks {
+		if strings.TrimSpace(task.ID) == "" {
+			problems = append(problems, fmt.Sprintf("tasks[%d].id: must not be empty", i))
+		}
+		problems = append(problems, checkOneOf(fmt.Sprintf("tasks[%d].outcome", i), task.Outcome, taskOutcomes)...)
+	}
+
+	// Fields that must agree with each other
+	if report.Status == "BLOCKED" && len(report.Blockers) == 0 {
+		problems = append(problems, "blockers: a BLOCKED report must list what is blocking it")
+	}
+	if report.ExitSignal && report.Status != "COMPLETE" {
+		problems = append(problems, fmt.Sprintf("exit_signal: true requires status COMPLETE (got %q)", report.Status))
+	}
+
+	if len(problems) > 0 {
+		return nil, &ReportError{Problems: problems}
+	}
+	return &report, nil
+}
+
+// FindStatusReport returns the last fenced status report in output
+func FindStatusReport(output string) ([]byte, bool) {
+	matches := statusFenceRegex.FindAllStringSubmatch(output, -1)
+	if len(matches) == 0 {
+		return nil, false
+	}
+	return []byte(strings.TrimSpace(matches[len(matches)-1][1])), true
+}
+
+// ToolStatusReport returns the arguments of a lisa_report_status tool call in a backend event
+// The call may be the event itself, a Codex item, or a content block of an assistant message
+func ToolStatusReport(event map[string]interface{}) ([]byte, bool) {
+	candidates := []interface{}{event, event["item"]}
+	candidates = append(candidates, contentBlocks(event["content"])...)
+	if msg, ok := event["message"].(map[string]interface{}); ok {
+		candidates = append(candidates, contentBlocks(msg["content"])...)
+	}
+
+	for _, candidate := range candidates {
+		call, ok := candidate.(map[string]interface{})
+		if !ok || call["name"] != StatusTool {
+			continue
+		}
+		for _, key := range []string{"input", "arguments", "parameters"} {
+			switch args := call[key].(type) {
+			case string:
+				return []byte(args), true
+			case map[string]interface{}:
+				if data, err := json.Marshal(args); err == nil {
+					return data, true
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+// Outcome returns the reported outcome of a task
+func (r *StatusReport) Outcome(id string) (TaskOutcome, bool) {
+	for _, task := range r.Tasks {
+		if task.ID == id {
+			return task, true
+		}
+	}
+	return TaskOutcome{}, false
+}
+
+// RALPHStatus maps the report onto the legacy status fields the loop acts on
+func (r *StatusReport) RALPHStatus() *RALPHStatus {
+	completed := 0
+	for _, task := range r.Tasks {
+		if task.Outcome == OutcomeCompleted {
+			completed++
+		}
+	}
+
+	recommendation := r.Recommendation
+	if r.Status == "BLOCKED" {
+		recommendation = strings.Join(r.Blockers, "; ")
+	}
+
+	return &RALPHStatus{
+		Status:         r.Status,
+		CurrentTask:    r.CurrentTask,
+		TasksCompleted: completed,
+		FilesModified:  len(r.FilesTouched),
+		TestsStatus:    r.TestsStatus,
+		WorkType:       "UNKNOWN",
+		ExitSignal:     r.ExitSignal,
+		Recommendation: recommendation,
+	}
+}
+
+// findReport returns the first valid status report: the tool call's, then the fenced block's
+// If a report was sent but none is valid, the problems with each are returned instead
+func findReport(output string, toolReport []byte) (*StatusReport, []string) {
+	var problems []string
+	if toolReport != nil {
+		report, err := ParseStatusReport(toolReport)
+		if err == nil {
+			return report, nil
+		}
+		problems = append(problems, reportProblems(StatusTool+" call", err)...)
+	}
+	if data, ok := FindStatusReport(output); ok {
+		report, err := ParseStatusReport(data)
+		if err == nil {
+			return report, nil
+		}
+		problems = append(problems, reportProblems(StatusFence+" block", err)...)
+	}
+	return nil, problems
+}
+
+// reportProblems prefixes the problems of a rejected report with where it came from
+func reportProblems(source string, err error) []string {
+	var reportErr *ReportError
+	if !errors.As(err, &reportErr) {
+		return []string{fmt.Sprintf("%s: %v", source, err)}
+	}
+	problems := make([]string, len(reportErr.Problems))
+	for i, problem := range reportErr.Problems {
+		problems[i] = fmt.Sprintf("%s: %s", source, problem)
+	}
+	return problems
+}
+
+// unknownFields reports keys of an object that the schema does not define
+func unknownFields(prefix string, fields map[string]json.RawMessage, known []string) []string {
+	var problems []string
+	for key := range fields {
+		if !contains(known, key) {
+			problems = append(problems, fmt.Sprintf("%s%s: unknown field", prefix, key))
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// checkOneOf reports a value outside its enum
+func checkOneOf(field, value string, allowed []string) []string {
+	if contains(allowed, value) {
+		return nil
+	}
+	return []string{fmt.Sprintf("%s: got %q, want one of %s", field, value, strings.Join(allowed, ", "))}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// contentBlocks returns the blocks of a message content array
+func contentBlocks(content interface{}) []interface{} {
+	blocks, _ := content.([]interface{})
+	return blocks
+}
@@ -0,0 +1,159 @@
+package analysis
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const validReport = `{
+  "version": 1,
+  "status": "WORKING",
+  "current_task": "T2",
+  "tasks": [
+    {"id": "T1", "outcome": "completed"},
+    {"id": "T2", "outcome": "in_progress", "note": "parser half done"}
+  ],
+  "tests_status": "PASSING",
+  "files_touched": ["parse.go", "parse_test.go"],
+  "follow_up_tasks": ["Handle CRLF input"],
+  "exit_signal": false
+}`
+
+func TestParseStatusReport(t *testing.T) {
+	report, err := ParseStatusReport([]byte(validReport))
+	if err != nil {
+		t.Fatalf("ParseStatusReport() error = %v", err)
+	}
+	if outcome, ok := report.Outcome("T2"); !ok || outcome.Note != "parser half done" {
+		t.Errorf("Outcome(T2) = %+v, %v", outcome, ok)
+	}
+
+	status := report.RALPHStatus()
+	if status.Status != "WORKING" || status.CurrentTask != "T2" || status.TasksCompleted != 1 || status.FilesModified != 2 {
+		t.Errorf("RALPHStatus() = %+v", status)
+	}
+}
+
+func TestParseStatusReport_Problems(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{"not json", `STATUS: WORKING`, []string{"not a JSON object"}},
+		{"missing fields", `{"version": 1}`, []string{`missing required field "status"`, `missing required field "tests_status"`, `missing required field "exit_signal"`}},
+		{"unknown fields", `{"version": 1, "status": "WORKING", "tests_status": "UNKNOWN", "exit_signal": false, "mood": "good", "tasks": [{"id": "T1", "outcome": "completed", "when": "now"}]}`,
+			[]string{"mood: unknown field", "tasks[0].when: unknown field"}},
+		{"wrong type", `{"version": 1, "status": "WORKING", "tests_status": "UNKNOWN", "exit_signal": "no"}`, []string{"exit_signal: got string, want bool"}},
+		{"bad values", `{"version": 2, "status": "DONE", "tests_status": "GREEN", "exit_signal": false, "tasks": [{"id": "", "outcome": "finished"}]}`,
+			[]string{"version: got 2, want 1", `status: got "DONE"`, `tests_status: got "GREEN"`, "tasks[0].id: must not be empty", `tasks[0].outcome: got "finished", want one of completed, in_progress, blocked, skipped`}},
+		{"blocked without blockers", `{"version": 1, "status": "BLOCKED", "tests_status": "UNKNOWN", "exit_signal": false}`, []string{"blockers: a BLOCKED report must list"}},
+		{"exit while working", `{"version": 1, "status": "WORKING", "tests_status": "PASSING", "exit_signal": true}`, []string{`exit_signal: true requires status COMPLETE (got "WORKING")`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseStatusReport([]byte(tt.data))
+			var reportErr *ReportError
+			if !errors.As(err, &reportErr) {
+				t.Fatalf("ParseStatusReport() error = %v, want a *ReportError", err)
+			}
+			if len(reportErr.Problems) != len(tt.want) {
+				t.Fatalf("problems = %q, want %d", reportErr.Problems, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(reportErr.Problems[i], want) {
+					t.Errorf("problems[%d] = %q, want %q", i, reportErr.Problems[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzeWithReport(t *testing.T) {
+	fenced := "Implemented the parser.\n```lisa-status\n" + validReport + "\n```\n"
+	legacy := "---RALPH_STATUS---\nSTATUS: COMPLETE\nEXIT_SIGNAL: true\n---END_RALPH_STATUS---\n"
+
+	// A fenced report takes precedence over the legacy block
+	result, _ := AnalyzeWithReport(fenced+legacy, nil, nil)
+	if result.Format != FormatReport || result.Report == nil || result.Status.Status != "WORKING" || result.ExitSignal {
+		t.Errorf("fenced report: format = %s, status = %+v", result.Format, result.Status)
+	}
+
+	// A tool call takes precedence over the fenced block
+	tool := `{"version": 1, "status": "COMPLETE", "tests_status": "PASSING", "exit_signal": true}`
+	result, _ = AnalyzeWithReport(fenced, []byte(tool), nil)
+	if result.Status.Status != "COMPLETE" || !result.ExitSignal {
+		t.Errorf("tool report: status = %+v", result.Status)
+	}
+
+	// A rejected report falls back to the legacy block and keeps its problems
+	invalid := "```lisa-status\n{\"version\": 1, \"status\": \"DONE\", \"tests_status\": \"PASSING\", \"exit_signal\": false}\n```\n"
+	result, _ = AnalyzeWithReport(invalid+legacy, nil, nil)
+	if result.Report != nil || result.Status.Status != "COMPLETE" {
+		t.Errorf("rejected report: report = %+v, status = %+v, want the legacy status", result.Report, result.Status)
+	}
+	if len(result.ReportErrors) != 1 || !strings.HasPrefix(result.ReportErrors[0], `lisa-status block: status: got "DONE"`) {
+		t.Errorf("ReportErrors = %q", result.ReportErrors)
+	}
+
+	// Legacy output alone is still accepted without complaint
+	result, _ = Analyze(legacy, nil)
+	if result.Format != FormatText || result.Status.Status != "COMPLETE" || len(result.ReportErrors) != 0 {
+		t.Errorf("legacy: format = %s, status = %+v, errors = %q", result.Format, result.Status, result.ReportErrors)
+	}
+}
+
+func TestToolStatusReport(t *testing.T) {
+	args := map[string]interface{}{"version": 1.0, "status": "WORKING"}
+	tests := []struct {
+		name  string
+		event map[string]interface{}
+		ok    bool
+	}{
+		{"openai tool_use", map[string]interface{}{"type": "tool_use", "name": StatusTool, "arguments": `{"version":1}`}, true},
+		{"codex item", map[string]interface{}{"type": "item.completed", "item": map[string]interface{}{"type": "function_call", "name": StatusTool, "arguments": `{"version":1}`}}, true},
+		{"claude content block", map[string]interface{}{"type": "assistant", "message": map[string]interface{}{
+			"content": []interface{}{map[string]interface{}{"type": "tool_use", "name": StatusTool, "input": args}},
+		}}, true},
+		{"other tool", map[string]interface{}{"type": "tool_use", "name": "read_file", "arguments": `{"path":"a"}`}, false},
+		{"result without arguments", map[string]interface{}{"type": "tool_result", "name": StatusTool}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, ok := ToolStatusReport(tt.event)
+			if ok != tt.ok {
+				t.Fatalf("ToolStatusReport() ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && !json.Valid(data) {
+				t.Errorf("ToolStatusReport() = %q, want JSON arguments", data)
+			}
+		})
+	}
+}
+
+func TestStatusSchema(t *testing.T) {
+	var schema struct {
+		Required   []string                   `json:"required"`
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	if err := json.Unmarshal(StatusSchema, &schema); err != nil {
+		t.Fatalf("StatusSchema is not valid JSON: %v", err)
+	}
+
+	// The published schema and the validator agree on the fields
+	if strings.Join(schema.Required, ",") != strings.Join(reportRequired, ",") {
+		t.Errorf("schema required = %v, validator requires %v", schema.Required, reportRequired)
+	}
+	if len(schema.Properties) != len(reportFields) {
+		t.Errorf("schema has %d properties, validator knows %d", len(schema.Properties), len(reportFields))
+	}
+	for _, field := range reportFields {
+		if _, ok := schema.Properties[field]; !ok {
+			t.Errorf("schema is missing field %q", field)
+		}
+	}
+}
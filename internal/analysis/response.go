@@ -4,14 +4,17 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+
+	"github.com/brainwhocodes/lisa-loop/internal/analysis/testparse"
 )
 
 // OutputFormat represents Codex output format
 type OutputFormat string
 
 const (
-	FormatJSON OutputFormat = "json"
-	FormatText OutputFormat = "text"
+	FormatJSON   OutputFormat = "json"
+	FormatText   OutputFormat = "text"
+	FormatReport OutputFormat = "report" // Status taken from a structured status report
 )
 
 // RALPHStatus represents a parsed RALPH_STATUS block
@@ -35,16 +38,42 @@ type Analysis struct {
 	ConfidenceScore      float64
 	HasErrors            bool
 	ErrorMessages        []string
+	Verified             bool // True if a verification gate ran for this output
+	VerificationPassed   bool // Result of the verification gate (only meaningful if Verified)
+
+	// The structured status report the status came from (nil for the legacy block), and
+	// the problems with a report that was sent but rejected, to be fed back to the agent
+	Report       *StatusReport
+	ReportErrors []string
+
+	// Claims the observed loop contradicts, such as "claimed 3 files modified, changed 0"
+	Discrepancies []string
+
+	// Test results recognized in the output or the verification gate's (nil if none)
+	Tests *testparse.Result
 }
 
 // Analyze analyzes Codex output and extracts status information
 func Analyze(output string, exitSignals []string) (*Analysis, error) {
+	return AnalyzeWithReport(output, nil, exitSignals)
+}
+
+// AnalyzeWithReport analyzes output along with the arguments of a lisa_report_status tool
+// call (nil if the agent made none)
+// The status comes from the tool call, else the last fenced status report, else the
+// legacy RALPH_STATUS block
+func AnalyzeWithReport(output string, toolReport []byte, exitSignals []string) (*Analysis, error) {
 	format := DetectFormat(output)
+	report, reportErrors := findReport(output, toolReport)
 
 	var status *RALPHStatus
 	var completionCount int
 
-	if format == FormatJSON {
+	if report != nil {
+		format = FormatReport
+		status = report.RALPHStatus()
+		completionCount = DetectCompletionKeywords(output)
+	} else if format == FormatJSON {
 		// JSON analysis
 		status, completionCount = analyzeJSONOutput(output)
 	} else {
@@ -52,6 +81,12 @@ func Analyze(output string, exitSignals []string) (*Analysis, error) {
 		status, completionCount = analyzeTextOutput(output)
 	}
 
+	// Test runner output in the agent's output is more reliable than its TESTS_STATUS
+	tests, _ := testparse.Parse(output)
+	if tests != nil {
+		status.TestsStatus = tests.TestsStatus()
+	}
+
 	// Calculate confidence using the helper function
 	confidenceScore := calculateConfidence(status, completionCount, output)
 
@@ -69,9 +104,63 @@ func Analyze(output string, exitSignals []string) (*Analysis, error) {
 		ConfidenceScore:      confidenceScore,
 		HasErrors:            hasErrors,
 		ErrorMessages:        errorMessages,
+		Report:               report,
+		ReportErrors:         reportErrors,
+		Tests:                tests,
 	}, nil
 }
 
+// ApplyVerification overrides the agent's self-reported test status with the
+// result of an external verification gate. A failed gate marks the analysis as
+// erroneous, cancels any exit signal and lowers confidence.
+func (a *Analysis) ApplyVerification(passed bool, summary string) {
+	if a == nil {
+		return
+	}
+
+	a.Verified = true
+	a.VerificationPassed = passed
+
+	if a.Status == nil {
+		a.Status = &RALPHStatus{Status: "UNKNOWN", WorkType: "UNKNOWN"}
+	}
+
+	if passed {
+		a.Status.TestsStatus = "PASSING"
+		return
+	}
+
+	a.Status.TestsStatus = "FAILING"
+	a.HasErrors = true
+	a.ExitSignal = false
+	a.Status.ExitSignal = false
+	if summary != "" {
+		a.ErrorMessages = append(a.ErrorMessages, summary)
+	}
+
+	a.ConfidenceScore -= 0.4
+	if a.ConfidenceScore < 0.0 {
+		a.ConfidenceScore = 0.0
+	}
+}
+
+// ApplyTestResults replaces the test status with the results of a test run, such as
+// the verification gate's. Failing tests mark the analysis as erroneous.
+func (a *Analysis) ApplyTestResults(tests *testparse.Result) {
+	if a == nil || tests == nil {
+		return
+	}
+	if a.Status == nil {
+		a.Status = &RALPHStatus{Status: "UNKNOWN", WorkType: "UNKNOWN"}
+	}
+
+	a.Tests = tests
+	a.Status.TestsStatus = tests.TestsStatus()
+	if !tests.Passing() {
+		a.HasErrors = true
+	}
+}
+
 // DetectFormat determines if output is JSON or text format
 func DetectFormat(output string) OutputFormat {
 	// Check if output starts with JSON structure
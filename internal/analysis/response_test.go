@@ -2,6 +2,8 @@ package analysis
 
 import (
 	"testing"
+
+	"github.com/brainwhocodes/lisa-loop/internal/analysis/testparse"
 )
 
 func TestDetectFormat(t *testing.T) {
@@ -334,6 +336,74 @@ TESTS_STATUS: FAILING
 	}
 }
 
+func TestApplyVerification(t *testing.T) {
+	input := `---RALPH_STATUS---
+STATUS: COMPLETE
+EXIT_SIGNAL: true
+TESTS_STATUS: PASSING
+---END_RALPH_STATUS---`
+
+	t.Run("failed gate overrides self-reported status", func(t *testing.T) {
+		result, _ := Analyze(input, nil)
+		before := result.ConfidenceScore
+
+		result.ApplyVerification(false, "verification failed: go test ./... (exit 1)")
+
+		if result.Status.TestsStatus != "FAILING" {
+			t.Errorf("TestsStatus = %s, expected FAILING", result.Status.TestsStatus)
+		}
+		if !result.HasErrors {
+			t.Error("HasErrors = false, expected true")
+		}
+		if result.ExitSignal {
+			t.Error("ExitSignal = true, expected false after failed verification")
+		}
+		if result.ConfidenceScore >= before {
+			t.Errorf("ConfidenceScore = %v, expected lower than %v", result.ConfidenceScore, before)
+		}
+		if !result.Verified || result.VerificationPassed {
+			t.Errorf("Verified/VerificationPassed = %v/%v, expected true/false", result.Verified, result.VerificationPassed)
+		}
+	})
+
+	t.Run("passing gate confirms tests", func(t *testing.T) {
+		result, _ := Analyze("no status block", nil)
+
+		result.ApplyVerification(true, "")
+
+		if result.Status.TestsStatus != "PASSING" {
+			t.Errorf("TestsStatus = %s, expected PASSING", result.Status.TestsStatus)
+		}
+		if result.HasErrors {
+			t.Error("HasErrors = true, expected false")
+		}
+	})
+}
+
+func TestAnalyze_TestOutput(t *testing.T) {
+	// The agent's test run overrides the status it reports
+	output := `--- FAIL: TestParse (0.00s)
+    parse_test.go:9: unexpected EOF
+FAIL	example.com/parse	0.01s
+---RALPH_STATUS---
+STATUS: WORKING
+TESTS_STATUS: PASSING
+EXIT_SIGNAL: false
+---END_RALPH_STATUS---`
+
+	result, _ := Analyze(output, nil)
+	if result.Tests == nil || result.Status.TestsStatus != "FAILING" || !result.HasErrors {
+		t.Errorf("Tests = %+v, TestsStatus = %s, HasErrors = %v, want the failing run", result.Tests, result.Status.TestsStatus, result.HasErrors)
+	}
+
+	// A verification gate's passing run replaces it
+	passing, _ := testparse.Parse("ok  \texample.com/parse\t0.01s\n")
+	result.ApplyTestResults(passing)
+	if result.Tests != passing || result.Status.TestsStatus != "PASSING" {
+		t.Errorf("after ApplyTestResults: Tests = %+v, TestsStatus = %s", result.Tests, result.Status.TestsStatus)
+	}
+}
+
 func TestCalculateConfidence(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -0,0 +1,88 @@
+package testparse
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// cargoSummary matches "test result: FAILED. 1 passed; 1 failed; 1 ignored; ..."
+	cargoSummary = regexp.MustCompile(`(?m)^test result: (?:ok|FAILED)\. (\d+) passed; (\d+) failed; (\d+) ignored`)
+	// cargoFailure matches a failing test: "test tests::it_fails ... FAILED"
+	cargoFailure = regexp.MustCompile(`^test (\S+) \.\.\. FAILED$`)
+	// cargoSection matches the header of a failing test's captured output
+	cargoSection = regexp.MustCompile(`^---- (\S+) std(?:out|err) ----$`)
+	// cargoPanic matches "thread 'x' panicked at src/lib.rs:10:5:" and the older
+	// "thread 'x' panicked at 'message', src/lib.rs:10:5"
+	cargoPanic = regexp.MustCompile(`^thread '[^']*' panicked at (?:'(.*)', \S+|\S+:)$`)
+	// cargoCompile matches "error: could not compile `crate`"
+	cargoCompile = regexp.MustCompile("^error: could not compile `([^`]+)`")
+)
+
+// parseCargo reads the output of cargo test
+func parseCargo(output string) (*Result, bool) {
+	result := newResult(FrameworkCargo)
+	found := false
+	for _, m := range cargoSummary.FindAllStringSubmatch(output, -1) {
+		found = true
+		passed, _ := strconv.Atoi(m[1])
+		failed, _ := strconv.Atoi(m[2])
+		ignored, _ := strconv.Atoi(m[3])
+		result.Passed += passed
+		result.Failed += failed
+		result.Skipped += ignored
+	}
+
+	lines := strings.Split(output, "\n")
+	messages := make(map[string]string)
+	compileError := ""
+	for i, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		if m := cargoSection.FindStringSubmatch(line); m != nil {
+			messages[m[1]] = cargoMessage(lines[i+1:])
+			continue
+		}
+		if strings.HasPrefix(line, "error[") && compileError == "" {
+			compileError = line
+		}
+		if m := cargoCompile.FindStringSubmatch(line); m != nil {
+			found = true
+			message := compileError
+			if message == "" {
+				message = "could not compile"
+			}
+			result.Failed++
+			result.addFailure(m[1], message)
+		}
+	}
+	if !found {
+		return nil, false
+	}
+
+	for _, line := range lines {
+		if m := cargoFailure.FindStringSubmatch(strings.TrimRight(line, " \t")); m != nil {
+			result.addFailure(m[1], messages[m[1]])
+		}
+	}
+	return result, true
+}
+
+// cargoMessage finds the panic message in a failing test's captured output
+func cargoMessage(lines []string) string {
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if cargoSection.MatchString(line) || line == "failures:" {
+			break
+		}
+		m := cargoPanic.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		if m[1] != "" {
+			return m[1]
+		}
+		return nextMessage(lines[i+1:])
+	}
+	return nextMessage(lines)
+}
@@ -0,0 +1,212 @@
+package testparse
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+var (
+	// goTestLine matches "--- FAIL: TestName (0.00s)", indented for subtests
+	goTestLine = regexp.MustCompile(`^(\s*)--- (PASS|FAIL|SKIP): (\S+)`)
+	// goPackageLine matches a package result: "ok  	pkg	0.01s" or "FAIL	pkg [build failed]"
+	goPackageLine = regexp.MustCompile(`^(ok|FAIL)\s+(\S+)\s+(\d+(?:\.\d+)?s|\(cached\)|\[build failed\]|\[setup failed\])`)
+	// goProgressLine matches the lines go test -v prints as tests start and resume
+	goProgressLine = regexp.MustCompile(`^\s*=== (RUN|CONT|PAUSE|NAME)\s+(\S+)`)
+)
+
+// parseGo reads the text output of go test, with or without -v
+func parseGo(output string) (*Result, bool) {
+	result := newResult(FrameworkGo)
+	found := false
+
+	lines := strings.Split(output, "\n")
+	logs := make(map[string][]string) // Lines logged by each test under -v, before its result
+	current := ""
+	buildErrors := make(map[string]string) // First compile error of each package
+	buildPkg := ""
+
+	for i, line := range lines {
+		if m := goProgressLine.FindStringSubmatch(line); m != nil {
+			current = m[2]
+			continue
+		}
+
+		if m := goTestLine.FindStringSubmatch(line); m != nil {
+			found = true
+			switch m[2] {
+			case "PASS":
+				result.Passed++
+			case "SKIP":
+				result.Skipped++
+			case "FAIL":
+				result.Failed++
+				result.addFailure(m[3], goFailureMessage(lines[i+1:], len(m[1]), logs[m[3]]))
+			}
+			current = ""
+			continue
+		}
+
+		if m := goPackageLine.FindStringSubmatch(line); m != nil {
+			found = true
+			if m[1] == "FAIL" && strings.HasPrefix(m[3], "[") {
+				message := strings.Trim(m[3], "[]")
+				if buildErr := buildErrors[m[2]]; buildErr != "" {
+					message = buildErr
+				}
+				result.Failed++
+				result.addFailure(m[2], message)
+			}
+			continue
+		}
+
+		// Compile errors follow a "# pkg" header
+		if strings.HasPrefix(line, "# ") {
+			buildPkg = strings.TrimSpace(strings.TrimPrefix(line, "# "))
+			continue
+		}
+		if buildPkg != "" {
+			if _, ok := buildErrors[buildPkg]; !ok && strings.TrimSpace(line) != "" {
+				buildErrors[buildPkg] = line
+			}
+			buildPkg = ""
+		}
+
+		if current != "" && strings.TrimSpace(line) != "" {
+			logs[current] = append(logs[current], line)
+		}
+	}
+
+	if !found {
+		return nil, false
+	}
+	dropFailedParents(result)
+	return result, true
+}
+
+// goFailureMessage finds why a test failed: the lines indented under its result line,
+// a panic right after it, or else what the test logged before it under -v
+func goFailureMessage(after []string, indent int, logged []string) string {
+	if len(after) > 0 {
+		next := after[0]
+		trimmed := strings.TrimSpace(next)
+		nextIndent := len(next) - len(strings.TrimLeft(next, " \t"))
+		switch {
+		case strings.HasPrefix(trimmed, "panic:"):
+			return trimmed
+		case nextIndent > indent && trimmed != "" && !strings.HasPrefix(trimmed, "--- "):
+			return trimmed
+		}
+	}
+	if len(logged) > 0 {
+		return logged[0]
+	}
+	return ""
+}
+
+// goEvent is one line of go test -json output
+type goEvent struct {
+	Action     string
+	Package    string
+	ImportPath string
+	Test       string
+	Output     string
+}
+
+// parseGoJSON reads the output of go test -json
+func parseGoJSON(output string) (*Result, bool) {
+	result := newResult(FrameworkGo)
+	found := false
+
+	logs := make(map[string][]string) // Output of each test, and of each package under ""
+	buildOutput := make(map[string]string)
+	failedTests := make(map[string]bool) // Packages with a failing test
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		var event goEvent
+		if json.Unmarshal([]byte(line), &event) != nil || event.Action == "" || (event.Package == "" && event.ImportPath == "") {
+			continue
+		}
+		found = true
+		key := event.Package + " " + event.Test
+
+		switch event.Action {
+		case "output":
+			text := strings.TrimSpace(event.Output)
+			if text != "" && !goProgressLine.MatchString(text) && !goTestLine.MatchString(text) {
+				logs[key] = append(logs[key], text)
+			}
+		case "build-output":
+			// Test binaries are built as "pkg [pkg.test]"
+			pkg := strings.Fields(event.ImportPath + " ")[0]
+			if text := strings.TrimSpace(event.Output); text != "" && !strings.HasPrefix(text, "#") && buildOutput[pkg] == "" {
+				buildOutput[pkg] = text
+			}
+		case "pass":
+			if event.Test != "" {
+				result.Passed++
+			}
+		case "skip":
+			if event.Test != "" {
+				result.Skipped++
+			}
+		case "fail":
+			if event.Test != "" {
+				result.Failed++
+				failedTests[event.Package] = true
+				message := ""
+				if logged := logs[key]; len(logged) > 0 {
+					message = logged[0]
+				}
+				result.addFailure(event.Test, message)
+				continue
+			}
+			// A package that fails without a failing test did not build or crashed
+			if !failedTests[event.Package] {
+				message := "package failed"
+				if text := buildOutput[event.Package]; text != "" {
+					message = text
+				}
+				for _, text := range logs[key] {
+					if strings.HasPrefix(text, "panic:") || strings.Contains(text, "[build failed]") {
+						message = text
+						break
+					}
+				}
+				result.Failed++
+				result.addFailure(event.Package, message)
+			}
+		}
+	}
+
+	if !found {
+		return nil, false
+	}
+	dropFailedParents(result)
+	return result, true
+}
+
+// dropFailedParents removes tests that failed only because a subtest did, leaving the
+// subtests that explain the failure
+func dropFailedParents(result *Result) {
+	var kept []Failure
+	for _, f := range result.Failures {
+		parent := false
+		for _, other := range result.Failures {
+			if strings.HasPrefix(other.Name, f.Name+"/") {
+				parent = true
+				break
+			}
+		}
+		if parent {
+			result.Failed--
+			continue
+		}
+		kept = append(kept, f)
+	}
+	result.Failures = kept
+}
@@ -0,0 +1,101 @@
+package testparse
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// jestSummary matches "Tests:       1 failed, 1 skipped, 5 passed, 7 total"
+	jestSummary = regexp.MustCompile(`(?m)^\s*Tests:\s+(.*\d+ total)\s*$`)
+	// jestFailure matches the header of a failure: "  ● suite › test name"
+	jestFailure = regexp.MustCompile(`^\s*● (.+)$`)
+	// jestSuite matches a test file result: " FAIL  src/sum.test.js"
+	jestSuite = regexp.MustCompile(`^\s*(?:PASS|FAIL)\s+(\S+)`)
+
+	// vitestSummary matches "      Tests  1 failed | 1 passed (2)"
+	vitestSummary = regexp.MustCompile(`(?m)^\s*Tests\s{2,}(.+?)\s*\(\d+\)\s*$`)
+	// vitestFailure matches a failing test: " FAIL  src/sum.test.ts > math > adds"
+	vitestFailure = regexp.MustCompile(`^\s*FAIL\s+(\S+ > .+?)\s*$`)
+
+	// countPart matches one count of a summary: "5 passed"
+	countPart = regexp.MustCompile(`(\d+) (passed|failed|skipped|todo|pending)`)
+)
+
+// parseJest reads the output of Jest
+func parseJest(output string) (*Result, bool) {
+	summaries := jestSummary.FindAllStringSubmatch(output, -1)
+	if len(summaries) == 0 {
+		return nil, false
+	}
+	result := newResult(FrameworkJest)
+	for _, m := range summaries {
+		addCounts(result, m[1])
+	}
+
+	lines := strings.Split(output, "\n")
+	suite := ""
+	for i, line := range lines {
+		if m := jestSuite.FindStringSubmatch(line); m != nil {
+			suite = m[1]
+			continue
+		}
+		m := jestFailure.FindStringSubmatch(line)
+		if m == nil || strings.TrimSpace(m[1]) == "Console" {
+			continue
+		}
+		name := m[1]
+		if name == "Test suite failed to run" && suite != "" {
+			name = suite
+		}
+		result.addFailure(name, nextMessage(lines[i+1:]))
+	}
+	return result, true
+}
+
+// parseVitest reads the output of Vitest
+func parseVitest(output string) (*Result, bool) {
+	summaries := vitestSummary.FindAllStringSubmatch(output, -1)
+	if len(summaries) == 0 {
+		return nil, false
+	}
+	result := newResult(FrameworkVitest)
+	for _, m := range summaries {
+		addCounts(result, m[1])
+	}
+
+	lines := strings.Split(output, "\n")
+	for i, line := range lines {
+		if m := vitestFailure.FindStringSubmatch(line); m != nil {
+			result.addFailure(m[1], nextMessage(lines[i+1:]))
+		}
+	}
+	return result, true
+}
+
+// addCounts adds the counts of a summary such as "1 failed, 5 passed" to result
+// Pending and todo tests count as skipped
+func addCounts(result *Result, summary string) {
+	for _, m := range countPart.FindAllStringSubmatch(summary, -1) {
+		n, _ := strconv.Atoi(m[1])
+		switch m[2] {
+		case "passed":
+			result.Passed += n
+		case "failed":
+			result.Failed += n
+		default:
+			result.Skipped += n
+		}
+	}
+}
+
+// nextMessage returns the first non-empty line of lines
+func nextMessage(lines []string) string {
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			return line
+		}
+	}
+	return ""
+}
@@ -0,0 +1,73 @@
+package testparse
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// pytestSummary matches the last line: "===== 1 failed, 3 passed, 1 skipped in 0.12s ====="
+	pytestSummary = regexp.MustCompile(`(?m)^=+ (.*?)\s*in \d+(?:\.\d+)?s(?: \([^)]*\))? =+\s*$`)
+	// pytestCount matches one count of the summary: "3 passed", "1 error"
+	pytestCount = regexp.MustCompile(`(\d+) (passed|failed|skipped|errors?|xfailed|xpassed|deselected)`)
+	// pytestShort matches the short test summary: "FAILED tests/test_x.py::test_add - assert 3 == 4"
+	pytestShort = regexp.MustCompile(`^(?:FAILED|ERROR) (\S+)(?: - (.*))?$`)
+	// pytestSection matches the header of a failure's traceback: "____ test_add ____"
+	pytestSection = regexp.MustCompile(`^_{3,} (.+?) _{3,}$`)
+)
+
+// parsePytest reads the output of pytest
+func parsePytest(output string) (*Result, bool) {
+	summaries := pytestSummary.FindAllStringSubmatch(output, -1)
+	counted := false
+	result := newResult(FrameworkPytest)
+	for _, m := range summaries {
+		if !pytestCount.MatchString(m[1]) && !strings.Contains(m[1], "no tests ran") {
+			continue
+		}
+		counted = true
+		for _, c := range pytestCount.FindAllStringSubmatch(m[1], -1) {
+			n, _ := strconv.Atoi(c[1])
+			switch c[2] {
+			case "passed", "xpassed":
+				result.Passed += n
+			case "failed", "error", "errors":
+				result.Failed += n
+			case "skipped", "xfailed":
+				result.Skipped += n
+			}
+		}
+	}
+	if !counted {
+		return nil, false
+	}
+
+	// The short summary names each failure; older runs only have traceback sections
+	lines := strings.Split(output, "\n")
+	for _, line := range lines {
+		if m := pytestShort.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			result.addFailure(m[1], m[2])
+		}
+	}
+	if len(result.Failures) == 0 {
+		for i, line := range lines {
+			m := pytestSection.FindStringSubmatch(strings.TrimSpace(line))
+			if m == nil {
+				continue
+			}
+			message := ""
+			for _, next := range lines[i+1:] {
+				if pytestSection.MatchString(strings.TrimSpace(next)) {
+					break
+				}
+				if strings.HasPrefix(next, "E ") {
+					message = strings.TrimPrefix(next, "E")
+					break
+				}
+			}
+			result.addFailure(m[1], message)
+		}
+	}
+	return result, true
+}
@@ -0,0 +1,131 @@
+// Package testparse recognizes the output of test runners (go test, Jest, Vitest, pytest
+// and cargo test) and extracts pass, fail and skip counts and the failing tests
+package testparse
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Test runners the parsers recognize
+const (
+	FrameworkGo     = "go"
+	FrameworkJest   = "jest"
+	FrameworkVitest = "vitest"
+	FrameworkPytest = "pytest"
+	FrameworkCargo  = "cargo"
+)
+
+// maxMessageLen caps a failure message, which is kept to its first line
+const maxMessageLen = 200
+
+// Failure is a failing test and why it failed
+type Failure struct {
+	Name    string `json:"name"`              // Test name, qualified the way its runner prints it
+	Message string `json:"message,omitempty"` // First line of the failure message
+}
+
+// String formats the failure as "name: message"
+func (f Failure) String() string {
+	if f.Message == "" {
+		return f.Name
+	}
+	return f.Name + ": " + f.Message
+}
+
+// Result holds the test results found in some output
+type Result struct {
+	Frameworks []string  `json:"frameworks"` // Runners whose output was recognized
+	Passed     int       `json:"passed"`
+	Failed     int       `json:"failed"`
+	Skipped    int       `json:"skipped,omitempty"`
+	Failures   []Failure `json:"failures,omitempty"`
+}
+
+// parser extracts one runner's results from output, reporting whether it recognized any
+type parser func(output string) (*Result, bool)
+
+// parsers are tried in turn; output from several runners is combined
+var parsers = []parser{parseGoJSON, parseGo, parseJest, parseVitest, parsePytest, parseCargo}
+
+// Parse returns the combined results of every test runner recognized in output, or
+// false if it holds no test results
+func Parse(output string) (*Result, bool) {
+	output = strings.ReplaceAll(output, "\r\n", "\n")
+
+	var combined *Result
+	for _, parse := range parsers {
+		result, ok := parse(output)
+		if !ok {
+			continue
+		}
+		if combined == nil {
+			combined = &Result{}
+		}
+		combined.Frameworks = append(combined.Frameworks, result.Frameworks...)
+		combined.Passed += result.Passed
+		combined.Failed += result.Failed
+		combined.Skipped += result.Skipped
+		combined.Failures = append(combined.Failures, result.Failures...)
+	}
+	return combined, combined != nil
+}
+
+// Passing reports whether no test failed
+func (r *Result) Passing() bool {
+	return r != nil && r.Failed == 0 && len(r.Failures) == 0
+}
+
+// TestsStatus maps the result onto the status report's tests_status: PASSING or FAILING
+func (r *Result) TestsStatus() string {
+	if r.Passing() {
+		return "PASSING"
+	}
+	return "FAILING"
+}
+
+// Summary returns a one-line description such as "go: 12 passed, 2 failed, 1 skipped"
+func (r *Result) Summary() string {
+	if r == nil {
+		return ""
+	}
+	summary := fmt.Sprintf("%s: %d passed, %d failed", strings.Join(r.Frameworks, "+"), r.Passed, r.Failed)
+	if r.Skipped > 0 {
+		summary += fmt.Sprintf(", %d skipped", r.Skipped)
+	}
+	return summary
+}
+
+// newResult starts the result of one runner
+func newResult(framework string) *Result {
+	return &Result{Frameworks: []string{framework}}
+}
+
+// addFailure records a failing test once, keeping the first message found for it
+func (r *Result) addFailure(name, message string) {
+	name = strings.TrimSpace(name)
+	for i, f := range r.Failures {
+		if f.Name == name {
+			if f.Message == "" {
+				r.Failures[i].Message = cleanMessage(message)
+			}
+			return
+		}
+	}
+	r.Failures = append(r.Failures, Failure{Name: name, Message: cleanMessage(message)})
+}
+
+// cleanMessage keeps the first non-empty line of a message, capped at maxMessageLen
+func cleanMessage(message string) string {
+	for _, line := range strings.Split(message, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if len(line) > maxMessageLen {
+			line = line[:maxMessageLen-3] + "..."
+		}
+		return line
+	}
+	return ""
+}
@@ -0,0 +1,222 @@
+package testparse
+
+import (
+	"strings"
+	"testing"
+)
+
+const goOutput = `--- FAIL: TestAdd (0.00s)
+    math_test.go:12: Add(1, 2) = 4, want 3
+--- FAIL: TestTable (0.00s)
+    --- FAIL: TestTable/negative (0.00s)
+        math_test.go:30: Abs(-1) = -1, want 1
+FAIL
+FAIL	example.com/math	0.004s
+ok  	example.com/util	(cached)
+# example.com/broken
+./broken.go:3:2: undefined: missing
+FAIL	example.com/broken [build failed]
+FAIL
+`
+
+const goVerboseOutput = `=== RUN   TestAdd
+    math_test.go:12: Add(1, 2) = 4, want 3
+--- FAIL: TestAdd (0.00s)
+=== RUN   TestSub
+--- PASS: TestSub (0.00s)
+=== RUN   TestSlow
+    math_test.go:40: skipping in short mode
+--- SKIP: TestSlow (0.00s)
+=== RUN   TestPanic
+--- FAIL: TestPanic (0.00s)
+panic: runtime error: index out of range [1] with length 1 [recovered]
+FAIL	example.com/math	0.004s
+`
+
+const goJSONOutput = `{"Action":"run","Package":"example.com/math","Test":"TestAdd"}
+{"Action":"output","Package":"example.com/math","Test":"TestAdd","Output":"=== RUN   TestAdd\n"}
+{"Action":"output","Package":"example.com/math","Test":"TestAdd","Output":"    math_test.go:12: Add(1, 2) = 4, want 3\n"}
+{"Action":"output","Package":"example.com/math","Test":"TestAdd","Output":"--- FAIL: TestAdd (0.00s)\n"}
+{"Action":"fail","Package":"example.com/math","Test":"TestAdd","Elapsed":0}
+{"Action":"pass","Package":"example.com/math","Test":"TestSub","Elapsed":0}
+{"Action":"skip","Package":"example.com/math","Test":"TestSlow","Elapsed":0}
+{"Action":"fail","Package":"example.com/math","Elapsed":0.004}
+{"ImportPath":"example.com/broken [example.com/broken.test]","Action":"build-output","Output":"# example.com/broken\n"}
+{"ImportPath":"example.com/broken [example.com/broken.test]","Action":"build-output","Output":"./broken.go:3:2: undefined: missing\n"}
+{"Action":"fail","Package":"example.com/broken","Elapsed":0}
+`
+
+const jestOutput = ` FAIL  src/sum.test.js
+  ● math › adds numbers
+
+    expect(received).toBe(expected) // Object.is equality
+
+    Expected: 4
+    Received: 3
+
+  ● Console
+
+    console.log
+      debugging
+
+ FAIL  src/broken.test.js
+  ● Test suite failed to run
+
+    Cannot find module './missing' from 'src/broken.test.js'
+
+ PASS  src/other.test.js
+
+Test Suites: 2 failed, 1 passed, 3 total
+Tests:       1 failed, 1 skipped, 5 passed, 7 total
+Snapshots:   0 total
+Time:        1.2 s
+`
+
+const vitestOutput = ` ❯ src/sum.test.ts (2 tests | 1 failed) 5ms
+   × adds numbers 3ms
+     → expected 3 to be 4 // Object.is equality
+
+⎯⎯⎯⎯⎯⎯⎯ Failed Tests 1 ⎯⎯⎯⎯⎯⎯⎯
+
+ FAIL  src/sum.test.ts > math > adds numbers
+AssertionError: expected 3 to be 4 // Object.is equality
+ ❯ src/sum.test.ts:5:17
+
+ Test Files  1 failed (1)
+      Tests  1 failed | 1 passed | 1 skipped (3)
+   Start at  10:00:00
+`
+
+const pytestOutput = `============================= test session starts ==============================
+collected 6 items
+
+tests/test_math.py .F.s                                                  [ 66%]
+tests/test_db.py E.                                                      [100%]
+
+=================================== FAILURES ===================================
+___________________________________ test_add ___________________________________
+
+    def test_add():
+>       assert add(1, 2) == 4
+E       assert 3 == 4
+
+tests/test_math.py:5: AssertionError
+=========================== short test summary info ============================
+FAILED tests/test_math.py::test_add - assert 3 == 4
+ERROR tests/test_db.py::test_conn - ConnectionRefusedError: refused
+=============== 1 failed, 3 passed, 1 skipped, 1 error in 0.12s ================
+`
+
+const cargoOutput = `running 3 tests
+test tests::it_works ... ok
+test tests::it_fails ... FAILED
+test tests::slow ... ignored
+
+failures:
+
+---- tests::it_fails stdout ----
+thread 'tests::it_fails' panicked at src/lib.rs:10:5:
+assertion ` + "`left == right`" + ` failed
+  left: 3
+ right: 4
+note: run with ` + "`RUST_BACKTRACE=1`" + ` environment variable to display a backtrace
+
+
+failures:
+    tests::it_fails
+
+test result: FAILED. 1 passed; 1 failed; 1 ignored; 0 measured; 0 filtered out; finished in 0.00s
+`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name                    string
+		output                  string
+		frameworks              string
+		passed, failed, skipped int
+		failures                []string
+	}{
+		{"go", goOutput, "go", 0, 3, 0, []string{
+			"TestAdd: math_test.go:12: Add(1, 2) = 4, want 3",
+			"TestTable/negative: math_test.go:30: Abs(-1) = -1, want 1",
+			"example.com/broken: ./broken.go:3:2: undefined: missing",
+		}},
+		{"go verbose", goVerboseOutput, "go", 1, 2, 1, []string{
+			"TestAdd: math_test.go:12: Add(1, 2) = 4, want 3",
+			"TestPanic: panic: runtime error: index out of range [1] with length 1 [recovered]",
+		}},
+		{"go json", goJSONOutput, "go", 1, 2, 1, []string{
+			"TestAdd: math_test.go:12: Add(1, 2) = 4, want 3",
+			"example.com/broken: ./broken.go:3:2: undefined: missing",
+		}},
+		{"jest", jestOutput, "jest", 5, 1, 1, []string{
+			"math › adds numbers: expect(received).toBe(expected) // Object.is equality",
+			"src/broken.test.js: Cannot find module './missing' from 'src/broken.test.js'",
+		}},
+		{"vitest", vitestOutput, "vitest", 1, 1, 1, []string{
+			"src/sum.test.ts > math > adds numbers: AssertionError: expected 3 to be 4 // Object.is equality",
+		}},
+		{"pytest", pytestOutput, "pytest", 3, 2, 1, []string{
+			"tests/test_math.py::test_add: assert 3 == 4",
+			"tests/test_db.py::test_conn: ConnectionRefusedError: refused",
+		}},
+		{"cargo", cargoOutput, "cargo", 1, 1, 1, []string{
+			"tests::it_fails: assertion `left == right` failed",
+		}},
+		{"go and jest", goVerboseOutput + "\n" + jestOutput, "go,jest", 6, 3, 2, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := Parse(tt.output)
+			if !ok {
+				t.Fatal("Parse() found no test results")
+			}
+			if got := strings.Join(result.Frameworks, ","); got != tt.frameworks {
+				t.Errorf("Frameworks = %s, want %s", got, tt.frameworks)
+			}
+			if result.Passed != tt.passed || result.Failed != tt.failed || result.Skipped != tt.skipped {
+				t.Errorf("counts = %d passed, %d failed, %d skipped, want %d, %d, %d",
+					result.Passed, result.Failed, result.Skipped, tt.passed, tt.failed, tt.skipped)
+			}
+			if tt.failures == nil {
+				return
+			}
+			var failures []string
+			for _, f := range result.Failures {
+				failures = append(failures, f.String())
+			}
+			if strings.Join(failures, "\n") != strings.Join(tt.failures, "\n") {
+				t.Errorf("Failures =\n%s\nwant\n%s", strings.Join(failures, "\n"), strings.Join(tt.failures, "\n"))
+			}
+		})
+	}
+}
+
+func TestParse_NoResults(t *testing.T) {
+	for _, output := range []string{
+		"",
+		"Implemented the parser. All tests pass.",
+		"Error: something FAILED\nok then\n",
+		`{"type": "message", "text": "hello"}`,
+	} {
+		if result, ok := Parse(output); ok {
+			t.Errorf("Parse(%q) = %+v, want no results", output, result)
+		}
+	}
+}
+
+func TestResult_Status(t *testing.T) {
+	result, _ := Parse("ok  \texample.com/math\t0.004s\n")
+	if !result.Passing() || result.TestsStatus() != "PASSING" {
+		t.Errorf("passing package: Passing() = %v, TestsStatus() = %s", result.Passing(), result.TestsStatus())
+	}
+
+	result, _ = Parse(pytestOutput)
+	if result.Passing() || result.TestsStatus() != "FAILING" {
+		t.Errorf("failing run: Passing() = %v, TestsStatus() = %s", result.Passing(), result.TestsStatus())
+	}
+	if got := result.Summary(); got != "pytest: 3 passed, 2 failed, 1 skipped" {
+		t.Errorf("Summary() = %q", got)
+	}
+}
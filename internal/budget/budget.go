@@ -0,0 +1,183 @@
+// Package budget enforces limits on the tokens and money a run, and a project per day,
+// may spend on backend calls
+package budget
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brainwhocodes/lisa-loop/internal/state"
+)
+
+// Usage counts tokens and cost
+type Usage = state.Usage
+
+// Limits caps spending; a zero limit is unlimited
+type Limits struct {
+	RunTokens int     `json:"run_tokens,omitempty"`
+	DayTokens int     `json:"day_tokens,omitempty"`
+	RunCost   float64 `json:"run_cost,omitempty"`
+	DayCost   float64 `json:"day_cost,omitempty"`
+
+	// Cost of a million tokens, charged for usage a backend reports without a cost
+	PricePerMillion float64 `json:"price_per_million,omitempty"`
+}
+
+// Enabled reports whether any limit is set
+func (l Limits) Enabled() bool {
+	return l.RunTokens > 0 || l.DayTokens > 0 || l.RunCost > 0 || l.DayCost > 0
+}
+
+// Status is a snapshot of spending against the limits
+type Status struct {
+	Run    Usage  `json:"run"`
+	Day    Usage  `json:"day"`
+	Limits Limits `json:"limits"`
+}
+
+// Tracker accumulates usage for the current run and day
+// It is safe for concurrent use by parallel workers
+type Tracker struct {
+	mu     sync.Mutex
+	limits Limits
+	run    Usage
+	day    state.DailyUsage
+	now    func() time.Time
+}
+
+// NewTracker creates a tracker with no usage recorded
+func NewTracker(limits Limits) *Tracker {
+	return &Tracker{limits: limits, now: time.Now}
+}
+
+// LoadState loads the day's usage from the state directory
+func (t *Tracker) LoadState() error {
+	du, err := state.LoadDailyUsage()
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.day = du
+	t.rollover()
+	return nil
+}
+
+// SetRunUsage restores the usage of a resumed run
+func (t *Tracker) SetRunUsage(u Usage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.run = u
+}
+
+// Record adds usage reported by a backend to the run and the day and saves the day
+func (t *Tracker) Record(u Usage) error {
+	if u.Cost == 0 && t.limits.PricePerMillion > 0 {
+		u.Cost = float64(u.Tokens()) * t.limits.PricePerMillion / 1e6
+	}
+
+	t.mu.Lock()
+	t.rollover()
+	t.run = t.run.Add(u)
+	t.day.Usage = t.day.Usage.Add(u)
+	day := t.day
+	t.mu.Unlock()
+
+	if err := state.SaveDailyUsage(day); err != nil {
+		return fmt.Errorf("failed to save budget usage: %w", err)
+	}
+	return nil
+}
+
+// Exhausted reports whether a limit has been reached, and which
+func (t *Tracker) Exhausted() (bool, string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rollover()
+
+	l := t.limits
+	switch {
+	case l.RunTokens > 0 && t.run.Tokens() >= l.RunTokens:
+		return true, fmt.Sprintf("run used %d of %d tokens", t.run.Tokens(), l.RunTokens)
+	case l.RunCost > 0 && t.run.Cost >= l.RunCost:
+		return true, fmt.Sprintf("run spent %s of %s", FormatCost(t.run.Cost), FormatCost(l.RunCost))
+	case l.DayTokens > 0 && t.day.Tokens() >= l.DayTokens:
+		return true, fmt.Sprintf("today used %d of %d tokens", t.day.Tokens(), l.DayTokens)
+	case l.DayCost > 0 && t.day.Cost >= l.DayCost:
+		return true, fmt.Sprintf("today spent %s of %s", FormatCost(t.day.Cost), FormatCost(l.DayCost))
+	}
+	return false, ""
+}
+
+// RunUsage returns the usage of the current run
+func (t *Tracker) RunUsage() Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.run
+}
+
+// Status returns the run's and day's usage with the limits
+func (t *Tracker) Status() Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rollover()
+	return Status{Run: t.run, Day: t.day.Usage, Limits: t.limits}
+}
+
+// rollover starts a new day's usage once the date changes (caller must hold mu)
+func (t *Tracker) rollover() {
+	today := t.now().Format("2006-01-02")
+	if t.day.Date != today {
+		t.day = state.DailyUsage{Date: today}
+	}
+}
+
+// Summary describes the spending of the run and the day against their limits
+// e.g. "run 12.3k/50k tokens $0.42/$5.00, today 80.1k tokens $1.20"
+func (s Status) Summary() string {
+	return "run " + s.RunSummary() + ", today " + s.DaySummary()
+}
+
+// RunSummary describes the spending of the run against its limits
+func (s Status) RunSummary() string {
+	return spend(s.Run, s.Limits.RunTokens, s.Limits.RunCost)
+}
+
+// DaySummary describes the day's spending against its limits
+func (s Status) DaySummary() string {
+	return spend(s.Day, s.Limits.DayTokens, s.Limits.DayCost)
+}
+
+func spend(u Usage, tokenLimit int, costLimit float64) string {
+	text := FormatTokens(u.Tokens())
+	if tokenLimit > 0 {
+		text += "/" + FormatTokens(tokenLimit)
+	}
+	text += " tokens"
+	if u.Cost > 0 || costLimit > 0 {
+		text += " " + FormatCost(u.Cost)
+		if costLimit > 0 {
+			text += "/" + FormatCost(costLimit)
+		}
+	}
+	return text
+}
+
+// FormatTokens formats a token count for display, e.g. 950, 12.3k or 1.2M
+func FormatTokens(n int) string {
+	switch {
+	case n >= 1000000:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(n)/1e6), ".0") + "M"
+	case n >= 1000:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(n)/1e3), ".0") + "k"
+	}
+	return fmt.Sprint(n)
+}
+
+// FormatCost formats an amount of money for display
+func FormatCost(cost float64) string {
+	return fmt.Sprintf("$%.2f", cost)
+}
@@ -0,0 +1,138 @@
+package budget
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// chdirTemp runs a test in a fresh directory, since the tracker saves its state
+func chdirTemp(t *testing.T) {
+	t.Helper()
+	origDir, _ := os.Getwd()
+	os.Chdir(t.TempDir())
+	t.Cleanup(func() { os.Chdir(origDir) })
+}
+
+func TestRunTokenLimit(t *testing.T) {
+	chdirTemp(t)
+	tracker := NewTracker(Limits{RunTokens: 1000})
+
+	if err := tracker.Record(Usage{PromptTokens: 600, CompletionTokens: 300}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if exhausted, reason := tracker.Exhausted(); exhausted {
+		t.Fatalf("Exhausted() = true (%s) at 900 of 1000 tokens", reason)
+	}
+
+	tracker.Record(Usage{PromptTokens: 100})
+	exhausted, reason := tracker.Exhausted()
+	if !exhausted {
+		t.Fatal("Exhausted() = false at 1000 of 1000 tokens")
+	}
+	if !strings.Contains(reason, "1000 of 1000 tokens") {
+		t.Errorf("reason = %q", reason)
+	}
+}
+
+func TestCostLimitUsesPriceWhenCostMissing(t *testing.T) {
+	chdirTemp(t)
+	tracker := NewTracker(Limits{RunCost: 1, PricePerMillion: 2})
+
+	tracker.Record(Usage{PromptTokens: 400000})
+	if got := tracker.RunUsage().Cost; got < 0.79 || got > 0.81 {
+		t.Fatalf("priced cost = %v, want 0.80", got)
+	}
+	tracker.Record(Usage{PromptTokens: 1, Cost: 0.25})
+	if exhausted, reason := tracker.Exhausted(); !exhausted || !strings.Contains(reason, "$1.05 of $1.00") {
+		t.Errorf("Exhausted() = %v, %q", exhausted, reason)
+	}
+}
+
+func TestDayUsagePersistsAcrossRuns(t *testing.T) {
+	chdirTemp(t)
+	first := NewTracker(Limits{DayTokens: 500})
+	first.Record(Usage{PromptTokens: 300})
+
+	second := NewTracker(Limits{DayTokens: 500})
+	if err := second.LoadState(); err != nil {
+		t.Fatalf("LoadState() error = %v", err)
+	}
+	status := second.Status()
+	if status.Day.Tokens() != 300 || status.Run.Tokens() != 0 {
+		t.Fatalf("status = %+v, want 300 tokens today and none this run", status)
+	}
+
+	second.Record(Usage{CompletionTokens: 200})
+	if exhausted, reason := second.Exhausted(); !exhausted || !strings.HasPrefix(reason, "today") {
+		t.Errorf("Exhausted() = %v, %q, want the daily limit", exhausted, reason)
+	}
+}
+
+func TestDayUsageRollsOver(t *testing.T) {
+	chdirTemp(t)
+	now := time.Date(2024, 5, 1, 23, 0, 0, 0, time.Local)
+	tracker := NewTracker(Limits{DayTokens: 100})
+	tracker.now = func() time.Time { return now }
+
+	tracker.Record(Usage{PromptTokens: 100})
+	if exhausted, _ := tracker.Exhausted(); !exhausted {
+		t.Fatal("daily limit should be reached")
+	}
+
+	now = now.Add(2 * time.Hour)
+	if exhausted, reason := tracker.Exhausted(); exhausted {
+		t.Errorf("Exhausted() = true (%s) on a new day", reason)
+	}
+	if got := tracker.Status().Run.Tokens(); got != 100 {
+		t.Errorf("run tokens = %d, want 100 after the day rolls over", got)
+	}
+}
+
+func TestStatusSummary(t *testing.T) {
+	status := Status{
+		Run:    Usage{PromptTokens: 12000, CompletionTokens: 300, Cost: 0.42},
+		Day:    Usage{PromptTokens: 80100},
+		Limits: Limits{RunTokens: 50000, RunCost: 5},
+	}
+	want := "run 12.3k/50k tokens $0.42/$5.00, today 80.1k tokens"
+	if got := status.Summary(); got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+	if got := FormatTokens(2000000); got != "2M" {
+		t.Errorf("FormatTokens(2000000) = %q, want 2M", got)
+	}
+}
+
+func TestParseUsage(t *testing.T) {
+	tests := []struct {
+		name  string
+		event map[string]interface{}
+		want  Usage
+		ok    bool
+	}{
+		{"usage event", UsageEvent(Usage{PromptTokens: 10, CompletionTokens: 5, Cost: 0.5}), Usage{PromptTokens: 10, CompletionTokens: 5, Cost: 0.5}, true},
+		{"decoded usage event", map[string]interface{}{"type": "usage", "prompt_tokens": 10.0, "completion_tokens": 5.0}, Usage{PromptTokens: 10, CompletionTokens: 5}, true},
+		{"codex turn", map[string]interface{}{
+			"type":  "turn.completed",
+			"usage": map[string]interface{}{"input_tokens": 1200.0, "cached_input_tokens": 1000.0, "output_tokens": 80.0},
+		}, Usage{PromptTokens: 1200, CompletionTokens: 80}, true},
+		{"claude result", map[string]interface{}{
+			"type":           "result",
+			"total_cost_usd": 0.12,
+			"usage":          map[string]interface{}{"input_tokens": 50.0, "output_tokens": 20.0},
+		}, Usage{PromptTokens: 50, CompletionTokens: 20, Cost: 0.12}, true},
+		{"turn without usage", map[string]interface{}{"type": "turn.completed"}, Usage{}, false},
+		{"message", map[string]interface{}{"type": "message", "content": "hi"}, Usage{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseUsage(tt.event)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("ParseUsage() = %+v, %v, want %+v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package budget
+
+// UsageEventType is the event runners emit to report the usage of one call
+const UsageEventType = "usage"
+
+// UsageEvent builds the event a runner emits to report usage
+func UsageEvent(u Usage) map[string]interface{} {
+	return map[string]interface{}{
+		"type":              UsageEventType,
+		"prompt_tokens":     u.PromptTokens,
+		"completion_tokens": u.CompletionTokens,
+		"cost":              u.Cost,
+	}
+}
+
+// ParseUsage extracts the usage reported by a runner event
+// It understands usage events, Codex "turn.completed" events and Claude "result" events
+func ParseUsage(event map[string]interface{}) (Usage, bool) {
+	eventType, _ := event["type"].(string)
+	switch eventType {
+	case UsageEventType:
+		return Usage{
+			PromptTokens:     intValue(event["prompt_tokens"]),
+			CompletionTokens: intValue(event["completion_tokens"]),
+			Cost:             floatValue(event["cost"]),
+		}, true
+
+	case "turn.completed", "result":
+		usage, ok := event["usage"].(map[string]interface{})
+		if !ok {
+			return Usage{}, false
+		}
+		return Usage{
+			PromptTokens:     intValue(usage["input_tokens"]),
+			CompletionTokens: intValue(usage["output_tokens"]),
+			Cost:             floatValue(event["total_cost_usd"]),
+		}, true
+	}
+	return Usage{}, false
+}
+
+// intValue reads a number from an event built in-process (int) or decoded from JSON (float64)
+func intValue(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return 0
+}
+
+func floatValue(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case int:
+		return float64(n)
+	}
+	return 0
+}
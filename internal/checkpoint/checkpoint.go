@@ -0,0 +1,289 @@
+package checkpoint
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/brainwhocodes/lisa-loop/internal/git"
+	"github.com/brainwhocodes/lisa-loop/internal/state"
+)
+
+// BranchPrefix is prepended to the run ID to name the checkpoint branch
+const BranchPrefix = "lisa/run-"
+
+// maxSubjectLength caps the length of generated commit subjects
+const maxSubjectLength = 72
+
+// excludedPatterns are Lisa-owned files that must never be committed or rolled back
+var excludedPatterns = []string{
+	state.Dir + "/",
+	// Legacy state files, until they are migrated into the state directory
+	".call_count",
+	".last_reset",
+	".codex_session_id",
+	".opencode_session_id",
+	".command_session_id",
+	".ralph_session",
+	".exit_signals",
+	".circuit_breaker_state",
+	".task_attempts",
+	".lisa_checkpoints",
+	"*.tmp",
+}
+
+// Checkpoint records the work tree state at the start of a loop iteration
+type Checkpoint struct {
+	RunID      string    `json:"run_id"`
+	Loop       int       `json:"loop"`
+	Branch     string    `json:"branch"`
+	SHA        string    `json:"sha"`                  // Commit the tree was at before the loop ran
+	CommitSHA  string    `json:"commit_sha,omitempty"` // Commit holding the loop's changes
+	Message    string    `json:"message,omitempty"`
+	RolledBack bool      `json:"rolled_back,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Manager creates, commits and rolls back checkpoints for a single run
+type Manager struct {
+	dir    string
+	runID  string
+	branch string
+}
+
+// NewManager creates a checkpoint manager for the repository containing dir
+func NewManager(dir, runID string) *Manager {
+	return &Manager{
+		dir:    dir,
+		runID:  runID,
+		branch: BranchPrefix + runID,
+	}
+}
+
+// NewRunID returns a run identifier based on the current time
+// A random suffix keeps runs started in the same second apart
+func NewRunID() string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// Branch returns the name of the checkpoint branch
+func (m *Manager) Branch() string {
+	return m.branch
+}
+
+// Start prepares the repository for checkpointing
+// It switches to the run branch (creating it from HEAD if it doesn't exist), keeps Lisa's
+// state files out of git, and commits any pre-existing uncommitted work so the
+// first rollback can never destroy it
+func (m *Manager) Start() error {
+	if !git.Available() {
+		return fmt.Errorf("git is not installed")
+	}
+	if !git.IsRepo(m.dir) {
+		return fmt.Errorf("not a git repository")
+	}
+
+	if err := git.EnsureExcluded(m.dir, excludedPatterns); err != nil {
+		return fmt.Errorf("failed to update git excludes: %w", err)
+	}
+
+	current, err := git.CurrentBranch(m.dir)
+	if err != nil {
+		// Unborn branch (no commits yet) - create the root commit first
+		if _, err := git.CommitAll(m.dir, "lisa: initial snapshot", true); err != nil {
+			return fmt.Errorf("failed to create initial commit: %w", err)
+		}
+		current = ""
+	}
+
+	// A resumed run goes back to its branch as it was; only a new run branches off HEAD
+	if current != m.branch {
+		args := []string{"checkout", "-q", m.branch}
+		if _, err := git.ResolveRef(m.dir, "refs/heads/"+m.branch); err != nil {
+			args = []string{"checkout", "-q", "-b", m.branch}
+		}
+		if _, err := git.Run(m.dir, args...); err != nil {
+			return fmt.Errorf("failed to switch to %s: %w", m.branch, err)
+		}
+	}
+
+	if _, err := git.CommitAll(m.dir, fmt.Sprintf("lisa: snapshot before run %s", m.runID), false); err != nil {
+		return fmt.Errorf("failed to snapshot work tree: %w", err)
+	}
+
+	return nil
+}
+
+// Begin snapshots the work tree before a loop iteration and persists the checkpoint
+func (m *Manager) Begin(loop int) (*Checkpoint, error) {
+	if _, err := git.CommitAll(m.dir, fmt.Sprintf("lisa: snapshot before loop %d", loop), false); err != nil {
+		return nil, fmt.Errorf("failed to snapshot work tree: %w", err)
+	}
+
+	sha, err := git.HeadSHA(m.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	cp := &Checkpoint{
+		RunID:     m.runID,
+		Loop:      loop,
+		Branch:    m.branch,
+		SHA:       sha,
+		CreatedAt: time.Now(),
+	}
+
+	if err := save(cp); err != nil {
+		return nil, err
+	}
+
+	return cp, nil
+}
+
+// Commit records the iteration's changes on the checkpoint branch
+// Returns false if the iteration did not change anything
+func (m *Manager) Commit(cp *Checkpoint, task string) (bool, error) {
+	message := CommitMessage(cp.Loop, task)
+
+	sha, err := git.CommitAll(m.dir, message, false)
+	if err != nil {
+		return false, fmt.Errorf("failed to commit loop %d: %w", cp.Loop, err)
+	}
+	if sha == "" {
+		return false, nil
+	}
+
+	cp.CommitSHA = sha
+	cp.Message = message
+	return true, save(cp)
+}
+
+// Rollback discards every change made since the checkpoint was taken
+func (m *Manager) Rollback(cp *Checkpoint) error {
+	if err := git.ResetHard(m.dir, cp.SHA); err != nil {
+		return fmt.Errorf("failed to roll back loop %d: %w", cp.Loop, err)
+	}
+
+	cp.RolledBack = true
+	return save(cp)
+}
+
+// CommitMessage builds a commit message for a loop iteration
+func CommitMessage(loop int, task string) string {
+	task = strings.Join(strings.Fields(task), " ")
+	if task == "" {
+		return fmt.Sprintf("lisa: loop %d", loop)
+	}
+
+	subject := fmt.Sprintf("lisa: loop %d: %s", loop, task)
+	if len(subject) > maxSubjectLength {
+		subject = strings.TrimSpace(subject[:maxSubjectLength-3]) + "..."
+	}
+	return subject
+}
+
+// Load returns all persisted checkpoints, oldest first
+func Load() ([]Checkpoint, error) {
+	return state.LoadDocument("", state.CheckpointsSchema, []Checkpoint{})
+}
+
+// Find returns the most recent checkpoint for a loop
+// If runID is empty, checkpoints from any run are considered
+func Find(loop int, runID string) (*Checkpoint, error) {
+	checkpoints, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(checkpoints) - 1; i >= 0; i-- {
+		cp := checkpoints[i]
+		if cp.Loop == loop && (runID == "" || cp.RunID == runID) {
+			return &cp, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no checkpoint found for loop %d", loop)
+}
+
+// RunStart returns the first checkpoint of a run
+// If runID is empty, the most recent run is used
+func RunStart(runID string) (*Checkpoint, error) {
+	checkpoints, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	if len(checkpoints) == 0 {
+		return nil, fmt.Errorf("no checkpoints recorded")
+	}
+
+	if runID == "" {
+		runID = checkpoints[len(checkpoints)-1].RunID
+	}
+
+	for _, cp := range checkpoints {
+		if cp.RunID == runID {
+			return &cp, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no checkpoints recorded for run %s", runID)
+}
+
+// RestoreTo resets the work tree in dir to the checkpoint taken before a loop
+// It refuses to run over uncommitted changes, which the reset would destroy
+func RestoreTo(dir string, loop int, runID string) (*Checkpoint, error) {
+	cp, err := Find(loop, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	dirty, err := git.IsDirty(dir)
+	if err != nil {
+		return nil, err
+	}
+	if dirty {
+		return nil, fmt.Errorf("the work tree has uncommitted changes; commit or stash them before rolling back")
+	}
+
+	if cp.Branch != "" {
+		current, err := git.CurrentBranch(dir)
+		if err == nil && current != cp.Branch {
+			if _, err := git.Run(dir, "checkout", "-q", cp.Branch); err != nil {
+				return nil, fmt.Errorf("failed to switch to %s: %w", cp.Branch, err)
+			}
+		}
+	}
+
+	if err := git.ResetHard(dir, cp.SHA); err != nil {
+		return nil, err
+	}
+
+	return cp, nil
+}
+
+// save inserts or updates a checkpoint in the state file
+func save(cp *Checkpoint) error {
+	checkpoints, err := Load()
+	if err != nil {
+		// A corrupt file shouldn't block checkpointing - start fresh
+		checkpoints = []Checkpoint{}
+	}
+
+	replaced := false
+	for i := range checkpoints {
+		if checkpoints[i].RunID == cp.RunID && checkpoints[i].Loop == cp.Loop {
+			checkpoints[i] = *cp
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		checkpoints = append(checkpoints, *cp)
+	}
+
+	return state.SaveDocument("", state.CheckpointsSchema, checkpoints)
+}
@@ -0,0 +1,270 @@
+package checkpoint
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/brainwhocodes/lisa-loop/internal/git"
+	"github.com/brainwhocodes/lisa-loop/internal/state"
+)
+
+// setupRepo creates a git repository with one commit and chdirs into it
+func setupRepo(t *testing.T) string {
+	t.Helper()
+	if !git.Available() {
+		t.Skip("git not installed")
+	}
+
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("Failed to chdir: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(origDir) })
+
+	if _, err := git.Run("", "init", "-q"); err != nil {
+		t.Fatalf("git init: %v", err)
+	}
+	writeFile(t, "main.go", "package main\n")
+	if _, err := git.CommitAll("", "initial", false); err != nil {
+		t.Fatalf("initial commit: %v", err)
+	}
+
+	return tmpDir
+}
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+}
+
+func readFile(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func TestManager_CommitAndRollback(t *testing.T) {
+	setupRepo(t)
+
+	m := NewManager("", "test")
+	if err := m.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	if branch, _ := git.CurrentBranch(""); branch != "lisa/run-test" {
+		t.Errorf("branch = %q, want lisa/run-test", branch)
+	}
+
+	// Loop 1 makes a good change that gets committed
+	cp1, err := m.Begin(1)
+	if err != nil {
+		t.Fatalf("Begin(1) error = %v", err)
+	}
+	writeFile(t, "main.go", "package main\n\nfunc main() {}\n")
+
+	committed, err := m.Commit(cp1, "Add main function")
+	if err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if !committed {
+		t.Fatal("Commit() = false, want true for changed tree")
+	}
+	if cp1.Message != "lisa: loop 1: Add main function" {
+		t.Errorf("Message = %q", cp1.Message)
+	}
+
+	// Loop 2 trashes the file and adds junk, then gets rolled back
+	cp2, err := m.Begin(2)
+	if err != nil {
+		t.Fatalf("Begin(2) error = %v", err)
+	}
+	writeFile(t, "main.go", "garbage")
+	writeFile(t, "junk.txt", "junk")
+
+	if err := m.Rollback(cp2); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+
+	if got := readFile(t, "main.go"); !strings.Contains(got, "func main()") {
+		t.Errorf("main.go = %q, want loop 1 content restored", got)
+	}
+	if _, err := os.Stat("junk.txt"); !os.IsNotExist(err) {
+		t.Error("junk.txt should have been removed by rollback")
+	}
+
+	// State file survives the rollback and records both checkpoints
+	checkpoints, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(checkpoints) != 2 {
+		t.Fatalf("len(checkpoints) = %d, want 2", len(checkpoints))
+	}
+	if !checkpoints[1].RolledBack {
+		t.Error("checkpoint for loop 2 should be marked rolled back")
+	}
+
+	// Restoring loop 1 brings back the original file
+	cp, err := RestoreTo("", 1, "")
+	if err != nil {
+		t.Fatalf("RestoreTo() error = %v", err)
+	}
+	if cp.SHA != cp1.SHA {
+		t.Errorf("restored SHA = %s, want %s", cp.SHA, cp1.SHA)
+	}
+	if got := readFile(t, "main.go"); got != "package main\n" {
+		t.Errorf("main.go = %q, want original content", got)
+	}
+}
+
+func TestManager_StartPreservesUncommittedWork(t *testing.T) {
+	dir := setupRepo(t)
+	writeFile(t, "wip.go", "package main\n")
+
+	m := NewManager("", "wip")
+	if err := m.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	cp, err := m.Begin(1)
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if err := m.Rollback(cp); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "wip.go")); err != nil {
+		t.Error("pre-existing uncommitted file should survive rollback")
+	}
+}
+
+func TestManager_StartKeepsExistingBranch(t *testing.T) {
+	setupRepo(t)
+	base, _ := git.CurrentBranch("")
+
+	m := NewManager("", "resume")
+	if err := m.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	cp, err := m.Begin(1)
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	writeFile(t, "main.go", "package main\n\nfunc main() {}\n")
+	if _, err := m.Commit(cp, "Add main"); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	runHead, _ := git.HeadSHA("")
+
+	// The user switches away before resuming the run
+	if _, err := git.Run("", "checkout", "-q", base); err != nil {
+		t.Fatalf("checkout %s: %v", base, err)
+	}
+	if err := NewManager("", "resume").Start(); err != nil {
+		t.Fatalf("resumed Start() error = %v", err)
+	}
+
+	if head, _ := git.HeadSHA(""); head != runHead {
+		t.Errorf("HEAD after resume = %s, want the run branch's checkpoint commit %s", head, runHead)
+	}
+	if got := readFile(t, "main.go"); !strings.Contains(got, "func main()") {
+		t.Errorf("main.go = %q, want the run's work back", got)
+	}
+}
+
+func TestManager_StateFilesExcluded(t *testing.T) {
+	setupRepo(t)
+	state.SaveExitSignals([]string{"one"})
+
+	m := NewManager("", "excl")
+	if err := m.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	cp, err := m.Begin(1)
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	state.SaveExitSignals([]string{"two"})
+	if err := m.Rollback(cp); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+
+	if got, _ := state.LoadExitSignals(); len(got) != 1 || got[0] != "two" {
+		t.Errorf("exit signals = %v, want state files untouched by rollback", got)
+	}
+
+	tracked, _ := git.Run("", "ls-files")
+	if strings.Contains(tracked, state.Dir) {
+		t.Errorf("state files should not be committed, tracked: %q", tracked)
+	}
+}
+
+func TestRestoreTo_RefusesDirtyTree(t *testing.T) {
+	setupRepo(t)
+
+	m := NewManager("", "dirty")
+	if err := m.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if _, err := m.Begin(1); err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	writeFile(t, "main.go", "package main\n\n// unsaved edit\n")
+
+	if _, err := RestoreTo("", 1, ""); err == nil {
+		t.Fatal("RestoreTo() error = nil, want a refusal over uncommitted changes")
+	}
+	if got := readFile(t, "main.go"); !strings.Contains(got, "unsaved edit") {
+		t.Errorf("main.go = %q, want the uncommitted edit kept", got)
+	}
+}
+
+func TestNewRunID_Unique(t *testing.T) {
+	if a, b := NewRunID(), NewRunID(); a == b {
+		t.Errorf("NewRunID() returned %q twice", a)
+	}
+}
+
+func TestFind_NoCheckpoint(t *testing.T) {
+	setupRepo(t)
+
+	if _, err := Find(5, ""); err == nil {
+		t.Error("Find() error = nil, want error when no checkpoint exists")
+	}
+}
+
+func TestCommitMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		loop int
+		task string
+		want string
+	}{
+		{"no task", 3, "", "lisa: loop 3"},
+		{"task", 1, "Add login form", "lisa: loop 1: Add login form"},
+		{"whitespace collapsed", 2, "  Fix\n  bug ", "lisa: loop 2: Fix bug"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CommitMessage(tt.loop, tt.task); got != tt.want {
+				t.Errorf("CommitMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	long := CommitMessage(1, strings.Repeat("x", 200))
+	if len(long) > maxSubjectLength {
+		t.Errorf("len(long subject) = %d, want <= %d", len(long), maxSubjectLength)
+	}
+}
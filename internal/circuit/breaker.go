@@ -2,6 +2,7 @@ package circuit
 
 import (
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/brainwhocodes/lisa-loop/internal/state"
@@ -36,9 +37,30 @@ type Breaker struct {
 	noProgressCount     int
 	sameErrorThreshold  int
 	sameErrorHistory    []string
+	errorGroups         []state.ErrorGroup
 	lastCheckTime       time.Time
+
+	// Recovery: an OPEN circuit moves to HALF_OPEN once its cool-down passes
+	cooldown   time.Duration // Base cool-down (0 = stay open until reset)
+	maxProbes  int           // Probe iterations allowed in HALF_OPEN
+	probesLeft int           // Probes remaining; 0 when HALF_OPEN was reached by thresholds
+	openedAt   time.Time
+	reopens    int // Failed recoveries since the circuit last closed; doubles the cool-down
+
+	// Stagnation: fingerprints of recent iterations' output and work tree diff
+	stagnationSimilarity float64 // 0 disables the check
+	outputHashes         []uint64
+	diffHashes           []uint64
+
+	onChange StateChangeCallback
 }
 
+// StateChangeCallback is called after every state transition
+type StateChangeCallback func(from, to State, reason string)
+
+// maxErrorGroups bounds the fingerprints kept; the least recently seen is dropped first
+const maxErrorGroups = 20
+
 // NewBreaker creates a new circuit breaker
 func NewBreaker(noProgressThreshold int, sameErrorThreshold int) *Breaker {
 	return &Breaker{
@@ -48,10 +70,13 @@ func NewBreaker(noProgressThreshold int, sameErrorThreshold int) *Breaker {
 		noProgressCount:     0,
 		sameErrorHistory:    []string{},
 		lastCheckTime:       time.Now(),
+		maxProbes:           1,
 	}
 }
 
 // RecordResult records a loop result
+// In HALF_OPEN, a loop that changed files without errors closes the circuit; a probe
+// that did not counts as a failure
 func (b *Breaker) RecordResult(loopNum int, filesChanged int, hasErrors bool) error {
 	// Update check time
 	b.lastCheckTime = time.Now()
@@ -59,64 +84,111 @@ func (b *Breaker) RecordResult(loopNum int, filesChanged int, hasErrors bool) er
 	// Check for no progress
 	if filesChanged == 0 {
 		b.noProgressCount++
+	} else {
+		// Reset no-progress counter on progress
+		b.noProgressCount = 0
+	}
 
-		// Trigger HALF_OPEN if threshold reached
-		if b.noProgressCount >= b.noProgressThreshold && b.state == StateClosed {
-			b.state = StateHalfOpen
+	if b.state == StateHalfOpen {
+		if filesChanged > 0 && !hasErrors {
+			b.close("loop made progress")
 			return b.SaveState()
 		}
+		if b.probesLeft > 0 {
+			b.failProbe(fmt.Sprintf("probe in loop %d made no progress", loopNum))
+			return b.SaveState()
+		}
+	}
 
+	if filesChanged == 0 {
 		// Trigger OPEN if threshold exceeded
 		if b.noProgressCount >= b.noProgressThreshold*2 {
-			b.state = StateOpen
+			b.open(fmt.Sprintf("no progress in %d loops", b.noProgressCount))
 			return b.SaveState()
 		}
-	} else {
-		// Reset no-progress counter on progress
-		b.noProgressCount = 0
-	}
 
-	// Note: Error tracking is handled by RecordError() method
-	// hasErrors parameter is used for state tracking only
+		// Trigger HALF_OPEN if threshold reached
+		if b.noProgressCount >= b.noProgressThreshold && b.state == StateClosed {
+			b.setState(StateHalfOpen, fmt.Sprintf("no progress in %d loops", b.noProgressCount))
+		}
+	}
 
 	return b.SaveState()
 }
 
 // RecordError records an error for repeated error detection
+// Errors are grouped by fingerprint, so only the same error repeating trips the circuit
 func (b *Breaker) RecordError(errorMsg string) error {
 	if errorMsg == "" {
 		return nil
 	}
 
+	group := b.addToGroup(errorMsg, time.Now())
+
+	// Keep only last N errors
 	b.sameErrorHistory = append(b.sameErrorHistory, errorMsg)
-	totalErrors := len(b.sameErrorHistory)
+	maxHistory := b.sameErrorThreshold * 2
+	if len(b.sameErrorHistory) > maxHistory {
+		b.sameErrorHistory = b.sameErrorHistory[len(b.sameErrorHistory)-maxHistory:]
+	}
 
-	// Trigger OPEN if threshold exceeded (check before capping)
-	if totalErrors >= b.sameErrorThreshold*2 {
-		b.state = StateOpen
-		// Keep only last N errors for history
-		maxHistory := b.sameErrorThreshold * 2
-		if len(b.sameErrorHistory) > maxHistory {
-			b.sameErrorHistory = b.sameErrorHistory[len(b.sameErrorHistory)-maxHistory:]
-		}
+	if b.state == StateHalfOpen && b.probesLeft > 0 {
+		b.failProbe("probe failed: " + errorMsg)
 		return b.SaveState()
 	}
 
-	// Trigger HALF_OPEN if threshold reached
-	if totalErrors >= b.sameErrorThreshold && b.state == StateClosed {
-		b.state = StateHalfOpen
+	// Trigger OPEN if threshold exceeded
+	if group.Count >= b.sameErrorThreshold*2 {
+		b.open(fmt.Sprintf("same error %d times", group.Count))
 		return b.SaveState()
 	}
 
-	// Keep only last N errors
-	maxHistory := b.sameErrorThreshold * 2
-	if len(b.sameErrorHistory) > maxHistory {
-		b.sameErrorHistory = b.sameErrorHistory[len(b.sameErrorHistory)-maxHistory:]
+	// Trigger HALF_OPEN if threshold reached
+	if group.Count >= b.sameErrorThreshold && b.state == StateClosed {
+		b.setState(StateHalfOpen, fmt.Sprintf("same error %d times", group.Count))
 	}
 
 	return b.SaveState()
 }
 
+// addToGroup counts errorMsg against its fingerprint group and returns the group
+func (b *Breaker) addToGroup(errorMsg string, at time.Time) state.ErrorGroup {
+	fp := Fingerprint(errorMsg)
+	for i := range b.errorGroups {
+		g := &b.errorGroups[i]
+		if g.Fingerprint == fp {
+			g.Count++
+			g.Sample = errorMsg
+			g.LastSeen = at
+			return *g
+		}
+	}
+
+	if len(b.errorGroups) >= maxErrorGroups {
+		oldest := 0
+		for i, g := range b.errorGroups {
+			if g.LastSeen.Before(b.errorGroups[oldest].LastSeen) {
+				oldest = i
+			}
+		}
+		b.errorGroups = append(b.errorGroups[:oldest], b.errorGroups[oldest+1:]...)
+	}
+	g := state.ErrorGroup{Fingerprint: fp, Sample: errorMsg, Count: 1, FirstSeen: at, LastSeen: at}
+	b.errorGroups = append(b.errorGroups, g)
+	return g
+}
+
+// maxGroupCount returns the count of the most repeated error
+func (b *Breaker) maxGroupCount() int {
+	max := 0
+	for _, g := range b.errorGroups {
+		if g.Count > max {
+			max = g.Count
+		}
+	}
+	return max
+}
+
 // GetState returns the current circuit state
 func (b *Breaker) GetState() State {
 	return b.state
@@ -144,65 +216,103 @@ func (b *Breaker) IsClosed() bool {
 
 // Reset resets the circuit to CLOSED state
 func (b *Breaker) Reset() error {
-	b.state = StateClosed
+	b.setState(StateClosed, "reset")
+	b.reopens = 0
+	b.probesLeft = 0
+	b.outputHashes = nil
+	b.diffHashes = nil
 	b.noProgressCount = 0
 	b.sameErrorHistory = []string{}
+	b.errorGroups = nil
 	b.lastCheckTime = time.Now()
 
 	return b.SaveState()
 }
 
+// ResetProgress clears the no-progress counter after the loop moved on by other means
+// (e.g. giving up on a stuck task). Repeated errors are kept: moving to another task
+// doesn't fix a failing backend. A HALF_OPEN circuit closes again; an OPEN circuit
+// stays open.
+func (b *Breaker) ResetProgress() error {
+	b.noProgressCount = 0
+	if b.state == StateHalfOpen {
+		b.setState(StateClosed, "progress reset")
+	}
+	return b.SaveState()
+}
+
 // LoadState loads circuit breaker state from file
 func (b *Breaker) LoadState() (*Breaker, error) {
-	stateMap, err := state.LoadCircuitBreakerState()
+	cs, err := state.LoadCircuitBreakerState()
 	if err != nil {
 		return nil, err
 	}
 
-	loaded := NewBreaker(3, 5)
+	loaded := NewBreaker(b.noProgressThreshold, b.sameErrorThreshold)
+	loaded.cooldown = b.cooldown
+	loaded.stagnationSimilarity = b.stagnationSimilarity
+	loaded.maxProbes = b.maxProbes
+	loaded.onChange = b.onChange
+	loaded.apply(cs)
 
-	if stateVal, ok := stateMap["state"].(string); ok {
-		switch stateVal {
-		case "CLOSED":
-			loaded.state = StateClosed
-		case "HALF_OPEN":
-			loaded.state = StateHalfOpen
-		case "OPEN":
-			loaded.state = StateOpen
-		}
-	}
+	return loaded, nil
+}
 
-	if lastCheck, ok := stateMap["last_check_time"].(string); ok {
-		loaded.lastCheckTime, _ = time.Parse(time.RFC3339, lastCheck)
-	}
+// Restore replaces the breaker's state with cs, such as the state saved with a run
+// record, and persists it
+func (b *Breaker) Restore(cs state.CircuitState) error {
+	b.apply(cs)
+	return b.SaveState()
+}
 
-	// Load counters
-	if noProg, ok := stateMap["no_progress_count"].(float64); ok {
-		loaded.noProgressCount = int(noProg)
+// apply sets the breaker's state from a saved state
+func (b *Breaker) apply(cs state.CircuitState) {
+	switch cs.State {
+	case "CLOSED":
+		b.state = StateClosed
+	case "HALF_OPEN":
+		b.state = StateHalfOpen
+	case "OPEN":
+		b.state = StateOpen
 	}
 
-	if errHist, ok := stateMap["error_history"].([]interface{}); ok {
-		loaded.sameErrorHistory = make([]string, len(errHist))
-		for i, err := range errHist {
-			if errStr, ok := err.(string); ok {
-				loaded.sameErrorHistory[i] = errStr
-			}
+	b.lastCheckTime = cs.LastCheckTime
+	b.noProgressCount = cs.NoProgressCount
+	b.openedAt = cs.OpenedAt
+	b.reopens = cs.Reopens
+	b.probesLeft = cs.ProbesLeft
+	b.outputHashes = parseHashes(cs.OutputFingerprints)
+	b.diffHashes = parseHashes(cs.DiffFingerprints)
+	b.sameErrorHistory = []string{}
+	if cs.ErrorHistory != nil {
+		b.sameErrorHistory = cs.ErrorHistory
+	}
+	b.errorGroups = cs.ErrorGroups
+	if b.errorGroups == nil {
+		// State saved before errors were grouped
+		for _, msg := range b.sameErrorHistory {
+			b.addToGroup(msg, cs.LastCheckTime)
 		}
 	}
-
-	return loaded, nil
 }
 
 // SaveState saves circuit breaker state to file
 func (b *Breaker) SaveState() error {
-	stateMap := map[string]interface{}{
-		"state":             b.state.String(),
-		"no_progress_count": b.noProgressCount,
-		"error_history":     b.sameErrorHistory,
-		"last_check_time":   b.lastCheckTime.Format(time.RFC3339),
+	cs := state.CircuitState{
+		State:           b.state.String(),
+		NoProgressCount: b.noProgressCount,
+		ErrorHistory:    b.sameErrorHistory,
+		ErrorGroups:     b.errorGroups,
+		LastCheckTime:   b.lastCheckTime,
+		OpenedAt:        b.openedAt,
+		Reopens:         b.reopens,
+		ProbesLeft:      b.probesLeft,
+
+		OutputFingerprints: formatHashes(b.outputHashes),
+		DiffFingerprints:   formatHashes(b.diffHashes),
 	}
 
-	if err := state.SaveCircuitBreakerState(stateMap); err != nil {
+	if err := state.SaveCircuitBreakerState(cs); err != nil {
 		return fmt.Errorf("failed to save circuit breaker state: %w", err)
 	}
 
@@ -214,10 +324,14 @@ func (b *Breaker) GetStats() map[string]interface{} {
 	return map[string]interface{}{
 		"state":                 b.state.String(),
 		"no_progress_count":     b.noProgressCount,
-		"same_error_count":      len(b.sameErrorHistory),
+		"same_error_count":      b.maxGroupCount(),
+		"error_groups":          b.GetErrorGroups(),
 		"last_check_time":       b.lastCheckTime.Format(time.RFC3339),
 		"no_progress_threshold": b.noProgressThreshold,
 		"same_error_threshold":  b.sameErrorThreshold,
+		"probes_left":           b.probesLeft,
+		"reopens":               b.reopens,
+		"stagnation_similarity": b.stagnationSimilarity,
 	}
 }
 
@@ -228,7 +342,7 @@ func (b *Breaker) CheckNoProgress() bool {
 
 // CheckRepeatedErrors checks if we've hit the repeated error threshold
 func (b *Breaker) CheckRepeatedErrors() bool {
-	return len(b.sameErrorHistory) >= b.sameErrorThreshold
+	return b.maxGroupCount() >= b.sameErrorThreshold
 }
 
 // GetNoProgressCount returns the current no-progress counter
@@ -241,8 +355,14 @@ func (b *Breaker) GetErrorHistory() []string {
 	return b.sameErrorHistory
 }
 
-// LoadBreakerFromFile loads a circuit breaker from the state file
-func LoadBreakerFromFile() (*Breaker, error) {
-	breaker := NewBreaker(3, 5)
-	return breaker.LoadState()
+// GetErrorGroups returns the recorded errors grouped by fingerprint, most repeated first
+func (b *Breaker) GetErrorGroups() []state.ErrorGroup {
+	groups := append([]state.ErrorGroup(nil), b.errorGroups...)
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].LastSeen.After(groups[j].LastSeen)
+	})
+	return groups
 }
@@ -1,13 +1,24 @@
 package circuit
 
 import (
+	"fmt"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/brainwhocodes/lisa-loop/internal/config"
 	"github.com/brainwhocodes/lisa-loop/internal/state"
 )
 
+// chdirTemp runs a test in a fresh directory, since the breaker saves its state
+func chdirTemp(t *testing.T) {
+	t.Helper()
+	origDir, _ := os.Getwd()
+	os.Chdir(t.TempDir())
+	t.Cleanup(func() { os.Chdir(origDir) })
+}
+
 func TestNewBreaker(t *testing.T) {
 	breaker := NewBreaker(3, 5)
 
@@ -29,6 +40,7 @@ func TestNewBreaker(t *testing.T) {
 }
 
 func TestRecordResultWithProgress(t *testing.T) {
+	chdirTemp(t)
 	breaker := NewBreaker(3, 5)
 
 	err := breaker.RecordResult(1, 5, false)
@@ -43,6 +55,7 @@ func TestRecordResultWithProgress(t *testing.T) {
 }
 
 func TestRecordResultNoProgress(t *testing.T) {
+	chdirTemp(t)
 	breaker := NewBreaker(3, 5)
 
 	err := breaker.RecordResult(1, 0, false)
@@ -57,6 +70,7 @@ func TestRecordResultNoProgress(t *testing.T) {
 }
 
 func TestNoProgressTriggerHalfOpen(t *testing.T) {
+	chdirTemp(t)
 	breaker := NewBreaker(3, 5)
 
 	breaker.RecordResult(1, 0, false)
@@ -69,6 +83,7 @@ func TestNoProgressTriggerHalfOpen(t *testing.T) {
 }
 
 func TestNoProgressTriggerOpen(t *testing.T) {
+	chdirTemp(t)
 	breaker := NewBreaker(3, 5)
 
 	for i := 0; i < 6; i++ {
@@ -81,6 +96,7 @@ func TestNoProgressTriggerOpen(t *testing.T) {
 }
 
 func TestRecordError(t *testing.T) {
+	chdirTemp(t)
 	breaker := NewBreaker(3, 5)
 
 	err := breaker.RecordError("test error")
@@ -99,6 +115,7 @@ func TestRecordError(t *testing.T) {
 }
 
 func TestRepeatedErrorsTriggerHalfOpen(t *testing.T) {
+	chdirTemp(t)
 	breaker := NewBreaker(3, 5)
 
 	for i := 0; i < 5; i++ {
@@ -111,6 +128,7 @@ func TestRepeatedErrorsTriggerHalfOpen(t *testing.T) {
 }
 
 func TestRepeatedErrorsTriggerOpen(t *testing.T) {
+	chdirTemp(t)
 	breaker := NewBreaker(3, 5)
 
 	for i := 0; i < 10; i++ {
@@ -123,6 +141,7 @@ func TestRepeatedErrorsTriggerOpen(t *testing.T) {
 }
 
 func TestShouldHalt(t *testing.T) {
+	chdirTemp(t)
 	breaker := NewBreaker(3, 5)
 
 	if breaker.ShouldHalt() {
@@ -139,6 +158,7 @@ func TestShouldHalt(t *testing.T) {
 }
 
 func TestIsHalfOpen(t *testing.T) {
+	chdirTemp(t)
 	breaker := NewBreaker(3, 5)
 
 	if breaker.IsHalfOpen() {
@@ -155,6 +175,7 @@ func TestIsHalfOpen(t *testing.T) {
 }
 
 func TestIsOpen(t *testing.T) {
+	chdirTemp(t)
 	breaker := NewBreaker(3, 5)
 
 	if breaker.IsOpen() {
@@ -171,6 +192,7 @@ func TestIsOpen(t *testing.T) {
 }
 
 func TestReset(t *testing.T) {
+	chdirTemp(t)
 	breaker := NewBreaker(3, 5)
 
 	for i := 0; i < 10; i++ {
@@ -196,7 +218,35 @@ func TestReset(t *testing.T) {
 	}
 }
 
+func TestResetProgress(t *testing.T) {
+	chdirTemp(t)
+	breaker := NewBreaker(3, 5)
+
+	for i := 0; i < 3; i++ {
+		breaker.RecordResult(i, 0, false)
+	}
+	breaker.RecordError("test")
+
+	if err := breaker.ResetProgress(); err != nil {
+		t.Errorf("ResetProgress() error = %v, want nil", err)
+	}
+	if breaker.state != StateClosed || breaker.noProgressCount != 0 {
+		t.Errorf("ResetProgress() state = %s, noProgress = %d", breaker.state, breaker.noProgressCount)
+	}
+	if len(breaker.sameErrorHistory) != 1 {
+		t.Errorf("ResetProgress() errors = %d, want repeated errors kept", len(breaker.sameErrorHistory))
+	}
+
+	// An OPEN circuit needs an explicit reset
+	breaker.state = StateOpen
+	breaker.ResetProgress()
+	if breaker.state != StateOpen {
+		t.Errorf("ResetProgress() should not close an OPEN circuit, got %s", breaker.state)
+	}
+}
+
 func TestGetStats(t *testing.T) {
+	chdirTemp(t)
 	breaker := NewBreaker(3, 5)
 	breaker.RecordResult(1, 2, false)
 	breaker.RecordError("test error")
@@ -228,6 +278,12 @@ func TestGetStats(t *testing.T) {
 	}
 }
 
+// newConfiguredBreaker builds a breaker with the thresholds the config defaults to
+func newConfiguredBreaker() *Breaker {
+	cfg := config.Default()
+	return NewBreaker(cfg.CircuitNoProgressThreshold, cfg.CircuitSameErrorThreshold)
+}
+
 func TestLoadState(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -236,35 +292,36 @@ func TestLoadState(t *testing.T) {
 	os.Chdir(tmpDir)
 	defer os.Chdir(origDir)
 
-	testState := map[string]interface{}{
-		"state":             "HALF_OPEN",
-		"no_progress_count": 2,
-		"error_history":     []interface{}{"error1", "error1"},
-		"last_check_time":   time.Now().Format(time.RFC3339),
+	testState := state.CircuitState{
+		State:           "HALF_OPEN",
+		NoProgressCount: 2,
+		ErrorHistory:    []string{"error1", "error1"},
+		LastCheckTime:   time.Now(),
 	}
 
 	state.SaveCircuitBreakerState(testState)
 
-	loadedBreaker, err := LoadBreakerFromFile()
+	loadedBreaker, err := newConfiguredBreaker().LoadState()
 
 	if err != nil {
-		t.Errorf("LoadBreakerFromFile() error = %v, want nil", err)
+		t.Errorf("LoadState() error = %v, want nil", err)
 	}
 
 	if loadedBreaker.state != StateHalfOpen {
-		t.Errorf("LoadBreakerFromFile() state = %s, want HALF_OPEN", loadedBreaker.state)
+		t.Errorf("LoadState() state = %s, want HALF_OPEN", loadedBreaker.state)
 	}
 
 	if loadedBreaker.noProgressCount != 2 {
-		t.Errorf("LoadBreakerFromFile() noProgressCount = %d, want 2", loadedBreaker.noProgressCount)
+		t.Errorf("LoadState() noProgressCount = %d, want 2", loadedBreaker.noProgressCount)
 	}
 
 	if len(loadedBreaker.sameErrorHistory) != 2 {
-		t.Errorf("LoadBreakerFromFile() errorHistory length = %d, want 2", len(loadedBreaker.sameErrorHistory))
+		t.Errorf("LoadState() errorHistory length = %d, want 2", len(loadedBreaker.sameErrorHistory))
 	}
 }
 
 func TestSaveState(t *testing.T) {
+	chdirTemp(t)
 	breaker := NewBreaker(3, 5)
 
 	breaker.RecordResult(1, 0, false)
@@ -276,7 +333,7 @@ func TestSaveState(t *testing.T) {
 		t.Errorf("SaveState() error = %v, want nil", err)
 	}
 
-	loadedBreaker, _ := LoadBreakerFromFile()
+	loadedBreaker, _ := newConfiguredBreaker().LoadState()
 
 	if loadedBreaker.noProgressCount != breaker.noProgressCount {
 		t.Errorf("SaveState() noProgressCount not persisted")
@@ -286,3 +343,73 @@ func TestSaveState(t *testing.T) {
 		t.Errorf("SaveState() errorHistory not persisted")
 	}
 }
+
+func TestDistinctErrorsDoNotTrip(t *testing.T) {
+	chdirTemp(t)
+	breaker := NewBreaker(3, 5)
+
+	for i := 0; i < 10; i++ {
+		breaker.RecordError(fmt.Sprintf("distinct failure %c", 'a'+i))
+	}
+
+	if breaker.state != StateClosed {
+		t.Errorf("RecordError() with distinct errors should stay CLOSED, got %s", breaker.state)
+	}
+	if got := len(breaker.GetErrorGroups()); got != 10 {
+		t.Errorf("GetErrorGroups() length = %d, want 10", got)
+	}
+	if breaker.CheckRepeatedErrors() {
+		t.Error("CheckRepeatedErrors() should be false for distinct errors")
+	}
+}
+
+func TestSimilarErrorsGrouped(t *testing.T) {
+	chdirTemp(t)
+	breaker := NewBreaker(3, 5)
+
+	for i := 0; i < 10; i++ {
+		breaker.RecordError(fmt.Sprintf("2024-05-01T12:00:%02dZ /tmp/run%d/main.go:%d: build failed", i, i, 10+i))
+		breaker.RecordError(fmt.Sprintf("unrelated failure %c", 'a'+i))
+	}
+
+	if breaker.state != StateOpen {
+		t.Errorf("RecordError() should trip OPEN when one error repeats 10 times, got %s", breaker.state)
+	}
+	groups := breaker.GetErrorGroups()
+	if len(groups) != 11 || groups[0].Count != 10 {
+		t.Fatalf("GetErrorGroups() = %+v, want the repeated error first with count 10", groups)
+	}
+	if !strings.Contains(groups[0].Sample, "main.go:19") {
+		t.Errorf("group sample = %q, want the most recent message", groups[0].Sample)
+	}
+	if breaker.GetStats()["same_error_count"] != 10 {
+		t.Errorf("GetStats() same_error_count = %v, want 10", breaker.GetStats()["same_error_count"])
+	}
+}
+
+func TestErrorGroupsPersisted(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	breaker := NewBreaker(3, 5)
+	for i := 0; i < 3; i++ {
+		breaker.RecordError(fmt.Sprintf("request %d timed out", i))
+	}
+
+	loaded, err := newConfiguredBreaker().LoadState()
+	if err != nil {
+		t.Fatalf("LoadState() error = %v", err)
+	}
+	if groups := loaded.GetErrorGroups(); len(groups) != 1 || groups[0].Count != 3 {
+		t.Errorf("loaded groups = %+v, want one group of 3", groups)
+	}
+
+	// State saved before errors were grouped is grouped from its history
+	state.SaveCircuitBreakerState(state.CircuitState{State: "CLOSED", ErrorHistory: []string{"e 1", "e 2", "other"}})
+	loaded, _ = newConfiguredBreaker().LoadState()
+	if groups := loaded.GetErrorGroups(); len(groups) != 2 || groups[0].Count != 2 {
+		t.Errorf("legacy groups = %+v, want two groups, the first of 2", groups)
+	}
+}
@@ -0,0 +1,46 @@
+package circuit
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+// Normalization rules, applied in order to the lowercased message
+var normalizers = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	// 2024-05-01T12:30:45.123Z, 2024-05-01 12:30:45, 12:30:45
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}([t ]\d{2}:\d{2}(:\d{2})?(\.\d+)?(z|[+-]\d{2}:?\d{2})?)?`), "<time>"},
+	{regexp.MustCompile(`\b\d{1,2}:\d{2}:\d{2}(\.\d+)?\b`), "<time>"},
+	// UUIDs and long hex strings (commit SHAs, request and session IDs)
+	{regexp.MustCompile(`\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`), "<id>"},
+	{regexp.MustCompile(`\b(0x)?[0-9a-f]{7,}\b`), "<id>"},
+	// Prefixed IDs such as req_01HX9 or ses-4f2a
+	{regexp.MustCompile(`\b[a-z]+[_-][a-z0-9]*\d[a-z0-9]*\b`), "<id>"},
+	// Absolute, relative and Windows paths, with any :line:col suffix
+	{regexp.MustCompile(`([a-z]:\\|\.{0,2}/)?([\w.-]+[/\\])+[\w.-]+(:\d+)*`), "<path>"},
+	{regexp.MustCompile(`\b[\w-]+\.[a-z]{1,5}:\d+(:\d+)?\b`), "<path>"},
+	// "line 42", "col 7"
+	{regexp.MustCompile(`\b(line|col|column|pos|offset)\s+\d+`), "$1 <n>"},
+	// Any remaining number: PIDs, ports, byte counts, durations
+	{regexp.MustCompile(`\d+(\.\d+)?`), "<n>"},
+}
+
+// NormalizeError reduces an error message to its stable shape by replacing
+// timestamps, IDs, paths, line numbers and other numbers with placeholders
+func NormalizeError(msg string) string {
+	s := strings.ToLower(strings.TrimSpace(msg))
+	for _, n := range normalizers {
+		s = n.re.ReplaceAllString(s, n.repl)
+	}
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Fingerprint identifies errors that differ only in volatile details
+func Fingerprint(msg string) string {
+	sum := sha1.Sum([]byte(NormalizeError(msg)))
+	return hex.EncodeToString(sum[:6])
+}
@@ -0,0 +1,49 @@
+package circuit
+
+import "testing"
+
+func TestNormalizeError(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+	}{
+		{"timestamps", "2024-05-01T12:30:45Z request failed", "2025-11-30T08:01:02.5+02:00 request failed"},
+		{"clock times", "timeout at 12:30:45", "timeout at 9:01:02"},
+		{"uuids", "session 3f2a9c1e-8b7d-4e6f-9a0b-1c2d3e4f5a6b not found", "session 00000000-1111-2222-3333-444444444444 not found"},
+		{"hex ids", "commit deadbeef1234 rejected", "commit 0a1b2c3d4e5f rejected"},
+		{"prefixed ids", "rate limited (req_01HX9K2)", "rate limited (req_7ZZQ1)"},
+		{"paths and lines", "/home/a/proj/main.go:12:5: undefined: foo", "./internal/x/util.go:140:2: undefined: foo"},
+		{"bare file lines", "main.go:12: syntax error", "util.go:7: syntax error"},
+		{"line words", "parse error on line 42", "parse error on line 7"},
+		{"numbers", "exit status 137 after 12.5s", "exit status 1 after 3s"},
+		{"case and spacing", "Connection  Refused", "connection refused"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if NormalizeError(tt.a) != NormalizeError(tt.b) {
+				t.Errorf("NormalizeError() differs:\n %q -> %q\n %q -> %q", tt.a, NormalizeError(tt.a), tt.b, NormalizeError(tt.b))
+			}
+			if Fingerprint(tt.a) != Fingerprint(tt.b) {
+				t.Errorf("Fingerprint(%q) != Fingerprint(%q)", tt.a, tt.b)
+			}
+		})
+	}
+}
+
+func TestFingerprint_Distinct(t *testing.T) {
+	errs := []string{
+		"connection refused",
+		"permission denied",
+		"main.go:12: undefined: foo",
+		"main.go:12: undefined: bar",
+		"context deadline exceeded",
+	}
+	seen := map[string]string{}
+	for _, e := range errs {
+		fp := Fingerprint(e)
+		if prev, ok := seen[fp]; ok {
+			t.Errorf("Fingerprint(%q) collides with %q (normalized %q)", e, prev, NormalizeError(e))
+		}
+		seen[fp] = e
+	}
+}
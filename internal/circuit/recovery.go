@@ -0,0 +1,108 @@
+package circuit
+
+import (
+	"fmt"
+	"time"
+)
+
+// maxBackoffDoublings caps the cool-down at 64 times its base
+const maxBackoffDoublings = 6
+
+// SetRecovery enables time-based recovery: an OPEN circuit moves to HALF_OPEN after
+// cooldown, doubled for every failed recovery since the circuit last closed, and then
+// allows probes iterations before re-opening. A zero cooldown keeps the circuit open
+// until it is reset.
+func (b *Breaker) SetRecovery(cooldown time.Duration, probes int) {
+	if probes < 1 {
+		probes = 1
+	}
+	b.cooldown = cooldown
+	b.maxProbes = probes
+}
+
+// SetStateChangeCallback sets the callback for state transitions
+func (b *Breaker) SetStateChangeCallback(cb StateChangeCallback) {
+	b.onChange = cb
+}
+
+// Cooldown returns how long the circuit stays OPEN before its next probe
+func (b *Breaker) Cooldown() time.Duration {
+	n := b.reopens
+	if n > maxBackoffDoublings {
+		n = maxBackoffDoublings
+	}
+	return b.cooldown << n
+}
+
+// NextProbe returns when an OPEN circuit moves to HALF_OPEN
+// ok is false if the circuit is not OPEN or stays open until reset
+func (b *Breaker) NextProbe() (at time.Time, ok bool) {
+	if b.state != StateOpen || b.cooldown <= 0 {
+		return time.Time{}, false
+	}
+	return b.openedAt.Add(b.Cooldown()), true
+}
+
+// CanRecover reports whether an OPEN circuit will move to HALF_OPEN on its own
+func (b *Breaker) CanRecover() bool {
+	return b.cooldown > 0
+}
+
+// CheckCooldown moves an OPEN circuit to HALF_OPEN once its cool-down has passed
+// Returns true if the circuit started probing
+func (b *Breaker) CheckCooldown() (bool, error) {
+	at, ok := b.NextProbe()
+	if !ok || time.Now().Before(at) {
+		return false, nil
+	}
+	b.probesLeft = b.maxProbes
+	b.setState(StateHalfOpen, fmt.Sprintf("cool-down of %s passed; allowing %d probe(s)", b.Cooldown(), b.probesLeft))
+	return true, b.SaveState()
+}
+
+// IsProbing reports whether the circuit is HALF_OPEN after a cool-down, so the next
+// iteration is a probe
+func (b *Breaker) IsProbing() bool {
+	return b.state == StateHalfOpen && b.probesLeft > 0
+}
+
+// ProbesLeft returns the probe iterations remaining in HALF_OPEN
+func (b *Breaker) ProbesLeft() int {
+	return b.probesLeft
+}
+
+// open trips the circuit and starts its cool-down
+func (b *Breaker) open(reason string) {
+	b.openedAt = time.Now()
+	b.probesLeft = 0
+	b.setState(StateOpen, reason)
+}
+
+// close closes the circuit and forgets the failures that opened it
+func (b *Breaker) close(reason string) {
+	b.probesLeft = 0
+	b.reopens = 0
+	b.noProgressCount = 0
+	b.sameErrorHistory = []string{}
+	b.errorGroups = nil
+	b.setState(StateClosed, reason)
+}
+
+// failProbe uses up one probe, re-opening the circuit with a longer cool-down after the last
+func (b *Breaker) failProbe(reason string) {
+	b.probesLeft--
+	if b.probesLeft > 0 {
+		return
+	}
+	b.reopens++
+	b.open(reason)
+}
+
+// setState transitions the circuit and notifies the state change callback
+func (b *Breaker) setState(to State, reason string) {
+	from := b.state
+	b.state = to
+	if from != to && b.onChange != nil {
+		b.onChange(from, to, reason)
+	}
+}
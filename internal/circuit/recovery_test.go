@@ -0,0 +1,102 @@
+package circuit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCooldownStartsProbing(t *testing.T) {
+	chdirTemp(t)
+	breaker := NewBreaker(3, 1)
+	breaker.SetRecovery(20*time.Millisecond, 2)
+
+	var transitions []string
+	breaker.SetStateChangeCallback(func(from, to State, reason string) {
+		transitions = append(transitions, from.String()+">"+to.String())
+	})
+
+	breaker.RecordError("boom")
+	breaker.RecordError("boom")
+	if !breaker.IsOpen() {
+		t.Fatalf("state = %s, want OPEN", breaker.GetState())
+	}
+	if started, _ := breaker.CheckCooldown(); started {
+		t.Error("CheckCooldown() should wait out the cool-down")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if started, err := breaker.CheckCooldown(); !started || err != nil {
+		t.Fatalf("CheckCooldown() = %v, %v, want probing", started, err)
+	}
+	if !breaker.IsProbing() || breaker.ProbesLeft() != 2 {
+		t.Errorf("IsProbing() = %v with %d probes, want 2 probes", breaker.IsProbing(), breaker.ProbesLeft())
+	}
+
+	// One failed probe leaves another; a successful one closes the circuit
+	breaker.RecordResult(1, 0, false)
+	if !breaker.IsProbing() {
+		t.Errorf("state = %s with %d probes after one failed probe, want still probing", breaker.GetState(), breaker.ProbesLeft())
+	}
+	breaker.RecordResult(2, 3, false)
+	if !breaker.IsClosed() || len(breaker.GetErrorGroups()) != 0 {
+		t.Errorf("state = %s with %d error groups, want CLOSED and cleared", breaker.GetState(), len(breaker.GetErrorGroups()))
+	}
+
+	want := []string{"CLOSED>HALF_OPEN", "HALF_OPEN>OPEN", "OPEN>HALF_OPEN", "HALF_OPEN>CLOSED"}
+	if len(transitions) != len(want) {
+		t.Fatalf("transitions = %v, want %v", transitions, want)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Errorf("transitions = %v, want %v", transitions, want)
+			break
+		}
+	}
+}
+
+func TestFailedProbeBacksOff(t *testing.T) {
+	chdirTemp(t)
+	breaker := NewBreaker(3, 1)
+	breaker.SetRecovery(time.Millisecond, 1)
+
+	breaker.RecordError("boom")
+	breaker.RecordError("boom")
+	for i, want := range []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond} {
+		if got := breaker.Cooldown(); got != want {
+			t.Errorf("Cooldown() after %d failed probes = %s, want %s", i, got, want)
+		}
+		time.Sleep(want + time.Millisecond)
+		if started, _ := breaker.CheckCooldown(); !started {
+			t.Fatalf("CheckCooldown() did not start probing after %s", want)
+		}
+		breaker.RecordError("still broken")
+		if !breaker.IsOpen() {
+			t.Fatalf("state = %s after a failed probe, want OPEN", breaker.GetState())
+		}
+	}
+
+	// The back-off is persisted with the state
+	loaded, _ := breaker.LoadState()
+	if loaded.Cooldown() != 8*time.Millisecond {
+		t.Errorf("loaded Cooldown() = %s, want 8ms", loaded.Cooldown())
+	}
+
+	breaker.Reset()
+	if breaker.Cooldown() != time.Millisecond {
+		t.Errorf("Cooldown() after Reset() = %s, want the base cool-down", breaker.Cooldown())
+	}
+}
+
+func TestNoCooldownStaysOpen(t *testing.T) {
+	chdirTemp(t)
+	breaker := NewBreaker(3, 1)
+
+	breaker.RecordError("boom")
+	breaker.RecordError("boom")
+	if _, ok := breaker.NextProbe(); ok || breaker.CanRecover() {
+		t.Error("a circuit without a cool-down should stay open until reset")
+	}
+	if started, _ := breaker.CheckCooldown(); started || !breaker.IsOpen() {
+		t.Errorf("state = %s, want OPEN", breaker.GetState())
+	}
+}
@@ -0,0 +1,64 @@
+package circuit
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// shingleSize is the number of words hashed together as one feature
+const shingleSize = 3
+
+// Simhash fingerprints text so that near-identical texts get fingerprints that differ
+// in few bits. Text is normalized like errors first, so timestamps, IDs, paths and
+// numbers do not count as differences.
+func Simhash(text string) uint64 {
+	words := strings.Fields(NormalizeError(text))
+	if len(words) == 0 {
+		return 0
+	}
+
+	var weights [64]int
+	add := func(feature string) {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		for i := 0; i < 64; i++ {
+			if sum&(1<<uint(i)) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	}
+
+	if len(words) < shingleSize {
+		add(strings.Join(words, " "))
+	}
+	for i := 0; i+shingleSize <= len(words); i++ {
+		add(strings.Join(words[i:i+shingleSize], " "))
+	}
+
+	var hash uint64
+	for i, w := range weights {
+		if w > 0 {
+			hash |= 1 << uint(i)
+		}
+	}
+	return hash
+}
+
+// Similarity returns the share of matching bits between two fingerprints (0-1)
+func Similarity(a, b uint64) float64 {
+	return 1 - float64(bits.OnesCount64(a^b))/64
+}
+
+func formatHash(h uint64) string {
+	return strconv.FormatUint(h, 16)
+}
+
+func parseHash(s string) (uint64, bool) {
+	h, err := strconv.ParseUint(s, 16, 64)
+	return h, err == nil
+}
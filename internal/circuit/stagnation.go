@@ -0,0 +1,78 @@
+package circuit
+
+import "fmt"
+
+// stagnationWindow is the number of previous iterations an iteration is compared with,
+// so an agent alternating between two answers is caught as well as one repeating itself
+const stagnationWindow = 3
+
+// SetStagnation sets the similarity (0-1) at which an iteration's output or diff counts
+// as a repeat of a recent iteration; 0 disables the check
+func (b *Breaker) SetStagnation(similarity float64) {
+	b.stagnationSimilarity = similarity
+}
+
+// CheckStagnation fingerprints an iteration's final output and work tree diff and
+// reports whether either nearly duplicates one of the last few iterations, with the
+// reason. Pass hasDiff false when no diff is available (outside a git repository).
+// The caller counts a stagnant iteration as no progress, whatever the agent reported.
+func (b *Breaker) CheckStagnation(output, diff string, hasDiff bool) (bool, string) {
+	if b.stagnationSimilarity <= 0 {
+		return false, ""
+	}
+
+	reason := ""
+	outputHash := Simhash(output)
+	if sim, ok := b.closest(b.outputHashes, outputHash); ok && sim >= b.stagnationSimilarity {
+		reason = fmt.Sprintf("output %.0f%% similar to a recent iteration", sim*100)
+	}
+	b.outputHashes = pushHash(b.outputHashes, outputHash)
+
+	if hasDiff {
+		diffHash := Simhash(diff)
+		if sim, ok := b.closest(b.diffHashes, diffHash); ok && sim >= b.stagnationSimilarity && reason == "" {
+			reason = fmt.Sprintf("changes %.0f%% similar to a recent iteration", sim*100)
+		}
+		b.diffHashes = pushHash(b.diffHashes, diffHash)
+	}
+
+	return reason != "", reason
+}
+
+// closest returns the highest similarity between h and the recorded fingerprints
+func (b *Breaker) closest(hashes []uint64, h uint64) (float64, bool) {
+	best, ok := 0.0, false
+	for _, prev := range hashes {
+		if sim := Similarity(prev, h); !ok || sim > best {
+			best, ok = sim, true
+		}
+	}
+	return best, ok
+}
+
+// pushHash appends h, keeping the last stagnationWindow fingerprints
+func pushHash(hashes []uint64, h uint64) []uint64 {
+	hashes = append(hashes, h)
+	if len(hashes) > stagnationWindow {
+		hashes = hashes[len(hashes)-stagnationWindow:]
+	}
+	return hashes
+}
+
+func formatHashes(hashes []uint64) []string {
+	var out []string
+	for _, h := range hashes {
+		out = append(out, formatHash(h))
+	}
+	return out
+}
+
+func parseHashes(hexes []string) []uint64 {
+	var out []uint64
+	for _, s := range hexes {
+		if h, ok := parseHash(s); ok {
+			out = append(out, h)
+		}
+	}
+	return out
+}
@@ -0,0 +1,70 @@
+package circuit
+
+import (
+	"strings"
+	"testing"
+)
+
+const agentReply = `I looked into the failing build again. The compiler still reports that the
+handler package cannot find the session store, so I updated the import path in
+server.go and re-ran the tests. The same error appears, which suggests the store
+was never moved to the new module. I will try moving it in the next iteration.
+---RALPH_STATUS---
+STATUS: WORKING
+FILES_MODIFIED: 1
+---END_RALPH_STATUS---`
+
+func TestSimhash(t *testing.T) {
+	// Loop numbers, paths and timestamps are normalized away
+	rerun := strings.Replace(agentReply, "server.go", "cmd/api/server.go:42", 1) + "\nFinished at 2024-05-01T10:00:00Z"
+	if sim := Similarity(Simhash(agentReply), Simhash(rerun)); sim < 0.95 {
+		t.Errorf("Similarity() of near-identical replies = %.2f, want >= 0.95", sim)
+	}
+
+	other := `Implemented the rate limiter middleware with a token bucket per client and
+added table-driven tests covering bursts, refills and the Retry-After header.
+All tests pass and the README documents the new limits.`
+	if sim := Similarity(Simhash(agentReply), Simhash(other)); sim >= 0.9 {
+		t.Errorf("Similarity() of unrelated replies = %.2f, want < 0.9", sim)
+	}
+
+	if Simhash("") != Simhash("  ") || Similarity(0, 0) != 1 {
+		t.Error("empty texts should be identical")
+	}
+}
+
+func TestCheckStagnation(t *testing.T) {
+	chdirTemp(t)
+	breaker := NewBreaker(3, 5)
+
+	if stagnant, _ := breaker.CheckStagnation(agentReply, "", false); stagnant {
+		t.Error("CheckStagnation() should be off by default")
+	}
+
+	breaker.SetStagnation(0.95)
+	steps := []struct {
+		output, diff string
+		want         bool
+	}{
+		{agentReply, "+a", false}, // Compared with nothing above (the check was off)
+		{agentReply, "+b", true},  // Same output
+		{"Refactored the parser.", "+c", false},
+		{"Fixed the lexer.", "+c", true}, // Same changes
+		{"Wrote the docs.", "+d", false},
+		{"Fixed the lexer.", "+e", true}, // Alternating with a recent iteration
+		{"Added benchmarks for the lexer.", "", false},
+	}
+	for i, step := range steps {
+		stagnant, reason := breaker.CheckStagnation(step.output, step.diff, true)
+		if stagnant != step.want {
+			t.Errorf("step %d: CheckStagnation() = %v (%s), want %v", i, stagnant, reason, step.want)
+		}
+	}
+
+	// Fingerprints survive a restart
+	breaker.SaveState()
+	loaded, _ := breaker.LoadState()
+	if stagnant, _ := loaded.CheckStagnation("Added benchmarks for the lexer.", "+f", true); !stagnant {
+		t.Error("loaded breaker should remember recent fingerprints")
+	}
+}
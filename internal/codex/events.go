@@ -1,5 +1,14 @@
 package codex
 
+// LogEventType is the event runners emit for a diagnostic that belongs in the log
+// rather than the agent's output
+const LogEventType = "lisa.log"
+
+// LogEvent builds the event a runner emits to log message
+func LogEvent(message string) Event {
+	return Event{"type": LogEventType, "message": message}
+}
+
 // ParsedEvent represents a parsed Codex event with extracted content
 type ParsedEvent struct {
 	Type        string // "reasoning", "message", "tool_call", "tool_result", "delta", "lifecycle", "unknown"
@@ -203,6 +212,11 @@ func extractToolTarget(data map[string]interface{}) string {
 	return ""
 }
 
+// ToolTarget extracts the file path or command from a tool's argument map
+func ToolTarget(args map[string]interface{}) string {
+	return extractTargetFromArgs(args)
+}
+
 // extractTargetFromArgs extracts target from argument map
 func extractTargetFromArgs(args map[string]interface{}) string {
 	// Common argument patterns for file paths (both snake_case and camelCase)
@@ -2,13 +2,16 @@ package codex
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
 	"os/exec"
 	"strings"
 	"time"
 
+	"github.com/brainwhocodes/lisa-loop/internal/proc"
 	"github.com/brainwhocodes/lisa-loop/internal/state"
 )
 
@@ -32,12 +35,44 @@ func (r *Runner) SetOutputCallback(cb OutputCallback) {
 }
 
 // Run executes a Codex command using the CLI with streaming
-func (r *Runner) Run(prompt string) (output string, threadID string, err error) {
-	return r.runCLI(prompt)
+// The Codex process and everything it started are killed when ctx ends or the
+// configured timeout passes; a timeout's error wraps context.DeadlineExceeded
+func (r *Runner) Run(ctx context.Context, prompt string) (output string, threadID string, err error) {
+	return r.runCLI(ctx, prompt)
 }
 
+// NewSession forgets the saved session so the next Run starts a new one
+func (r *Runner) NewSession() error {
+	return state.SaveCodexSessionIn(r.config.WorkDir, "")
+}
+
+// ExecError is an agent process that exited with an error
+// Its message shows stderr, or the agent's own output when stderr is empty; only
+// Stderr describes the failure itself
+type ExecError struct {
+	Name   string
+	Err    error
+	Stderr string
+	Output string
+}
+
+func (e *ExecError) Error() string {
+	msg := e.Stderr
+	if msg == "" {
+		msg = e.Output
+	}
+	return fmt.Sprintf("%s execution failed: %v\nOutput: %s", e.Name, e.Err, msg)
+}
+
+func (e *ExecError) Unwrap() error {
+	return e.Err
+}
+
+// waitDelay bounds the wait for output pipes held open by processes that outlived Codex
+const waitDelay = 2 * time.Second
+
 // runCLI executes Codex CLI in non-interactive mode with streaming
-func (r *Runner) runCLI(prompt string) (string, string, error) {
+func (r *Runner) runCLI(ctx context.Context, prompt string) (string, string, error) {
 	args := []string{
 		"exec",
 		"--json",
@@ -46,28 +81,41 @@ func (r *Runner) runCLI(prompt string) (string, string, error) {
 	}
 
 	// Add thread ID if session exists for conversation continuity
-	if id, err := LoadSessionID(); err == nil && id != "" {
+	if id, err := state.LoadCodexSessionIn(r.config.WorkDir); err == nil && id != "" {
 		args = append(args, "resume", "--last")
 	}
 
-	cmd := exec.Command("codex", args...)
+	timeout := time.Duration(r.config.Timeout) * time.Second
+	var runCtx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		runCtx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		runCtx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	cmd := exec.CommandContext(runCtx, "codex", args...)
+	cmd.Dir = r.config.WorkDir
 	cmd.Stdin = strings.NewReader(prompt)
+	proc.KillGroup(cmd)
+	cmd.WaitDelay = waitDelay
+
+	// Stderr is collected while stdout is read, so a chatty stderr can't fill its pipe
+	// and stall Codex
+	var stderrOutput bytes.Buffer
+	cmd.Stderr = &stderrOutput
 
 	if r.config.Verbose {
 		fmt.Printf("Executing: codex %s\n", strings.Join(args, " "))
 	}
 
-	// Set up pipes for streaming
+	// Set up pipe for streaming
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		return "", "", fmt.Errorf("failed to create stdout pipe: %w", err)
 	}
 
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return "", "", fmt.Errorf("failed to create stderr pipe: %w", err)
-	}
-
 	// Start command
 	if err := cmd.Start(); err != nil {
 		return "", "", fmt.Errorf("failed to start codex: %w", err)
@@ -118,38 +166,29 @@ func (r *Runner) runCLI(prompt string) (string, string, error) {
 		}
 	}
 
-	// Check for scanner errors (e.g., token too long)
-	if err := scanner.Err(); err != nil {
-		return "", "", fmt.Errorf("error reading codex output: %w", err)
-	}
-
-	// Read stderr with same buffer size
-	stderrScanner := bufio.NewScanner(stderr)
-	stderrScanner.Buffer(make([]byte, maxScannerBuffer), maxScannerBuffer)
-	var stderrOutput strings.Builder
-	for stderrScanner.Scan() {
-		stderrOutput.WriteString(stderrScanner.Text())
-		stderrOutput.WriteString("\n")
-	}
-	if err := stderrScanner.Err(); err != nil {
-		// Log stderr scanner error but don't fail - stderr is secondary
-		if r.config.Verbose {
-			fmt.Printf("Warning: error reading stderr: %v\n", err)
-		}
+	// Check for scanner errors (e.g., token too long); Codex is stopped rather than
+	// left blocked on a pipe nobody reads
+	scanErr := scanner.Err()
+	if scanErr != nil {
+		cancel()
 	}
 
 	// Wait for command to complete
-	if err := cmd.Wait(); err != nil {
-		errMsg := stderrOutput.String()
-		if errMsg == "" {
-			errMsg = outputBuilder.String()
-		}
-		return "", "", fmt.Errorf("codex execution failed: %w\nOutput: %s", err, errMsg)
+	waitErr := cmd.Wait()
+	switch {
+	case ctx.Err() != nil:
+		return "", "", fmt.Errorf("codex cancelled: %w", ctx.Err())
+	case waitErr != nil && errors.Is(runCtx.Err(), context.DeadlineExceeded):
+		return "", "", fmt.Errorf("codex timed out after %s: %w", timeout, context.DeadlineExceeded)
+	case scanErr != nil:
+		return "", "", fmt.Errorf("error reading codex output: %w", scanErr)
+	case waitErr != nil:
+		return "", "", &ExecError{Name: "codex", Err: waitErr, Stderr: stderrOutput.String(), Output: outputBuilder.String()}
 	}
 
 	// Save session ID if we got one
 	if threadID != "" {
-		if err := SaveSessionID(threadID); err != nil {
+		if err := state.SaveCodexSessionIn(r.config.WorkDir, threadID); err != nil {
 			return outputBuilder.String(), threadID, fmt.Errorf("failed to save session ID: %w", err)
 		}
 	}
@@ -284,13 +323,12 @@ func SessionAgeHours() (int, error) {
 		return 0, nil
 	}
 
-	sessionFile := ".codex_session_id"
-	info, err := os.Stat(sessionFile)
+	sess, err := state.LoadSessionIn("", "codex")
 	if err != nil {
 		return 0, err
 	}
 
-	age := time.Since(info.ModTime()).Hours()
+	age := time.Since(sess.UpdatedAt).Hours()
 	return int(age), nil
 }
 
@@ -322,32 +360,18 @@ func LoadSessionMetadata() (*SessionMetadata, error) {
 		return nil, err
 	}
 
-	meta := &SessionMetadata{
-		ID: "",
-	}
-
-	if id, ok := sess["id"].(string); ok {
-		meta.ID = id
-	}
-
-	if created, ok := sess["created_at"].(string); ok {
-		meta.CreatedAt, _ = time.Parse(time.RFC3339, created)
-	}
-
-	if lastUsed, ok := sess["last_used"].(string); ok {
-		meta.LastUsed, _ = time.Parse(time.RFC3339, lastUsed)
-	}
-
-	return meta, nil
+	return &SessionMetadata{
+		ID:        sess.ID,
+		CreatedAt: sess.CreatedAt,
+		LastUsed:  sess.LastUsed,
+	}, nil
 }
 
 // SaveSessionMetadata saves session metadata
 func SaveSessionMetadata(meta *SessionMetadata) error {
-	sess := map[string]interface{}{
-		"id":         meta.ID,
-		"created_at": meta.CreatedAt.Format(time.RFC3339),
-		"last_used":  meta.LastUsed.Format(time.RFC3339),
-	}
-
-	return state.SaveLisaSession(sess)
+	return state.SaveLisaSession(state.LisaSession{
+		ID:        meta.ID,
+		CreatedAt: meta.CreatedAt,
+		LastUsed:  meta.LastUsed,
+	})
 }
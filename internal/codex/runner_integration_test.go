@@ -4,6 +4,7 @@
 package codex
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -123,7 +124,7 @@ func TestRunnerWithCallback(t *testing.T) {
 	})
 
 	prompt := "Say hello and describe what you see in the project."
-	output, threadID, err := runner.Run(prompt)
+	output, threadID, err := runner.Run(context.Background(), prompt)
 
 	fmt.Println("=== FINAL OUTPUT ===")
 	fmt.Printf("Thread ID: %s\n", threadID)
@@ -0,0 +1,73 @@
+//go:build !windows
+
+package codex
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeCodex puts a "codex" shell script running script first on PATH and returns the
+// directory it runs in
+func fakeCodex(t *testing.T, script string) string {
+	t.Helper()
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "codex"), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return t.TempDir()
+}
+
+func TestRun_TimeoutKillsProcessGroup(t *testing.T) {
+	// The background sleep holds codex's stdout open, so Run only returns at the
+	// timeout, rather than waitDelay later, if the sleep is killed along with codex
+	dir := fakeCodex(t, "sleep 30 &\nwait\n")
+	r := NewRunner(Config{WorkDir: dir, Timeout: 1})
+
+	start := time.Now()
+	_, _, err := r.Run(context.Background(), "prompt")
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("Run() error = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second+waitDelay/2 {
+		t.Errorf("Run() took %s, want it stopped at the timeout", elapsed)
+	}
+}
+
+func TestRun_Cancel(t *testing.T) {
+	dir := fakeCodex(t, "sleep 30\n")
+	r := NewRunner(Config{WorkDir: dir})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, _, err := r.Run(ctx, "prompt")
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "cancelled") {
+		t.Fatalf("Run() error = %v, want a cancellation", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Run() took %s, want it stopped when the context ended", elapsed)
+	}
+}
+
+func TestRun_LargeStderr(t *testing.T) {
+	// More stderr than a pipe buffer holds, before any stdout
+	dir := fakeCodex(t, `head -c 1000000 /dev/zero | tr '\0' x >&2
+echo '{"type": "message", "text": "done"}'
+`)
+	r := NewRunner(Config{WorkDir: dir, Timeout: 10})
+
+	output, _, err := r.Run(context.Background(), "prompt")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if output != "done" {
+		t.Errorf("Run() output = %q, want done", output)
+	}
+}
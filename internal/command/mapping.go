@@ -0,0 +1,270 @@
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/brainwhocodes/lisa-loop/internal/codex"
+)
+
+// Event kinds a mapped event can have (the codex.ParsedEvent types)
+var eventKinds = map[string]bool{
+	"reasoning":   true,
+	"message":     true,
+	"delta":       true,
+	"tool_call":   true,
+	"tool_result": true,
+	"lifecycle":   true,
+}
+
+// FieldMapping describes where the fields of a JSONL event live
+// Paths are dot-separated; numeric segments index arrays and "*" picks the
+// first array element for which the rest of the path resolves
+type FieldMapping struct {
+	Type       string            // Path to the raw event type
+	Kinds      map[string]string // Raw event type -> event kind (reasoning, message, delta, tool_call, tool_result, lifecycle)
+	Text       string            // Path to the event text (arrays of content blocks are joined)
+	ToolName   string            // Path to the tool name
+	ToolTarget string            // Path to the tool target (a string, or an argument object)
+	SessionID  string            // Path to the session ID
+	Result     string            // Path to the final answer; overrides accumulated message text
+}
+
+// Presets are the built-in output formats
+// "text" treats stdout as plain text; "codex" passes Codex-style events through unchanged
+var Presets = map[string]*FieldMapping{
+	"text":  nil,
+	"codex": nil,
+	"claude": {
+		Type: "type",
+		Kinds: map[string]string{
+			"system":    "lifecycle",
+			"assistant": "message",
+			"user":      "tool_result",
+			"result":    "lifecycle",
+		},
+		Text:       "message.content",
+		ToolName:   "message.content.*.name",
+		ToolTarget: "message.content.*.input",
+		SessionID:  "session_id",
+		Result:     "result",
+	},
+}
+
+// ParseMapping resolves an output format: a preset name or an inline mapping such as
+// "type=type,text=message.content,tool=name,target=input,session=session_id,result=result,kind.thinking=reasoning"
+func ParseMapping(spec string) (*FieldMapping, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+	if m, ok := Presets[spec]; ok {
+		return m, nil
+	}
+	if !strings.Contains(spec, "=") {
+		return nil, fmt.Errorf("unknown output format %q (presets: %s)", spec, strings.Join(presetNames(), ", "))
+	}
+
+	m := &FieldMapping{Kinds: make(map[string]string)}
+	for _, pair := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || key == "" || value == "" {
+			return nil, fmt.Errorf("invalid mapping entry %q (want key=path)", pair)
+		}
+
+		switch key {
+		case "type":
+			m.Type = value
+		case "text":
+			m.Text = value
+		case "tool":
+			m.ToolName = value
+		case "target":
+			m.ToolTarget = value
+		case "session":
+			m.SessionID = value
+		case "result":
+			m.Result = value
+		default:
+			raw, isKind := strings.CutPrefix(key, "kind.")
+			if !isKind || raw == "" {
+				return nil, fmt.Errorf("unknown mapping key %q", key)
+			}
+			if !eventKinds[value] {
+				return nil, fmt.Errorf("unknown event kind %q for %s", value, key)
+			}
+			m.Kinds[raw] = value
+		}
+	}
+
+	if m.Type == "" {
+		m.Type = "type"
+	}
+	return m, nil
+}
+
+// Parse maps a JSONL event onto the codex.ParsedEvent shape
+func (m *FieldMapping) Parse(event map[string]interface{}) *codex.ParsedEvent {
+	if event == nil {
+		return nil
+	}
+
+	rawType, _ := lookup(event, m.Type).(string)
+	result := &codex.ParsedEvent{
+		Type:     m.Kinds[rawType],
+		RawType:  rawType,
+		Text:     textValue(lookup(event, m.Text)),
+		ToolName: stringValue(lookup(event, m.ToolName)),
+	}
+
+	switch target := lookup(event, m.ToolTarget).(type) {
+	case string:
+		result.ToolTarget = target
+	case map[string]interface{}:
+		result.ToolTarget = codex.ToolTarget(target)
+	}
+
+	switch {
+	case result.Type == "message" && result.Text == "" && result.ToolName != "":
+		// A content block list that only holds a tool call
+		result.Type = "tool_call"
+	case result.Type == "":
+		if result.Text != "" {
+			result.Type = "message"
+		} else {
+			result.Type = "lifecycle"
+		}
+	}
+
+	switch result.Type {
+	case "tool_call":
+		result.ToolStatus = "started"
+	case "tool_result":
+		result.ToolStatus = "completed"
+	}
+
+	return result
+}
+
+// SessionIDFrom extracts the session ID from an event, if the mapping has one
+func (m *FieldMapping) SessionIDFrom(event map[string]interface{}) string {
+	return stringValue(lookup(event, m.SessionID))
+}
+
+// ResultFrom extracts the final answer from an event, if the mapping has one
+func (m *FieldMapping) ResultFrom(event map[string]interface{}) string {
+	return textValue(lookup(event, m.Result))
+}
+
+// toEvent converts a parsed event back into the Codex event shape the loop understands
+func toEvent(parsed *codex.ParsedEvent) map[string]interface{} {
+	switch parsed.Type {
+	case "reasoning":
+		return map[string]interface{}{
+			"type": "item.completed",
+			"item": map[string]interface{}{"type": "reasoning", "text": parsed.Text},
+		}
+	case "message":
+		return map[string]interface{}{"type": "message", "content": parsed.Text}
+	case "delta":
+		return map[string]interface{}{
+			"type":  "content_block_delta",
+			"delta": map[string]interface{}{"text": parsed.Text},
+		}
+	case "tool_call":
+		return map[string]interface{}{
+			"type":   "tool_use",
+			"name":   parsed.ToolName,
+			"target": parsed.ToolTarget,
+			"status": parsed.ToolStatus,
+		}
+	case "tool_result":
+		return map[string]interface{}{
+			"type":   "tool_result",
+			"name":   parsed.ToolName,
+			"target": parsed.ToolTarget,
+		}
+	default:
+		return map[string]interface{}{"type": parsed.RawType}
+	}
+}
+
+// lookup resolves a dot-separated path in a decoded JSON value
+func lookup(value interface{}, path string) interface{} {
+	if path == "" {
+		return nil
+	}
+	return lookupParts(value, strings.Split(path, "."))
+}
+
+func lookupParts(value interface{}, parts []string) interface{} {
+	if len(parts) == 0 {
+		return value
+	}
+
+	part, rest := parts[0], parts[1:]
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return lookupParts(v[part], rest)
+	case []interface{}:
+		if part == "*" {
+			for _, item := range v {
+				if found := lookupParts(item, rest); found != nil && found != "" {
+					return found
+				}
+			}
+			return nil
+		}
+		i, err := strconv.Atoi(part)
+		if err != nil || i < 0 || i >= len(v) {
+			return nil
+		}
+		return lookupParts(v[i], rest)
+	}
+	return nil
+}
+
+// textValue renders a string, or joins the text of a content block list
+func textValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []interface{}:
+		var b strings.Builder
+		for _, item := range v {
+			switch block := item.(type) {
+			case string:
+				b.WriteString(block)
+			case map[string]interface{}:
+				if text, ok := block["text"].(string); ok {
+					b.WriteString(text)
+				}
+			}
+		}
+		return b.String()
+	}
+	return ""
+}
+
+// stringValue renders scalar values as strings
+func stringValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+func presetNames() []string {
+	names := make([]string, 0, len(Presets))
+	for name := range Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,145 @@
+package command
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		wantErr string
+		check   func(t *testing.T, m *FieldMapping)
+	}{
+		{name: "empty", spec: "", check: func(t *testing.T, m *FieldMapping) {
+			if m != nil {
+				t.Errorf("ParseMapping(\"\") = %+v, want nil", m)
+			}
+		}},
+		{name: "preset", spec: "claude", check: func(t *testing.T, m *FieldMapping) {
+			if m == nil || m.SessionID != "session_id" {
+				t.Errorf("claude preset = %+v", m)
+			}
+		}},
+		{name: "inline", spec: "text=msg.body, tool=call.name, session=sid, kind.think=reasoning", check: func(t *testing.T, m *FieldMapping) {
+			if m.Type != "type" || m.Text != "msg.body" || m.ToolName != "call.name" || m.SessionID != "sid" {
+				t.Errorf("inline mapping = %+v", m)
+			}
+			if m.Kinds["think"] != "reasoning" {
+				t.Errorf("Kinds = %v, want think=reasoning", m.Kinds)
+			}
+		}},
+		{name: "unknown preset", spec: "gemini-ultra", wantErr: "unknown output format"},
+		{name: "unknown key", spec: "colour=red", wantErr: "unknown mapping key"},
+		{name: "unknown kind", spec: "kind.x=shouting", wantErr: "unknown event kind"},
+		{name: "missing path", spec: "text=", wantErr: "invalid mapping entry"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseMapping(tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseMapping(%q) error = %v, want %q", tt.spec, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseMapping(%q) error = %v", tt.spec, err)
+			}
+			tt.check(t, m)
+		})
+	}
+}
+
+func TestFieldMapping_ParseClaude(t *testing.T) {
+	m := Presets["claude"]
+
+	tests := []struct {
+		name       string
+		event      map[string]interface{}
+		wantType   string
+		wantText   string
+		wantTool   string
+		wantTarget string
+	}{
+		{
+			name: "assistant text",
+			event: map[string]interface{}{
+				"type": "assistant",
+				"message": map[string]interface{}{"content": []interface{}{
+					map[string]interface{}{"type": "text", "text": "Hello "},
+					map[string]interface{}{"type": "text", "text": "world"},
+				}},
+			},
+			wantType: "message",
+			wantText: "Hello world",
+		},
+		{
+			name: "assistant tool call",
+			event: map[string]interface{}{
+				"type": "assistant",
+				"message": map[string]interface{}{"content": []interface{}{
+					map[string]interface{}{"type": "tool_use", "name": "Edit", "input": map[string]interface{}{"file_path": "main.go"}},
+				}},
+			},
+			wantType:   "tool_call",
+			wantTool:   "Edit",
+			wantTarget: "main.go",
+		},
+		{
+			name:     "system init",
+			event:    map[string]interface{}{"type": "system", "subtype": "init", "session_id": "abc"},
+			wantType: "lifecycle",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed := m.Parse(tt.event)
+			if parsed.Type != tt.wantType || parsed.Text != tt.wantText {
+				t.Errorf("Parse() = type %q text %q, want %q %q", parsed.Type, parsed.Text, tt.wantType, tt.wantText)
+			}
+			if parsed.ToolName != tt.wantTool || parsed.ToolTarget != tt.wantTarget {
+				t.Errorf("Parse() tool = %q %q, want %q %q", parsed.ToolName, parsed.ToolTarget, tt.wantTool, tt.wantTarget)
+			}
+		})
+	}
+
+	result := map[string]interface{}{"type": "result", "result": "Done", "session_id": "abc"}
+	if got := m.ResultFrom(result); got != "Done" {
+		t.Errorf("ResultFrom() = %q, want Done", got)
+	}
+	if got := m.SessionIDFrom(result); got != "abc" {
+		t.Errorf("SessionIDFrom() = %q, want abc", got)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	event := map[string]interface{}{
+		"a": map[string]interface{}{
+			"list": []interface{}{
+				map[string]interface{}{"x": "first"},
+				map[string]interface{}{"y": "second"},
+			},
+		},
+	}
+
+	tests := []struct {
+		path string
+		want interface{}
+	}{
+		{"a.list.0.x", "first"},
+		{"a.list.1.y", "second"},
+		{"a.list.*.y", "second"},
+		{"a.list.5.x", nil},
+		{"a.missing", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := lookup(event, tt.path); got != tt.want {
+			t.Errorf("lookup(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/brainwhocodes/lisa-loop/internal/budget"
+	"github.com/brainwhocodes/lisa-loop/internal/codex"
+	"github.com/brainwhocodes/lisa-loop/internal/config"
+	"github.com/brainwhocodes/lisa-loop/internal/proc"
+)
+
+// Prompt modes
+const (
+	PromptStdin = "stdin"
+	PromptArg   = "arg"
+	PromptFile  = "file"
+)
+
+// OutputCallback is called for each event parsed from the command's output
+type OutputCallback func(event map[string]interface{})
+
+// Runner executes an arbitrary agent CLI described by a command template
+type Runner struct {
+	config         config.Config
+	outputCallback OutputCallback
+}
+
+// NewRunner creates a new command runner
+func NewRunner(cfg config.Config) *Runner {
+	return &Runner{config: cfg}
+}
+
+// SetOutputCallback sets the callback for streaming output
+func (r *Runner) SetOutputCallback(cb OutputCallback) {
+	r.outputCallback = cb
+}
+
+// Stop is a no-op; each Run starts and waits for its own process
+func (r *Runner) Stop() error {
+	return nil
+}
+
+// NewSession forgets the saved session so the next Run starts a new one
+func (r *Runner) NewSession() error {
+	return SaveSessionIDIn(r.config.WorkDir, "")
+}
+
+// Validate checks the command backend settings without running anything
+func Validate(cfg config.Config) error {
+	args, err := splitCommand(cfg.CommandTemplate)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("command backend needs a command template (--command)")
+	}
+	switch cfg.CommandPrompt {
+	case "", PromptStdin, PromptArg, PromptFile:
+	default:
+		return fmt.Errorf("unknown prompt mode %q (want stdin, arg or file)", cfg.CommandPrompt)
+	}
+	if _, err := splitCommand(cfg.CommandResume); err != nil {
+		return err
+	}
+	_, err = ParseMapping(cfg.CommandOutput)
+	return err
+}
+
+// Run executes the command with the prompt and returns the final text and session ID
+// Ending ctx, or running past Config.Timeout seconds, kills the command
+func (r *Runner) Run(ctx context.Context, prompt string) (string, string, error) {
+	if err := Validate(r.config); err != nil {
+		return "", "", err
+	}
+	mapping, _ := ParseMapping(r.config.CommandOutput)
+
+	sessionID, err := LoadSessionIDIn(r.config.WorkDir)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to load session ID: %w", err)
+	}
+
+	vars := map[string]string{
+		"{prompt}":     prompt,
+		"{session_id}": sessionID,
+		"{workdir}":    r.config.WorkDir,
+	}
+
+	mode := r.config.CommandPrompt
+	if mode == "" {
+		mode = PromptStdin
+	}
+	if mode == PromptFile {
+		path, err := writePromptFile(prompt)
+		if err != nil {
+			return "", "", err
+		}
+		defer os.Remove(path)
+		vars["{prompt_file}"] = path
+	}
+
+	args := r.buildArgs(mode, sessionID, vars)
+
+	timeout := time.Duration(r.config.Timeout) * time.Second
+	var runCtx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		runCtx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		runCtx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	cmd := exec.CommandContext(runCtx, args[0], args[1:]...)
+	cmd.Dir = r.config.WorkDir
+	// Kill the tools the agent started along with it, and don't wait forever on
+	// grandchildren that inherited the output pipes
+	proc.KillGroup(cmd)
+	cmd.WaitDelay = 2 * time.Second
+	if mode == PromptStdin {
+		cmd.Stdin = strings.NewReader(prompt)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if r.config.Verbose {
+		r.emit(codex.LogEvent("Executing: " + strings.Join(args, " ")))
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return "", "", fmt.Errorf("failed to start %s: %w", args[0], err)
+	}
+
+	// Use 1MB buffer to handle large JSONL lines
+	const maxScannerBuffer = 1024 * 1024 // 1MB
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, maxScannerBuffer), maxScannerBuffer)
+
+	var outputBuilder strings.Builder
+	var message strings.Builder
+	var result string
+	newSessionID := ""
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		outputBuilder.WriteString(line)
+		outputBuilder.WriteString("\n")
+
+		event, err := codex.ParseJSONLLine(line)
+		if r.config.CommandOutput == "" || r.config.CommandOutput == "text" || err != nil || event == nil {
+			if strings.TrimSpace(line) != "" {
+				r.emit(map[string]interface{}{"type": "message", "content": line})
+			}
+			continue
+		}
+
+		var parsed *codex.ParsedEvent
+		if mapping == nil {
+			// Codex-style events pass through unchanged
+			parsed = codex.ParseEvent(event)
+			if codex.MessageType(event) == "thread.started" || codex.EventType(event) == "thread.started" {
+				if tid := codex.ThreadID(event); tid != "" {
+					newSessionID = tid
+				}
+			}
+			r.emit(event)
+		} else {
+			parsed = mapping.Parse(event)
+			if id := mapping.SessionIDFrom(event); id != "" {
+				newSessionID = id
+			}
+			if text := mapping.ResultFrom(event); text != "" {
+				result = text
+			}
+			r.emit(toEvent(parsed))
+			if usage, ok := budget.ParseUsage(event); ok {
+				r.emit(budget.UsageEvent(usage))
+			}
+		}
+
+		if parsed != nil && parsed.Type == "message" && parsed.Text != "" {
+			message.WriteString(parsed.Text)
+			message.WriteString("\n")
+		}
+	}
+
+	// A scanner error (e.g. a line over the buffer size) stops the command rather than
+	// leaving it blocked on a pipe nobody reads
+	scanErr := scanner.Err()
+	if scanErr != nil {
+		cancel()
+	}
+
+	waitErr := cmd.Wait()
+	switch {
+	case ctx.Err() != nil:
+		return "", "", fmt.Errorf("%s cancelled: %w", args[0], ctx.Err())
+	case waitErr != nil && errors.Is(runCtx.Err(), context.DeadlineExceeded):
+		return "", "", fmt.Errorf("%s timed out after %s: %w", args[0], timeout, context.DeadlineExceeded)
+	case scanErr != nil:
+		return "", "", fmt.Errorf("error reading %s output: %w", args[0], scanErr)
+	case waitErr != nil:
+		return "", "", &codex.ExecError{Name: args[0], Err: waitErr, Stderr: stderr.String(), Output: outputBuilder.String()}
+	}
+
+	if newSessionID != "" && newSessionID != sessionID {
+		if err := SaveSessionIDIn(r.config.WorkDir, newSessionID); err != nil {
+			return outputBuilder.String(), newSessionID, fmt.Errorf("failed to save session ID: %w", err)
+		}
+	}
+	if newSessionID == "" {
+		newSessionID = sessionID
+	}
+
+	switch {
+	case result != "":
+		return strings.TrimSpace(result), newSessionID, nil
+	case message.Len() > 0:
+		return strings.TrimSpace(message.String()), newSessionID, nil
+	}
+	return outputBuilder.String(), newSessionID, nil
+}
+
+// buildArgs expands the command template, adding the resume arguments and the prompt
+func (r *Runner) buildArgs(mode, sessionID string, vars map[string]string) []string {
+	template, _ := splitCommand(r.config.CommandTemplate)
+	if sessionID != "" {
+		resume, _ := splitCommand(r.config.CommandResume)
+		template = append(template, resume...)
+	}
+
+	// Without a placeholder the prompt (or prompt file) goes last
+	placeholder := map[string]string{PromptArg: "{prompt}", PromptFile: "{prompt_file}"}[mode]
+	if placeholder != "" && !strings.Contains(strings.Join(template, " "), placeholder) {
+		template = append(template, placeholder)
+	}
+
+	// A single pass, so placeholder text inside the prompt is left alone
+	pairs := make([]string, 0, 2*len(vars))
+	for key, value := range vars {
+		pairs = append(pairs, key, value)
+	}
+	replacer := strings.NewReplacer(pairs...)
+
+	args := make([]string, len(template))
+	for i, arg := range template {
+		args[i] = replacer.Replace(arg)
+	}
+	return args
+}
+
+func (r *Runner) emit(event map[string]interface{}) {
+	if r.outputCallback != nil {
+		r.outputCallback(event)
+	}
+}
+
+// writePromptFile stores the prompt in a temporary file for PromptFile mode
+func writePromptFile(prompt string) (string, error) {
+	f, err := os.CreateTemp("", "lisa-prompt-*.md")
+	if err != nil {
+		return "", fmt.Errorf("failed to create prompt file: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(prompt); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write prompt file: %w", err)
+	}
+	return filepath.Clean(f.Name()), nil
+}
+
+// splitCommand splits a command line into words, honoring single quotes,
+// double quotes and backslash escapes. No other shell syntax is interpreted.
+func splitCommand(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(c)
+			}
+		case c == '\\' && quote != '\'':
+			if i+1 < len(runes) {
+				i++
+				word.WriteRune(runes[i])
+			}
+			inWord = true
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inWord = true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(c)
+			inWord = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in command %q", line)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,254 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brainwhocodes/lisa-loop/internal/codex"
+	"github.com/brainwhocodes/lisa-loop/internal/config"
+)
+
+// writeScript creates an executable shell script in dir
+func writeScript(t *testing.T, dir, body string) string {
+	t.Helper()
+	path := filepath.Join(dir, "agent.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0755); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+	return path
+}
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{line: "claude -p --output-format stream-json", want: []string{"claude", "-p", "--output-format", "stream-json"}},
+		{line: `agent --msg "hello world" 'it''s'`, want: []string{"agent", "--msg", "hello world", "its"}},
+		{line: `agent a\ b "say \"hi\""`, want: []string{"agent", "a b", `say "hi"`}},
+		{line: `agent ""`, want: []string{"agent", ""}},
+		{line: "", want: nil},
+		{line: `agent "open`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := splitCommand(tt.line)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("splitCommand(%q) expected error", tt.line)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("splitCommand(%q) error = %v", tt.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitCommand(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		wantErr bool
+	}{
+		{name: "minimal", cfg: config.Config{CommandTemplate: "agent"}},
+		{name: "no template", cfg: config.Config{}, wantErr: true},
+		{name: "bad prompt mode", cfg: config.Config{CommandTemplate: "agent", CommandPrompt: "pipe"}, wantErr: true},
+		{name: "bad output", cfg: config.Config{CommandTemplate: "agent", CommandOutput: "xml"}, wantErr: true},
+		{name: "bad resume", cfg: config.Config{CommandTemplate: "agent", CommandResume: "'open"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRun_PromptModes(t *testing.T) {
+	tests := []struct {
+		name   string
+		mode   string
+		script string
+	}{
+		{name: "stdin", mode: PromptStdin, script: "cat\n"},
+		{name: "arg", mode: PromptArg, script: "printf '%s\\n' \"$1\"\n"},
+		{name: "file", mode: PromptFile, script: "cat \"$1\"\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			script := writeScript(t, dir, tt.script)
+
+			r := NewRunner(config.Config{
+				CommandTemplate: script,
+				CommandPrompt:   tt.mode,
+				WorkDir:         dir,
+			})
+			var lines []string
+			r.SetOutputCallback(func(event map[string]interface{}) {
+				if parsed := codex.ParseEvent(event); parsed != nil {
+					lines = append(lines, parsed.Text)
+				}
+			})
+
+			output, _, err := r.Run(context.Background(), "do the {session_id} thing")
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if !strings.Contains(output, "do the {session_id} thing") {
+				t.Errorf("Run() output = %q, want the prompt echoed", output)
+			}
+			if len(lines) != 1 || lines[0] != "do the {session_id} thing" {
+				t.Errorf("streamed lines = %q", lines)
+			}
+		})
+	}
+}
+
+func TestRun_ClaudeStream(t *testing.T) {
+	dir := t.TempDir()
+	script := writeScript(t, dir, `cat >/dev/null
+echo "args: $*" >&2
+echo '{"type":"system","subtype":"init","session_id":"sess-1"}'
+echo '{"type":"assistant","message":{"content":[{"type":"tool_use","name":"Write","input":{"file_path":"a.go"}}]}}'
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"Working on it"}]}}'
+echo 'not json'
+echo '{"type":"result","result":"All done","session_id":"sess-1"}'
+echo "$*" > args.txt
+`)
+
+	r := NewRunner(config.Config{
+		CommandTemplate: script + " -p",
+		CommandOutput:   "claude",
+		CommandResume:   "--resume {session_id}",
+		WorkDir:         dir,
+	})
+
+	var events []*codex.ParsedEvent
+	r.SetOutputCallback(func(event map[string]interface{}) {
+		events = append(events, codex.ParseEvent(event))
+	})
+
+	output, sessionID, err := r.Run(context.Background(), "prompt")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if output != "All done" {
+		t.Errorf("Run() output = %q, want result text", output)
+	}
+	if sessionID != "sess-1" {
+		t.Errorf("Run() sessionID = %q, want sess-1", sessionID)
+	}
+	if saved, _ := LoadSessionIDIn(dir); saved != "sess-1" {
+		t.Errorf("saved session = %q, want sess-1", saved)
+	}
+
+	var sawTool, sawText, sawRaw bool
+	for _, e := range events {
+		switch {
+		case e.Type == "tool_call" && e.ToolName == "Write" && e.ToolTarget == "a.go":
+			sawTool = true
+		case e.Type == "message" && e.Text == "Working on it":
+			sawText = true
+		case e.Type == "message" && e.Text == "not json":
+			sawRaw = true
+		}
+	}
+	if !sawTool || !sawText || !sawRaw {
+		t.Errorf("events missing: tool=%v text=%v raw=%v", sawTool, sawText, sawRaw)
+	}
+
+	// The saved session is resumed on the next run
+	if _, _, err := r.Run(context.Background(), "prompt"); err != nil {
+		t.Fatalf("second Run() error = %v", err)
+	}
+	args, _ := os.ReadFile(filepath.Join(dir, "args.txt"))
+	if strings.TrimSpace(string(args)) != "-p --resume sess-1" {
+		t.Errorf("second run args = %q, want resume arguments", args)
+	}
+}
+
+func TestRun_VerboseLogsCommand(t *testing.T) {
+	dir := t.TempDir()
+	script := writeScript(t, dir, "echo done\n")
+
+	r := NewRunner(config.Config{CommandTemplate: script, WorkDir: dir, Verbose: true})
+	var logs []string
+	r.SetOutputCallback(func(event map[string]interface{}) {
+		if event["type"] == codex.LogEventType {
+			logs = append(logs, event["message"].(string))
+		}
+	})
+	if _, _, err := r.Run(context.Background(), "prompt"); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(logs) != 1 || logs[0] != "Executing: "+script {
+		t.Errorf("log events = %q, want the command line", logs)
+	}
+}
+
+func TestRun_Failure(t *testing.T) {
+	dir := t.TempDir()
+	script := writeScript(t, dir, "echo 'rate limited' >&2\nexit 3\n")
+
+	r := NewRunner(config.Config{CommandTemplate: script, WorkDir: dir})
+	_, _, err := r.Run(context.Background(), "prompt")
+	if err == nil {
+		t.Fatal("Run() expected error for failing command")
+	}
+	if !strings.Contains(err.Error(), "rate limited") {
+		t.Errorf("Run() error = %v, want stderr included", err)
+	}
+}
+
+func TestRun_Timeout(t *testing.T) {
+	dir := t.TempDir()
+	script := writeScript(t, dir, "sleep 30\n")
+
+	r := NewRunner(config.Config{CommandTemplate: script, WorkDir: dir, Timeout: 1})
+	start := time.Now()
+	_, _, err := r.Run(context.Background(), "prompt")
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("Run() error = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Run() took %s, want it stopped at the timeout", elapsed)
+	}
+}
+
+func TestRun_LineTooLong(t *testing.T) {
+	dir := t.TempDir()
+	// One line over the scanner buffer, then the agent would keep writing forever
+	script := writeScript(t, dir, "head -c 2000000 /dev/zero | tr '\\0' x\necho\nwhile :; do echo more; done\n")
+
+	r := NewRunner(config.Config{CommandTemplate: script, WorkDir: dir})
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := r.Run(context.Background(), "prompt")
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "error reading") {
+			t.Errorf("Run() error = %v, want the read error", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run() did not return after the output became unreadable")
+	}
+}
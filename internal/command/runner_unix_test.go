@@ -0,0 +1,32 @@
+//go:build !windows
+
+package command
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brainwhocodes/lisa-loop/internal/config"
+)
+
+func TestRun_CancelKillsProcessGroup(t *testing.T) {
+	dir := t.TempDir()
+	// A tool the agent started in a shell of its own
+	script := writeScript(t, dir, "sh -c 'sleep 1; touch marker'\n")
+
+	r := NewRunner(config.Config{CommandTemplate: script, WorkDir: dir})
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, _, err := r.Run(ctx, "prompt"); err == nil {
+		t.Fatal("Run() expected a cancellation error")
+	}
+
+	// The nested shell would have created the marker by now
+	time.Sleep(1500 * time.Millisecond)
+	if _, err := os.Stat(filepath.Join(dir, "marker")); err == nil {
+		t.Error("the agent's child process outlived the cancellation")
+	}
+}
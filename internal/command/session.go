@@ -0,0 +1,21 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/brainwhocodes/lisa-loop/internal/state"
+)
+
+// sessionBackend is the key the command session is saved under in the state directory
+const sessionBackend = "command"
+
+// LoadSessionIDIn loads the command backend session ID kept in dir ("" for the current directory)
+func LoadSessionIDIn(dir string) (string, error) {
+	sess, err := state.LoadSessionIn(dir, sessionBackend)
+	return strings.TrimSpace(sess.ID), err
+}
+
+// SaveSessionIDIn saves the command backend session ID in dir ("" for the current directory)
+func SaveSessionIDIn(dir, id string) error {
+	return state.SaveSessionIn(dir, sessionBackend, id)
+}
@@ -9,10 +9,70 @@ type Config struct {
 	Timeout      int
 	Verbose      bool
 	ResetCircuit bool
+	WorkDir      string // Directory the backend runs in and keeps its session in (default: current directory)
+	PlanFile     string // Plan file to use instead of the one detected from the project mode
+
+	// Circuit breaker and rate limit configuration
+	CircuitNoProgressThreshold int // Loops without progress before the circuit opens
+	CircuitSameErrorThreshold  int // Loops with the same error before the circuit opens
+	RateLimitHours             int // Hours of the sliding window MaxCalls applies to
+
+	// Sliding windows shared by every Lisa process on the machine, e.g. "40/1h, 300/1d"
+	RateLimitWindows string
+
+	// Stagnation: percent similarity between a loop's output or diff and a recent loop's
+	// at which it counts as no progress, whatever the agent reported (0 = off)
+	CircuitStagnation int
+
+	// Circuit breaker recovery
+	CircuitCooldown       int    // Seconds an OPEN circuit waits before probing, doubled after each failed probe (0 = stay open until reset)
+	CircuitHalfOpenProbes int    // Probe iterations allowed in HALF_OPEN before the circuit re-opens
+	CircuitProbePrompt    string // Prompt file used for probe iterations instead of the mode's prompt
+	CircuitProbeModel     string // Model used for probe iterations (opencode and openai backends)
+
+	// Spending budgets, checked before each iteration (0 = unlimited)
+	BudgetRunTokens int     // Tokens one run may use
+	BudgetDayTokens int     // Tokens all runs in the project may use per day
+	BudgetRunCost   float64 // Cost one run may incur
+	BudgetDayCost   float64 // Cost all runs in the project may incur per day
+	BudgetTokenCost float64 // Cost per million tokens, for backends that report no cost
 
 	// OpenCode backend configuration
 	OpenCodeServerURL  string // URL for OpenCode server (env: OPENCODE_SERVER_URL)
 	OpenCodeUsername   string // Username for OpenCode auth (env: OPENCODE_SERVER_USERNAME)
 	OpenCodePassword   string // Password for OpenCode auth (env: OPENCODE_SERVER_PASSWORD)
 	OpenCodeModelID    string // Model ID to use (env: OPENCODE_MODEL_ID, default: glm-4.7)
+
+	// OpenAI-compatible backend configuration
+	OpenAIBaseURL string // Chat completions base URL, e.g. http://localhost:8080/v1 (env: OPENAI_BASE_URL)
+	OpenAIAPIKey  string // API key sent as a bearer token (env: OPENAI_API_KEY)
+	OpenAIModel   string // Model name (env: OPENAI_MODEL)
+
+	// Command backend configuration
+	CommandTemplate string // Command line run for each prompt; {prompt}, {prompt_file}, {session_id} and {workdir} are substituted
+	CommandPrompt   string // How the prompt is passed: stdin (default), arg or file
+	CommandOutput   string // Output format: text (default), codex, claude, or an inline JSONL field mapping
+	CommandResume   string // Arguments appended when a session ID is saved, e.g. "--resume {session_id}"
+
+	// Record and replay configuration
+	RecordDir    string // Save every prompt, event stream and output here (call-NNNN.json)
+	ReplayDir    string // Recordings served by the replay backend
+	ReplayMatch  string // How replayed calls are matched: loop (default) or hash
+	ReplayTiming bool   // Deliver replayed events at their recorded offsets
+
+	// Verification gate configuration
+	VerifyCommand       string // Command run after each iteration (e.g. "go test ./...")
+	VerifyTimeout       int    // Verification timeout in seconds (0 = no timeout)
+	VerifyUncheckOnFail bool   // Un-mark tasks ticked during an iteration that failed verification
+
+	// Git checkpoint configuration
+	Checkpoint        bool // Commit each iteration on a lisa/run-<id> branch
+	RollbackOnFailure bool // Roll back iterations that fail verification or open the circuit breaker
+
+	// Focus mode configuration
+	FocusMode       bool // Controller picks one ready task per iteration instead of the agent
+	MaxTaskAttempts int  // Failed attempts before a focused task is marked BLOCKED (0 = default)
+
+	// Parallel execution
+	Parallel int // Number of workers running ready tasks in separate git worktrees (<= 1 = serial)
 }
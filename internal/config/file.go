@@ -0,0 +1,285 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectFileNames are the project config files looked for, in order
+var ProjectFileNames = []string{"lisa.yaml", ".lisa.yaml"}
+
+// SourceDefault labels values that were never overridden
+const SourceDefault = "default"
+
+// LoadOptions controls where configuration is read from
+type LoadOptions struct {
+	ProjectDir string                      // Directory the project config search starts in
+	ConfigFile string                      // Explicit project config file (skips the search)
+	UserConfig string                      // User config file (default: <user config dir>/lisa/config.yaml)
+	Profile    string                      // Profile to apply (default: $LISA_PROFILE)
+	Flags      map[string]string           // Flags set on the command line, by flag name
+	LookupEnv  func(string) (string, bool) // Environment lookup (default: os.LookupEnv)
+	NoUserFile bool                        // Skip the user config file
+}
+
+// Resolved is the effective configuration and where each value came from
+type Resolved struct {
+	Config  Config
+	Sources map[string]string // Setting key -> provenance label
+	Files   []string          // Config files that were read, lowest precedence first
+	Profile string
+}
+
+// Source returns the provenance label for a setting key
+func (r *Resolved) Source(key string) string {
+	if src, ok := r.Sources[key]; ok {
+		return src
+	}
+	return SourceDefault
+}
+
+// configFile is one parsed config file
+type configFile struct {
+	path     string
+	label    string
+	values   map[string]interface{}
+	profiles map[string]map[string]interface{}
+}
+
+// Load layers defaults < user config < project config < environment < flags
+// A profile is applied on top of each file's base values
+func Load(opts LoadOptions) (*Resolved, error) {
+	lookupEnv := opts.LookupEnv
+	if lookupEnv == nil {
+		lookupEnv = os.LookupEnv
+	}
+
+	r := &Resolved{Config: Default(), Sources: make(map[string]string)}
+
+	r.Profile = opts.Profile
+	if r.Profile == "" {
+		if v, ok := lookupEnv("LISA_PROFILE"); ok {
+			r.Profile = strings.TrimSpace(v)
+		}
+	}
+
+	var files []*configFile
+	if !opts.NoUserFile {
+		path := opts.UserConfig
+		if path == "" {
+			path = UserConfigPath()
+		}
+		if path != "" {
+			f, err := readConfigFile(path, "user config")
+			if err != nil {
+				return nil, err
+			}
+			if f != nil {
+				files = append(files, f)
+			}
+		}
+	}
+
+	projectPath := opts.ConfigFile
+	if projectPath == "" {
+		projectPath = FindProjectFile(opts.ProjectDir)
+	}
+	if projectPath != "" {
+		f, err := readConfigFile(projectPath, "project config")
+		if err != nil {
+			return nil, err
+		}
+		if f == nil {
+			return nil, fmt.Errorf("config file not found: %s", projectPath)
+		}
+		files = append(files, f)
+	}
+
+	profileFound := false
+	for _, f := range files {
+		r.Files = append(r.Files, f.path)
+		if err := r.apply(f.values, fmt.Sprintf("%s %s", f.label, f.path)); err != nil {
+			return nil, err
+		}
+		if r.Profile == "" {
+			continue
+		}
+		if values, ok := f.profiles[r.Profile]; ok {
+			profileFound = true
+			if err := r.apply(values, fmt.Sprintf("profile %s (%s)", r.Profile, f.path)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if r.Profile != "" && !profileFound {
+		return nil, fmt.Errorf("unknown profile %q", r.Profile)
+	}
+
+	for _, s := range Settings {
+		if s.Env == "" {
+			continue
+		}
+		if v, ok := lookupEnv(s.Env); ok && v != "" {
+			if err := s.Set(&r.Config, v); err != nil {
+				return nil, fmt.Errorf("env %s: %w", s.Env, err)
+			}
+			r.Sources[s.Key] = "env " + s.Env
+		}
+	}
+
+	for _, s := range Settings {
+		if s.Flag == "" {
+			continue
+		}
+		if v, ok := opts.Flags[s.Flag]; ok {
+			if err := s.Set(&r.Config, v); err != nil {
+				return nil, fmt.Errorf("flag --%s: %w", s.Flag, err)
+			}
+			r.Sources[s.Key] = "flag --" + s.Flag
+		}
+	}
+
+	// OpenCode runs longer sessions, so it gets more loops unless calls was chosen
+	if r.Config.Backend == "opencode" && r.Source("calls") == SourceDefault {
+		r.Config.MaxCalls = 10
+		r.Sources["calls"] = "default (opencode)"
+	}
+
+	return r, nil
+}
+
+// apply sets flattened values from one layer
+func (r *Resolved) apply(values map[string]interface{}, label string) error {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s, _ := LookupSetting(key)
+		if err := s.Set(&r.Config, values[key]); err != nil {
+			return fmt.Errorf("%s: %w", label, err)
+		}
+		r.Sources[key] = label
+	}
+	return nil
+}
+
+// UserConfigPath returns the per-user config file location
+func UserConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "lisa", "config.yaml")
+}
+
+// FindProjectFile looks for a project config file in dir and its parents,
+// stopping at the repository root
+func FindProjectFile(dir string) string {
+	if dir == "" {
+		dir = "."
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+
+	for {
+		for _, name := range ProjectFileNames {
+			path := filepath.Join(abs, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path
+			}
+		}
+		if _, err := os.Stat(filepath.Join(abs, ".git")); err == nil {
+			return ""
+		}
+		parent := filepath.Dir(abs)
+		if parent == abs {
+			return ""
+		}
+		abs = parent
+	}
+}
+
+// readConfigFile parses a config file, returning nil if it does not exist
+func readConfigFile(path, label string) (*configFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%s: invalid YAML: %w", path, err)
+	}
+
+	f := &configFile{
+		path:     path,
+		label:    label,
+		values:   make(map[string]interface{}),
+		profiles: make(map[string]map[string]interface{}),
+	}
+
+	profiles, hasProfiles := raw["profiles"]
+	delete(raw, "profiles")
+	if err := flatten("", raw, f.values); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if hasProfiles && profiles != nil {
+		byName, ok := profiles.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: profiles must be a mapping of profile names", path)
+		}
+		for name, body := range byName {
+			values := make(map[string]interface{})
+			if body != nil {
+				m, ok := body.(map[string]interface{})
+				if !ok {
+					return nil, fmt.Errorf("%s: profile %s must be a mapping", path, name)
+				}
+				if err := flatten("", m, values); err != nil {
+					return nil, fmt.Errorf("%s: profile %s: %w", path, name, err)
+				}
+			}
+			f.profiles[name] = values
+		}
+	}
+
+	return f, nil
+}
+
+// flatten turns nested mappings into dotted keys and rejects unknown settings
+func flatten(prefix string, in map[string]interface{}, out map[string]interface{}) error {
+	for k, v := range in {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if nested, ok := v.(map[string]interface{}); ok {
+			if err := flatten(key, nested, out); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, ok := LookupSetting(key); !ok {
+			return fmt.Errorf("unknown setting %q", key)
+		}
+		if v == nil {
+			continue
+		}
+		out[key] = v
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	r, err := Load(LoadOptions{ProjectDir: t.TempDir(), NoUserFile: true, LookupEnv: env(nil)})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if r.Config != Default() {
+		t.Errorf("Load() = %+v, want defaults", r.Config)
+	}
+	if len(r.Files) != 0 {
+		t.Errorf("Files = %v, want none", r.Files)
+	}
+	if r.Source("backend") != SourceDefault {
+		t.Errorf("Source(backend) = %q, want default", r.Source("backend"))
+	}
+}
+
+func TestLoad_Layering(t *testing.T) {
+	dir := t.TempDir()
+	userFile := filepath.Join(dir, "user", "config.yaml")
+	writeFile(t, userFile, `
+timeout: 100
+verify:
+  timeout: 50
+opencode:
+  model: user-model
+`)
+	project := filepath.Join(dir, "project")
+	writeFile(t, filepath.Join(project, "lisa.yaml"), `
+timeout: 200
+circuit:
+  no_progress_threshold: 7
+opencode:
+  model: project-model
+`)
+
+	r, err := Load(LoadOptions{
+		ProjectDir: project,
+		UserConfig: userFile,
+		LookupEnv:  env(map[string]string{"OPENCODE_MODEL_ID": "env-model", "LISA_VERIFY_COMMAND": "make test"}),
+		Flags:      map[string]string{"verify": "go test ./...", "calls": "9"},
+	})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		key    string
+		want   interface{}
+		source string
+	}{
+		{"verify.timeout", 50, "user config"},
+		{"timeout", 200, "project config"},
+		{"circuit.no_progress_threshold", 7, "project config"},
+		{"opencode.model", "env-model", "env OPENCODE_MODEL_ID"},
+		{"verify.command", "go test ./...", "flag --verify"},
+		{"calls", 9, "flag --calls"},
+		{"rate_limit.window_hours", 1, SourceDefault},
+	}
+	for _, tt := range tests {
+		s, _ := LookupSetting(tt.key)
+		if got := s.Get(&r.Config); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.key, got, tt.want)
+		}
+		if got := r.Source(tt.key); !strings.HasPrefix(got, tt.source) {
+			t.Errorf("Source(%s) = %q, want prefix %q", tt.key, got, tt.source)
+		}
+	}
+	if len(r.Files) != 2 {
+		t.Errorf("Files = %v, want user and project config", r.Files)
+	}
+}
+
+func TestLoad_Profile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "lisa.yaml"), `
+calls: 5
+profiles:
+  ci:
+    calls: 20
+    checkpoint:
+      enabled: true
+  overnight:
+    rate_limit:
+      window_hours: 8
+`)
+
+	r, err := Load(LoadOptions{ProjectDir: dir, NoUserFile: true, LookupEnv: env(map[string]string{"LISA_PROFILE": "ci"})})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if r.Profile != "ci" || r.Config.MaxCalls != 20 || !r.Config.Checkpoint {
+		t.Errorf("profile ci: profile=%q calls=%d checkpoint=%v", r.Profile, r.Config.MaxCalls, r.Config.Checkpoint)
+	}
+	if r.Config.RateLimitHours != 1 {
+		t.Errorf("overnight settings leaked into ci: window_hours = %d", r.Config.RateLimitHours)
+	}
+	if !strings.HasPrefix(r.Source("calls"), "profile ci") {
+		t.Errorf("Source(calls) = %q, want profile ci", r.Source("calls"))
+	}
+
+	// An explicit profile wins over LISA_PROFILE
+	r, err = Load(LoadOptions{ProjectDir: dir, NoUserFile: true, Profile: "overnight", LookupEnv: env(map[string]string{"LISA_PROFILE": "ci"})})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if r.Config.MaxCalls != 5 || r.Config.RateLimitHours != 8 {
+		t.Errorf("profile overnight: calls=%d window_hours=%d, want 5 and 8", r.Config.MaxCalls, r.Config.RateLimitHours)
+	}
+
+	if _, err := Load(LoadOptions{ProjectDir: dir, NoUserFile: true, Profile: "weekend", LookupEnv: env(nil)}); err == nil {
+		t.Error("Load() with unknown profile: want error")
+	}
+}
+
+func TestLoad_OpenCodeCalls(t *testing.T) {
+	dir := t.TempDir()
+	r, err := Load(LoadOptions{ProjectDir: dir, NoUserFile: true, Flags: map[string]string{"backend": "opencode"}, LookupEnv: env(nil)})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if r.Config.MaxCalls != 10 {
+		t.Errorf("opencode calls = %d, want 10", r.Config.MaxCalls)
+	}
+
+	writeFile(t, filepath.Join(dir, "lisa.yaml"), "backend: opencode\ncalls: 4\n")
+	r, err = Load(LoadOptions{ProjectDir: dir, NoUserFile: true, LookupEnv: env(nil)})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if r.Config.MaxCalls != 4 {
+		t.Errorf("opencode calls from config = %d, want 4", r.Config.MaxCalls)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"unknown key", "backnd: cli\n", `unknown setting "backnd"`},
+		{"unknown nested key", "circuit:\n  threshold: 2\n", `unknown setting "circuit.threshold"`},
+		{"unknown profile key", "profiles:\n  ci:\n    colls: 2\n", `profile ci: unknown setting "colls"`},
+		{"wrong type", "calls: lots\n", "calls: expected an integer"},
+		{"wrong bool", "verbose: sometimes\n", "verbose: expected true or false"},
+		{"invalid yaml", "calls: [\n", "invalid YAML"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, "lisa.yaml"), tt.content)
+			_, err := Load(LoadOptions{ProjectDir: dir, NoUserFile: true, LookupEnv: env(nil)})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := Load(LoadOptions{NoUserFile: true, ConfigFile: filepath.Join(t.TempDir(), "missing.yaml"), LookupEnv: env(nil)}); err == nil {
+		t.Error("Load() with missing --config file: want error")
+	}
+	if _, err := Load(LoadOptions{ProjectDir: t.TempDir(), NoUserFile: true, Flags: map[string]string{"parallel": "two"}, LookupEnv: env(nil)}); err == nil {
+		t.Error("Load() with invalid flag value: want error")
+	}
+}
+
+func TestFindProjectFile(t *testing.T) {
+	root := t.TempDir()
+	os.Mkdir(filepath.Join(root, ".git"), 0755)
+	nested := filepath.Join(root, "a", "b")
+	os.MkdirAll(nested, 0755)
+
+	if got := FindProjectFile(nested); got != "" {
+		t.Errorf("FindProjectFile() = %q, want none", got)
+	}
+
+	writeFile(t, filepath.Join(root, ".lisa.yaml"), "calls: 2\n")
+	if got := FindProjectFile(nested); got != filepath.Join(root, ".lisa.yaml") {
+		t.Errorf("FindProjectFile() = %q, want root .lisa.yaml", got)
+	}
+
+	writeFile(t, filepath.Join(root, "a", "lisa.yaml"), "calls: 2\n")
+	if got := FindProjectFile(nested); got != filepath.Join(root, "a", "lisa.yaml") {
+		t.Errorf("FindProjectFile() = %q, want nearest lisa.yaml", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if errs := Validate(Default()); len(errs) != 0 {
+		t.Errorf("Validate(Default()) = %v, want no errors", errs)
+	}
+
+	cfg := Default()
+	cfg.Backend = "claude"
+	cfg.ReplayMatch = "random"
+	cfg.CircuitSameErrorThreshold = 0
+	cfg.Parallel = 0
+	errs := Validate(cfg)
+	if len(errs) != 4 {
+		t.Fatalf("Validate() = %v, want 4 errors", errs)
+	}
+	for i, want := range []string{"backend", "replay.match", "circuit.same_error_threshold", "scheduling.parallel"} {
+		if !strings.HasPrefix(errs[i].Error(), want+":") {
+			t.Errorf("errs[%d] = %v, want %s", i, errs[i], want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateWindow allows at most Calls backend calls in any Period
+type RateWindow struct {
+	Calls  int
+	Period time.Duration
+}
+
+func (w RateWindow) String() string {
+	period := w.Period.String()
+	switch {
+	case w.Period%(24*time.Hour) == 0:
+		period = fmt.Sprintf("%dd", w.Period/(24*time.Hour))
+	case w.Period%time.Hour == 0:
+		period = fmt.Sprintf("%dh", w.Period/time.Hour)
+	case w.Period%time.Minute == 0:
+		period = fmt.Sprintf("%dm", w.Period/time.Minute)
+	}
+	return fmt.Sprintf("%d/%s", w.Calls, period)
+}
+
+// ParseRateWindows parses a comma-separated list of windows such as "40/1h, 300/1d"
+// A period is a Go duration, a number of days ("1d"), or a bare unit ("h", "day")
+func ParseRateWindows(spec string) ([]RateWindow, error) {
+	var windows []RateWindow
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		calls, period, ok := strings.Cut(part, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate window %q (want calls/period, e.g. 40/1h)", part)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(calls))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid call count in rate window %q", part)
+		}
+		d, err := parsePeriod(strings.TrimSpace(period))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid period in rate window %q", part)
+		}
+		windows = append(windows, RateWindow{Calls: n, Period: d})
+	}
+	return windows, nil
+}
+
+func parsePeriod(s string) (time.Duration, error) {
+	switch s {
+	case "m", "min", "minute":
+		return time.Minute, nil
+	case "h", "hour":
+		return time.Hour, nil
+	case "d", "day":
+		return 24 * time.Hour, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRateWindows(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []RateWindow
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"40/1h, 300/1d", []RateWindow{{40, time.Hour}, {300, 24 * time.Hour}}, false},
+		{"5/90m", []RateWindow{{5, 90 * time.Minute}}, false},
+		{"10/hour,100/day", []RateWindow{{10, time.Hour}, {100, 24 * time.Hour}}, false},
+		{"40", nil, true},
+		{"0/1h", nil, true},
+		{"40/soon", nil, true},
+		{"40/-1h", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseRateWindows(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRateWindows(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseRateWindows(%q) = %v, want %v", tt.spec, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("window %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRateWindowString(t *testing.T) {
+	for window, want := range map[RateWindow]string{
+		{40, time.Hour}:              "40/1h",
+		{300, 24 * time.Hour}:        "300/1d",
+		{5, 90 * time.Minute}:        "5/90m",
+		{1, 1500 * time.Millisecond}: "1/1.5s",
+	} {
+		if got := window.String(); got != want {
+			t.Errorf("String() = %q, want %q", got, want)
+		}
+	}
+}
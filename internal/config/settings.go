@@ -0,0 +1,294 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Setting describes one configurable value and where it can come from
+type Setting struct {
+	Key    string // Dotted key in lisa.yaml, e.g. "circuit.no_progress_threshold"
+	Flag   string // CLI flag name (without dashes), if any
+	Env    string // Environment variable, if any
+	Doc    string // One-line description
+	Secret bool   // Masked by "lisa config show"
+
+	field func(c *Config) interface{} // Pointer to the Config field
+}
+
+// Settings lists every configurable value in display order
+var Settings = []Setting{
+	{Key: "backend", Flag: "backend", Env: "LISA_BACKEND", Doc: "Backend: cli, opencode, openai, command or replay",
+		field: func(c *Config) interface{} { return &c.Backend }},
+	{Key: "prompt", Flag: "prompt", Doc: "Prompt file",
+		field: func(c *Config) interface{} { return &c.PromptPath }},
+	{Key: "plan_file", Flag: "plan", Doc: "Plan file (default: detected from the project mode)",
+		field: func(c *Config) interface{} { return &c.PlanFile }},
+	{Key: "calls", Flag: "calls", Doc: "Max loop iterations (10 for opencode unless set)",
+		field: func(c *Config) interface{} { return &c.MaxCalls }},
+	{Key: "timeout", Flag: "timeout", Doc: "Agent timeout in seconds",
+		field: func(c *Config) interface{} { return &c.Timeout }},
+	{Key: "verbose", Flag: "verbose", Doc: "Verbose output",
+		field: func(c *Config) interface{} { return &c.Verbose }},
+
+	{Key: "circuit.no_progress_threshold", Doc: "Loops without progress before the circuit opens",
+		field: func(c *Config) interface{} { return &c.CircuitNoProgressThreshold }},
+	{Key: "circuit.same_error_threshold", Doc: "Loops with the same error before the circuit opens",
+		field: func(c *Config) interface{} { return &c.CircuitSameErrorThreshold }},
+	{Key: "circuit.stagnation_similarity", Doc: "Percent similarity to a recent loop's output or diff that counts as no progress (0 = off)",
+		field: func(c *Config) interface{} { return &c.CircuitStagnation }},
+	{Key: "circuit.cooldown", Doc: "Seconds an open circuit waits before probing (0 = until reset)",
+		field: func(c *Config) interface{} { return &c.CircuitCooldown }},
+	{Key: "circuit.half_open_probes", Doc: "Probe loops allowed before the circuit re-opens",
+		field: func(c *Config) interface{} { return &c.CircuitHalfOpenProbes }},
+	{Key: "circuit.probe_prompt", Doc: "Prompt file for probe loops",
+		field: func(c *Config) interface{} { return &c.CircuitProbePrompt }},
+	{Key: "circuit.probe_model", Doc: "Model for probe loops (opencode and openai backends)",
+		field: func(c *Config) interface{} { return &c.CircuitProbeModel }},
+	{Key: "rate_limit.window_hours", Doc: "Hours of the sliding window that --calls limits",
+		field: func(c *Config) interface{} { return &c.RateLimitHours }},
+	{Key: "rate_limit.windows", Flag: "rate-limit", Env: "LISA_RATE_LIMIT", Doc: "Call limits shared by all Lisa processes, e.g. 40/1h,300/1d",
+		field: func(c *Config) interface{} { return &c.RateLimitWindows }},
+	{Key: "budget.run_tokens", Flag: "budget-tokens", Doc: "Tokens one run may use (0 = unlimited)",
+		field: func(c *Config) interface{} { return &c.BudgetRunTokens }},
+	{Key: "budget.day_tokens", Doc: "Tokens all runs may use per day (0 = unlimited)",
+		field: func(c *Config) interface{} { return &c.BudgetDayTokens }},
+	{Key: "budget.run_cost", Flag: "budget-cost", Doc: "Cost one run may incur (0 = unlimited)",
+		field: func(c *Config) interface{} { return &c.BudgetRunCost }},
+	{Key: "budget.day_cost", Doc: "Cost all runs may incur per day (0 = unlimited)",
+		field: func(c *Config) interface{} { return &c.BudgetDayCost }},
+	{Key: "budget.token_cost", Doc: "Cost per million tokens, for backends that report no cost",
+		field: func(c *Config) interface{} { return &c.BudgetTokenCost }},
+
+	{Key: "opencode.url", Flag: "opencode-url", Env: "OPENCODE_SERVER_URL", Doc: "OpenCode server URL",
+		field: func(c *Config) interface{} { return &c.OpenCodeServerURL }},
+	{Key: "opencode.username", Flag: "opencode-user", Env: "OPENCODE_SERVER_USERNAME", Doc: "OpenCode username",
+		field: func(c *Config) interface{} { return &c.OpenCodeUsername }},
+	{Key: "opencode.password", Flag: "opencode-pass", Env: "OPENCODE_SERVER_PASSWORD", Doc: "OpenCode password", Secret: true,
+		field: func(c *Config) interface{} { return &c.OpenCodePassword }},
+	{Key: "opencode.model", Flag: "opencode-model", Env: "OPENCODE_MODEL_ID", Doc: "OpenCode model ID",
+		field: func(c *Config) interface{} { return &c.OpenCodeModelID }},
+
+	{Key: "openai.url", Flag: "openai-url", Env: "OPENAI_BASE_URL", Doc: "Chat completions base URL",
+		field: func(c *Config) interface{} { return &c.OpenAIBaseURL }},
+	{Key: "openai.api_key", Flag: "openai-key", Env: "OPENAI_API_KEY", Doc: "API key for the openai backend", Secret: true,
+		field: func(c *Config) interface{} { return &c.OpenAIAPIKey }},
+	{Key: "openai.model", Flag: "openai-model", Env: "OPENAI_MODEL", Doc: "Model name for the openai backend",
+		field: func(c *Config) interface{} { return &c.OpenAIModel }},
+
+	{Key: "command.template", Flag: "command", Env: "LISA_COMMAND", Doc: "Agent command line for the command backend",
+		field: func(c *Config) interface{} { return &c.CommandTemplate }},
+	{Key: "command.prompt", Flag: "command-prompt", Doc: "How the prompt is passed: stdin, arg or file",
+		field: func(c *Config) interface{} { return &c.CommandPrompt }},
+	{Key: "command.output", Flag: "command-output", Doc: "Output format: text, codex, claude or a JSONL field mapping",
+		field: func(c *Config) interface{} { return &c.CommandOutput }},
+	{Key: "command.resume", Flag: "command-resume", Doc: "Arguments added when resuming a saved session",
+		field: func(c *Config) interface{} { return &c.CommandResume }},
+
+	{Key: "record.dir", Flag: "record", Doc: "Record every call to this directory",
+		field: func(c *Config) interface{} { return &c.RecordDir }},
+	{Key: "replay.dir", Flag: "replay-dir", Doc: "Recordings served by the replay backend",
+		field: func(c *Config) interface{} { return &c.ReplayDir }},
+	{Key: "replay.match", Flag: "replay-match", Doc: "Match replayed calls by loop or hash",
+		field: func(c *Config) interface{} { return &c.ReplayMatch }},
+	{Key: "replay.timing", Flag: "replay-timing", Doc: "Replay events with their original timing",
+		field: func(c *Config) interface{} { return &c.ReplayTiming }},
+
+	{Key: "verify.command", Flag: "verify", Env: "LISA_VERIFY_COMMAND", Doc: "Verification command run after each loop",
+		field: func(c *Config) interface{} { return &c.VerifyCommand }},
+	{Key: "verify.timeout", Flag: "verify-timeout", Doc: "Verification timeout in seconds",
+		field: func(c *Config) interface{} { return &c.VerifyTimeout }},
+	{Key: "verify.uncheck_on_fail", Flag: "verify-uncheck", Doc: "Un-mark tasks ticked during a failed loop",
+		field: func(c *Config) interface{} { return &c.VerifyUncheckOnFail }},
+
+	{Key: "checkpoint.enabled", Flag: "checkpoint", Doc: "Commit each loop on a lisa/run-<id> branch",
+		field: func(c *Config) interface{} { return &c.Checkpoint }},
+	{Key: "checkpoint.rollback_on_failure", Flag: "rollback-on-failure", Doc: "Roll back loops that fail verification or open the circuit",
+		field: func(c *Config) interface{} { return &c.RollbackOnFailure }},
+
+	{Key: "scheduling.focus", Flag: "focus", Doc: "Work on one controller-selected task per loop",
+		field: func(c *Config) interface{} { return &c.FocusMode }},
+	{Key: "scheduling.max_task_attempts", Flag: "max-task-attempts", Doc: "Failed attempts before a task is marked BLOCKED",
+		field: func(c *Config) interface{} { return &c.MaxTaskAttempts }},
+	{Key: "scheduling.parallel", Flag: "parallel", Doc: "Ready tasks run at once in separate git worktrees",
+		field: func(c *Config) interface{} { return &c.Parallel }},
+}
+
+// Default returns the built-in configuration
+func Default() Config {
+	return Config{
+		Backend:                    "cli",
+		PromptPath:                 "PROMPT.md",
+		MaxCalls:                   3,
+		Timeout:                    600,
+		CircuitNoProgressThreshold: 3,
+		CircuitSameErrorThreshold:  5,
+		CircuitCooldown:            300,
+		CircuitHalfOpenProbes:      1,
+		CircuitStagnation:          95,
+		RateLimitHours:             1,
+		OpenCodeUsername:           "opencode",
+		OpenCodeModelID:            "glm-4.7",
+		CommandPrompt:              "stdin",
+		CommandOutput:              "text",
+		ReplayMatch:                "loop",
+		VerifyTimeout:              300,
+		RollbackOnFailure:          true,
+		MaxTaskAttempts:            3,
+		Parallel:                   1,
+	}
+}
+
+// LookupSetting finds a setting by key
+func LookupSetting(key string) (Setting, bool) {
+	for _, s := range Settings {
+		if s.Key == key {
+			return s, true
+		}
+	}
+	return Setting{}, false
+}
+
+// Get returns the setting's value in c
+func (s Setting) Get(c *Config) interface{} {
+	switch p := s.field(c).(type) {
+	case *string:
+		return *p
+	case *int:
+		return *p
+	case *bool:
+		return *p
+	case *float64:
+		return *p
+	}
+	return nil
+}
+
+// IsBool reports whether the setting is a boolean
+func (s Setting) IsBool() bool {
+	_, ok := s.field(&Config{}).(*bool)
+	return ok
+}
+
+// IsInt reports whether the setting is an integer
+func (s Setting) IsInt() bool {
+	_, ok := s.field(&Config{}).(*int)
+	return ok
+}
+
+// Set parses value and stores it in c
+func (s Setting) Set(c *Config, value interface{}) error {
+	switch p := s.field(c).(type) {
+	case *string:
+		switch v := value.(type) {
+		case string:
+			*p = v
+		case int, float64, bool:
+			*p = fmt.Sprint(v)
+		default:
+			return fmt.Errorf("%s: expected a string, got %T", s.Key, value)
+		}
+	case *int:
+		switch v := value.(type) {
+		case int:
+			*p = v
+		case float64:
+			if v != float64(int(v)) {
+				return fmt.Errorf("%s: expected an integer, got %v", s.Key, v)
+			}
+			*p = int(v)
+		case string:
+			n, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				return fmt.Errorf("%s: expected an integer, got %q", s.Key, v)
+			}
+			*p = n
+		default:
+			return fmt.Errorf("%s: expected an integer, got %T", s.Key, value)
+		}
+	case *bool:
+		switch v := value.(type) {
+		case bool:
+			*p = v
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return fmt.Errorf("%s: expected true or false, got %q", s.Key, v)
+			}
+			*p = b
+		default:
+			return fmt.Errorf("%s: expected true or false, got %T", s.Key, value)
+		}
+	case *float64:
+		switch v := value.(type) {
+		case float64:
+			*p = v
+		case int:
+			*p = float64(v)
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return fmt.Errorf("%s: expected a number, got %q", s.Key, v)
+			}
+			*p = f
+		default:
+			return fmt.Errorf("%s: expected a number, got %T", s.Key, value)
+		}
+	}
+	return nil
+}
+
+// Validate checks value ranges and enumerations
+// Backend-specific requirements are checked where the backend is built
+func Validate(c Config) []error {
+	var errs []error
+	oneOf := func(key, value string, allowed ...string) {
+		for _, a := range allowed {
+			if value == a {
+				return
+			}
+		}
+		errs = append(errs, fmt.Errorf("%s: %q is not one of %s", key, value, strings.Join(allowed, ", ")))
+	}
+	atLeast := func(key string, value, min int) {
+		if value < min {
+			errs = append(errs, fmt.Errorf("%s: must be at least %d, got %d", key, min, value))
+		}
+	}
+	between := func(key string, value, min, max int) {
+		if value < min || value > max {
+			errs = append(errs, fmt.Errorf("%s: must be between %d and %d, got %d", key, min, max, value))
+		}
+	}
+	notNegative := func(key string, value float64) {
+		if value < 0 {
+			errs = append(errs, fmt.Errorf("%s: must not be negative, got %v", key, value))
+		}
+	}
+
+	oneOf("backend", c.Backend, "cli", "opencode", "openai", "command", "replay")
+	oneOf("command.prompt", c.CommandPrompt, "stdin", "arg", "file")
+	oneOf("replay.match", c.ReplayMatch, "loop", "hash")
+	atLeast("calls", c.MaxCalls, 1)
+	atLeast("timeout", c.Timeout, 0)
+	atLeast("circuit.no_progress_threshold", c.CircuitNoProgressThreshold, 1)
+	atLeast("circuit.same_error_threshold", c.CircuitSameErrorThreshold, 1)
+	atLeast("circuit.cooldown", c.CircuitCooldown, 0)
+	atLeast("circuit.half_open_probes", c.CircuitHalfOpenProbes, 1)
+	between("circuit.stagnation_similarity", c.CircuitStagnation, 0, 100)
+	atLeast("rate_limit.window_hours", c.RateLimitHours, 1)
+	if _, err := ParseRateWindows(c.RateLimitWindows); err != nil {
+		errs = append(errs, fmt.Errorf("rate_limit.windows: %w", err))
+	}
+	atLeast("budget.run_tokens", c.BudgetRunTokens, 0)
+	atLeast("budget.day_tokens", c.BudgetDayTokens, 0)
+	notNegative("budget.run_cost", c.BudgetRunCost)
+	notNegative("budget.day_cost", c.BudgetDayCost)
+	notNegative("budget.token_cost", c.BudgetTokenCost)
+	atLeast("verify.timeout", c.VerifyTimeout, 0)
+	atLeast("scheduling.max_task_attempts", c.MaxTaskAttempts, 1)
+	atLeast("scheduling.parallel", c.Parallel, 1)
+
+	return errs
+}
@@ -0,0 +1,347 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ErrConflict is returned when a merge or rebase stops on conflicting changes
+// The operation has already been aborted, leaving the work tree as it was
+var ErrConflict = errors.New("conflicting changes")
+
+// Run executes a git command in dir and returns its trimmed stdout
+func Run(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	if dir != "" {
+		cmd.Dir = dir
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = strings.TrimSpace(stdout.String())
+		}
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, msg)
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// Available reports whether the git binary can be found
+func Available() bool {
+	_, err := exec.LookPath("git")
+	return err == nil
+}
+
+// IsRepo reports whether dir is inside a git work tree
+func IsRepo(dir string) bool {
+	out, err := Run(dir, "rev-parse", "--is-inside-work-tree")
+	return err == nil && out == "true"
+}
+
+// Root returns the top-level directory of the work tree containing dir
+func Root(dir string) (string, error) {
+	return Run(dir, "rev-parse", "--show-toplevel")
+}
+
+// Prefix returns the path of dir relative to the top of its work tree ("" at the top)
+func Prefix(dir string) (string, error) {
+	return Run(dir, "rev-parse", "--show-prefix")
+}
+
+// HeadSHA returns the commit SHA of HEAD, or "" if the repository has no commits
+func HeadSHA(dir string) (string, error) {
+	if _, err := Run(dir, "rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		return "", nil
+	}
+	return Run(dir, "rev-parse", "HEAD")
+}
+
+// ResolveRef returns the commit SHA for a ref
+func ResolveRef(dir, ref string) (string, error) {
+	return Run(dir, "rev-parse", "--verify", ref+"^{commit}")
+}
+
+// CurrentBranch returns the checked-out branch name ("HEAD" if detached)
+func CurrentBranch(dir string) (string, error) {
+	return Run(dir, "rev-parse", "--abbrev-ref", "HEAD")
+}
+
+// IsDirty reports whether the work tree has uncommitted or untracked changes
+func IsDirty(dir string) (bool, error) {
+	out, err := Run(dir, "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	return out != "", nil
+}
+
+// CommitAll stages every change and commits it
+// Returns the new HEAD SHA, or "" if there was nothing to commit and allowEmpty is false
+func CommitAll(dir, message string, allowEmpty bool) (string, error) {
+	if _, err := Run(dir, "add", "-A"); err != nil {
+		return "", err
+	}
+
+	dirty, err := hasStagedChanges(dir)
+	if err != nil {
+		return "", err
+	}
+	if !dirty && !allowEmpty {
+		return "", nil
+	}
+
+	args := append(identityArgs(dir), "commit", "--quiet", "--no-verify", "-m", message)
+	if allowEmpty {
+		args = append(args, "--allow-empty")
+	}
+	if _, err := Run(dir, args...); err != nil {
+		return "", err
+	}
+
+	return HeadSHA(dir)
+}
+
+// FileChange describes a path that differs between a ref and the work tree
+type FileChange struct {
+	Status string // A (added), M (modified), D (deleted), R (renamed), ? (untracked)
+	Path   string
+}
+
+// ChangedFiles lists paths that differ between ref and the work tree, including untracked files
+// Only paths under dir are listed, relative to it like the untracked ones; paths in
+// exclude are left out
+func ChangedFiles(dir, ref string, exclude ...string) ([]FileChange, error) {
+	out, err := Run(dir, append([]string{"diff", "--name-status", "--relative", "-M", ref}, excludePathspecs(exclude)...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []FileChange
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 {
+			continue
+		}
+		// Renames list old and new paths - the new one is what exists now
+		changes = append(changes, FileChange{
+			Status: fields[0][:1],
+			Path:   fields[len(fields)-1],
+		})
+	}
+
+	untracked, err := Run(dir, append([]string{"ls-files", "--others", "--exclude-standard"}, excludePathspecs(exclude)...)...)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range strings.Split(untracked, "\n") {
+		if path != "" {
+			changes = append(changes, FileChange{Status: "?", Path: path})
+		}
+	}
+
+	return changes, nil
+}
+
+// AddedLines returns the lines added to tracked files under dir between ref and the work tree
+// Paths in exclude are left out
+func AddedLines(dir, ref string, exclude ...string) ([]string, error) {
+	out, err := Run(dir, append([]string{"diff", "--unified=0", "--no-color", "--relative", ref}, excludePathspecs(exclude)...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	var added []string
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
+			added = append(added, line[1:])
+		}
+	}
+	return added, nil
+}
+
+// Snapshot returns a commit holding the work tree's tracked changes, leaving the work
+// tree and index untouched; HEAD if there are none, "" if the repository has no commits
+func Snapshot(dir string) (string, error) {
+	sha, err := Run(dir, append(identityArgs(dir), "stash", "create")...)
+	if err != nil {
+		return "", err
+	}
+	if sha != "" {
+		return sha, nil
+	}
+	return HeadSHA(dir)
+}
+
+// Diff returns the patch between ref and the work tree under dir, followed by the untracked paths
+// Paths in exclude are left out
+func Diff(dir, ref string, exclude ...string) (string, error) {
+	patch, err := Run(dir, append([]string{"diff", "--no-color", "--no-ext-diff", "--relative", ref}, excludePathspecs(exclude)...)...)
+	if err != nil {
+		return "", err
+	}
+	untracked, err := Run(dir, append([]string{"ls-files", "--others", "--exclude-standard"}, excludePathspecs(exclude)...)...)
+	if err != nil {
+		return "", err
+	}
+	if untracked == "" {
+		return patch, nil
+	}
+	return patch + "\n" + untracked, nil
+}
+
+// excludePathspecs turns paths into git pathspec arguments that exclude them
+func excludePathspecs(paths []string) []string {
+	if len(paths) == 0 {
+		return nil
+	}
+	specs := []string{"--", "."}
+	for _, p := range paths {
+		specs = append(specs, ":(exclude)"+p)
+	}
+	return specs
+}
+
+// ResetHard restores the work tree to sha and removes untracked, non-ignored files
+func ResetHard(dir, sha string) error {
+	if _, err := Run(dir, "reset", "--hard", "--quiet", sha); err != nil {
+		return err
+	}
+	_, err := Run(dir, "clean", "-fd", "--quiet")
+	return err
+}
+
+// WorktreeAdd checks out a new branch created from base into a linked work tree at path
+func WorktreeAdd(dir, path, branch, base string) error {
+	_, err := Run(dir, "worktree", "add", "-q", "-B", branch, path, base)
+	return err
+}
+
+// WorktreeRemove deletes a linked work tree and, if branch is set, its branch
+func WorktreeRemove(dir, path, branch string) error {
+	if _, err := Run(dir, "worktree", "remove", "--force", path); err != nil {
+		return err
+	}
+	if branch == "" {
+		return nil
+	}
+	_, err := Run(dir, "branch", "-D", branch)
+	return err
+}
+
+// Merge merges branch into the checked-out branch with a merge commit
+// On conflict the merge is aborted and ErrConflict is returned
+func Merge(dir, branch, message string) error {
+	args := append(identityArgs(dir), "merge", "--no-ff", "--no-edit", "-q", "-m", message, branch)
+	if _, err := Run(dir, args...); err != nil {
+		if _, abortErr := Run(dir, "merge", "--abort"); abortErr == nil {
+			return fmt.Errorf("merge %s: %w", branch, ErrConflict)
+		}
+		return err
+	}
+	return nil
+}
+
+// Rebase replays the checked-out branch onto ref
+// On conflict the rebase is aborted and ErrConflict is returned
+func Rebase(dir, ref string) error {
+	args := append(identityArgs(dir), "rebase", "-q", ref)
+	if _, err := Run(dir, args...); err != nil {
+		if _, abortErr := Run(dir, "rebase", "--abort"); abortErr == nil {
+			return fmt.Errorf("rebase onto %s: %w", ref, ErrConflict)
+		}
+		return err
+	}
+	return nil
+}
+
+// CheckoutFile restores path in the work tree and index to its content at ref
+func CheckoutFile(dir, ref, path string) error {
+	_, err := Run(dir, "checkout", ref, "--", path)
+	return err
+}
+
+// EnsureExcluded appends patterns to .git/info/exclude unless already present
+// This keeps tool-owned files out of commits without touching the project's .gitignore
+func EnsureExcluded(dir string, patterns []string) error {
+	gitDir, err := Run(dir, "rev-parse", "--git-common-dir")
+	if err != nil {
+		return err
+	}
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(dir, gitDir)
+	}
+
+	excludePath := filepath.Join(gitDir, "info", "exclude")
+	existing, err := os.ReadFile(excludePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	present := make(map[string]bool)
+	for _, line := range strings.Split(string(existing), "\n") {
+		present[strings.TrimSpace(line)] = true
+	}
+
+	var missing []string
+	for _, p := range patterns {
+		if !present[p] {
+			missing = append(missing, p)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(excludePath), 0755); err != nil {
+		return err
+	}
+
+	content := string(existing)
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	content += "# Lisa state files\n" + strings.Join(missing, "\n") + "\n"
+
+	return os.WriteFile(excludePath, []byte(content), 0644)
+}
+
+// hasStagedChanges reports whether the index differs from HEAD
+func hasStagedChanges(dir string) (bool, error) {
+	head, err := HeadSHA(dir)
+	if err != nil {
+		return false, err
+	}
+	if head == "" {
+		// No commits yet - anything in the index is a change
+		out, err := Run(dir, "ls-files")
+		return out != "", err
+	}
+
+	cmd := exec.Command("git", "diff", "--cached", "--quiet")
+	cmd.Dir = dir
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			return true, nil
+		}
+		return false, fmt.Errorf("git diff --cached: %w", err)
+	}
+	return false, nil
+}
+
+// identityArgs supplies a fallback committer identity when none is configured
+func identityArgs(dir string) []string {
+	if email, err := Run(dir, "config", "user.email"); err == nil && email != "" {
+		return nil
+	}
+	return []string{"-c", "user.name=Lisa", "-c", "user.email=lisa@localhost"}
+}
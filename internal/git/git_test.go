@@ -0,0 +1,203 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupRepo creates a git repository with one commit and returns its path
+func setupRepo(t *testing.T) string {
+	t.Helper()
+	if !Available() {
+		t.Skip("git not installed")
+	}
+
+	dir := t.TempDir()
+	if _, err := Run(dir, "init", "-q"); err != nil {
+		t.Fatalf("git init: %v", err)
+	}
+	writeFile(t, filepath.Join(dir, "file.txt"), "base\n")
+	if _, err := CommitAll(dir, "initial", false); err != nil {
+		t.Fatalf("initial commit: %v", err)
+	}
+	return dir
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func TestWorktreeAddRemove(t *testing.T) {
+	dir := setupRepo(t)
+	head, _ := HeadSHA(dir)
+	wt := filepath.Join(t.TempDir(), "wt")
+
+	if err := WorktreeAdd(dir, wt, "feature", head); err != nil {
+		t.Fatalf("WorktreeAdd() error = %v", err)
+	}
+	if branch, _ := CurrentBranch(wt); branch != "feature" {
+		t.Errorf("worktree branch = %q, want feature", branch)
+	}
+
+	writeFile(t, filepath.Join(wt, "new.txt"), "new\n")
+	if _, err := CommitAll(wt, "add new", false); err != nil {
+		t.Fatalf("CommitAll() in worktree error = %v", err)
+	}
+	if err := Merge(dir, "feature", "merge feature"); err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new.txt")); err != nil {
+		t.Errorf("merged file missing: %v", err)
+	}
+
+	if err := WorktreeRemove(dir, wt, "feature"); err != nil {
+		t.Fatalf("WorktreeRemove() error = %v", err)
+	}
+	if _, err := os.Stat(wt); !os.IsNotExist(err) {
+		t.Errorf("worktree still exists after WorktreeRemove()")
+	}
+	if _, err := ResolveRef(dir, "feature"); err == nil {
+		t.Errorf("branch still exists after WorktreeRemove()")
+	}
+}
+
+func TestMergeConflict(t *testing.T) {
+	dir := setupRepo(t)
+	head, _ := HeadSHA(dir)
+	wt := filepath.Join(t.TempDir(), "wt")
+	if err := WorktreeAdd(dir, wt, "feature", head); err != nil {
+		t.Fatalf("WorktreeAdd() error = %v", err)
+	}
+
+	writeFile(t, filepath.Join(wt, "file.txt"), "feature\n")
+	CommitAll(wt, "feature edit", false)
+	writeFile(t, filepath.Join(dir, "file.txt"), "main\n")
+	CommitAll(dir, "main edit", false)
+
+	err := Merge(dir, "feature", "merge feature")
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("Merge() error = %v, want ErrConflict", err)
+	}
+	if dirty, _ := IsDirty(dir); dirty {
+		t.Errorf("work tree dirty after aborted merge")
+	}
+
+	mainHead, _ := HeadSHA(dir)
+	if err := Rebase(wt, mainHead); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Rebase() error = %v, want ErrConflict", err)
+	}
+	if branch, _ := CurrentBranch(wt); branch != "feature" {
+		t.Errorf("worktree branch after aborted rebase = %q, want feature", branch)
+	}
+}
+
+func TestRebase(t *testing.T) {
+	dir := setupRepo(t)
+	head, _ := HeadSHA(dir)
+	wt := filepath.Join(t.TempDir(), "wt")
+	if err := WorktreeAdd(dir, wt, "feature", head); err != nil {
+		t.Fatalf("WorktreeAdd() error = %v", err)
+	}
+
+	writeFile(t, filepath.Join(wt, "feature.txt"), "feature\n")
+	CommitAll(wt, "feature edit", false)
+	writeFile(t, filepath.Join(dir, "main.txt"), "main\n")
+	CommitAll(dir, "main edit", false)
+
+	mainHead, _ := HeadSHA(dir)
+	if err := Rebase(wt, mainHead); err != nil {
+		t.Fatalf("Rebase() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(wt, "main.txt")); err != nil {
+		t.Errorf("rebased worktree missing main.txt: %v", err)
+	}
+	parent, _ := Run(wt, "rev-parse", "HEAD~1")
+	if strings.TrimSpace(parent) != mainHead {
+		t.Errorf("rebased parent = %s, want %s", parent, mainHead)
+	}
+}
+
+func TestCheckoutFile(t *testing.T) {
+	dir := setupRepo(t)
+	head, _ := HeadSHA(dir)
+
+	writeFile(t, filepath.Join(dir, "file.txt"), "changed\n")
+	if err := CheckoutFile(dir, head, "file.txt"); err != nil {
+		t.Fatalf("CheckoutFile() error = %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "file.txt"))
+	if string(data) != "base\n" {
+		t.Errorf("file.txt = %q, want base", data)
+	}
+}
+
+func TestSnapshotDiff(t *testing.T) {
+	dir := setupRepo(t)
+	head, _ := HeadSHA(dir)
+
+	if sha, err := Snapshot(dir); err != nil || sha != head {
+		t.Errorf("Snapshot() of a clean tree = %q, %v, want HEAD %s", sha, err, head)
+	}
+
+	writeFile(t, filepath.Join(dir, "file.txt"), "base\nfirst\n")
+	snap, err := Snapshot(dir)
+	if err != nil || snap == head {
+		t.Fatalf("Snapshot() = %q, %v, want a new commit", snap, err)
+	}
+	if dirty, _ := IsDirty(dir); !dirty {
+		t.Error("Snapshot() should leave the work tree changes in place")
+	}
+
+	// The diff against the snapshot holds only what changed since
+	writeFile(t, filepath.Join(dir, "file.txt"), "base\nfirst\nsecond\n")
+	writeFile(t, filepath.Join(dir, "new.txt"), "new\n")
+	diff, err := Diff(dir, snap)
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	if !strings.Contains(diff, "+second") || strings.Contains(diff, "+first") || !strings.HasSuffix(diff, "new.txt") {
+		t.Errorf("Diff() = %q, want only the second line and the untracked file", diff)
+	}
+}
+
+func TestChangedFiles_Subdirectory(t *testing.T) {
+	dir := setupRepo(t)
+	project := filepath.Join(dir, "project")
+	os.Mkdir(project, 0755)
+	os.Mkdir(filepath.Join(dir, "sibling"), 0755)
+	writeFile(t, filepath.Join(project, "main.go"), "package main\n")
+	writeFile(t, filepath.Join(dir, "sibling", "lib.go"), "package lib\n")
+	base, err := CommitAll(dir, "add project and sibling", false)
+	if err != nil {
+		t.Fatalf("CommitAll() error = %v", err)
+	}
+
+	// Changes both inside and outside the project
+	writeFile(t, filepath.Join(project, "main.go"), "package main\n\nfunc Run() {}\n")
+	writeFile(t, filepath.Join(project, "new.go"), "package main\n")
+	writeFile(t, filepath.Join(dir, "sibling", "lib.go"), "package lib\n\nfunc Other() {}\n")
+	writeFile(t, filepath.Join(dir, "sibling", "extra.go"), "package lib\n")
+
+	changes, err := ChangedFiles(project, base)
+	if err != nil {
+		t.Fatalf("ChangedFiles() error = %v", err)
+	}
+	want := []FileChange{{Status: "M", Path: "main.go"}, {Status: "?", Path: "new.go"}}
+	if len(changes) != len(want) || changes[0] != want[0] || changes[1] != want[1] {
+		t.Errorf("ChangedFiles() = %+v, want %+v", changes, want)
+	}
+
+	added, err := AddedLines(project, base)
+	if err != nil {
+		t.Fatalf("AddedLines() error = %v", err)
+	}
+	if strings.Contains(strings.Join(added, "\n"), "Other") {
+		t.Errorf("AddedLines() = %q, want only the project's lines", added)
+	}
+}
@@ -0,0 +1,196 @@
+// Package journal keeps an append-only, rotating JSONL record of loop events
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/brainwhocodes/lisa-loop/internal/state"
+)
+
+// Dir is the journal directory inside the state directory
+const Dir = "journal"
+
+// currentFile receives new records; rotated files are numbered events.1.jsonl (newest) upward
+const currentFile = "events.jsonl"
+
+// Rotation limits
+const (
+	DefaultMaxSize  = 8 << 20 // Bytes before the current file is rotated
+	DefaultMaxFiles = 5       // Files kept, including the current one
+)
+
+// Record is one journaled event
+type Record struct {
+	Time  time.Time       `json:"time"`
+	RunID string          `json:"run_id"`
+	Loop  int             `json:"loop"`
+	Type  string          `json:"type"`
+	Event json.RawMessage `json:"event"`
+}
+
+// Writer appends records to the journal of a project
+type Writer struct {
+	dir      string
+	maxSize  int64
+	maxFiles int
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// Open opens the journal of project dir ("" for the current directory) for appending
+func Open(projectDir string) (*Writer, error) {
+	w := &Writer{
+		dir:      state.Path(projectDir, Dir),
+		maxSize:  DefaultMaxSize,
+		maxFiles: DefaultMaxFiles,
+	}
+	if err := os.MkdirAll(w.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create journal directory: %w", err)
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// SetLimits overrides the rotation limits (for testing)
+func (w *Writer) SetLimits(maxSize int64, maxFiles int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.maxSize = maxSize
+	w.maxFiles = maxFiles
+}
+
+// Append writes one record, rotating the current file once it grows past the size limit
+func (w *Writer) Append(rec Record) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal record: %w", err)
+	}
+	line = append(line, '\n')
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.f == nil {
+		return errors.New("journal is closed")
+	}
+	if w.size > 0 && w.size+int64(len(line)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := w.f.Write(line)
+	w.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return nil
+}
+
+// Close closes the current file
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return nil
+	}
+	err := w.f.Close()
+	w.f = nil
+	return err
+}
+
+func (w *Writer) open() error {
+	f, err := os.OpenFile(filepath.Join(w.dir, currentFile), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat journal: %w", err)
+	}
+	w.f = f
+	w.size = info.Size()
+	return nil
+}
+
+// rotate shifts every file up one number, dropping the oldest, and starts a new current file
+func (w *Writer) rotate() error {
+	if err := w.f.Close(); err != nil {
+		return fmt.Errorf("failed to close journal: %w", err)
+	}
+	w.f = nil
+
+	os.Remove(w.rotated(w.maxFiles - 1))
+	for i := w.maxFiles - 2; i >= 1; i-- {
+		os.Rename(w.rotated(i), w.rotated(i+1))
+	}
+	if w.maxFiles > 1 {
+		if err := os.Rename(filepath.Join(w.dir, currentFile), w.rotated(1)); err != nil {
+			return fmt.Errorf("failed to rotate journal: %w", err)
+		}
+	} else {
+		os.Remove(filepath.Join(w.dir, currentFile))
+	}
+	return w.open()
+}
+
+func (w *Writer) rotated(n int) string {
+	return filepath.Join(w.dir, fmt.Sprintf("events.%d.jsonl", n))
+}
+
+// Read returns every record in the journal of project dir, oldest first
+// Lines that cannot be parsed (such as one cut short by a crash) are skipped
+func Read(projectDir string) ([]Record, error) {
+	dir := state.Path(projectDir, Dir)
+	files, err := filepath.Glob(filepath.Join(dir, "events.*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Oldest rotated file first, current file last
+	var ordered []string
+	for i := len(files); i >= 1; i-- {
+		path := filepath.Join(dir, fmt.Sprintf("events.%d.jsonl", i))
+		if _, err := os.Stat(path); err == nil {
+			ordered = append(ordered, path)
+		}
+	}
+	ordered = append(ordered, filepath.Join(dir, currentFile))
+
+	var records []Record
+	for _, path := range ordered {
+		f, err := os.Open(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read journal: %w", err)
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 16<<20)
+		for scanner.Scan() {
+			var rec Record
+			if err := json.Unmarshal(scanner.Bytes(), &rec); err == nil {
+				records = append(records, rec)
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+	return records, nil
+}
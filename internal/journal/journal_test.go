@@ -0,0 +1,78 @@
+package journal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brainwhocodes/lisa-loop/internal/state"
+)
+
+func TestAppendAndRead(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	for i := 1; i <= 3; i++ {
+		rec := Record{Time: time.Now(), RunID: "r1", Loop: i, Type: "log", Event: json.RawMessage(`{}`)}
+		if err := w.Append(rec); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+	w.Close()
+
+	// A line cut short by a crash is skipped
+	f, _ := os.OpenFile(filepath.Join(state.Path(dir, Dir), currentFile), os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"time":"2025-`)
+	f.Close()
+
+	records, err := Read(dir)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(records) != 3 || records[0].Loop != 1 || records[2].Loop != 3 {
+		t.Errorf("Read() = %+v, want loops 1..3 in order", records)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.SetLimits(200, 3)
+
+	for i := 0; i < 20; i++ {
+		rec := Record{Time: time.Now(), RunID: fmt.Sprintf("run-%02d", i), Type: "log", Event: json.RawMessage(`{}`)}
+		if err := w.Append(rec); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	files, _ := filepath.Glob(filepath.Join(state.Path(dir, Dir), "*.jsonl"))
+	if len(files) != 3 {
+		t.Errorf("journal files = %v, want 3 after rotation", files)
+	}
+
+	records, err := Read(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) == 0 || len(records) >= 20 {
+		t.Fatalf("Read() = %d records, want the oldest dropped", len(records))
+	}
+	for i := 1; i < len(records); i++ {
+		if records[i].RunID <= records[i-1].RunID {
+			t.Errorf("records out of order: %s after %s", records[i].RunID, records[i-1].RunID)
+		}
+	}
+	if records[len(records)-1].RunID != "run-19" {
+		t.Errorf("last record = %s, want run-19", records[len(records)-1].RunID)
+	}
+}
@@ -0,0 +1,57 @@
+package loop
+
+import (
+	"fmt"
+
+	"github.com/brainwhocodes/lisa-loop/internal/budget"
+)
+
+// budgetLimits returns the spending limits configured in cfg
+func budgetLimits(cfg Config) budget.Limits {
+	return budget.Limits{
+		RunTokens:       cfg.BudgetRunTokens,
+		DayTokens:       cfg.BudgetDayTokens,
+		RunCost:         cfg.BudgetRunCost,
+		DayCost:         cfg.BudgetDayCost,
+		PricePerMillion: cfg.BudgetTokenCost,
+	}
+}
+
+// loadBudget loads the day's usage so far, shared by every run in the project
+func (c *Controller) loadBudget() {
+	if err := c.budget.LoadState(); err != nil {
+		c.emitLog(LogLevelWarn, fmt.Sprintf("Failed to load budget usage: %v", err))
+	}
+}
+
+// recordUsage adds usage reported by a runner to the budget and reports the new totals
+func (c *Controller) recordUsage(worker int, usage budget.Usage) {
+	if err := c.budget.Record(usage); err != nil {
+		c.emitLog(LogLevelWarn, err.Error())
+	}
+	c.emit(LoopEvent{
+		Type:       EventTypeUsage,
+		Worker:     worker,
+		LoopNumber: c.loopNum,
+		Budget:     c.budgetStatus(),
+	})
+}
+
+// budgetStatus returns spending against the budget, or nil if there are no limits
+// and nothing has been spent
+func (c *Controller) budgetStatus() *budget.Status {
+	status := c.budget.Status()
+	if !status.Limits.Enabled() && status.Run.Tokens() == 0 && status.Run.Cost == 0 {
+		return nil
+	}
+	return &status
+}
+
+// budgetExhausted returns the preflight skip reason once a budget is used up
+func (c *Controller) budgetExhausted() (bool, string) {
+	exhausted, reason := c.budget.Exhausted()
+	if !exhausted {
+		return false, ""
+	}
+	return true, "Budget exhausted: " + reason
+}
@@ -0,0 +1,109 @@
+package loop
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/brainwhocodes/lisa-loop/internal/budget"
+	"github.com/brainwhocodes/lisa-loop/internal/circuit"
+	"github.com/brainwhocodes/lisa-loop/internal/runner"
+	"github.com/brainwhocodes/lisa-loop/internal/state"
+)
+
+// spendingRunner completes a task per call and reports usage like a backend would
+type spendingRunner struct {
+	planMarkingRunner
+	usage budget.Usage
+	cb    runner.OutputCallback
+	calls int
+}
+
+func (r *spendingRunner) Run(ctx context.Context, prompt string) (string, string, error) {
+	r.calls++
+	if r.cb != nil {
+		r.cb(budget.UsageEvent(r.usage))
+	}
+	return r.planMarkingRunner.Run(ctx, prompt)
+}
+
+func (r *spendingRunner) SetOutputCallback(cb runner.OutputCallback) { r.cb = cb }
+
+func TestRun_StopsWhenBudgetExhausted(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	os.WriteFile("@fix_plan.md", []byte("- [ ] One\n- [ ] Two\n- [ ] Three\n- [ ] Four\n"), 0644)
+	os.WriteFile("PROMPT.md", []byte("Test prompt"), 0644)
+
+	cfg := Config{MaxCalls: 10, Backend: "cli", BudgetRunTokens: 1500}
+	controller := NewController(cfg, NewRateLimiter(10, 1), circuit.NewBreaker(3, 5))
+	r := &spendingRunner{
+		planMarkingRunner: planMarkingRunner{planFile: "@fix_plan.md", output: `---RALPH_STATUS---
+STATUS: WORKING
+TASKS_COMPLETED_THIS_LOOP: 1
+FILES_MODIFIED: 1
+TESTS_STATUS: PASSING
+EXIT_SIGNAL: false
+---END_RALPH_STATUS---`},
+		usage: budget.Usage{PromptTokens: 800, CompletionTokens: 200},
+	}
+	controller.SetRunner(r)
+
+	var lastPreflight *PreflightSummary
+	var usageEvents int
+	controller.SetEventCallback(func(event LoopEvent) {
+		switch event.Type {
+		case EventTypePreflight:
+			lastPreflight = event.Preflight
+		case EventTypeUsage:
+			usageEvents++
+		}
+	})
+
+	if err := controller.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if r.calls != 2 {
+		t.Errorf("runner called %d times, want 2 before 1500 tokens are spent", r.calls)
+	}
+	if usageEvents != 2 {
+		t.Errorf("usage events = %d, want 2", usageEvents)
+	}
+	if lastPreflight == nil || !strings.HasPrefix(lastPreflight.SkipReason, "Budget exhausted: run used 2000 of 1500 tokens") {
+		t.Fatalf("last preflight = %+v, want a budget skip", lastPreflight)
+	}
+	if lastPreflight.Budget == nil || lastPreflight.Budget.Run.Tokens() != 2000 {
+		t.Errorf("preflight budget = %+v, want 2000 run tokens", lastPreflight.Budget)
+	}
+
+	// The spend is kept for the day and with the run record
+	if du, _ := state.LoadDailyUsage(); du.Tokens() != 2000 {
+		t.Errorf("daily usage = %d tokens, want 2000", du.Tokens())
+	}
+	if rec, _ := state.LoadRunRecord(); rec.Usage.Tokens() != 2000 {
+		t.Errorf("run record usage = %d tokens, want 2000", rec.Usage.Tokens())
+	}
+}
+
+func TestResumeRun_RestoresRunUsage(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	cfg := Config{MaxCalls: 10, Backend: "cli", BudgetRunCost: 1}
+	controller := NewController(cfg, NewRateLimiter(10, 1), circuit.NewBreaker(3, 5))
+	err := controller.ResumeRun(state.RunRecord{ID: "run-1", Status: state.RunInterrupted, Usage: state.Usage{Cost: 1.25}})
+	if err != nil {
+		t.Fatalf("ResumeRun() error = %v", err)
+	}
+
+	if exhausted, reason := controller.budgetExhausted(); !exhausted || !strings.Contains(reason, "$1.25 of $1.00") {
+		t.Errorf("budgetExhausted() = %v, %q, want the resumed run's spend to count", exhausted, reason)
+	}
+}
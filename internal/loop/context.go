@@ -1,12 +1,15 @@
 package loop
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"strings"
 
+	"github.com/brainwhocodes/lisa-loop/internal/analysis"
+	"github.com/brainwhocodes/lisa-loop/internal/analysis/testparse"
+	"github.com/brainwhocodes/lisa-loop/internal/plan"
 	"github.com/brainwhocodes/lisa-loop/internal/project"
+	"github.com/brainwhocodes/lisa-loop/internal/verify"
 )
 
 // LoadPlan loads remaining tasks from the plan file based on detected project mode
@@ -24,6 +27,27 @@ func LoadFixPlan() ([]string, error) {
 
 // LoadPlanWithFile loads tasks and returns the plan file path
 func LoadPlanWithFile() ([]string, string, error) {
+	doc, err := LoadPlanDocument()
+	if err != nil {
+		if doc != nil {
+			return nil, doc.Path, err
+		}
+		return nil, "", err
+	}
+	return doc.Strings(), doc.Path, nil
+}
+
+// planFileOverride replaces plan file detection when set
+var planFileOverride string
+
+// SetPlanFile makes the loop use path as the plan file instead of detecting it
+// An empty path restores detection from the project mode
+func SetPlanFile(path string) {
+	planFileOverride = path
+}
+
+// LoadPlanDocument finds the plan file for the detected project mode and parses it
+func LoadPlanDocument() (*plan.Plan, error) {
 	// Detect mode and get the appropriate plan file
 	mode := DetectProjectMode()
 	planFile := GetPlanFileForMode(mode)
@@ -45,16 +69,14 @@ func LoadPlanWithFile() ([]string, string, error) {
 	}
 
 	if planFile == "" {
-		return nil, "", fmt.Errorf("failed to find plan file - need REFACTOR_PLAN.md, IMPLEMENTATION_PLAN.md, or @fix_plan.md")
+		return nil, fmt.Errorf("failed to find plan file - need REFACTOR_PLAN.md, IMPLEMENTATION_PLAN.md, or @fix_plan.md")
 	}
 
-	data, err := os.ReadFile(planFile)
+	doc, err := plan.Load(planFile)
 	if err != nil {
-		return nil, planFile, fmt.Errorf("failed to read plan file %s: %w", planFile, err)
+		return &plan.Plan{Path: planFile}, err
 	}
-
-	tasks, err := parseTasksFromPlan(string(data), planFile)
-	return tasks, planFile, err
+	return doc, nil
 }
 
 // parseTasksFromPlan extracts checklist tasks from a plan file
@@ -64,89 +86,13 @@ func LoadPlanWithFile() ([]string, string, error) {
 //   - "1. [ ] task" or "1. [x] task" (Numbered)
 //   - Indented checklists (nested tasks)
 func parseTasksFromPlan(content, filename string) ([]string, error) {
-	var tasks []string
-	scanner := bufio.NewScanner(strings.NewReader(content))
-
-	for scanner.Scan() {
-		line := scanner.Text()
-		trimmed := strings.TrimSpace(line)
-		if trimmed == "" {
-			continue
-		}
-
-		// Match various checklist formats
-		checked, taskText, found := extractChecklistItem(trimmed)
-		if found {
-			// Preserve the checkbox state in the task
-			if checked {
-				tasks = append(tasks, "[x] "+taskText)
-			} else {
-				tasks = append(tasks, "[ ] "+taskText)
-			}
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("failed to parse %s: %w", filename, err)
-	}
-
-	// Warn if no tasks found
-	if len(tasks) == 0 {
-		// Return empty tasks with no error - caller can decide if this is a problem
-		return tasks, nil
-	}
-
-	return tasks, nil
+	return plan.Parse(content).Strings(), nil
 }
 
 // extractChecklistItem attempts to extract a checklist item from a line
 // Returns (isChecked, taskText, found)
 func extractChecklistItem(line string) (bool, string, bool) {
-	// Pattern: "- [ ] task" or "- [x] task"
-	if strings.HasPrefix(line, "- [") && len(line) >= 6 {
-		checkbox := line[2:5] // "[ ]" or "[x]" or "[X]"
-		if checkbox == "[ ]" || checkbox == "[x]" || checkbox == "[X]" {
-			taskText := strings.TrimSpace(line[5:])
-			return checkbox != "[ ]", taskText, true
-		}
-	}
-
-	// Pattern: "* [ ] task" or "* [x] task"
-	if strings.HasPrefix(line, "* [") && len(line) >= 6 {
-		checkbox := line[2:5]
-		if checkbox == "[ ]" || checkbox == "[x]" || checkbox == "[X]" {
-			taskText := strings.TrimSpace(line[5:])
-			return checkbox != "[ ]", taskText, true
-		}
-	}
-
-	// Pattern: "1. [ ] task" or "1. [x] task" (numbered lists)
-	// Match regex: ^\d+\.\s*\[([ xX])\]\s*(.+)$
-	if idx := strings.Index(line, ". ["); idx > 0 && idx < 5 {
-		// Check if prefix is a number
-		prefix := line[:idx]
-		if isNumber(prefix) {
-			checkboxStart := idx + 2
-			if len(line) >= checkboxStart+3 {
-				checkbox := line[checkboxStart : checkboxStart+3]
-				if checkbox == "[ ]" || checkbox == "[x]" || checkbox == "[X]" {
-					taskText := strings.TrimSpace(line[checkboxStart+3:])
-					return checkbox != "[ ]", taskText, true
-				}
-			}
-		}
-	}
-
-	// Pattern: "[ ] task" or "[x] task" (bare checkbox, no bullet)
-	if strings.HasPrefix(line, "[ ") || strings.HasPrefix(line, "[x") || strings.HasPrefix(line, "[X") {
-		if len(line) >= 4 && line[2] == ']' {
-			checkbox := line[:3]
-			taskText := strings.TrimSpace(line[3:])
-			return checkbox != "[ ]", taskText, true
-		}
-	}
-
-	return false, "", false
+	return plan.ParseChecklistItem(line)
 }
 
 // isNumber checks if a string consists only of digits
@@ -177,8 +123,11 @@ func DetectProjectMode() ProjectMode {
 }
 
 // GetPlanFileForMode returns the plan file path for a given mode
-// Delegates to the unified project.GetPlanFile function
+// Delegates to the unified project.GetPlanFile function unless SetPlanFile was called
 func GetPlanFileForMode(mode ProjectMode) string {
+	if planFileOverride != "" {
+		return planFileOverride
+	}
 	return project.GetPlanFile(mode)
 }
 
@@ -234,6 +183,52 @@ func BuildContext(loopNum int, remainingTasks []string, circuitState string, pre
 
 // BuildContextWithPlanFile builds loop context with explicit plan file path
 func BuildContextWithPlanFile(loopNum int, remainingTasks []string, circuitState string, prevSummary string, planFile string) (string, error) {
+	return BuildContextWithOptions(ContextOptions{
+		LoopNum:        loopNum,
+		RemainingTasks: remainingTasks,
+		CircuitState:   circuitState,
+		PrevSummary:    prevSummary,
+		PlanFile:       planFile,
+	})
+}
+
+// ContextOptions holds everything that goes into the loop context block
+type ContextOptions struct {
+	LoopNum        int
+	RemainingTasks []string // Tasks the agent may pick from (dependencies met)
+	BlockedTasks   []string // Unchecked tasks still waiting on prerequisites
+	CircuitState   string
+	PrevSummary    string
+	PlanFile       string
+	Verification   *verify.Result    // Result of the previous loop's verification gate
+	StatusErrors   []string          // Problems with the previous loop's status report
+	Discrepancies  []string          // Claims of the previous loop that its observed changes contradict
+	Tests          *testparse.Result // Test results parsed from the previous loop
+
+	// Focus mode: the single task selected for this iteration
+	FocusTask       string
+	FocusTaskID     string
+	FocusAttempt    int
+	MaxTaskAttempts int
+}
+
+// maxContextTasks is how many tasks are listed in the loop context
+const maxContextTasks = 5
+
+// verificationTailLines is how much failing verification output is fed back to the agent
+const verificationTailLines = 30
+
+// maxContextFailures is how many failing tests are listed in the loop context
+const maxContextFailures = 10
+
+// BuildContextWithOptions builds loop context from the given options
+func BuildContextWithOptions(opts ContextOptions) (string, error) {
+	loopNum := opts.LoopNum
+	remainingTasks := opts.RemainingTasks
+	circuitState := opts.CircuitState
+	prevSummary := opts.PrevSummary
+	planFile := opts.PlanFile
+
 	var ctxBuilder strings.Builder
 
 	ctxBuilder.WriteString("\n--- RALPH LOOP CONTEXT ---\n")
@@ -250,13 +245,70 @@ func BuildContextWithPlanFile(loopNum int, remainingTasks []string, circuitState
 	fmt.Fprintf(&ctxBuilder, "After completing each task, you MUST edit %s to change `- [ ]` to `- [x]`\n", planFile)
 	ctxBuilder.WriteString("This is how Lisa tracks progress. Tasks not marked [x] will be repeated!\n")
 
-	if len(remainingTasks) > 0 && len(remainingTasks) <= 5 {
+	if opts.FocusTask != "" {
+		// Focus mode: only the selected task is offered
+		fmt.Fprintf(&ctxBuilder, "\nCurrent Task (attempt %d of %d):\n", opts.FocusAttempt, opts.MaxTaskAttempts)
+		fmt.Fprintf(&ctxBuilder, "  %s\n", opts.FocusTask)
+		if opts.FocusTaskID != "" {
+			fmt.Fprintf(&ctxBuilder, "  Task ID: %s\n", opts.FocusTaskID)
+		}
+		ctxBuilder.WriteString("Work ONLY on this task in this loop. If you cannot complete it, report status BLOCKED and list what is stopping you in blockers.\n")
+	} else if len(opts.BlockedTasks) > 0 {
+		// With dependencies in play, always say which tasks are allowed
+		ctxBuilder.WriteString("\nReady Tasks (prerequisites done, not yet marked [x]):\n")
+		for i, task := range remainingTasks {
+			if i == maxContextTasks {
+				fmt.Fprintf(&ctxBuilder, "  ... and %d more\n", len(remainingTasks)-maxContextTasks)
+				break
+			}
+			fmt.Fprintf(&ctxBuilder, "  %d. %s\n", i+1, task)
+		}
+		fmt.Fprintf(&ctxBuilder, "Do NOT start the %d blocked task(s) in the plan until their prerequisites are marked [x].\n", len(opts.BlockedTasks))
+	} else if len(remainingTasks) > 0 && len(remainingTasks) <= maxContextTasks {
 		ctxBuilder.WriteString("\nRemaining Tasks (not yet marked [x]):\n")
 		for i, task := range remainingTasks {
 			fmt.Fprintf(&ctxBuilder, "  %d. %s\n", i+1, task)
 		}
 	}
 
+	if v := opts.Verification; v != nil && !v.Passed {
+		ctxBuilder.WriteString("\n** VERIFICATION FAILED **\n")
+		fmt.Fprintf(&ctxBuilder, "The previous loop's changes failed `%s`", v.Command)
+		if v.TimedOut {
+			ctxBuilder.WriteString(" (timed out)")
+		} else {
+			fmt.Fprintf(&ctxBuilder, " (exit code %d)", v.ExitCode)
+		}
+		ctxBuilder.WriteString(". Fix this before starting new work.\n")
+		if v.Tests != nil && len(v.Tests.Failures) > 0 {
+			writeFailingTests(&ctxBuilder, v.Tests)
+		} else if tail := v.Tail(verificationTailLines); tail != "" {
+			fmt.Fprintf(&ctxBuilder, "```\n%s\n```\n", tail)
+		}
+	} else if opts.Tests != nil && len(opts.Tests.Failures) > 0 {
+		ctxBuilder.WriteString("\n** FAILING TESTS **\n")
+		ctxBuilder.WriteString("Tests failed in the previous loop's output. Fix them before starting new work.\n")
+		writeFailingTests(&ctxBuilder, opts.Tests)
+	}
+
+	if len(opts.StatusErrors) > 0 {
+		ctxBuilder.WriteString("\n** STATUS REPORT REJECTED **\n")
+		ctxBuilder.WriteString("Your previous status report did not match the schema:\n")
+		for _, problem := range opts.StatusErrors {
+			fmt.Fprintf(&ctxBuilder, "  - %s\n", problem)
+		}
+		ctxBuilder.WriteString("Send a corrected report at the end of this loop.\n")
+	}
+
+	if len(opts.Discrepancies) > 0 {
+		ctxBuilder.WriteString("\n** CLAIMS NOT BORNE OUT **\n")
+		ctxBuilder.WriteString("Your previous status did not match what the loop actually changed:\n")
+		for _, discrepancy := range opts.Discrepancies {
+			fmt.Fprintf(&ctxBuilder, "  - %s\n", discrepancy)
+		}
+		ctxBuilder.WriteString("Report only work you did: files you changed and tasks you marked `- [x]` in the plan.\n")
+	}
+
 	if prevSummary != "" {
 		ctxBuilder.WriteString("\nPrevious Loop Output (for context only, do not respond to this):\n")
 		fmt.Fprintf(&ctxBuilder, "```\n%s\n```\n", prevSummary)
@@ -266,21 +318,37 @@ func BuildContextWithPlanFile(loopNum int, remainingTasks []string, circuitState
 	ctxBuilder.WriteString("\n** WORKFLOW REQUIREMENTS **\n")
 	ctxBuilder.WriteString("1. Work on ONE task from the plan\n")
 	fmt.Fprintf(&ctxBuilder, "2. After completing the task, EDIT %s to mark it `- [x]`\n", planFile)
-	ctxBuilder.WriteString("3. End your response with a RALPH_STATUS block:\n")
-	ctxBuilder.WriteString("---RALPH_STATUS---\n")
-	ctxBuilder.WriteString("STATUS: WORKING | COMPLETE | BLOCKED\n")
-	ctxBuilder.WriteString("CURRENT_TASK: <exact text of task you just completed or are working on>\n")
-	ctxBuilder.WriteString("TASKS_COMPLETED_THIS_LOOP: <number>\n")
-	ctxBuilder.WriteString("FILES_MODIFIED: <number>\n")
-	ctxBuilder.WriteString("TESTS_STATUS: PASSING | FAILING | UNKNOWN\n")
-	ctxBuilder.WriteString("EXIT_SIGNAL: true (if ALL tasks [x]) | false (if work remains)\n")
-	ctxBuilder.WriteString("---END_RALPH_STATUS---\n")
+	fmt.Fprintf(&ctxBuilder, "3. End your response with a status report (version %d) in a fenced %s block,\n", analysis.StatusReportVersion, analysis.StatusFence)
+	fmt.Fprintf(&ctxBuilder, "   or send it as a %s tool call if you have that tool:\n", analysis.StatusTool)
+	fmt.Fprintf(&ctxBuilder, "```%s\n", analysis.StatusFence)
+	ctxBuilder.WriteString(`{"version": 1, "status": "WORKING", "current_task": "<task id>",` + "\n")
+	ctxBuilder.WriteString(` "tasks": [{"id": "<task id>", "outcome": "completed", "note": ""}],` + "\n")
+	ctxBuilder.WriteString(` "tests_status": "PASSING", "files_touched": ["<path>"], "blockers": [],` + "\n")
+	ctxBuilder.WriteString(` "follow_up_tasks": [], "exit_signal": false, "recommendation": ""}` + "\n")
+	ctxBuilder.WriteString("```\n")
+	ctxBuilder.WriteString("   status: WORKING | COMPLETE | BLOCKED (BLOCKED needs blockers)\n")
+	ctxBuilder.WriteString("   tasks[].outcome: completed | in_progress | blocked | skipped, by the task ids above\n")
+	ctxBuilder.WriteString("   tests_status: PASSING | FAILING | UNKNOWN\n")
+	ctxBuilder.WriteString("   exit_signal: true only with status COMPLETE, when ALL tasks are [x]\n")
+	ctxBuilder.WriteString("   This report replaces the RALPH_STATUS block, which is still accepted.\n")
 
 	ctxBuilder.WriteString("--- END LOOP CONTEXT ---\n\n")
 
 	return ctxBuilder.String(), nil
 }
 
+// writeFailingTests lists failing tests with their messages instead of the raw test log
+func writeFailingTests(b *strings.Builder, tests *testparse.Result) {
+	fmt.Fprintf(b, "%s\n", tests.Summary())
+	for i, failure := range tests.Failures {
+		if i == maxContextFailures {
+			fmt.Fprintf(b, "  ... and %d more\n", len(tests.Failures)-i)
+			break
+		}
+		fmt.Fprintf(b, "  - %s\n", failure)
+	}
+}
+
 // InjectContext prepends context to prompt
 func InjectContext(prompt string, ctx string) string {
 	return ctx + prompt
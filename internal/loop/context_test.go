@@ -5,6 +5,9 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/brainwhocodes/lisa-loop/internal/analysis/testparse"
+	"github.com/brainwhocodes/lisa-loop/internal/verify"
 )
 
 func TestLoadFixPlan(t *testing.T) {
@@ -47,6 +50,30 @@ func TestLoadFixPlan(t *testing.T) {
 	}
 }
 
+func TestSetPlanFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "@fix_plan.md"), []byte("- [ ] Fix task\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "TASKS.md"), []byte("- [ ] Custom task\n- [x] Done\n"), 0644)
+
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	SetPlanFile("TASKS.md")
+	defer SetPlanFile("")
+
+	tasks, planFile, err := LoadPlanWithFile()
+	if err != nil {
+		t.Fatalf("LoadPlanWithFile() error = %v", err)
+	}
+	if planFile != "TASKS.md" {
+		t.Errorf("plan file = %q, want TASKS.md", planFile)
+	}
+	if len(tasks) != 2 || tasks[0] != "[ ] Custom task" {
+		t.Errorf("tasks = %v, want the tasks from TASKS.md", tasks)
+	}
+}
+
 func TestBuildContext(t *testing.T) {
 	// Test with remaining tasks
 	tasks := []string{"Task 1", "Task 2"}
@@ -82,6 +109,77 @@ func TestBuildContextNoTasks(t *testing.T) {
 	}
 }
 
+func TestBuildContextWithVerificationFailure(t *testing.T) {
+	result := &verify.Result{
+		Command:  "go test ./...",
+		ExitCode: 1,
+		Output:   "--- FAIL: TestSomething\nFAIL\n",
+	}
+
+	context, _ := BuildContextWithOptions(ContextOptions{
+		LoopNum:      2,
+		CircuitState: "CLOSED",
+		Verification: result,
+	})
+
+	for _, expected := range []string{"VERIFICATION FAILED", "go test ./...", "exit code 1", "--- FAIL: TestSomething"} {
+		if !strings.Contains(context, expected) {
+			t.Errorf("BuildContextWithOptions() missing '%s'", expected)
+		}
+	}
+
+	// Passing verification should not add anything
+	result.Passed = true
+	context, _ = BuildContextWithOptions(ContextOptions{LoopNum: 2, Verification: result})
+	if strings.Contains(context, "VERIFICATION FAILED") {
+		t.Errorf("BuildContextWithOptions() should not report passing verification as failed")
+	}
+}
+
+func TestBuildContextWithFailingTests(t *testing.T) {
+	output := "=== RUN   TestParse\n    parse_test.go:9: unexpected EOF\n--- FAIL: TestParse (0.00s)\nFAIL\texample.com/parse\t0.01s\n"
+	tests, _ := testparse.Parse(output)
+
+	// A failed gate lists the failing tests instead of the raw log
+	context, _ := BuildContextWithOptions(ContextOptions{
+		LoopNum:      2,
+		Verification: &verify.Result{Command: "go test ./...", ExitCode: 1, Output: output, Tests: tests},
+	})
+	for _, expected := range []string{"VERIFICATION FAILED", "go: 0 passed, 1 failed", "  - TestParse: parse_test.go:9: unexpected EOF"} {
+		if !strings.Contains(context, expected) {
+			t.Errorf("BuildContextWithOptions() missing '%s':\n%s", expected, context)
+		}
+	}
+	if strings.Contains(context, "=== RUN") {
+		t.Errorf("BuildContextWithOptions() should not include the raw test log:\n%s", context)
+	}
+
+	// Failures the agent's own test run showed are listed without a gate
+	context, _ = BuildContextWithOptions(ContextOptions{LoopNum: 2, Tests: tests})
+	if !strings.Contains(context, "FAILING TESTS") || !strings.Contains(context, "TestParse: parse_test.go:9") {
+		t.Errorf("BuildContextWithOptions() should list failing tests:\n%s", context)
+	}
+}
+
+func TestBuildContextWithBlockedTasks(t *testing.T) {
+	context, _ := BuildContextWithOptions(ContextOptions{
+		LoopNum:        3,
+		CircuitState:   "CLOSED",
+		RemainingTasks: []string{"Write migrations"},
+		BlockedTasks:   []string{"Seed data"},
+	})
+
+	for _, expected := range []string{"Ready Tasks", "Write migrations", "1 blocked task(s)"} {
+		if !strings.Contains(context, expected) {
+			t.Errorf("BuildContextWithOptions() missing '%s'", expected)
+		}
+	}
+
+	if strings.Contains(context, "Seed data") {
+		t.Errorf("BuildContextWithOptions() should not offer blocked tasks to the agent")
+	}
+}
+
 func TestInjectContext(t *testing.T) {
 	prompt := "Main prompt here"
 	context := "\n--- RALPH CONTEXT ---\nTest context\n--- END ---"
@@ -2,16 +2,24 @@ package loop
 
 import (
 	stdcontext "context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/brainwhocodes/lisa-loop/internal/analysis"
+	"github.com/brainwhocodes/lisa-loop/internal/analysis/testparse"
+	"github.com/brainwhocodes/lisa-loop/internal/budget"
+	"github.com/brainwhocodes/lisa-loop/internal/checkpoint"
 	"github.com/brainwhocodes/lisa-loop/internal/circuit"
 	"github.com/brainwhocodes/lisa-loop/internal/codex"
 	"github.com/brainwhocodes/lisa-loop/internal/config"
+	"github.com/brainwhocodes/lisa-loop/internal/journal"
+	"github.com/brainwhocodes/lisa-loop/internal/plan"
 	"github.com/brainwhocodes/lisa-loop/internal/runner"
 	"github.com/brainwhocodes/lisa-loop/internal/state"
+	"github.com/brainwhocodes/lisa-loop/internal/verify"
 )
 
 // Config is an alias to the unified config type
@@ -19,69 +27,106 @@ type Config = config.Config
 
 // PreflightSummary holds preflight check information
 type PreflightSummary struct {
-	Mode           string   // Project mode (fix, implement, refactor)
-	PlanFile       string   // Path to the plan file
-	TotalTasks     int      // Total number of tasks
-	RemainingCount int      // Number of remaining tasks
-	RemainingTasks []string // First N remaining tasks
-	CircuitState   string   // Circuit breaker state
-	RateLimitOK    bool     // Whether rate limit allows a call
-	CallsRemaining int      // Number of calls remaining
-	ShouldSkip     bool     // Whether loop should be skipped
-	SkipReason     string   // Reason for skipping (if ShouldSkip is true)
+	Mode           string   `json:"mode,omitempty"`            // Project mode (fix, implement, refactor)
+	PlanFile       string   `json:"plan_file,omitempty"`       // Path to the plan file
+	TotalTasks     int      `json:"total_tasks,omitempty"`     // Total number of tasks
+	RemainingCount int      `json:"remaining_count,omitempty"` // Number of remaining tasks
+	RemainingTasks []string `json:"remaining_tasks,omitempty"` // First N remaining tasks
+	ReadyCount     int      `json:"ready_count,omitempty"`     // Remaining tasks whose dependencies are met
+	BlockedCount   int      `json:"blocked_count,omitempty"`   // Remaining tasks waiting on unfinished dependencies
+	PlanErrors     []string `json:"plan_errors,omitempty"`     // Dependency validation errors (unknown tasks, cycles)
+	CircuitState   string   `json:"circuit_state,omitempty"`   // Circuit breaker state
+	RateLimitOK    bool     `json:"rate_limit_ok,omitempty"`   // Whether rate limit allows a call
+	CallsRemaining int      `json:"calls_remaining,omitempty"` // Number of calls remaining
+	ShouldSkip     bool     `json:"should_skip,omitempty"`     // Whether loop should be skipped
+	SkipReason     string   `json:"skip_reason,omitempty"`     // Reason for skipping (if ShouldSkip is true)
+
+	// Spending against the budget, if one is set or anything was spent
+	Budget *budget.Status `json:"budget,omitempty"`
 }
 
 // LoopEvent represents an event from the loop controller
 type LoopEvent struct {
-	Type         EventType
-	LoopNumber   int
-	CallsUsed    int
-	Status       string
-	LogMessage   string
-	LogLevel     LogLevel
-	CircuitState string
+	Type         EventType `json:"type,omitempty"`
+	Worker       int       `json:"worker,omitempty"` // Parallel worker lane (1-based); 0 for the serial loop
+	LoopNumber   int       `json:"loop_number,omitempty"`
+	CallsUsed    int       `json:"calls_used,omitempty"`
+	Status       string    `json:"status,omitempty"`
+	LogMessage   string    `json:"log_message,omitempty"`
+	LogLevel     LogLevel  `json:"log_level,omitempty"`
+	CircuitState string    `json:"circuit_state,omitempty"`
+
+	// Circuit breaker details: the state before a state change (whose reason is in
+	// LogMessage), and errors grouped by fingerprint, most repeated first (loop updates)
+	PrevCircuitState string             `json:"prev_circuit_state,omitempty"`
+	ErrorGroups      []state.ErrorGroup `json:"error_groups,omitempty"`
+
+	// Spending of the run and the day against the budget (loop updates and usage events)
+	Budget *budget.Status `json:"budget,omitempty"`
+
+	// Time until the rate limit allows the next call ("rate_limited" loop updates), or
+	// until a failed iteration is retried ("retry_wait" and "quota_wait" loop updates)
+	RateLimitWait time.Duration `json:"rate_limit_wait,omitempty"`
 
 	// Codex output streaming fields
-	OutputLine    string // Raw output line
-	OutputType    OutputType
-	ReasoningText string // Reasoning/thinking text
-	ToolName      string // Tool being called
-	ToolTarget    string // File path or command
-	ToolStatus    ToolStatus
+	OutputLine    string     `json:"output_line,omitempty"` // Raw output line
+	OutputType    OutputType `json:"output_type,omitempty"`
+	ReasoningText string     `json:"reasoning_text,omitempty"` // Reasoning/thinking text
+	ToolName      string     `json:"tool_name,omitempty"`      // Tool being called
+	ToolTarget    string     `json:"tool_target,omitempty"`    // File path or command
+	ToolStatus    ToolStatus `json:"tool_status,omitempty"`
 
 	// Analysis result fields (from RALPH_STATUS block)
-	AnalysisStatus     string  // WORKING, COMPLETE, BLOCKED
-	CurrentTask        string  // Current task being worked on or just completed
-	TasksCompleted     int     // Tasks completed this loop
-	FilesModified      int     // Files modified this loop
-	TestsStatus        string  // PASSING, FAILING, UNKNOWN
-	ExitSignal         bool    // Whether exit was signaled
-	ConfidenceScore    float64 // Confidence in completion (0-1)
+	AnalysisStatus  string  `json:"analysis_status,omitempty"`  // WORKING, COMPLETE, BLOCKED
+	CurrentTask     string  `json:"current_task,omitempty"`     // Current task being worked on or just completed
+	TasksCompleted  int     `json:"tasks_completed,omitempty"`  // Tasks completed this loop
+	FilesModified   int     `json:"files_modified,omitempty"`   // Files modified this loop
+	TestsStatus     string  `json:"tests_status,omitempty"`     // PASSING, FAILING, UNKNOWN
+	ExitSignal      bool    `json:"exit_signal,omitempty"`      // Whether exit was signaled
+	ConfidenceScore float64 `json:"confidence_score,omitempty"` // Confidence in completion (0-1)
+
+	// Claims the observed loop contradicts, such as "claimed 3 files modified, changed 0"
+	Discrepancies []string `json:"discrepancies,omitempty"`
 
 	// Context tracking fields
-	ContextUsagePercent  float64 // Current context window usage (0-1)
-	ContextTotalTokens   int     // Total tokens used
-	ContextLimit         int     // Context window limit
-	ContextThreshold     bool    // True if threshold reached
-	ContextWasCompacted  bool    // True if OpenCode compacted the session
+	ContextUsagePercent float64 `json:"context_usage_percent,omitempty"` // Current context window usage (0-1)
+	ContextTotalTokens  int     `json:"context_total_tokens,omitempty"`  // Total tokens used
+	ContextLimit        int     `json:"context_limit,omitempty"`         // Context window limit
+	ContextThreshold    bool    `json:"context_threshold,omitempty"`     // True if threshold reached
+	ContextWasCompacted bool    `json:"context_was_compacted,omitempty"` // True if OpenCode compacted the session
 
 	// Preflight summary
-	Preflight *PreflightSummary
+	Preflight *PreflightSummary `json:"preflight,omitempty"`
+
+	// Verification gate result
+	Verification *verify.Result `json:"verification,omitempty"`
+
+	// Focus mode: attempt number for CurrentTask
+	TaskAttempt int `json:"task_attempt,omitempty"`
 
 	// Loop outcome
-	Outcome *LoopOutcome
+	Outcome *LoopOutcome `json:"outcome,omitempty"`
 }
 
 // LoopOutcome represents the result of a loop iteration
 type LoopOutcome struct {
-	Success        bool
-	TasksCompleted int
-	FilesModified  int
-	TestsStatus    string
-	ExitSignal     bool
-	Error          string
+	Success        bool   `json:"success,omitempty"`
+	TasksCompleted int    `json:"tasks_completed,omitempty"`
+	FilesModified  int    `json:"files_modified,omitempty"`
+	TestsStatus    string `json:"tests_status,omitempty"`
+	ExitSignal     bool   `json:"exit_signal,omitempty"`
+	Error          string `json:"error,omitempty"`
+
+	// Verification gate results (only meaningful if Verified)
+	Verified           bool `json:"verified,omitempty"`
+	VerificationPassed bool `json:"verification_passed,omitempty"`
+
+	// Test results parsed from the verification gate's or the agent's output
+	Tests *testparse.Result `json:"tests,omitempty"`
+
+	// True if the backend call was stopped for running past its timeout
+	TimedOut bool `json:"timed_out,omitempty"`
 }
-
 // EventCallback is called when the controller has an update
 type EventCallback func(event LoopEvent)
 
@@ -90,16 +135,71 @@ type Controller struct {
 	config        ControllerConfig
 	rateLimiter   *RateLimiter
 	breaker       *circuit.Breaker
+	budget        *budget.Tracker
 	runner        runner.Runner
+	runnerConfig  Config
+	newRunner     func(cfg Config) runner.Runner
+	probeRunner   runner.Runner // Runner for HALF_OPEN probes with a probe model (created on first use)
 	loopNum       int
 	lastOutput    string
 	shouldStop    bool
 	eventCallback EventCallback
+	emitMu        sync.Mutex
 	paused        bool
 	backend       string
 
+	// Run journal, opened on the first event (nil once it has failed)
+	runID         string
+	journal       *journal.Writer
+	journalFailed bool
+
+	// Run record for --resume
+	runStarted  time.Time
+	currentTask string
+	resumed     bool
+
+	// Closed when the current Run returns, so a shutdown can wait for its cleanup
+	runMu   sync.Mutex
+	runDone chan struct{}
+
+	// Verification gate
+	verifier         *verify.Gate
+	uncheckOnFail    bool
+	lastVerification *verify.Result
+	lastTests        *testparse.Result // Test results of the last loop, listed in the next one's context
+
+	// Status reports: those sent as tool calls, per worker, until the output is analyzed,
+	// and the problems with the last report, fed back to the agent in the next loop
+	reportMu     sync.Mutex
+	toolReports  map[int][]byte
+	statusErrors []string
+
+	// Observed work: tool calls per worker during the current loop (guarded by reportMu),
+	// whether the backend streams tool calls at all, and the claims the last loop's
+	// observation contradicted, fed back to the agent in the next loop
+	toolCalls     map[int]int
+	streamsTools  bool
+	discrepancies []string
+
+	// Failed iterations in a row, and whether the last retry started a new session
+	consecutiveErrors int
+	sessionRotated    bool
+
+	// Git checkpoints (nil when disabled)
+	checkpoints        *checkpoint.Manager
+	checkpointsStarted bool
+	rollbackOnFailure  bool
+
+	// Focus mode: one controller-selected task per iteration
+	focusMode       bool
+	maxTaskAttempts int
+
+	// Parallel workers (<= 1 runs the serial loop)
+	parallel int
+
 	// Cached plan state (refreshed each loop iteration)
 	cachedMode      ProjectMode
+	cachedPlan      *plan.Plan
 	cachedPlanFile  string
 	cachedTasks     []string
 	cacheValid      bool
@@ -125,15 +225,38 @@ func NewController(cfg Config, rateLimiter *RateLimiter, breaker *circuit.Breake
 		},
 		rateLimiter:   rateLimiter,
 		breaker:       breaker,
+		budget:        budget.NewTracker(budgetLimits(cfg)),
 		runner:        r,
+		runnerConfig:  cfg,
+		newRunner:     runner.New,
 		loopNum:       0,
 		lastOutput:    "",
 		shouldStop:    false,
 		eventCallback: nil,
 		paused:        false,
 		backend:       cfg.Backend,
+		verifier:      verify.NewGate(cfg.VerifyCommand, "", time.Duration(cfg.VerifyTimeout)*time.Second),
+		uncheckOnFail: cfg.VerifyUncheckOnFail,
+
+		rollbackOnFailure: cfg.RollbackOnFailure,
+		focusMode:         cfg.FocusMode,
+		maxTaskAttempts:   cfg.MaxTaskAttempts,
+		parallel:          cfg.Parallel,
+		runID:             checkpoint.NewRunID(),
+	}
+
+	if c.maxTaskAttempts <= 0 {
+		c.maxTaskAttempts = defaultMaxTaskAttempts
 	}
 
+	if cfg.Checkpoint {
+		c.checkpoints = checkpoint.NewManager("", c.runID)
+	}
+
+	breaker.SetRecovery(time.Duration(cfg.CircuitCooldown)*time.Second, cfg.CircuitHalfOpenProbes)
+	breaker.SetStateChangeCallback(c.emitStateChange)
+	breaker.SetStagnation(float64(cfg.CircuitStagnation) / 100)
+
 	// Set up output callback for streaming
 	r.SetOutputCallback(func(event runner.Event) {
 		c.handleCodexEvent(codex.Event(event))
@@ -156,8 +279,23 @@ func (c *Controller) SetRunner(r runner.Runner) {
 	})
 }
 
-// emit sends an event to the callback if set
+// SetRunnerFactory injects the constructor used for parallel worker runners (for testing)
+func (c *Controller) SetRunnerFactory(newRunner func(cfg Config) runner.Runner) {
+	c.newRunner = newRunner
+}
+
+// RunID returns the ID this controller's run is journaled (and checkpointed) under
+func (c *Controller) RunID() string {
+	return c.runID
+}
+
+// emit journals an event and sends it to the callback if set
+// Parallel workers emit from their own goroutines, so delivery is serialized
 func (c *Controller) emit(event LoopEvent) {
+	c.emitMu.Lock()
+	defer c.emitMu.Unlock()
+
+	c.journalEvent(event)
 	if c.eventCallback != nil {
 		c.eventCallback(event)
 	}
@@ -174,36 +312,47 @@ func (c *Controller) emitLog(level LogLevel, message string) {
 
 // emitUpdate sends a loop update event
 func (c *Controller) emitUpdate(status string) {
-	c.emit(LoopEvent{
+	c.emit(c.loopUpdate(status))
+}
+
+// loopUpdate builds a loop update event for the current state
+func (c *Controller) loopUpdate(status string) LoopEvent {
+	return LoopEvent{
 		Type:         EventTypeLoopUpdate,
 		LoopNumber:   c.loopNum,
 		CallsUsed:    c.rateLimiter.CallsMade(),
 		Status:       status,
 		CircuitState: c.breaker.GetState().String(),
-	})
+		ErrorGroups:  c.breaker.GetErrorGroups(),
+		Budget:       c.budgetStatus(),
+	}
 }
 
 // emitCodexOutput sends a codex output event
-func (c *Controller) emitCodexOutput(line string, outputType OutputType) {
+// worker is the parallel worker that produced it (0 for the serial loop)
+func (c *Controller) emitCodexOutput(worker int, line string, outputType OutputType) {
 	c.emit(LoopEvent{
 		Type:       EventTypeCodexOutput,
+		Worker:     worker,
 		OutputLine: line,
 		OutputType: outputType,
 	})
 }
 
 // emitCodexReasoning sends a codex reasoning event
-func (c *Controller) emitCodexReasoning(text string) {
+func (c *Controller) emitCodexReasoning(worker int, text string) {
 	c.emit(LoopEvent{
 		Type:          EventTypeCodexReasoning,
+		Worker:        worker,
 		ReasoningText: text,
 	})
 }
 
 // emitCodexTool sends a codex tool call event
-func (c *Controller) emitCodexTool(toolName, target string, status ToolStatus) {
+func (c *Controller) emitCodexTool(worker int, toolName, target string, status ToolStatus) {
 	c.emit(LoopEvent{
 		Type:       EventTypeCodexTool,
+		Worker:     worker,
 		ToolName:   toolName,
 		ToolTarget: target,
 		ToolStatus: status,
@@ -221,6 +370,7 @@ func (c *Controller) emitAnalysis(result *analysis.Analysis) {
 		LoopNumber:      c.loopNum,
 		ExitSignal:      result.ExitSignal,
 		ConfidenceScore: result.ConfidenceScore,
+		Discrepancies:   result.Discrepancies,
 	}
 
 	if result.Status != nil {
@@ -251,6 +401,44 @@ func (c *Controller) emitOutcome(outcome *LoopOutcome) {
 	})
 }
 
+// emitVerification sends a verification gate result event
+func (c *Controller) emitVerification(result *verify.Result) {
+	c.emit(LoopEvent{
+		Type:         EventTypeVerification,
+		LoopNumber:   c.loopNum,
+		Verification: result,
+	})
+}
+
+// emitFocus sends the task focus mode selected for this iteration
+func (c *Controller) emitFocus(task *plan.Task, attempt int) {
+	c.emit(LoopEvent{
+		Type:        EventTypeFocus,
+		LoopNumber:  c.loopNum,
+		CurrentTask: task.Text,
+		TaskAttempt: attempt,
+	})
+}
+
+// emitWorker sends a parallel worker lane update
+// An empty task keeps the lane's current task
+func (c *Controller) emitWorker(worker int, task, status, detail string) {
+	c.emitWorkerAt(c.loopNum, worker, task, status, detail)
+}
+
+// emitWorkerAt sends a worker lane update for the given loop; worker goroutines use it
+// because the coordinator advances c.loopNum while they run
+func (c *Controller) emitWorkerAt(loop, worker int, task, status, detail string) {
+	c.emit(LoopEvent{
+		Type:        EventTypeWorker,
+		Worker:      worker,
+		LoopNumber:  loop,
+		CurrentTask: task,
+		Status:      status,
+		LogMessage:  detail,
+	})
+}
+
 // emitContextUsage sends context window usage event
 func (c *Controller) emitContextUsage(usagePercent float64, totalTokens, limit int, thresholdReached, wasCompacted bool) {
 	c.emit(LoopEvent{
@@ -288,6 +476,20 @@ func (c *Controller) Stop() {
 
 // Run executes the main loop
 func (c *Controller) Run(ctx stdcontext.Context) error {
+	done := make(chan struct{})
+	c.runMu.Lock()
+	c.runDone = done
+	c.runMu.Unlock()
+	defer close(done)
+	defer c.closeJournal()
+
+	c.beginRunRecord()
+	defer c.endRunRecord(ctx)
+
+	if c.parallel > 1 {
+		return c.RunParallel(ctx, c.parallel)
+	}
+
 	c.emitLog(LogLevelInfo, fmt.Sprintf("Starting Lisa Codex loop (max %d calls)", c.config.MaxLoops))
 	c.emitUpdate("starting")
 
@@ -310,11 +512,23 @@ func (c *Controller) Run(ctx stdcontext.Context) error {
 			c.emitUpdate("cancelled")
 			return ctx.Err()
 		default:
+			// An OPEN circuit with a cool-down waits here, then probes
+			if !c.awaitCooldown(ctx) {
+				continue
+			}
+			// A full rate-limit window waits here, reporting the wait
+			if !c.awaitRateLimit(ctx) {
+				continue
+			}
+
 			c.emitUpdate("running")
 
 			// Preflight check before executing loop
 			preflight, shouldSkip := c.RunPreflight()
 			c.emitPreflight(preflight)
+			for _, planErr := range preflight.PlanErrors {
+				c.emitLog(LogLevelWarn, fmt.Sprintf("Plan: %s", planErr))
+			}
 
 			if shouldSkip {
 				c.emitLog(LogLevelInfo, fmt.Sprintf("Skipped: %s", preflight.SkipReason))
@@ -327,17 +541,19 @@ func (c *Controller) Run(ctx stdcontext.Context) error {
 			err := c.ExecuteLoop(ctx)
 
 			if err != nil {
-				c.emitLog(LogLevelError, fmt.Sprintf("Loop iteration error: %v", err))
-				c.emitUpdate("error")
-				// Don't return on error - start a new loop iteration instead
-				// This handles message.error and other transient failures
-				c.emitLog(LogLevelInfo, "Waiting 5s before retrying...")
-				time.Sleep(5 * time.Second)
-				c.emitLog(LogLevelInfo, "Starting new loop iteration after error...")
+				// Don't return on error - start a new loop iteration instead, after a
+				// wait that depends on the kind of error; only fatal errors stop the run
+				if fatal := c.handleLoopError(ctx, err); fatal != nil {
+					return fatal
+				}
 				c.loopNum++
+				c.saveRunRecord(state.RunRunning)
 				continue
 			}
 
+			c.resetErrors()
+			c.loopNum++
+
 			// Check if we should stop
 			if c.ShouldContinue() {
 				c.emitLog(LogLevelSuccess, fmt.Sprintf("Lisa Codex loop complete after %d iterations", c.loopNum))
@@ -345,7 +561,7 @@ func (c *Controller) Run(ctx stdcontext.Context) error {
 				return nil
 			}
 
-			c.loopNum++
+			c.saveRunRecord(state.RunRunning)
 		}
 	}
 }
@@ -353,13 +569,15 @@ func (c *Controller) Run(ctx stdcontext.Context) error {
 // refreshPlanCache reloads plan data once per loop iteration
 func (c *Controller) refreshPlanCache() {
 	c.cachedMode = DetectProjectMode()
-	tasks, planFile, err := LoadPlanWithFile()
+	doc, err := LoadPlanDocument()
 	if err != nil {
+		c.cachedPlan = nil
 		c.cachedTasks = nil
 		c.cachedPlanFile = ""
 	} else {
-		c.cachedTasks = tasks
-		c.cachedPlanFile = planFile
+		c.cachedPlan = doc
+		c.cachedTasks = doc.Strings()
+		c.cachedPlanFile = doc.Path
 	}
 	c.cacheValid = true
 }
@@ -386,21 +604,25 @@ func (c *Controller) RunPreflight() (*PreflightSummary, bool) {
 		}, true
 	}
 
-	// Count remaining tasks
-	remainingTasks := []string{}
-	for _, task := range tasks {
-		if !strings.HasPrefix(task, "[x]") {
-			remainingTasks = append(remainingTasks, task)
-		}
+	// Count remaining tasks and which of them are ready to work on
+	remainingTasks := remainingTaskStrings(c.cachedPlan)
+	readyCount := len(c.cachedPlan.Ready())
+	blockedCount := len(remainingTasks) - readyCount
+
+	var planErrors []string
+	for _, err := range c.cachedPlan.Validate() {
+		planErrors = append(planErrors, err.Error())
 	}
 
 	// Get circuit breaker state
 	circuitState := c.breaker.GetState().String()
 
-	// Get rate limit status
+	// Get rate limit status; a full window is waited out rather than skipped
 	rateLimitOK := c.rateLimiter.CanMakeCall()
 	callsRemaining := c.rateLimiter.CallsRemaining()
 
+	budgetExhausted, budgetReason := c.budgetExhausted()
+
 	// Determine if we should skip
 	shouldSkip := false
 	skipReason := ""
@@ -408,12 +630,18 @@ func (c *Controller) RunPreflight() (*PreflightSummary, bool) {
 	if len(remainingTasks) == 0 {
 		shouldSkip = true
 		skipReason = "All tasks complete"
+	} else if readyCount == 0 {
+		shouldSkip = true
+		skipReason = fmt.Sprintf("All %d remaining tasks are blocked by unfinished dependencies", len(remainingTasks))
+		if marked := countMarkedBlocked(c.cachedPlan); marked > 0 {
+			skipReason = fmt.Sprintf("All %d remaining tasks are blocked (%d marked BLOCKED)", len(remainingTasks), marked)
+		}
 	} else if c.breaker.ShouldHalt() {
 		shouldSkip = true
 		skipReason = "Circuit breaker is OPEN"
-	} else if !rateLimitOK {
+	} else if budgetExhausted {
 		shouldSkip = true
-		skipReason = fmt.Sprintf("Rate limit exhausted (%d calls remaining)", callsRemaining)
+		skipReason = budgetReason
 	} else if c.loopNum >= c.config.MaxLoops {
 		shouldSkip = true
 		skipReason = fmt.Sprintf("Max loops reached (%d)", c.config.MaxLoops)
@@ -432,9 +660,13 @@ func (c *Controller) RunPreflight() (*PreflightSummary, bool) {
 		TotalTasks:     len(tasks),
 		RemainingCount: len(remainingTasks),
 		RemainingTasks: tasksToShow,
+		ReadyCount:     readyCount,
+		BlockedCount:   blockedCount,
+		PlanErrors:     planErrors,
 		CircuitState:   circuitState,
 		RateLimitOK:    rateLimitOK,
 		CallsRemaining: callsRemaining,
+		Budget:         c.budgetStatus(),
 		ShouldSkip:     shouldSkip,
 		SkipReason:     skipReason,
 	}, shouldSkip
@@ -451,10 +683,8 @@ func (c *Controller) ExecuteLoop(ctx stdcontext.Context) error {
 	c.emitUpdate("executing")
 
 	// Check rate limit
-	if !c.rateLimiter.CanMakeCall() {
-		c.emitLog(LogLevelWarn, fmt.Sprintf("Rate limit reached. Calls remaining: %d", c.rateLimiter.CallsRemaining()))
-		c.emitUpdate("rate_limited")
-		return c.rateLimiter.WaitForReset(ctx)
+	if !c.awaitRateLimit(ctx) {
+		return ctx.Err()
 	}
 
 	// Check circuit breaker
@@ -468,7 +698,11 @@ func (c *Controller) ExecuteLoop(ctx stdcontext.Context) error {
 	c.refreshPlanCache()
 
 	// Load prompt
-	prompt, err := GetPrompt()
+	probing := c.breaker.IsProbing()
+	if probing {
+		c.emitLog(LogLevelWarn, fmt.Sprintf("Circuit breaker is HALF_OPEN: probe iteration (%d left)", c.breaker.ProbesLeft()))
+	}
+	prompt, err := c.iterationPrompt(probing)
 	if err != nil {
 		c.emitLog(LogLevelError, fmt.Sprintf("Failed to load prompt: %v", err))
 		c.emitUpdate("error")
@@ -485,15 +719,37 @@ func (c *Controller) ExecuteLoop(ctx stdcontext.Context) error {
 	}
 
 	// Build context
+	// Only offer tasks whose dependencies are met
 	circuitState := c.breaker.GetState().String()
-	remainingTasks := []string{}
-	for _, task := range tasks {
-		if !strings.HasPrefix(task, "[x]") {
-			remainingTasks = append(remainingTasks, task)
-		}
+	readyTasks := contextTaskStrings(c.cachedPlan.Ready())
+	blockedTasks := taskStrings(c.cachedPlan.Blocked())
+
+	opts := ContextOptions{
+		LoopNum:        c.loopNum + 1,
+		RemainingTasks: readyTasks,
+		BlockedTasks:   blockedTasks,
+		CircuitState:   circuitState,
+		PrevSummary:    c.lastOutput,
+		PlanFile:       planFile,
+		Verification:   c.lastVerification,
+		StatusErrors:   c.statusErrors,
+		Discrepancies:  c.discrepancies,
+		Tests:          c.lastTests,
+	}
+
+	// In focus mode the controller picks the task, not the agent
+	focus := c.selectFocusTask()
+	if focus != nil {
+		opts.FocusTask = focus.String()
+		opts.FocusTaskID = focus.ID
+		opts.FocusAttempt = c.taskAttempts(focus.ID) + 1
+		opts.MaxTaskAttempts = c.maxTaskAttempts
+		c.currentTask = focus.Text
+		c.emitLog(LogLevelInfo, fmt.Sprintf("Focus: %s (attempt %d/%d)", focus.Text, opts.FocusAttempt, c.maxTaskAttempts))
+		c.emitFocus(focus, opts.FocusAttempt)
 	}
 
-	loopContext, err := BuildContextWithPlanFile(c.loopNum+1, remainingTasks, circuitState, c.lastOutput, planFile)
+	loopContext, err := BuildContextWithOptions(opts)
 	if err != nil {
 		c.emitLog(LogLevelError, fmt.Sprintf("Failed to build context: %v", err))
 		c.emitUpdate("error")
@@ -502,16 +758,30 @@ func (c *Controller) ExecuteLoop(ctx stdcontext.Context) error {
 
 	promptWithContext := InjectContext(prompt, loopContext)
 
-	// Execute runner (Codex CLI or OpenCode)
+	// Snapshot the work tree so a harmful iteration can be undone
+	cp := c.beginCheckpoint(c.loopNum + 1)
+	diffBase := c.diffBase(cp)
+
+	// Execute runner
 	backendName := "Codex"
-	if c.backend == "opencode" {
+	switch c.backend {
+	case "opencode":
 		backendName = "OpenCode"
+	case "openai":
+		backendName = "chat completions"
+	case "command":
+		backendName = "agent command"
+	case "replay":
+		backendName = "replay"
 	}
 	c.emitLog(LogLevelInfo, fmt.Sprintf("Loop %d: Executing %s", c.loopNum+1, backendName))
 	c.emitUpdate("codex_running")
-	c.emitCodexOutput(fmt.Sprintf("Starting %s execution (loop %d)...", backendName, c.loopNum+1), OutputTypeRaw)
-	c.emitCodexOutput(fmt.Sprintf("Prompt size: %d bytes", len(promptWithContext)), OutputTypeRaw)
-	output, _, err := c.runner.Run(promptWithContext)
+	c.emitCodexOutput(0, fmt.Sprintf("Starting %s execution (loop %d)...", backendName, c.loopNum+1), OutputTypeRaw)
+	c.emitCodexOutput(0, fmt.Sprintf("Prompt size: %d bytes", len(promptWithContext)), OutputTypeRaw)
+	work := c.snapshotWork("", cp, planFile)
+	c.takeToolReport(0)
+	c.takeToolCalls(0)
+	output, _, err := c.iterationRunner(probing).Run(runner.WithLoop(ctx, c.loopNum+1), promptWithContext)
 
 	if err != nil {
 		// Don't pass error messages as prevSummary - they confuse the AI
@@ -521,17 +791,39 @@ func (c *Controller) ExecuteLoop(ctx stdcontext.Context) error {
 			c.emitLog(LogLevelWarn, fmt.Sprintf("Failed to record call: %v", rlErr))
 		}
 
+		// A cancelled call is not the agent's failure and doesn't use up a focus attempt;
+		// its partial changes stay in place
+		if ctx.Err() != nil {
+			c.emitLog(LogLevelWarn, fmt.Sprintf("%s execution cancelled", backendName))
+			return ctx.Err()
+		}
+
 		// Record error in circuit breaker
 		if cbErr := c.breaker.RecordError(err.Error()); cbErr != nil {
 			c.emitLog(LogLevelWarn, fmt.Sprintf("Failed to record error in circuit breaker: %v", cbErr))
 		}
-		c.emitLog(LogLevelError, fmt.Sprintf("Codex execution failed: %v", err))
-		c.emitUpdate("execution_error")
+		timedOut := errors.Is(err, stdcontext.DeadlineExceeded)
+		if timedOut {
+			c.emitLog(LogLevelError, fmt.Sprintf("%s execution timed out: %v", backendName, err))
+			c.emitUpdate("execution_timeout")
+		} else {
+			c.emitLog(LogLevelError, fmt.Sprintf("%s execution failed: %v", backendName, err))
+			c.emitUpdate("execution_error")
+		}
+
+		// Leave partial changes in place unless the breaker gave up on them
+		if c.breaker.ShouldHalt() {
+			c.finishCheckpoint(cp, "", true)
+		}
+		if spentFocusAttempt(ctx, err) {
+			c.finishFocusTask(focus, err.Error())
+		}
 
 		// Emit outcome event for error case
 		c.emitOutcome(&LoopOutcome{
-			Success: false,
-			Error:   err.Error(),
+			Success:  false,
+			Error:    err.Error(),
+			TimedOut: timedOut,
 		})
 
 		return err
@@ -559,10 +851,26 @@ func (c *Controller) ExecuteLoop(ctx stdcontext.Context) error {
 
 	// Analyze output for exit conditions using the analysis package
 	exitSignals, _ := state.LoadExitSignals()
-	analysisResult, err := analysis.Analyze(output, exitSignals)
+	analysisResult, err := analysis.AnalyzeWithReport(output, c.takeToolReport(0), exitSignals)
 	if err != nil {
 		c.emitLog(LogLevelWarn, fmt.Sprintf("Output analysis failed: %v", err))
 	}
+	c.statusErrors = c.checkStatusReport(0, analysisResult, c.cachedPlan)
+	// A replayed loop changes nothing, so there is nothing to check its claims against
+	if c.backend != "replay" {
+		c.discrepancies = c.applyObservation(0, analysisResult, c.observe(0, work))
+	}
+
+	// Run the verification gate so the agent's self-reported status is not trusted blindly
+	verification := c.runVerification(ctx)
+	verificationFailed := verification != nil && !verification.Passed
+	if verification != nil && analysisResult != nil {
+		analysisResult.ApplyVerification(verification.Passed, verification.Summary())
+		analysisResult.ApplyTestResults(verification.Tests)
+	}
+	if verificationFailed && c.uncheckOnFail {
+		c.revertCompletedTasks(tasks, planFile)
+	}
 
 	// Determine hasErrors and filesChanged from analysis
 	hasErrors := false
@@ -573,6 +881,11 @@ func (c *Controller) ExecuteLoop(ctx stdcontext.Context) error {
 			filesChanged = analysisResult.Status.FilesModified
 		}
 
+		// Changes that break verification don't count as progress
+		if verificationFailed {
+			filesChanged = 0
+		}
+
 		// Emit analysis results to UI
 		c.emitAnalysis(analysisResult)
 
@@ -591,6 +904,12 @@ func (c *Controller) ExecuteLoop(ctx stdcontext.Context) error {
 		}
 	}
 
+	// Output or changes that repeat a recent iteration don't count as progress either
+	if stagnant, reason := c.checkStagnation(output, diffBase); stagnant && filesChanged > 0 {
+		c.emitLog(LogLevelWarn, fmt.Sprintf("No progress: %s (agent reported %d files modified)", reason, filesChanged))
+		filesChanged = 0
+	}
+
 	// Record result in circuit breaker
 	err = c.breaker.RecordResult(c.loopNum, filesChanged, hasErrors)
 	if err != nil {
@@ -606,6 +925,18 @@ func (c *Controller) ExecuteLoop(ctx stdcontext.Context) error {
 		return err
 	}
 
+	// Commit the iteration, or roll it back if it did more harm than good
+	harmful := verificationFailed || c.breaker.ShouldHalt()
+	currentTask := ""
+	if analysisResult != nil && analysisResult.Status != nil {
+		currentTask = analysisResult.Status.CurrentTask
+	}
+	if currentTask != "" {
+		c.currentTask = currentTask
+	}
+	c.finishCheckpoint(cp, currentTask, harmful)
+	c.finishFocusTask(focus, focusFailureReason(focus, analysisResult, verification))
+
 	// Emit outcome event for success case
 	outcome := &LoopOutcome{
 		Success:   true,
@@ -616,6 +947,15 @@ func (c *Controller) ExecuteLoop(ctx stdcontext.Context) error {
 		outcome.FilesModified = analysisResult.Status.FilesModified
 		outcome.TestsStatus = analysisResult.Status.TestsStatus
 	}
+	c.lastTests = nil
+	if analysisResult != nil {
+		outcome.Tests = analysisResult.Tests
+		c.lastTests = analysisResult.Tests
+	}
+	if verification != nil {
+		outcome.Verified = true
+		outcome.VerificationPassed = verification.Passed
+	}
 	c.emitOutcome(outcome)
 
 	// Invalidate cache so next iteration reloads plan
@@ -624,39 +964,298 @@ func (c *Controller) ExecuteLoop(ctx stdcontext.Context) error {
 	return nil
 }
 
-// ShouldContinue checks if the loop should continue
-func (c *Controller) ShouldContinue() bool {
-	tasks, err := LoadPlan()
+// runVerification runs the configured verification gate, if any
+// Returns nil when no gate is configured or the run could not complete
+func (c *Controller) runVerification(ctx stdcontext.Context) *verify.Result {
+	if !c.verifier.Enabled() {
+		return nil
+	}
+
+	c.emitUpdate("verifying")
+	c.emitLog(LogLevelInfo, fmt.Sprintf("Running verification: %s", c.verifier.Command()))
+
+	result, err := c.verifier.Run(ctx)
 	if err != nil {
-		return false
+		c.emitLog(LogLevelWarn, fmt.Sprintf("Verification did not complete: %v", err))
+		return nil
 	}
 
-	// Check if all tasks are complete
-	allComplete := true
-	for _, task := range tasks {
-		if !strings.HasPrefix(task, "[x]") {
-			allComplete = false
+	c.lastVerification = result
+	c.emitVerification(result)
+
+	if result.Passed {
+		c.emitLog(LogLevelSuccess, fmt.Sprintf("✓ %s", result.Summary()))
+	} else {
+		c.emitLog(LogLevelError, fmt.Sprintf("✗ %s", result.Summary()))
+	}
+
+	return result
+}
+
+// revertCompletedTasks un-marks tasks the agent ticked during a failed iteration
+func (c *Controller) revertCompletedTasks(tasksBefore []string, planFile string) {
+	tasksAfter, err := LoadPlan()
+	if err != nil {
+		c.emitLog(LogLevelWarn, fmt.Sprintf("Failed to reload plan for un-marking: %v", err))
+		return
+	}
+
+	completed := newlyCompletedTasks(tasksBefore, tasksAfter)
+	if len(completed) == 0 {
+		return
+	}
+
+	n, err := UncheckTasks(planFile, completed)
+	if err != nil {
+		c.emitLog(LogLevelWarn, fmt.Sprintf("Failed to un-mark tasks: %v", err))
+		return
+	}
+	if n > 0 {
+		c.emitLog(LogLevelWarn, fmt.Sprintf("Un-marked %d task(s) completed during failed verification", n))
+	}
+}
+
+// defaultMaxTaskAttempts is how often focus mode retries a task before marking it BLOCKED
+const defaultMaxTaskAttempts = 3
+
+// selectFocusTask returns the first ready task when focus mode is on, or nil
+func (c *Controller) selectFocusTask() *plan.Task {
+	if !c.focusMode || c.cachedPlan == nil {
+		return nil
+	}
+	ready := c.cachedPlan.Ready()
+	if len(ready) == 0 {
+		return nil
+	}
+	return ready[0]
+}
+
+// taskAttempts returns how many failed attempts a task has used so far
+func (c *Controller) taskAttempts(id string) int {
+	attempts, err := state.LoadTaskAttempts()
+	if err != nil {
+		c.emitLog(LogLevelWarn, fmt.Sprintf("Failed to load task attempts: %v", err))
+		return 0
+	}
+	return attempts[id].Attempts
+}
+
+// finishFocusTask records the outcome of a focused iteration
+// The attempt succeeded if the task is checked in the plan afterwards (after any
+// rollback or un-marking). Otherwise it costs an attempt, and once the budget is spent
+// the task is marked BLOCKED in the plan so the loop can move on.
+func (c *Controller) finishFocusTask(task *plan.Task, reason string) {
+	if task == nil {
+		return
+	}
+
+	attempts, err := state.LoadTaskAttempts()
+	if err != nil {
+		c.emitLog(LogLevelWarn, fmt.Sprintf("Failed to load task attempts: %v", err))
+		attempts = map[string]state.TaskAttempts{}
+	}
+
+	doc, err := LoadPlanDocument()
+	if err != nil {
+		c.emitLog(LogLevelWarn, fmt.Sprintf("Failed to reload plan for focus tracking: %v", err))
+		return
+	}
+
+	current := doc.Find(task.ID)
+	switch {
+	case current == nil:
+		// The agent rewrote the task; its attempts no longer apply
+		c.emitLog(LogLevelWarn, fmt.Sprintf("Focused task no longer in plan: %s", task.Text))
+		delete(attempts, task.ID)
+
+	case current.Checked:
+		delete(attempts, task.ID)
+
+	default:
+		entry := attempts[task.ID]
+		entry.Attempts++
+		entry.LastReason = reason
+		entry.UpdatedAt = time.Now()
+		attempts[task.ID] = entry
+
+		if entry.Attempts < c.maxTaskAttempts {
+			c.emitLog(LogLevelWarn, fmt.Sprintf("Task not completed (attempt %d/%d): %s", entry.Attempts, c.maxTaskAttempts, reason))
+			break
+		}
+
+		doc.SetBlocked(current, reason)
+		if err := doc.Save(); err != nil {
+			c.emitLog(LogLevelError, fmt.Sprintf("Failed to mark task BLOCKED: %v", err))
 			break
 		}
+		delete(attempts, task.ID)
+		c.emitLog(LogLevelWarn, fmt.Sprintf("⊘ Marked BLOCKED after %d attempts: %s (%s)", entry.Attempts, current.Text, reason))
+
+		// Giving up on the task is how the loop makes progress; don't let
+		// the unproductive attempts trip the no-progress check as well
+		if err := c.breaker.ResetProgress(); err != nil {
+			c.emitLog(LogLevelWarn, fmt.Sprintf("Failed to reset circuit breaker progress: %v", err))
+		}
 	}
 
-	if allComplete {
-		c.shouldStop = true
+	if err := state.SaveTaskAttempts(attempts); err != nil {
+		c.emitLog(LogLevelWarn, fmt.Sprintf("Failed to save task attempts: %v", err))
+	}
+}
+
+// spentFocusAttempt reports whether a failed backend call used up an attempt at the
+// focused task. Only a timeout means the agent worked on the task; network errors,
+// exhausted quotas and crashes say nothing about it.
+func spentFocusAttempt(ctx stdcontext.Context, err error) bool {
+	return ctx.Err() == nil && errors.Is(err, stdcontext.DeadlineExceeded)
+}
+
+// focusFailureReason explains why a focused task was not completed
+// Prefers the agent's own explanation: the task's note in its status report, then the
+// blockers or recommendation
+func focusFailureReason(task *plan.Task, result *analysis.Analysis, verification *verify.Result) string {
+	if task != nil && result != nil && result.Report != nil {
+		if outcome, ok := result.Report.Outcome(task.ID); ok && outcome.Outcome != analysis.OutcomeCompleted && strings.TrimSpace(outcome.Note) != "" {
+			return strings.TrimSpace(outcome.Note)
+		}
+	}
+	if result != nil && result.Status != nil {
+		if rec := strings.TrimSpace(result.Status.Recommendation); rec != "" && result.Status.Status == "BLOCKED" {
+			return rec
+		}
+	}
+	if verification != nil && !verification.Passed {
+		return verification.Summary()
+	}
+	if result != nil && result.Status != nil {
+		if rec := strings.TrimSpace(result.Status.Recommendation); rec != "" {
+			return rec
+		}
+		if result.Status.Status == "BLOCKED" {
+			return "agent reported BLOCKED"
+		}
+	}
+	return "task not marked complete"
+}
+
+// countMarkedBlocked counts remaining tasks carrying a BLOCKED marker
+func countMarkedBlocked(doc *plan.Plan) int {
+	n := 0
+	for _, t := range doc.Remaining() {
+		if t.Blocked != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// remainingTaskStrings formats a plan's unchecked tasks for context and preflight
+func remainingTaskStrings(doc *plan.Plan) []string {
+	if doc == nil {
+		return []string{}
+	}
+	return taskStrings(doc.Remaining())
+}
+
+// taskStrings formats tasks in the legacy "[ ] text" form
+func taskStrings(tasks []*plan.Task) []string {
+	out := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		out = append(out, task.String())
+	}
+	return out
+}
+
+// startCheckpoints switches to the run branch on first use
+// Returns false (and disables checkpoints) if that is not possible
+func (c *Controller) startCheckpoints() bool {
+	if c.checkpoints == nil {
+		return false
+	}
+	if c.checkpointsStarted {
 		return true
 	}
 
-	// Check circuit breaker
-	if c.breaker.ShouldHalt() {
+	if err := c.checkpoints.Start(); err != nil {
+		c.emitLog(LogLevelWarn, fmt.Sprintf("Git checkpoints disabled: %v", err))
+		c.checkpoints = nil
+		return false
+	}
+	c.checkpointsStarted = true
+	c.emitLog(LogLevelInfo, fmt.Sprintf("Checkpointing iterations on branch %s", c.checkpoints.Branch()))
+	return true
+}
+
+// beginCheckpoint snapshots the work tree before an iteration
+// Returns nil when checkpointing is disabled or unavailable
+func (c *Controller) beginCheckpoint(loop int) *checkpoint.Checkpoint {
+	if !c.startCheckpoints() {
+		return nil
+	}
+
+	cp, err := c.checkpoints.Begin(loop)
+	if err != nil {
+		c.emitLog(LogLevelWarn, fmt.Sprintf("Failed to create checkpoint: %v", err))
+		return nil
+	}
+
+	return cp
+}
+
+// finishCheckpoint commits the iteration's changes or rolls them back
+func (c *Controller) finishCheckpoint(cp *checkpoint.Checkpoint, task string, harmful bool) {
+	if cp == nil || c.checkpoints == nil {
+		return
+	}
+
+	if harmful && c.rollbackOnFailure {
+		if err := c.checkpoints.Rollback(cp); err != nil {
+			c.emitLog(LogLevelError, fmt.Sprintf("Rollback failed: %v", err))
+			return
+		}
+		c.emitLog(LogLevelWarn, fmt.Sprintf("↺ Rolled back loop %d to %s", cp.Loop, shortSHA(cp.SHA)))
+		return
+	}
+
+	committed, err := c.checkpoints.Commit(cp, task)
+	if err != nil {
+		c.emitLog(LogLevelWarn, fmt.Sprintf("Failed to commit checkpoint: %v", err))
+		return
+	}
+	if committed {
+		c.emitLog(LogLevelInfo, fmt.Sprintf("Checkpoint %s: %s", shortSHA(cp.CommitSHA), cp.Message))
+	}
+}
+
+// shortSHA abbreviates a commit SHA for display
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+// ShouldContinue checks if the loop should continue
+func (c *Controller) ShouldContinue() bool {
+	doc, err := LoadPlanDocument()
+	if err != nil {
+		return false
+	}
+
+	// Check if all tasks are complete
+	if len(doc.Remaining()) == 0 {
 		c.shouldStop = true
 		return true
 	}
 
-	// Check rate limit
-	if !c.rateLimiter.CanMakeCall() {
+	// Check circuit breaker; with a cool-down the next iteration waits and probes instead
+	if c.breaker.ShouldHalt() && !c.breaker.CanRecover() {
 		c.shouldStop = true
 		return true
 	}
 
+	// A full rate-limit window is waited out by the next iteration
+
 	// Check max loops
 	if c.loopNum >= c.config.MaxLoops {
 		c.shouldStop = true
@@ -666,7 +1265,28 @@ func (c *Controller) ShouldContinue() bool {
 	return false
 }
 
+// WaitForRun waits up to timeout for a running Run to return; false if it is still running
+func (c *Controller) WaitForRun(timeout time.Duration) bool {
+	c.runMu.Lock()
+	done := c.runDone
+	c.runMu.Unlock()
+	if done == nil {
+		return true
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
 // GracefulExit performs cleanup before exiting
+// Call it once Run has returned: Run itself records a cancelled run as interrupted, keeping
+// the circuit breaker and backend sessions so "lisa run --resume" can continue
 func (c *Controller) GracefulExit() error {
 	fmt.Println("\n🧹 Performing graceful exit...")
 
@@ -678,15 +1298,10 @@ func (c *Controller) GracefulExit() error {
 			fmt.Printf("Warning: failed to stop runner: %v\n", err)
 		}
 	}
-
-	// Reset circuit breaker
-	if err := c.breaker.Reset(); err != nil {
-		fmt.Printf("Warning: failed to reset circuit breaker: %v\n", err)
-	}
-
-	// Reset session
-	if err := codex.NewSession(); err != nil {
-		return fmt.Errorf("failed to reset session: %w", err)
+	if c.probeRunner != nil && c.probeRunner != c.runner {
+		if err := c.probeRunner.Stop(); err != nil {
+			fmt.Printf("Warning: failed to stop probe runner: %v\n", err)
+		}
 	}
 
 	fmt.Println("✅ Graceful exit complete")
@@ -707,12 +1322,34 @@ func (c *Controller) GetStats() map[string]interface{} {
 // handleCodexEvent processes streaming events from codex and emits them to TUI
 // Uses the unified event parser from the codex package
 func (c *Controller) handleCodexEvent(event codex.Event) {
+	c.handleWorkerEvent(0, event)
+}
+
+// handleWorkerEvent processes a streaming event from the runner of a parallel worker
+// (0 for the serial loop) and emits it tagged with that worker
+func (c *Controller) handleWorkerEvent(worker int, event codex.Event) {
 	// Debug: log raw event type
 	eventType, _ := event["type"].(string)
 	if eventType != "" {
 		c.emitLog(LogLevelDebug, fmt.Sprintf("SSE event: %s", eventType))
 	}
 
+	// Usage counts against the budget; usage events carry nothing else
+	if usage, ok := budget.ParseUsage(event); ok {
+		c.recordUsage(worker, usage)
+		if eventType == budget.UsageEventType {
+			return
+		}
+	}
+
+	// Runner diagnostics, such as the command line under --verbose
+	if eventType == codex.LogEventType {
+		if message, _ := event["message"].(string); message != "" {
+			c.emitWorkerLog(worker, LogLevelInfo, message)
+		}
+		return
+	}
+
 	// Handle context usage events directly (not parsed by codex parser)
 	if eventType == "context.usage" {
 		usagePercent, _ := event["usage_percent"].(float64)
@@ -724,6 +1361,11 @@ func (c *Controller) handleCodexEvent(event codex.Event) {
 		return
 	}
 
+	// A status report sent as a tool call is analyzed with the output
+	if report, ok := analysis.ToolStatusReport(event); ok {
+		c.recordToolReport(worker, report)
+	}
+
 	parsed := codex.ParseEvent(event)
 	if parsed == nil {
 		return
@@ -738,33 +1380,37 @@ func (c *Controller) handleCodexEvent(event codex.Event) {
 	switch parsed.Type {
 	case "reasoning":
 		if parsed.Text != "" {
-			c.emitCodexReasoning(parsed.Text)
+			c.emitCodexReasoning(worker, parsed.Text)
 		}
 
 	case "message", "delta":
 		if parsed.Text != "" {
-			c.emitCodexOutput(parsed.Text, OutputTypeAgentMessage)
+			c.emitCodexOutput(worker, parsed.Text, OutputTypeAgentMessage)
 		}
 
 	case "tool_call", "tool_result":
+		// Tool calls are evidence of work; reporting status is not
+		if parsed.Type == "tool_call" && parsed.ToolName != analysis.StatusTool {
+			c.recordToolCall(worker)
+		}
 		if parsed.ToolName != "" {
 			status := ToolStatusStarted
 			if parsed.ToolStatus == "completed" {
 				status = ToolStatusCompleted
 			}
-			c.emitCodexTool(parsed.ToolName, parsed.ToolTarget, status)
+			c.emitCodexTool(worker, parsed.ToolName, parsed.ToolTarget, status)
 		}
 
 	case "lifecycle":
 		// Lifecycle events (start, stop, etc.) - just show the type
 		if parsed.RawType != "" {
-			c.emitCodexOutput(fmt.Sprintf(">>> %s", parsed.RawType), OutputTypeRaw)
+			c.emitCodexOutput(worker, fmt.Sprintf(">>> %s", parsed.RawType), OutputTypeRaw)
 		}
 
 	default:
 		// Unknown event with text
 		if parsed.Text != "" {
-			c.emitCodexOutput(parsed.Text, OutputTypeRaw)
+			c.emitCodexOutput(worker, parsed.Text, OutputTypeRaw)
 		}
 	}
 }
@@ -1,11 +1,20 @@
 package loop
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/brainwhocodes/lisa-loop/internal/circuit"
+	"github.com/brainwhocodes/lisa-loop/internal/codex"
+	"github.com/brainwhocodes/lisa-loop/internal/git"
+	"github.com/brainwhocodes/lisa-loop/internal/runner"
+	"github.com/brainwhocodes/lisa-loop/internal/state"
+	"github.com/brainwhocodes/lisa-loop/internal/verify"
 )
 
 func TestRunPreflight(t *testing.T) {
@@ -122,6 +131,45 @@ func TestRunPreflight_MaxLoops(t *testing.T) {
 	}
 }
 
+func TestRunPreflight_Dependencies(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	planContent := `- [ ] Schema <!-- id: T1 -->
+- [ ] Migrations (after: T1) <!-- id: T2 -->
+- [ ] Seed (after: T9) <!-- id: T3 -->
+`
+	os.WriteFile("@fix_plan.md", []byte(planContent), 0644)
+	os.WriteFile("PROMPT.md", []byte("Test prompt"), 0644)
+
+	controller := NewController(Config{MaxCalls: 5, Backend: "cli"}, NewRateLimiter(10, 1), circuit.NewBreaker(3, 5))
+
+	summary, shouldSkip := controller.RunPreflight()
+	if shouldSkip {
+		t.Fatalf("RunPreflight() shouldSkip = true (%s), want false", summary.SkipReason)
+	}
+	if summary.ReadyCount != 1 || summary.BlockedCount != 2 {
+		t.Errorf("RunPreflight() ready/blocked = %d/%d, want 1/2", summary.ReadyCount, summary.BlockedCount)
+	}
+	if len(summary.PlanErrors) != 1 || !strings.Contains(summary.PlanErrors[0], "unknown task T9") {
+		t.Errorf("RunPreflight() PlanErrors = %v, want unknown task T9", summary.PlanErrors)
+	}
+
+	// Everything left waits on a prerequisite that can never be met
+	controller.cacheValid = false
+	os.WriteFile("@fix_plan.md", []byte("- [ ] Seed (after: T9) <!-- id: T3 -->\n"), 0644)
+
+	summary, shouldSkip = controller.RunPreflight()
+	if !shouldSkip {
+		t.Fatal("RunPreflight() shouldSkip = false, want true when every task is blocked")
+	}
+	if !strings.Contains(summary.SkipReason, "blocked") {
+		t.Errorf("RunPreflight() SkipReason = %s, want blocked reason", summary.SkipReason)
+	}
+}
+
 func TestRunPreflight_NoPlanFile(t *testing.T) {
 	tmpDir := t.TempDir()
 	origDir, _ := os.Getwd()
@@ -188,6 +236,11 @@ func TestPreflightSummary_TasksToShow(t *testing.T) {
 }
 
 func TestEmitPreflight(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
 	rateLimiter := NewRateLimiter(10, 1)
 	breaker := circuit.NewBreaker(3, 5)
 
@@ -235,7 +288,37 @@ func TestEmitPreflight(t *testing.T) {
 	}
 }
 
+func TestHandleCodexEvent_LogEvent(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	controller := NewController(Config{MaxCalls: 5, Backend: "command"}, NewRateLimiter(10, 1), circuit.NewBreaker(3, 5))
+	var logs, outputs []string
+	controller.SetEventCallback(func(event LoopEvent) {
+		switch event.Type {
+		case EventTypeLog:
+			if event.LogLevel == LogLevelInfo {
+				logs = append(logs, event.LogMessage)
+			}
+		case EventTypeCodexOutput:
+			outputs = append(outputs, event.OutputLine)
+		}
+	})
+
+	controller.handleCodexEvent(codex.LogEvent("Executing: agent --json"))
+	if len(logs) != 1 || logs[0] != "Executing: agent --json" || len(outputs) != 0 {
+		t.Errorf("logs = %q, outputs = %q, want the message logged and not shown as output", logs, outputs)
+	}
+}
+
 func TestEmitOutcome(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
 	rateLimiter := NewRateLimiter(10, 1)
 	breaker := circuit.NewBreaker(3, 5)
 
@@ -327,3 +410,309 @@ func TestRateLimiter_RecordSuccessfulCall(t *testing.T) {
 		t.Errorf("After RecordCall, CallsMade = %d, want 1", rateLimiter.CallsMade())
 	}
 }
+
+// planMarkingRunner is a test double that marks the first unchecked task complete
+type planMarkingRunner struct {
+	planFile string
+	output   string
+}
+
+func (r *planMarkingRunner) Run(ctx context.Context, prompt string) (string, string, error) {
+	data, err := os.ReadFile(r.planFile)
+	if err != nil {
+		return "", "", err
+	}
+	content := strings.Replace(string(data), "- [ ]", "- [x]", 1)
+	if err := os.WriteFile(r.planFile, []byte(content), 0644); err != nil {
+		return "", "", err
+	}
+	return r.output, "test-session", nil
+}
+
+func (r *planMarkingRunner) SetOutputCallback(cb runner.OutputCallback) {}
+
+func (r *planMarkingRunner) Stop() error { return nil }
+
+func TestExecuteLoop_VerificationGate(t *testing.T) {
+	statusOutput := `---RALPH_STATUS---
+STATUS: WORKING
+TASKS_COMPLETED_THIS_LOOP: 1
+FILES_MODIFIED: 2
+TESTS_STATUS: PASSING
+EXIT_SIGNAL: false
+---END_RALPH_STATUS---`
+
+	tests := []struct {
+		name          string
+		command       string
+		uncheck       bool
+		wantPassed    bool
+		wantRemaining int
+	}{
+		{name: "passing gate keeps marks", command: "true", uncheck: true, wantPassed: true, wantRemaining: 1},
+		{name: "failing gate un-marks tasks", command: "echo boom; exit 1", uncheck: true, wantPassed: false, wantRemaining: 2},
+		{name: "failing gate without uncheck", command: "exit 1", uncheck: false, wantPassed: false, wantRemaining: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			origDir, _ := os.Getwd()
+			os.Chdir(tmpDir)
+			defer os.Chdir(origDir)
+
+			os.WriteFile("@fix_plan.md", []byte("- [ ] First task\n- [ ] Second task\n"), 0644)
+			os.WriteFile("PROMPT.md", []byte("Test prompt"), 0644)
+
+			cfg := Config{
+				MaxCalls:            5,
+				Backend:             "cli",
+				VerifyCommand:       tt.command,
+				VerifyUncheckOnFail: tt.uncheck,
+			}
+			controller := NewController(cfg, NewRateLimiter(10, 1), circuit.NewBreaker(3, 5))
+			controller.SetRunner(&planMarkingRunner{planFile: "@fix_plan.md", output: statusOutput})
+
+			var verification *verify.Result
+			var outcome *LoopOutcome
+			var testsStatus string
+			controller.SetEventCallback(func(event LoopEvent) {
+				switch event.Type {
+				case EventTypeVerification:
+					verification = event.Verification
+				case EventTypeOutcome:
+					outcome = event.Outcome
+				case EventTypeAnalysis:
+					testsStatus = event.TestsStatus
+				}
+			})
+
+			if err := controller.ExecuteLoop(context.Background()); err != nil {
+				t.Fatalf("ExecuteLoop() error = %v", err)
+			}
+
+			if verification == nil {
+				t.Fatal("ExecuteLoop() did not emit a verification event")
+			}
+			if verification.Passed != tt.wantPassed {
+				t.Errorf("Verification.Passed = %v, want %v", verification.Passed, tt.wantPassed)
+			}
+			if outcome == nil || !outcome.Verified || outcome.VerificationPassed != tt.wantPassed {
+				t.Errorf("Outcome verification = %+v, want Verified=true Passed=%v", outcome, tt.wantPassed)
+			}
+			if !tt.wantPassed && testsStatus != "FAILING" {
+				t.Errorf("Analysis TestsStatus = %s, want FAILING after failed verification", testsStatus)
+			}
+
+			tasks, _ := LoadPlan()
+			remaining := 0
+			for _, task := range tasks {
+				if !strings.HasPrefix(task, "[x]") {
+					remaining++
+				}
+			}
+			if remaining != tt.wantRemaining {
+				t.Errorf("remaining tasks = %d, want %d", remaining, tt.wantRemaining)
+			}
+		})
+	}
+}
+
+func TestExecuteLoop_Replay(t *testing.T) {
+	statusOutput := `---RALPH_STATUS---
+STATUS: WORKING
+TASKS_COMPLETED_THIS_LOOP: 1
+FILES_MODIFIED: 3
+TESTS_STATUS: PASSING
+EXIT_SIGNAL: false
+---END_RALPH_STATUS---`
+
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	os.WriteFile("@fix_plan.md", []byte("- [ ] First task\n- [ ] Second task\n"), 0644)
+	os.WriteFile("PROMPT.md", []byte("Test prompt"), 0644)
+
+	// Record a loop against a real runner, then replay it with no agent
+	recordDir := filepath.Join(tmpDir, "recording")
+	recorder := runner.NewRecorder(&planMarkingRunner{planFile: "@fix_plan.md", output: statusOutput}, recordDir, "cli")
+	controller := NewController(Config{MaxCalls: 5, Backend: "cli"}, NewRateLimiter(10, 1), circuit.NewBreaker(3, 5))
+	controller.SetRunner(recorder)
+	if err := controller.ExecuteLoop(context.Background()); err != nil {
+		t.Fatalf("recorded ExecuteLoop() error = %v", err)
+	}
+
+	cfg := Config{MaxCalls: 5, Backend: "replay", ReplayDir: recordDir}
+	controller = NewController(cfg, NewRateLimiter(10, 1), circuit.NewBreaker(3, 5))
+
+	var status string
+	var filesModified int
+	controller.SetEventCallback(func(event LoopEvent) {
+		if event.Type == EventTypeAnalysis {
+			status = event.AnalysisStatus
+			filesModified = event.FilesModified
+		}
+	})
+	if err := controller.ExecuteLoop(context.Background()); err != nil {
+		t.Fatalf("replayed ExecuteLoop() error = %v", err)
+	}
+
+	if status != "WORKING" || filesModified != 3 {
+		t.Errorf("replayed analysis = %s/%d files, want WORKING/3", status, filesModified)
+	}
+}
+
+func TestExecuteLoop_Checkpoint(t *testing.T) {
+	if !git.Available() {
+		t.Skip("git not installed")
+	}
+
+	statusOutput := `---RALPH_STATUS---
+STATUS: WORKING
+CURRENT_TASK: First task
+TASKS_COMPLETED_THIS_LOOP: 1
+FILES_MODIFIED: 1
+TESTS_STATUS: PASSING
+EXIT_SIGNAL: false
+---END_RALPH_STATUS---`
+
+	tests := []struct {
+		name          string
+		command       string
+		wantRemaining int
+		wantSubject   string
+	}{
+		{name: "passing loop is committed", command: "true", wantRemaining: 1, wantSubject: "lisa: loop 1: First task"},
+		{name: "failing loop is rolled back", command: "exit 1", wantRemaining: 2, wantSubject: "lisa: initial snapshot"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			origDir, _ := os.Getwd()
+			os.Chdir(tmpDir)
+			defer os.Chdir(origDir)
+
+			os.WriteFile("@fix_plan.md", []byte("- [ ] First task\n- [ ] Second task\n"), 0644)
+			os.WriteFile("PROMPT.md", []byte("Test prompt"), 0644)
+			if _, err := git.Run("", "init", "-q"); err != nil {
+				t.Fatalf("git init: %v", err)
+			}
+
+			cfg := Config{
+				MaxCalls:          5,
+				Backend:           "cli",
+				VerifyCommand:     tt.command,
+				Checkpoint:        true,
+				RollbackOnFailure: true,
+			}
+			controller := NewController(cfg, NewRateLimiter(10, 1), circuit.NewBreaker(3, 5))
+			controller.SetRunner(&planMarkingRunner{planFile: "@fix_plan.md", output: statusOutput})
+
+			if err := controller.ExecuteLoop(context.Background()); err != nil {
+				t.Fatalf("ExecuteLoop() error = %v", err)
+			}
+
+			tasks, _ := LoadPlan()
+			remaining := 0
+			for _, task := range tasks {
+				if !strings.HasPrefix(task, "[x]") {
+					remaining++
+				}
+			}
+			if remaining != tt.wantRemaining {
+				t.Errorf("remaining tasks = %d, want %d", remaining, tt.wantRemaining)
+			}
+
+			subject, _ := git.Run("", "log", "-1", "--format=%s")
+			if !strings.HasPrefix(subject, tt.wantSubject) {
+				t.Errorf("HEAD subject = %q, want prefix %q", subject, tt.wantSubject)
+			}
+		})
+	}
+}
+
+// stuckRunner is a test double that never completes its task
+// It rewrites touchFile, if set, so its loops still change something
+type stuckRunner struct {
+	output    string
+	touchFile string
+	prompts   []string
+}
+
+func (r *stuckRunner) Run(ctx context.Context, prompt string) (string, string, error) {
+	r.prompts = append(r.prompts, prompt)
+	if r.touchFile != "" {
+		os.WriteFile(r.touchFile, []byte(fmt.Sprintf("attempt %d\n", len(r.prompts))), 0644)
+	}
+	return r.output, "test-session", nil
+}
+
+func (r *stuckRunner) SetOutputCallback(cb runner.OutputCallback) {}
+
+func (r *stuckRunner) Stop() error { return nil }
+
+func TestExecuteLoop_FocusMode(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	os.WriteFile("@fix_plan.md", []byte("- [ ] First task\n- [ ] Second task\n"), 0644)
+	os.WriteFile("PROMPT.md", []byte("Test prompt"), 0644)
+
+	cfg := Config{
+		MaxCalls:        10,
+		Backend:         "cli",
+		FocusMode:       true,
+		MaxTaskAttempts: 2,
+	}
+	breaker := circuit.NewBreaker(3, 5)
+	controller := NewController(cfg, NewRateLimiter(10, 1), breaker)
+	stuck := &stuckRunner{output: `---RALPH_STATUS---
+STATUS: BLOCKED
+FILES_MODIFIED: 0
+RECOMMENDATION: missing API credentials
+---END_RALPH_STATUS---`}
+	controller.SetRunner(stuck)
+
+	var focused []string
+	controller.SetEventCallback(func(event LoopEvent) {
+		if event.Type == EventTypeFocus {
+			focused = append(focused, fmt.Sprintf("%s#%d", event.CurrentTask, event.TaskAttempt))
+		}
+	})
+
+	for i := 0; i < 3; i++ {
+		if err := controller.ExecuteLoop(context.Background()); err != nil {
+			t.Fatalf("ExecuteLoop() #%d error = %v", i+1, err)
+		}
+		controller.loopNum++
+	}
+
+	want := []string{"First task#1", "First task#2", "Second task#1"}
+	if !reflect.DeepEqual(focused, want) {
+		t.Errorf("focused tasks = %v, want %v", focused, want)
+	}
+
+	if !strings.Contains(stuck.prompts[0], "Current Task (attempt 1 of 2)") || strings.Contains(stuck.prompts[0], "Second task") {
+		t.Errorf("focus prompt should offer only the selected task:\n%s", stuck.prompts[0])
+	}
+
+	data, _ := os.ReadFile("@fix_plan.md")
+	if !strings.Contains(string(data), "- [ ] First task (BLOCKED: missing API credentials)") {
+		t.Errorf("plan = %q, want first task marked BLOCKED with the agent's reason", string(data))
+	}
+
+	attempts, _ := state.LoadTaskAttempts()
+	if len(attempts) != 1 {
+		t.Errorf("task attempts = %+v, want only the second task tracked", attempts)
+	}
+
+	// Three loops without progress would normally half-open the breaker
+	if !breaker.IsClosed() {
+		t.Errorf("breaker state = %s, want CLOSED after giving up on the stuck task", breaker.GetState())
+	}
+}
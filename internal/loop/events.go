@@ -16,6 +16,10 @@ const (
 	EventTypeContextUsage   EventType = "context_usage" // Context window usage tracking
 	EventTypePreflight      EventType = "preflight"     // Preflight check summary
 	EventTypeOutcome        EventType = "outcome"       // Loop iteration outcome
+	EventTypeVerification   EventType = "verification"  // Verification gate result
+	EventTypeFocus          EventType = "focus"         // Task selected by focus mode
+	EventTypeWorker         EventType = "worker"        // Parallel worker lane status
+	EventTypeUsage          EventType = "usage"         // Tokens and cost reported by the backend
 )
 
 // LogLevel represents the severity level of a log entry
@@ -0,0 +1,292 @@
+package loop
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/brainwhocodes/lisa-loop/internal/journal"
+)
+
+// journalEvent appends an event to the run journal
+// Called with emitMu held; a journal that fails is reported once and then skipped
+func (c *Controller) journalEvent(event LoopEvent) {
+	if c.journalFailed {
+		return
+	}
+
+	err := func() error {
+		if c.journal == nil {
+			w, err := journal.Open("")
+			if err != nil {
+				return err
+			}
+			c.journal = w
+		}
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		return c.journal.Append(journal.Record{
+			Time:  time.Now(),
+			RunID: c.runID,
+			Loop:  c.journalLoop(),
+			Type:  string(event.Type),
+			Event: data,
+		})
+	}()
+	if err == nil {
+		return
+	}
+
+	c.journalFailed = true
+	if c.eventCallback != nil {
+		c.eventCallback(LoopEvent{
+			Type:       EventTypeLog,
+			LogMessage: fmt.Sprintf("Run journal disabled: %v", err),
+			LogLevel:   LogLevelWarn,
+		})
+	}
+}
+
+// journalLoop is the 1-based iteration an event belongs to
+// The serial loop counts finished iterations; parallel mode counts dispatched tasks
+func (c *Controller) journalLoop() int {
+	if c.parallel > 1 {
+		return c.loopNum
+	}
+	return c.loopNum + 1
+}
+
+// closeJournal closes the run journal; the next event reopens it
+func (c *Controller) closeJournal() {
+	c.emitMu.Lock()
+	defer c.emitMu.Unlock()
+	if c.journal != nil {
+		c.journal.Close()
+		c.journal = nil
+	}
+}
+
+// RunSummary summarizes one journaled run
+type RunSummary struct {
+	RunID          string
+	Started        time.Time
+	Ended          time.Time // Time of the last journaled event
+	Iterations     int       // Loop iterations (or parallel tasks) that produced an outcome
+	TasksCompleted int
+	ExitReason     string
+	Events         int
+}
+
+// Duration returns the time between the run's first and last events
+func (r RunSummary) Duration() time.Duration {
+	return r.Ended.Sub(r.Started)
+}
+
+// JournalEntry is one event from a run's journal
+type JournalEntry struct {
+	Time  time.Time
+	Loop  int
+	Event LoopEvent
+}
+
+// LoadRunHistory summarizes every run in the journal of project dir, most recent first
+func LoadRunHistory(projectDir string) ([]RunSummary, error) {
+	runs, err := loadJournalRuns(projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]RunSummary, 0, len(runs))
+	for id, entries := range runs {
+		summaries = append(summaries, summarizeRun(id, entries))
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Started.After(summaries[j].Started)
+	})
+	return summaries, nil
+}
+
+// LoadRunEvents returns the summary and timeline of one run
+// runID may be a unique prefix; an empty runID selects the most recent run
+func LoadRunEvents(projectDir, runID string) (RunSummary, []JournalEntry, error) {
+	runs, err := loadJournalRuns(projectDir)
+	if err != nil {
+		return RunSummary{}, nil, err
+	}
+	if len(runs) == 0 {
+		return RunSummary{}, nil, fmt.Errorf("no runs journaled yet")
+	}
+
+	var matches []string
+	if runID == "" {
+		latest := ""
+		for id, entries := range runs {
+			if latest == "" || entries[0].Time.After(runs[latest][0].Time) {
+				latest = id
+			}
+		}
+		matches = []string{latest}
+	} else if _, ok := runs[runID]; ok {
+		matches = []string{runID}
+	} else {
+		for id := range runs {
+			if strings.HasPrefix(id, runID) {
+				matches = append(matches, id)
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return RunSummary{}, nil, fmt.Errorf("no run matches %q", runID)
+	case 1:
+	default:
+		sort.Strings(matches)
+		return RunSummary{}, nil, fmt.Errorf("run %q is ambiguous: %s", runID, strings.Join(matches, ", "))
+	}
+
+	entries := runs[matches[0]]
+	return summarizeRun(matches[0], entries), entries, nil
+}
+
+// loadJournalRuns groups journal records by run ID, in journal order
+func loadJournalRuns(projectDir string) (map[string][]JournalEntry, error) {
+	records, err := journal.Read(projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	runs := make(map[string][]JournalEntry)
+	for _, rec := range records {
+		var event LoopEvent
+		if err := json.Unmarshal(rec.Event, &event); err != nil {
+			continue
+		}
+		runs[rec.RunID] = append(runs[rec.RunID], JournalEntry{Time: rec.Time, Loop: rec.Loop, Event: event})
+	}
+	return runs, nil
+}
+
+func summarizeRun(id string, entries []JournalEntry) RunSummary {
+	summary := RunSummary{RunID: id, Events: len(entries)}
+	if len(entries) == 0 {
+		return summary
+	}
+	summary.Started = entries[0].Time
+	summary.Ended = entries[len(entries)-1].Time
+
+	var lastUpdate *LoopEvent
+	var lastPreflight *PreflightSummary
+	for i := range entries {
+		event := &entries[i].Event
+		switch event.Type {
+		case EventTypeOutcome:
+			summary.Iterations++
+			if event.Outcome != nil {
+				summary.TasksCompleted += event.Outcome.TasksCompleted
+			}
+		case EventTypeLoopUpdate:
+			lastUpdate = event
+		case EventTypePreflight:
+			lastPreflight = event.Preflight
+		}
+	}
+	summary.ExitReason = exitReason(lastUpdate, lastPreflight)
+	return summary
+}
+
+// exitReason derives why a run ended from its last status update
+func exitReason(lastUpdate *LoopEvent, lastPreflight *PreflightSummary) string {
+	if lastUpdate == nil {
+		return "interrupted"
+	}
+	switch lastUpdate.Status {
+	case "complete":
+		if lastUpdate.CircuitState == "OPEN" {
+			return "circuit open"
+		}
+		return "complete"
+	case "skipped":
+		if lastPreflight != nil && lastPreflight.SkipReason != "" {
+			return "skipped: " + lastPreflight.SkipReason
+		}
+		return "skipped"
+	case "stopped", "cancelled":
+		return lastUpdate.Status
+	}
+	// The process exited (or is still running) without a final status
+	return "interrupted"
+}
+
+// Describe summarizes an event on one line for timelines
+func (e LoopEvent) Describe() string {
+	switch e.Type {
+	case EventTypeLoopUpdate:
+		return fmt.Sprintf("%s (circuit %s, %d calls)", e.Status, e.CircuitState, e.CallsUsed)
+	case EventTypeLog:
+		return fmt.Sprintf("[%s] %s", e.LogLevel, e.LogMessage)
+	case EventTypeStateChange:
+		return fmt.Sprintf("circuit %s → %s: %s", e.PrevCircuitState, e.CircuitState, e.LogMessage)
+	case EventTypeCodexOutput:
+		return e.OutputLine
+	case EventTypeCodexReasoning:
+		return e.ReasoningText
+	case EventTypeCodexTool:
+		return strings.TrimSpace(fmt.Sprintf("%s %s (%s)", e.ToolName, e.ToolTarget, e.ToolStatus))
+	case EventTypeAnalysis:
+		return fmt.Sprintf("%s: %d tasks, %d files, tests %s, exit signal %v", e.AnalysisStatus, e.TasksCompleted, e.FilesModified, e.TestsStatus, e.ExitSignal)
+	case EventTypeContextUsage:
+		return fmt.Sprintf("context %.0f%% (%d/%d tokens)", e.ContextUsagePercent*100, e.ContextTotalTokens, e.ContextLimit)
+	case EventTypePreflight:
+		if e.Preflight == nil {
+			return ""
+		}
+		return fmt.Sprintf("%d/%d tasks remaining (%d ready), circuit %s", e.Preflight.RemainingCount, e.Preflight.TotalTasks, e.Preflight.ReadyCount, e.Preflight.CircuitState)
+	case EventTypeOutcome:
+		if e.Outcome == nil {
+			return ""
+		}
+		if e.Outcome.Error != "" {
+			return "failed: " + e.Outcome.Error
+		}
+		result := "succeeded"
+		if !e.Outcome.Success {
+			result = "failed"
+		}
+		return fmt.Sprintf("%s: %d tasks, %d files, tests %s", result, e.Outcome.TasksCompleted, e.Outcome.FilesModified, e.Outcome.TestsStatus)
+	case EventTypeVerification:
+		if e.Verification == nil {
+			return ""
+		}
+		result := "passed"
+		if !e.Verification.Passed {
+			result = "failed"
+		}
+		return fmt.Sprintf("%s %s (exit %d, %s)", e.Verification.Command, result, e.Verification.ExitCode, e.Verification.Duration.Round(time.Millisecond))
+	case EventTypeFocus:
+		return fmt.Sprintf("%s (attempt %d)", e.CurrentTask, e.TaskAttempt)
+	case EventTypeWorker:
+		return strings.TrimSpace(fmt.Sprintf("worker %d %s: %s %s", e.Worker, e.Status, e.CurrentTask, e.LogMessage))
+	case EventTypeUsage:
+		if e.Budget == nil {
+			return ""
+		}
+		return "spent " + e.Budget.Summary()
+	}
+	return e.Status
+}
+
+// IsDetail reports whether the event is agent output or debug logging rather than a loop milestone
+func (e LoopEvent) IsDetail() bool {
+	switch e.Type {
+	case EventTypeCodexOutput, EventTypeCodexReasoning, EventTypeContextUsage, EventTypeUsage:
+		return true
+	case EventTypeLog:
+		return e.LogLevel == LogLevelDebug
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package loop
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/brainwhocodes/lisa-loop/internal/circuit"
+)
+
+func TestRunJournal(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	os.WriteFile("@fix_plan.md", []byte("- [ ] First task\n- [ ] Second task\n"), 0644)
+	os.WriteFile("PROMPT.md", []byte("Test prompt"), 0644)
+
+	statusOutput := `---RALPH_STATUS---
+STATUS: WORKING
+TASKS_COMPLETED_THIS_LOOP: 1
+FILES_MODIFIED: 1
+TESTS_STATUS: PASSING
+EXIT_SIGNAL: false
+---END_RALPH_STATUS---`
+
+	controller := NewController(Config{MaxCalls: 5, Backend: "cli"}, NewRateLimiter(10, 1), circuit.NewBreaker(3, 5))
+	controller.SetRunner(&planMarkingRunner{planFile: "@fix_plan.md", output: statusOutput})
+
+	// Events are journaled with or without a callback
+	if err := controller.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	history, err := LoadRunHistory("")
+	if err != nil {
+		t.Fatalf("LoadRunHistory() error = %v", err)
+	}
+	if len(history) != 1 {
+		t.Fatalf("LoadRunHistory() = %d runs, want 1", len(history))
+	}
+	run := history[0]
+	if run.RunID != controller.RunID() {
+		t.Errorf("RunID = %q, want %q", run.RunID, controller.RunID())
+	}
+	if run.Iterations != 2 || run.TasksCompleted != 2 || run.ExitReason != "complete" {
+		t.Errorf("summary = %+v, want 2 iterations, 2 tasks, complete", run)
+	}
+
+	summary, entries, err := LoadRunEvents("", run.RunID[:4])
+	if err != nil {
+		t.Fatalf("LoadRunEvents() error = %v", err)
+	}
+	if summary.RunID != run.RunID || len(entries) != run.Events {
+		t.Errorf("LoadRunEvents() = %s with %d entries, want %s with %d", summary.RunID, len(entries), run.RunID, run.Events)
+	}
+
+	loops := map[int]bool{}
+	for _, entry := range entries {
+		if entry.Event.Type == EventTypeOutcome {
+			loops[entry.Loop] = true
+		}
+	}
+	if !loops[1] || !loops[2] {
+		t.Errorf("outcome loops = %v, want loops 1 and 2", loops)
+	}
+
+	if _, _, err := LoadRunEvents("", "nope"); err == nil {
+		t.Error("LoadRunEvents() of an unknown run should fail")
+	}
+}
+
+func TestExitReason(t *testing.T) {
+	tests := []struct {
+		name      string
+		update    *LoopEvent
+		preflight *PreflightSummary
+		want      string
+	}{
+		{"no status", nil, nil, "interrupted"},
+		{"still running", &LoopEvent{Status: "executing"}, nil, "interrupted"},
+		{"complete", &LoopEvent{Status: "complete", CircuitState: "CLOSED"}, nil, "complete"},
+		{"circuit opened", &LoopEvent{Status: "complete", CircuitState: "OPEN"}, nil, "circuit open"},
+		{"skipped", &LoopEvent{Status: "skipped"}, &PreflightSummary{SkipReason: "All tasks complete"}, "skipped: All tasks complete"},
+		{"cancelled", &LoopEvent{Status: "cancelled"}, nil, "cancelled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitReason(tt.update, tt.preflight); got != tt.want {
+				t.Errorf("exitReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
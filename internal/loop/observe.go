@@ -0,0 +1,249 @@
+package loop
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/brainwhocodes/lisa-loop/internal/analysis"
+	"github.com/brainwhocodes/lisa-loop/internal/checkpoint"
+	"github.com/brainwhocodes/lisa-loop/internal/git"
+	"github.com/brainwhocodes/lisa-loop/internal/plan"
+	"github.com/brainwhocodes/lisa-loop/internal/state"
+)
+
+// maxObservedFiles bounds the modification time scan outside a git repository
+const maxObservedFiles = 20000
+
+// fileStamp is what a file looked like when a work tree snapshot was taken
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// workSnapshot records the work tree and plan before a loop, so what the loop
+// actually changed can be checked against what the agent claims
+type workSnapshot struct {
+	dir      string               // Project directory
+	base     string               // Commit tracked files are diffed against ("" outside git)
+	stamps   map[string]fileStamp // Files git cannot diff: untracked files, or every file outside git
+	filesOK  bool
+	tasks    []string // Plan tasks, as task strings
+	planFile string
+}
+
+// snapshotWork takes a snapshot of the work tree in dir and of planFile ("" to leave
+// the plan out) before a loop, diffing against the loop's checkpoint when there is one
+func (c *Controller) snapshotWork(dir string, cp *checkpoint.Checkpoint, planFile string) *workSnapshot {
+	snap := &workSnapshot{dir: dir, planFile: planFile}
+	if planFile != "" {
+		if doc, err := plan.Load(filepath.Join(dir, planFile)); err == nil {
+			snap.tasks = doc.Strings()
+		}
+	}
+
+	if !git.IsRepo(dir) {
+		stamps, err := stampFiles(dir, nil)
+		if err != nil {
+			c.emitLog(LogLevelDebug, fmt.Sprintf("Cannot observe work tree: %v", err))
+			return snap
+		}
+		snap.stamps, snap.filesOK = stamps, true
+		return snap
+	}
+
+	base := ""
+	if cp != nil {
+		base = cp.SHA
+	} else if sha, err := git.Snapshot(dir); err == nil {
+		base = sha
+	}
+	if base == "" {
+		c.emitLog(LogLevelDebug, "Cannot observe work tree: no commit to diff against")
+		return snap
+	}
+
+	// Untracked files are listed whether or not the loop touched them
+	changes, err := git.ChangedFiles(dir, base, state.Dir)
+	if err != nil {
+		c.emitLog(LogLevelDebug, fmt.Sprintf("Cannot observe work tree: %v", err))
+		return snap
+	}
+	var untracked []string
+	for _, change := range changes {
+		if change.Status == "?" {
+			untracked = append(untracked, change.Path)
+		}
+	}
+	stamps, err := stampFiles(dir, untracked)
+	if err != nil {
+		c.emitLog(LogLevelDebug, fmt.Sprintf("Cannot observe work tree: %v", err))
+		return snap
+	}
+	snap.base, snap.stamps, snap.filesOK = base, stamps, true
+	return snap
+}
+
+// observe compares the work tree and plan with the snapshot and counts the worker's
+// tool calls during the loop
+func (c *Controller) observe(worker int, snap *workSnapshot) analysis.Observation {
+	toolCalls, toolsKnown := c.takeToolCalls(worker)
+	obs := analysis.Observation{ToolCalls: toolCalls, ToolsKnown: toolsKnown}
+	if snap == nil {
+		return obs
+	}
+
+	if snap.filesOK {
+		files, err := snap.changedFiles()
+		if err != nil {
+			c.emitWorkerLog(worker, LogLevelDebug, fmt.Sprintf("Cannot observe work tree: %v", err))
+		} else {
+			obs.FilesChanged, obs.FilesKnown = files, true
+		}
+	}
+
+	if snap.tasks != nil && snap.planFile != "" {
+		if doc, err := plan.Load(filepath.Join(snap.dir, snap.planFile)); err == nil {
+			obs.TasksChecked = len(newlyCompletedTasks(snap.tasks, doc.Strings()))
+			obs.TasksUnchecked = len(doc.Remaining())
+			obs.PlanKnown = true
+		}
+	}
+	return obs
+}
+
+// changedFiles lists the files that changed since the snapshot
+func (s *workSnapshot) changedFiles() ([]string, error) {
+	var files []string
+	if s.base == "" {
+		now, err := stampFiles(s.dir, nil)
+		if err != nil {
+			return nil, err
+		}
+		for path, stamp := range now {
+			if before, ok := s.stamps[path]; !ok || before != stamp {
+				files = append(files, path)
+			}
+		}
+		for path := range s.stamps {
+			if _, ok := now[path]; !ok {
+				files = append(files, path)
+			}
+		}
+		return files, nil
+	}
+
+	changes, err := git.ChangedFiles(s.dir, s.base, state.Dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range changes {
+		before, existed := s.stamps[change.Path]
+		if change.Status == "?" && existed {
+			if now, err := stampFile(filepath.Join(s.dir, change.Path)); err == nil && now == before {
+				continue
+			}
+		}
+		files = append(files, change.Path)
+	}
+	return files, nil
+}
+
+// stampFiles records the given files in dir, or every project file when paths is nil
+// Hidden directories, the state directory and node_modules are skipped
+func stampFiles(dir string, paths []string) (map[string]fileStamp, error) {
+	root := dir
+	if root == "" {
+		root = "."
+	}
+	stamps := make(map[string]fileStamp)
+
+	if paths != nil {
+		for _, path := range paths {
+			if stamp, err := stampFile(filepath.Join(root, path)); err == nil {
+				stamps[path] = stamp
+			}
+		}
+		return stamps, nil
+	}
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(name, ".") || name == state.Dir || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if len(stamps) >= maxObservedFiles {
+			return fmt.Errorf("more than %d files", maxObservedFiles)
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		stamps[filepath.ToSlash(rel)] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stamps, nil
+}
+
+// stampFile records one file
+func stampFile(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// recordToolCall counts a tool call made by the agent of a parallel worker (0 for the
+// serial loop); once any is seen the backend is known to stream its tool calls
+func (c *Controller) recordToolCall(worker int) {
+	c.reportMu.Lock()
+	defer c.reportMu.Unlock()
+	if c.toolCalls == nil {
+		c.toolCalls = make(map[int]int)
+	}
+	c.toolCalls[worker]++
+	c.streamsTools = true
+}
+
+// takeToolCalls returns and resets a worker's tool call count, and whether the
+// backend streams tool calls at all
+func (c *Controller) takeToolCalls(worker int) (int, bool) {
+	c.reportMu.Lock()
+	defer c.reportMu.Unlock()
+	n := c.toolCalls[worker]
+	delete(c.toolCalls, worker)
+	return n, c.streamsTools
+}
+
+// applyObservation checks an analysis against the observed loop and logs each discrepancy
+// Returns the discrepancies, to be fed back to the agent in the next loop
+func (c *Controller) applyObservation(worker int, result *analysis.Analysis, obs analysis.Observation) []string {
+	if result == nil {
+		return nil
+	}
+	result.ApplyObservation(obs)
+	for _, discrepancy := range result.Discrepancies {
+		c.emitWorkerLog(worker, LogLevelWarn, fmt.Sprintf("Discrepancy: %s", discrepancy))
+	}
+	return result.Discrepancies
+}
@@ -0,0 +1,137 @@
+package loop
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/brainwhocodes/lisa-loop/internal/circuit"
+	"github.com/brainwhocodes/lisa-loop/internal/git"
+)
+
+func TestExecuteLoop_Discrepancies(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	os.WriteFile("@fix_plan.md", []byte("- [ ] First task\n- [ ] Second task\n"), 0644)
+	os.WriteFile("PROMPT.md", []byte("Test prompt"), 0644)
+
+	controller := NewController(Config{MaxCalls: 10, Backend: "cli"}, NewRateLimiter(10, 1), circuit.NewBreaker(3, 5))
+
+	// The agent claims to have finished everything but touches nothing
+	r := &stuckRunner{output: `---RALPH_STATUS---
+STATUS: COMPLETE
+TASKS_COMPLETED_THIS_LOOP: 2
+FILES_MODIFIED: 3
+TESTS_STATUS: PASSING
+EXIT_SIGNAL: true
+---END_RALPH_STATUS---`}
+	controller.SetRunner(r)
+
+	var discrepancies []string
+	var exitSignal bool
+	controller.SetEventCallback(func(event LoopEvent) {
+		if event.Type == EventTypeAnalysis {
+			discrepancies, exitSignal = event.Discrepancies, event.ExitSignal
+		}
+	})
+
+	for i := 0; i < 2; i++ {
+		if err := controller.ExecuteLoop(context.Background()); err != nil {
+			t.Fatalf("ExecuteLoop() #%d error = %v", i+1, err)
+		}
+		controller.loopNum++
+	}
+
+	want := []string{"claimed 3 files modified, changed 0", "claimed 2 tasks completed, 0 checked off in the plan", "EXIT_SIGNAL with 2 unchecked tasks"}
+	if strings.Join(discrepancies, "|") != strings.Join(want, "|") {
+		t.Errorf("Discrepancies = %q, want %q", discrepancies, want)
+	}
+	if exitSignal || controller.shouldStop {
+		t.Errorf("ExitSignal = %v, shouldStop = %v, want the exit blocked", exitSignal, controller.shouldStop)
+	}
+
+	// The discrepancies are fed back in the next loop
+	if strings.Contains(r.prompts[0], "CLAIMS NOT BORNE OUT") {
+		t.Error("first prompt should not report discrepancies")
+	}
+	if !strings.Contains(r.prompts[1], "CLAIMS NOT BORNE OUT") || !strings.Contains(r.prompts[1], "EXIT_SIGNAL with 2 unchecked tasks") {
+		t.Errorf("second prompt should feed back the discrepancies:\n%s", r.prompts[1])
+	}
+}
+
+func TestObserve_Git(t *testing.T) {
+	if !git.Available() {
+		t.Skip("git not installed")
+	}
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	os.WriteFile("@fix_plan.md", []byte("- [ ] First task\n- [ ] Second task\n"), 0644)
+	os.WriteFile("main.go", []byte("package main\n"), 0644)
+	if _, err := git.Run("", "init", "-q"); err != nil {
+		t.Fatalf("git init: %v", err)
+	}
+	if _, err := git.CommitAll("", "initial", false); err != nil {
+		t.Fatalf("git commit: %v", err)
+	}
+	os.WriteFile("notes.txt", []byte("untracked before the loop\n"), 0644)
+
+	controller := NewController(Config{MaxCalls: 10, Backend: "cli"}, NewRateLimiter(10, 1), circuit.NewBreaker(3, 5))
+	snap := controller.snapshotWork("", nil, "@fix_plan.md")
+
+	os.WriteFile("main.go", []byte("package main\n\nfunc main() {}\n"), 0644)
+	os.WriteFile("util.go", []byte("package main\n"), 0644)
+	os.WriteFile("@fix_plan.md", []byte("- [x] First task\n- [ ] Second task\n"), 0644)
+	controller.recordToolCall(0)
+
+	obs := controller.observe(0, snap)
+	sort.Strings(obs.FilesChanged)
+	if !obs.FilesKnown || strings.Join(obs.FilesChanged, ",") != "@fix_plan.md,main.go,util.go" {
+		t.Errorf("FilesChanged = %v, want the plan, main.go and util.go but not notes.txt", obs.FilesChanged)
+	}
+	if !obs.PlanKnown || obs.TasksChecked != 1 || obs.TasksUnchecked != 1 {
+		t.Errorf("tasks checked/unchecked = %d/%d, want 1/1", obs.TasksChecked, obs.TasksUnchecked)
+	}
+	if !obs.ToolsKnown || obs.ToolCalls != 1 {
+		t.Errorf("ToolCalls = %d (known %v), want 1", obs.ToolCalls, obs.ToolsKnown)
+	}
+
+	// Tool calls are counted per loop
+	if n, known := controller.takeToolCalls(0); n != 0 || !known {
+		t.Errorf("takeToolCalls() = %d, %v after observing, want 0, true", n, known)
+	}
+}
+
+func TestObserve_ModTimes(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	os.WriteFile("a.txt", []byte("a"), 0644)
+	os.WriteFile("b.txt", []byte("b"), 0644)
+	os.Mkdir(".cache", 0755)
+
+	controller := NewController(Config{MaxCalls: 10, Backend: "cli"}, NewRateLimiter(10, 1), circuit.NewBreaker(3, 5))
+	snap := controller.snapshotWork("", nil, "")
+
+	os.WriteFile("a.txt", []byte("changed"), 0644)
+	os.Remove("b.txt")
+	os.WriteFile(".cache/skipped", []byte("x"), 0644)
+
+	obs := controller.observe(0, snap)
+	sort.Strings(obs.FilesChanged)
+	if !obs.FilesKnown || strings.Join(obs.FilesChanged, ",") != "a.txt,b.txt" {
+		t.Errorf("FilesChanged = %v, want a.txt and b.txt", obs.FilesChanged)
+	}
+	if obs.PlanKnown || obs.ToolsKnown {
+		t.Errorf("PlanKnown = %v, ToolsKnown = %v, want neither observed", obs.PlanKnown, obs.ToolsKnown)
+	}
+}
@@ -0,0 +1,560 @@
+package loop
+
+import (
+	stdcontext "context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/brainwhocodes/lisa-loop/internal/analysis"
+	"github.com/brainwhocodes/lisa-loop/internal/checkpoint"
+	"github.com/brainwhocodes/lisa-loop/internal/codex"
+	"github.com/brainwhocodes/lisa-loop/internal/git"
+	"github.com/brainwhocodes/lisa-loop/internal/plan"
+	"github.com/brainwhocodes/lisa-loop/internal/runner"
+	"github.com/brainwhocodes/lisa-loop/internal/state"
+	"github.com/brainwhocodes/lisa-loop/internal/verify"
+)
+
+// Worker lane statuses reported in EventTypeWorker events
+const (
+	WorkerStatusRunning   = "running"
+	WorkerStatusVerifying = "verifying"
+	WorkerStatusMerging   = "merging"
+	WorkerStatusMerged    = "merged"
+	WorkerStatusFailed    = "failed"
+	WorkerStatusIdle      = "idle"
+)
+
+// worker runs one focused task at a time in its own git worktree
+type worker struct {
+	id      int
+	dir     string // Worktree root
+	workDir string // Project directory inside the worktree
+	branch  string
+	runner  runner.Runner
+}
+
+// workerJob is a task handed to a worker
+type workerJob struct {
+	worker   *worker
+	task     *plan.Task
+	loop     int
+	base     string // Commit the worktree was created from
+	prompt   string
+	planFile string
+}
+
+// workerResult is what a worker reports back after running a task
+type workerResult struct {
+	job          workerJob
+	output       string
+	err          error
+	analysis     *analysis.Analysis
+	verification *verify.Result
+	completed    bool // Task checked in the worker's copy of the plan
+	committed    bool // Worker branch has changes to merge
+}
+
+// parallelRun holds the state of a RunParallel call
+type parallelRun struct {
+	root    string // Main work tree root
+	prefix  string // Project directory relative to root
+	tmpDir  string // Parent directory of the worker worktrees
+	workers []*worker
+	results chan workerResult
+	fatal   *FatalError // The backend failure that stopped the run, if any
+}
+
+// RunParallel executes ready tasks concurrently, one per worker, each in its own
+// git worktree with its own runner. Successful branches are merged back into the
+// run branch; on conflict the branch is rebased and re-verified before merging.
+// The plan file is owned by the controller: workers' edits to it are discarded and
+// tasks are marked complete in the main work tree only after a successful merge.
+func (c *Controller) RunParallel(ctx stdcontext.Context, n int) error {
+	c.emitLog(LogLevelInfo, fmt.Sprintf("Starting Lisa parallel loop (%d workers, max %d calls)", n, c.config.MaxLoops))
+	c.emitUpdate("starting")
+
+	preflight, shouldSkip := c.RunPreflight()
+	c.emitPreflight(preflight)
+	for _, planErr := range preflight.PlanErrors {
+		c.emitLog(LogLevelWarn, fmt.Sprintf("Plan: %s", planErr))
+	}
+	if shouldSkip {
+		c.emitLog(LogLevelInfo, fmt.Sprintf("Skipped: %s", preflight.SkipReason))
+		c.emitUpdate("skipped")
+		return nil
+	}
+
+	run, err := c.startParallel(n)
+	if err != nil {
+		c.emitLog(LogLevelError, fmt.Sprintf("Parallel mode unavailable: %v", err))
+		c.emitUpdate("error")
+		return err
+	}
+	defer c.stopParallel(run)
+
+	idle := append([]*worker(nil), run.workers...)
+	inFlight := make(map[string]bool)
+	stopReason := ""
+
+	for {
+		// Hand ready tasks to idle workers; with none busy, a full rate-limit window is waited out first
+		if stopReason == "" {
+			if len(inFlight) == 0 && !c.awaitRateLimit(ctx) {
+				stopReason = "cancelled"
+			} else {
+				stopReason = c.dispatchReady(ctx, run, &idle, inFlight)
+			}
+		}
+
+		if len(inFlight) == 0 {
+			break
+		}
+
+		var res workerResult
+		select {
+		case res = <-run.results:
+		case <-ctx.Done():
+			// Cancellation stops the runners; wait for them so worktrees can be removed
+			if stopReason == "" {
+				stopReason = "cancelled"
+				c.emitLog(LogLevelWarn, "Loop cancelled, waiting for workers to finish")
+			}
+			res = <-run.results
+		}
+
+		delete(inFlight, res.job.task.ID)
+		c.finishWorkerJob(ctx, run, res)
+		c.saveRunRecord(state.RunRunning)
+		idle = append(idle, res.job.worker)
+	}
+
+	switch stopReason {
+	case "cancelled":
+		c.emitUpdate("cancelled")
+		return ctx.Err()
+	case "", "All tasks complete":
+		c.emitLog(LogLevelSuccess, fmt.Sprintf("Lisa parallel loop complete after %d tasks", c.loopNum))
+		c.emitUpdate("complete")
+	default:
+		c.emitLog(LogLevelInfo, fmt.Sprintf("Stopped: %s", stopReason))
+		c.emitUpdate("stopped")
+	}
+	if run.fatal != nil {
+		return run.fatal
+	}
+	return nil
+}
+
+// startParallel switches to a run branch and prepares n workers
+func (c *Controller) startParallel(n int) (*parallelRun, error) {
+	if c.checkpoints == nil {
+		c.checkpoints = checkpoint.NewManager("", c.runID)
+	}
+	if !c.startCheckpoints() {
+		return nil, fmt.Errorf("parallel mode needs a git repository")
+	}
+
+	root, err := git.Root("")
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := git.Prefix("")
+	if err != nil {
+		return nil, err
+	}
+	tmpDir, err := os.MkdirTemp("", "lisa-worktrees-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create worktree directory: %w", err)
+	}
+
+	run := &parallelRun{
+		root:    root,
+		prefix:  prefix,
+		tmpDir:  tmpDir,
+		results: make(chan workerResult, n),
+	}
+
+	for i := 1; i <= n; i++ {
+		dir := filepath.Join(tmpDir, fmt.Sprintf("worker-%d", i))
+		w := &worker{
+			id:      i,
+			dir:     dir,
+			workDir: filepath.Join(dir, prefix),
+			branch:  fmt.Sprintf("%s-w%d", c.checkpoints.Branch(), i),
+		}
+
+		cfg := c.runnerConfig
+		cfg.WorkDir = w.workDir
+		w.runner = c.newRunner(cfg)
+
+		id := w.id
+		w.runner.SetOutputCallback(func(event runner.Event) {
+			c.handleWorkerEvent(id, codex.Event(event))
+		})
+
+		run.workers = append(run.workers, w)
+		c.emitWorker(w.id, "", WorkerStatusIdle, "")
+	}
+
+	return run, nil
+}
+
+// stopParallel shuts down worker runners and removes their worktrees
+func (c *Controller) stopParallel(run *parallelRun) {
+	for _, w := range run.workers {
+		if err := w.runner.Stop(); err != nil {
+			c.emitLog(LogLevelWarn, fmt.Sprintf("Worker %d: failed to stop runner: %v", w.id, err))
+		}
+	}
+	if _, err := git.Run(run.root, "worktree", "prune"); err != nil {
+		c.emitLog(LogLevelWarn, fmt.Sprintf("Failed to prune worktrees: %v", err))
+	}
+	os.RemoveAll(run.tmpDir)
+}
+
+// dispatchReady starts ready tasks on idle workers
+// Returns a non-empty reason once no further tasks should be started
+func (c *Controller) dispatchReady(ctx stdcontext.Context, run *parallelRun, idle *[]*worker, inFlight map[string]bool) string {
+	select {
+	case <-ctx.Done():
+		return "cancelled"
+	default:
+	}
+
+	c.refreshPlanCache()
+	if c.cachedPlan == nil {
+		return "No plan file found"
+	}
+	if len(c.cachedPlan.Remaining()) == 0 {
+		return "All tasks complete"
+	}
+
+	if _, err := c.breaker.CheckCooldown(); err != nil {
+		c.emitLog(LogLevelWarn, fmt.Sprintf("Failed to save circuit breaker state: %v", err))
+	}
+
+	for _, task := range c.cachedPlan.Ready() {
+		if len(*idle) == 0 {
+			return ""
+		}
+		if inFlight[task.ID] {
+			continue
+		}
+
+		switch {
+		case c.shouldStop:
+			return "stop requested"
+		case c.breaker.ShouldHalt():
+			return "Circuit breaker is OPEN"
+		case !c.rateLimiter.CanMakeCall():
+			// Dispatch again once a worker finishes, or wait for the window when none is busy
+			if len(inFlight) > 0 {
+				return ""
+			}
+			return fmt.Sprintf("Rate limit exhausted (%d calls remaining)", c.rateLimiter.CallsRemaining())
+		case c.loopNum >= c.config.MaxLoops:
+			return fmt.Sprintf("Max loops reached (%d)", c.config.MaxLoops)
+		}
+		if exhausted, reason := c.budgetExhausted(); exhausted {
+			return reason
+		}
+
+		w := (*idle)[0]
+		job, err := c.prepareWorkerJob(run, w, task)
+		if err != nil {
+			c.emitLog(LogLevelError, fmt.Sprintf("Worker %d: %v", w.id, err))
+			c.emitWorker(w.id, task.Text, WorkerStatusFailed, err.Error())
+			return err.Error()
+		}
+
+		*idle = (*idle)[1:]
+		inFlight[task.ID] = true
+		c.loopNum++
+		if err := c.rateLimiter.RecordCall(); err != nil {
+			c.emitLog(LogLevelWarn, fmt.Sprintf("Failed to record call: %v", err))
+		}
+
+		c.emitLog(LogLevelInfo, fmt.Sprintf("Worker %d: %s", w.id, task.Text))
+		c.emitWorker(w.id, task.Text, WorkerStatusRunning, "")
+		c.emitUpdate("codex_running")
+
+		go func() {
+			run.results <- c.runWorkerJob(ctx, job)
+		}()
+	}
+
+	if len(inFlight) == 0 {
+		return fmt.Sprintf("All %d remaining tasks are blocked", len(c.cachedPlan.Remaining()))
+	}
+	return ""
+}
+
+// prepareWorkerJob creates the worker's worktree from the current run branch and builds its prompt
+func (c *Controller) prepareWorkerJob(run *parallelRun, w *worker, task *plan.Task) (workerJob, error) {
+	// Commit plan edits (BLOCKED markers) so the worktree sees the current plan
+	if _, err := git.CommitAll(run.root, "lisa: update plan", false); err != nil {
+		return workerJob{}, fmt.Errorf("failed to commit plan: %w", err)
+	}
+	base, err := git.HeadSHA(run.root)
+	if err != nil {
+		return workerJob{}, err
+	}
+
+	if err := git.WorktreeAdd(run.root, w.dir, w.branch, base); err != nil {
+		return workerJob{}, fmt.Errorf("failed to create worktree: %w", err)
+	}
+
+	prompt, err := GetPrompt()
+	if err != nil {
+		return workerJob{}, fmt.Errorf("failed to load prompt: %w", err)
+	}
+
+	attempt := c.taskAttempts(task.ID) + 1
+	loopContext, err := BuildContextWithOptions(ContextOptions{
+		LoopNum:         c.loopNum + 1,
+		CircuitState:    c.breaker.GetState().String(),
+		PlanFile:        c.cachedPlanFile,
+		FocusTask:       task.String(),
+		FocusTaskID:     task.ID,
+		FocusAttempt:    attempt,
+		MaxTaskAttempts: c.maxTaskAttempts,
+	})
+	if err != nil {
+		return workerJob{}, fmt.Errorf("failed to build context: %w", err)
+	}
+
+	return workerJob{
+		worker:   w,
+		task:     task,
+		loop:     c.loopNum + 1,
+		base:     base,
+		prompt:   InjectContext(prompt, loopContext),
+		planFile: c.cachedPlanFile,
+	}, nil
+}
+
+// runWorkerJob runs on the worker's goroutine: it executes the task, verifies it and
+// commits the result on the worker branch. The coordinator owns the controller's state
+// meanwhile, so only these controller methods are called from here:
+//   - emitWorkerAt and emitWorkerLog, since emit serializes events on emitMu and the loop
+//     number comes from the job rather than c.loopNum
+//   - takeToolReport and takeToolCalls (also through observe), which take reportMu
+//   - snapshotWork, observe and applyObservation, which otherwise only read the worker's
+//     directory and emit logs
+func (c *Controller) runWorkerJob(ctx stdcontext.Context, job workerJob) workerResult {
+	w := job.worker
+	res := workerResult{job: job}
+
+	// The worker's plan holds every task but its own, so only its files are observed
+	work := c.snapshotWork(w.workDir, nil, "")
+	c.takeToolReport(w.id)
+	c.takeToolCalls(w.id)
+	res.output, _, res.err = w.runner.Run(runner.WithLoop(ctx, job.loop), job.prompt)
+	if res.err != nil {
+		return res
+	}
+
+	res.analysis, _ = analysis.AnalyzeWithReport(res.output, c.takeToolReport(w.id), nil)
+	c.applyObservation(w.id, res.analysis, c.observe(w.id, work))
+
+	if gate := c.verifier.In(w.workDir); gate.Enabled() {
+		c.emitWorkerAt(job.loop, w.id, job.task.Text, WorkerStatusVerifying, "")
+		if result, err := gate.Run(ctx); err == nil {
+			res.verification = result
+		}
+	}
+
+	if doc, err := plan.Load(filepath.Join(w.workDir, job.planFile)); err == nil {
+		if t := doc.Find(job.task.ID); t != nil {
+			res.completed = t.Checked
+		}
+	}
+	if !res.completed || (res.verification != nil && !res.verification.Passed) {
+		return res
+	}
+
+	// The controller marks the plan itself; dropping the worker's edits avoids plan conflicts
+	if err := git.CheckoutFile(w.workDir, job.base, job.planFile); err != nil {
+		res.err = fmt.Errorf("failed to restore plan: %w", err)
+		return res
+	}
+	sha, err := git.CommitAll(w.workDir, checkpoint.CommitMessage(job.loop, job.task.Text), false)
+	if err != nil {
+		res.err = err
+		return res
+	}
+	res.committed = sha != ""
+	return res
+}
+
+// classifyWorkerError stops the run on a fatal backend error and starts a worker whose
+// session overflowed on a new one; other failures are retried when the task comes up again
+func (c *Controller) classifyWorkerError(run *parallelRun, w *worker, err error) {
+	cls := runner.Classify(err)
+	switch {
+	case cls.Class.Fatal():
+		if run.fatal == nil {
+			run.fatal = &FatalError{Class: cls.Class, Hint: fatalHint(c.backend, cls), Err: err}
+			c.emitLog(LogLevelError, run.fatal.Hint)
+			c.emitUpdate("fatal_error")
+		}
+		c.shouldStop = true
+	case cls.Class == runner.ErrorContextOverflow:
+		if _, err := runner.NewSession(w.runner); err != nil {
+			c.emitWorkerLog(w.id, LogLevelWarn, fmt.Sprintf("Failed to start a new session: %v", err))
+		}
+	}
+}
+
+// finishWorkerJob records a worker's result on the coordinator goroutine
+func (c *Controller) finishWorkerJob(ctx stdcontext.Context, run *parallelRun, res workerResult) {
+	w, task := res.job.worker, res.job.task
+	defer c.removeWorktree(run, w)
+
+	if res.err != nil {
+		c.emitLog(LogLevelError, fmt.Sprintf("Worker %d failed: %v", w.id, res.err))
+		if err := c.breaker.RecordError(res.err.Error()); err != nil {
+			c.emitLog(LogLevelWarn, fmt.Sprintf("Failed to record error in circuit breaker: %v", err))
+		}
+		c.emitWorker(w.id, task.Text, WorkerStatusFailed, res.err.Error())
+		c.emitOutcome(&LoopOutcome{Success: false, Error: res.err.Error()})
+		if spentFocusAttempt(ctx, res.err) {
+			c.finishFocusTask(task, res.err.Error())
+		}
+		c.classifyWorkerError(run, w, res.err)
+		return
+	}
+
+	c.checkStatusReport(w.id, res.analysis, c.cachedPlan)
+	if res.verification != nil {
+		res.analysis.ApplyTestResults(res.verification.Tests)
+	}
+	c.emitAnalysis(res.analysis)
+	if res.verification != nil {
+		c.emitVerification(res.verification)
+	}
+
+	merged := false
+	reason := focusFailureReason(task, res.analysis, res.verification)
+	if res.completed && (res.verification == nil || res.verification.Passed) {
+		merged, reason = c.mergeWorker(ctx, run, res)
+	}
+
+	filesChanged := 0
+	hasErrors := false
+	if res.analysis != nil {
+		hasErrors = res.analysis.HasErrors
+		if merged && res.analysis.Status != nil {
+			filesChanged = res.analysis.Status.FilesModified
+		}
+	}
+	if merged && filesChanged == 0 {
+		filesChanged = 1 // A merged task is progress even if the agent didn't report files
+	}
+	if err := c.breaker.RecordResult(res.job.loop, filesChanged, hasErrors); err != nil {
+		c.emitLog(LogLevelWarn, fmt.Sprintf("Failed to record result: %v", err))
+	}
+
+	outcome := &LoopOutcome{Success: merged}
+	if res.analysis != nil && res.analysis.Status != nil {
+		outcome.TasksCompleted = res.analysis.Status.TasksCompleted
+		outcome.FilesModified = res.analysis.Status.FilesModified
+		outcome.TestsStatus = res.analysis.Status.TestsStatus
+	}
+	if res.analysis != nil {
+		outcome.Tests = res.analysis.Tests
+	}
+	if res.verification != nil {
+		outcome.Verified = true
+		outcome.VerificationPassed = res.verification.Passed
+	}
+	if !merged {
+		outcome.Error = reason
+	}
+	c.emitOutcome(outcome)
+
+	if merged {
+		c.emitLog(LogLevelSuccess, fmt.Sprintf("Worker %d: merged %s", w.id, task.Text))
+		c.emitWorker(w.id, task.Text, WorkerStatusMerged, "")
+	} else {
+		c.emitWorker(w.id, task.Text, WorkerStatusFailed, reason)
+	}
+	c.finishFocusTask(task, reason)
+}
+
+// mergeWorker merges a worker branch into the run branch and marks its task complete
+// On conflict the branch is rebased onto the run branch and re-verified first
+// Returns whether the task was merged, or why not
+func (c *Controller) mergeWorker(ctx stdcontext.Context, run *parallelRun, res workerResult) (bool, string) {
+	w, task := res.job.worker, res.job.task
+	c.emitWorker(w.id, task.Text, WorkerStatusMerging, "")
+
+	cp := c.beginCheckpoint(res.job.loop)
+
+	if res.committed {
+		message := fmt.Sprintf("lisa: merge worker %d: %s", w.id, task.Text)
+		err := git.Merge(run.root, w.branch, message)
+		if errors.Is(err, git.ErrConflict) {
+			c.emitLog(LogLevelWarn, fmt.Sprintf("Worker %d: merge conflict, rebasing", w.id))
+			err = c.rebaseWorker(ctx, run, w)
+			if err == nil {
+				err = git.Merge(run.root, w.branch, message)
+			}
+		}
+		if err != nil {
+			return false, fmt.Sprintf("merge failed: %v", err)
+		}
+	}
+
+	doc, err := LoadPlanDocument()
+	if err != nil {
+		return false, fmt.Sprintf("failed to reload plan: %v", err)
+	}
+	current := doc.Find(task.ID)
+	if current == nil {
+		return false, "task no longer in plan"
+	}
+	doc.SetChecked(current, true)
+	if err := doc.Save(); err != nil {
+		return false, fmt.Sprintf("failed to mark task: %v", err)
+	}
+
+	c.finishCheckpoint(cp, task.Text, false)
+	return true, ""
+}
+
+// rebaseWorker replays a worker branch onto the run branch and re-runs verification
+func (c *Controller) rebaseWorker(ctx stdcontext.Context, run *parallelRun, w *worker) error {
+	head, err := git.HeadSHA(run.root)
+	if err != nil {
+		return err
+	}
+	if err := git.Rebase(w.workDir, head); err != nil {
+		return err
+	}
+
+	gate := c.verifier.In(w.workDir)
+	if !gate.Enabled() {
+		return nil
+	}
+	c.emitWorker(w.id, "", WorkerStatusVerifying, "after rebase")
+	result, err := gate.Run(ctx)
+	if err != nil {
+		return fmt.Errorf("verification after rebase did not complete: %w", err)
+	}
+	c.emitVerification(result)
+	if !result.Passed {
+		return fmt.Errorf("verification failed after rebase: %s", result.Summary())
+	}
+	return nil
+}
+
+// removeWorktree deletes a worker's worktree and branch once its task is finished
+func (c *Controller) removeWorktree(run *parallelRun, w *worker) {
+	if err := git.WorktreeRemove(run.root, w.dir, w.branch); err != nil {
+		c.emitLog(LogLevelWarn, fmt.Sprintf("Worker %d: failed to remove worktree: %v", w.id, err))
+	}
+}
@@ -0,0 +1,142 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/brainwhocodes/lisa-loop/internal/circuit"
+	"github.com/brainwhocodes/lisa-loop/internal/git"
+	"github.com/brainwhocodes/lisa-loop/internal/runner"
+)
+
+// worktreeRunner is a test double that completes the focused task inside its work directory
+type worktreeRunner struct {
+	workDir string
+	output  string
+}
+
+func (r *worktreeRunner) Run(ctx context.Context, prompt string) (string, string, error) {
+	planPath := filepath.Join(r.workDir, "@fix_plan.md")
+	data, err := os.ReadFile(planPath)
+	if err != nil {
+		return "", "", err
+	}
+
+	// The focused task is the line after the "Current Task" heading
+	_, rest, _ := strings.Cut(prompt, "Current Task")
+	parts := strings.SplitN(rest, "\n", 3)
+	if len(parts) < 2 {
+		return "", "", fmt.Errorf("prompt has no focus task")
+	}
+	text := strings.TrimPrefix(strings.TrimSpace(parts[1]), "[ ] ")
+
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "- [ ] "+text) {
+			continue
+		}
+		lines[i] = strings.Replace(line, "- [ ]", "- [x]", 1)
+		name := strings.ReplaceAll(strings.ToLower(text), " ", "_") + ".txt"
+		if err := os.WriteFile(filepath.Join(r.workDir, name), []byte(text+"\n"), 0644); err != nil {
+			return "", "", err
+		}
+		break
+	}
+	if err := os.WriteFile(planPath, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		return "", "", err
+	}
+	return r.output, "test-session", nil
+}
+
+func (r *worktreeRunner) SetOutputCallback(cb runner.OutputCallback) {}
+
+func (r *worktreeRunner) Stop() error { return nil }
+
+func TestRunParallel(t *testing.T) {
+	if !git.Available() {
+		t.Skip("git not installed")
+	}
+
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	os.WriteFile("@fix_plan.md", []byte("- [ ] Alpha task <!-- id: A -->\n- [ ] Beta task\n- [ ] Gamma task (after: A)\n"), 0644)
+	os.WriteFile("PROMPT.md", []byte("Test prompt"), 0644)
+	if _, err := git.Run("", "init", "-q"); err != nil {
+		t.Fatalf("git init: %v", err)
+	}
+
+	cfg := Config{
+		MaxCalls:      10,
+		Backend:       "cli",
+		VerifyCommand: "test -n \"$(ls *_task.txt)\"",
+		Parallel:      2,
+	}
+	controller := NewController(cfg, NewRateLimiter(10, 1), circuit.NewBreaker(3, 5))
+
+	var mu sync.Mutex
+	var workDirs []string
+	controller.SetRunnerFactory(func(cfg Config) runner.Runner {
+		mu.Lock()
+		defer mu.Unlock()
+		workDirs = append(workDirs, cfg.WorkDir)
+		return &worktreeRunner{workDir: cfg.WorkDir, output: `---RALPH_STATUS---
+STATUS: WORKING
+TASKS_COMPLETED_THIS_LOOP: 1
+FILES_MODIFIED: 1
+TESTS_STATUS: PASSING
+EXIT_SIGNAL: false
+---END_RALPH_STATUS---`}
+	})
+
+	merged := make(map[string]bool)
+	controller.SetEventCallback(func(event LoopEvent) {
+		if event.Type == EventTypeWorker && event.Status == WorkerStatusMerged {
+			merged[event.CurrentTask] = true
+		}
+	})
+
+	if err := controller.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if len(workDirs) != 2 {
+		t.Fatalf("runners created = %d, want 2", len(workDirs))
+	}
+	if workDirs[0] == workDirs[1] {
+		t.Errorf("workers share a work directory: %s", workDirs[0])
+	}
+
+	tasks, _ := LoadPlan()
+	for _, task := range tasks {
+		if !strings.HasPrefix(task, "[x]") {
+			t.Errorf("task not completed in main plan: %s", task)
+		}
+	}
+	for _, name := range []string{"Alpha task", "Beta task", "Gamma task"} {
+		if !merged[name] {
+			t.Errorf("no merged worker event for %q", name)
+		}
+	}
+	for _, name := range []string{"alpha_task.txt", "beta_task.txt", "gamma_task.txt"} {
+		if _, err := os.Stat(name); err != nil {
+			t.Errorf("merged file %s missing: %v", name, err)
+		}
+	}
+
+	for _, dir := range workDirs {
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			t.Errorf("worktree %s not removed", dir)
+		}
+	}
+	if list, _ := git.Run("", "worktree", "list"); strings.Count(list, "\n") > 0 {
+		t.Errorf("worktrees remain after run:\n%s", list)
+	}
+}
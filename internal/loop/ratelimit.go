@@ -1,211 +1,305 @@
 package loop
 
 import (
-	"context"
+	stdcontext "context"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/brainwhocodes/lisa-loop/internal/config"
 	"github.com/brainwhocodes/lisa-loop/internal/state"
 )
 
-// RateLimiter tracks API calls per hour
+// RateWindow allows at most Calls backend calls in any Period
+type RateWindow = config.RateWindow
+
+// rateLimitPoll bounds each wait for the rate limit, so calls other processes make
+// (or stop making) in the meantime are noticed
+const rateLimitPoll = 30 * time.Second
+
+// RateLimiter limits backend calls over sliding windows
+// Once it shares a call log, its maxCalls window and the shared windows count the calls
+// of every Lisa process using that log; until then its window counts this process's calls
 type RateLimiter struct {
-	maxCalls     int
-	resetHours   int
-	currentCalls int
-	lastReset    time.Time
+	mu         sync.Mutex
+	maxCalls   int
+	resetHours int
+	calls      []time.Time // This process's calls within its window, oldest first
+
+	shared     []RateWindow
+	sharedPath string      // Call log of the shared windows
+	sharedLog  []time.Time // Calls in the call log when it was last read
+	now        func() time.Time
 }
 
-// NewRateLimiter creates a new rate limiter
+// NewRateLimiter creates a rate limiter allowing maxCalls calls in any resetHours hours
 func NewRateLimiter(maxCalls int, resetHours int) *RateLimiter {
 	return &RateLimiter{
-		maxCalls:     maxCalls,
-		resetHours:   resetHours,
-		currentCalls: 0,
-		lastReset:    time.Now(),
+		maxCalls:   maxCalls,
+		resetHours: resetHours,
+		now:        time.Now,
 	}
 }
 
+// ShareWindows counts the calls of every Lisa process recording its calls in the call
+// log at path against the limiter's window and the added windows
+func (r *RateLimiter) ShareWindows(path string, windows []RateWindow) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.shared = windows
+	r.sharedPath = path
+	return r.readSharedLog()
+}
+
 // CanMakeCall checks if another call can be made
 func (r *RateLimiter) CanMakeCall() bool {
-	return r.currentCalls < r.maxCalls
+	return r.TimeUntilNextCall() == 0
 }
 
-// RecordCall records a call and updates tracking
+// RecordCall records a call now, in the shared call log as well if there is one
 func (r *RateLimiter) RecordCall() error {
-	// Check if we need to reset
-	if r.ShouldReset() {
-		if err := r.Reset(); err != nil {
-			return fmt.Errorf("failed to reset rate limiter: %w", err)
-		}
-	}
-
-	r.currentCalls++
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	// Update last reset time to track when we made this call
-	if r.currentCalls == 1 {
-		r.lastReset = time.Now()
+	now := r.now()
+	r.calls = append(r.prune(r.calls, r.window()), now)
+	if r.sharedPath == "" {
+		return nil
 	}
 
-	// Persist state
-	if err := r.SaveState(); err != nil {
-		return fmt.Errorf("failed to save rate limiter state: %w", err)
+	longest := r.longestWindow()
+	log, err := state.UpdateCallLog(r.sharedPath, func(log *state.CallLog) {
+		log.Calls = append(r.prune(log.Calls, longest), now)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record call in %s: %w", r.sharedPath, err)
 	}
-
+	r.sharedLog = log.Calls
 	return nil
 }
 
-// CallsMade returns the number of calls made in current hour
+// CallsMade returns the number of calls this process made in its window
 func (r *RateLimiter) CallsMade() int {
-	return r.currentCalls
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.prune(r.calls, r.window()))
 }
 
-// CallsRemaining returns the number of calls remaining in current hour
+// CallsRemaining returns the calls left before a window is full
 func (r *RateLimiter) CallsRemaining() int {
-	return r.maxCalls - r.currentCalls
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.readSharedLog()
+	return r.callsRemaining()
 }
 
-// LastResetTime returns the time of the last reset
-func (r *RateLimiter) LastResetTime() time.Time {
-	return r.lastReset
+// callsRemaining returns the calls left before a window is full (caller must hold mu)
+func (r *RateLimiter) callsRemaining() int {
+	remaining := r.maxCalls - len(r.prune(r.windowCalls(), r.window()))
+	for _, w := range r.shared {
+		if left := w.Calls - len(r.prune(r.sharedLog, w.Period)); left < remaining {
+			remaining = left
+		}
+	}
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining
 }
 
-// ShouldReset checks if the rate limit should be reset
-func (r *RateLimiter) ShouldReset() bool {
-	return time.Since(r.lastReset).Hours() >= float64(r.resetHours)
+// TimeUntilNextCall returns how long until every window has room for another call
+func (r *RateLimiter) TimeUntilNextCall() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.readSharedLog()
+	return r.timeUntilNextCall()
 }
 
-// Reset resets the call counter
-func (r *RateLimiter) Reset() error {
-	r.currentCalls = 0
-	r.lastReset = time.Now()
-
-	return r.SaveState()
+// timeUntilNextCall returns how long until every window has room (caller must hold mu)
+func (r *RateLimiter) timeUntilNextCall() time.Duration {
+	wait := r.waitFor(r.windowCalls(), r.ownWindow())
+	for _, w := range r.shared {
+		if d := r.waitFor(r.sharedLog, w); d > wait {
+			wait = d
+		}
+	}
+	return wait
 }
 
-// TimeUntilReset returns time remaining until next reset
-func (r *RateLimiter) TimeUntilReset() time.Duration {
-	elapsed := time.Since(r.lastReset)
-	resetWindow := time.Duration(r.resetHours) * time.Hour
-	remaining := resetWindow - elapsed
+// FullWindows describes the windows that have no room for another call
+func (r *RateLimiter) FullWindows() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	if remaining < 0 {
-		remaining = 0
+	var full []string
+	if r.waitFor(r.windowCalls(), r.ownWindow()) > 0 {
+		full = append(full, r.describe(r.ownWindow()))
+	}
+	for _, w := range r.shared {
+		if r.waitFor(r.sharedLog, w) > 0 {
+			full = append(full, r.describe(w))
+		}
 	}
+	return strings.Join(full, ", ")
+}
 
-	return remaining
+// Calls returns this process's calls within its window, oldest first
+func (r *RateLimiter) Calls() []time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]time.Time(nil), r.prune(r.calls, r.window())...)
 }
 
-// LoadState loads rate limiter state from files while preserving configured limits
-func (r *RateLimiter) LoadState() (*RateLimiter, error) {
-	count, err := state.LoadCallCount()
-	if err != nil {
-		return nil, err
-	}
+// RestoreCalls counts calls made before this process started, such as those of a
+// resumed run, in its window
+func (r *RateLimiter) RestoreCalls(calls []time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	merged := append(append([]time.Time(nil), calls...), r.calls...)
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Before(merged[j]) })
+	r.calls = r.prune(merged, r.window())
+}
 
-	reset, err := state.LoadLastReset()
-	if err != nil {
-		return nil, err
-	}
+// Reset forgets this process's calls; the shared call log is kept
+func (r *RateLimiter) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = nil
+}
 
-	// Preserve configured maxCalls and resetHours, only update runtime state
-	return &RateLimiter{
-		maxCalls:     r.maxCalls,   // Preserve configured value
-		resetHours:   r.resetHours, // Preserve configured value
-		currentCalls: count,
-		lastReset:    reset,
-	}, nil
+// SetMaxCalls updates the calls allowed in this process's window
+func (r *RateLimiter) SetMaxCalls(maxCalls int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxCalls = maxCalls
 }
 
-// LoadStateInto loads persisted state into the current rate limiter instance
-func (r *RateLimiter) LoadStateInto() error {
-	count, err := state.LoadCallCount()
-	if err != nil {
-		return err
-	}
+// SetResetHours updates the length of this process's window in hours
+func (r *RateLimiter) SetResetHours(hours int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.resetHours = hours
+}
 
-	reset, err := state.LoadLastReset()
-	if err != nil {
-		return err
-	}
+// GetStats returns current rate limiter statistics
+func (r *RateLimiter) GetStats() map[string]interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.readSharedLog()
 
-	// Only update runtime state, preserve configured limits
-	r.currentCalls = count
-	r.lastReset = reset
+	windows := []string{r.describe(r.ownWindow())}
+	for _, w := range r.shared {
+		windows = append(windows, r.describe(w))
+	}
+	return map[string]interface{}{
+		"max_calls":            r.maxCalls,
+		"current_calls":        len(r.prune(r.calls, r.window())),
+		"calls_remaining":      r.callsRemaining(),
+		"reset_hours":          r.resetHours,
+		"windows":              windows,
+		"time_until_next_call": r.timeUntilNextCall().String(),
+	}
+}
 
-	return nil
+// window returns the length of the limiter's maxCalls window
+func (r *RateLimiter) window() time.Duration {
+	return time.Duration(r.resetHours) * time.Hour
 }
 
-// SaveState saves rate limiter state to files
-func (r *RateLimiter) SaveState() error {
-	if err := state.SaveCallCount(r.currentCalls); err != nil {
-		return fmt.Errorf("failed to save call count: %w", err)
-	}
+// ownWindow returns the limiter's maxCalls window
+func (r *RateLimiter) ownWindow() RateWindow {
+	return RateWindow{Calls: r.maxCalls, Period: r.window()}
+}
 
-	if err := state.SaveLastReset(r.lastReset); err != nil {
-		return fmt.Errorf("failed to save last reset: %w", err)
+// windowCalls returns the calls counted in the maxCalls window: every process's once the
+// call log is shared, otherwise this process's
+func (r *RateLimiter) windowCalls() []time.Time {
+	if r.sharedPath != "" {
+		return r.sharedLog
 	}
-
-	return nil
+	return r.calls
 }
 
-// WaitForReset waits for the next hour with countdown
-func (r *RateLimiter) WaitForReset(ctx context.Context) error {
-	remaining := r.TimeUntilReset()
-	if remaining == 0 {
-		// Already reset, just wait a bit
-		time.Sleep(1 * time.Second)
-		return nil
+// describe names w, marking the windows counted in the shared call log
+func (r *RateLimiter) describe(w RateWindow) string {
+	if r.sharedPath != "" {
+		return w.String() + " shared"
 	}
+	return w.String()
+}
 
-	fmt.Printf("\nRate limit reached. Waiting %v until next hour...\n", remaining.Round(time.Second))
-
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			remaining = r.TimeUntilReset()
-			if remaining <= 0 {
-				fmt.Println("Rate limit reset!")
-				return nil
-			}
-			remainingSec := int(remaining.Seconds()) + 1
-			fmt.Printf("\r%2d seconds remaining...", remainingSec)
-
-		case <-ctx.Done():
-			return fmt.Errorf("wait for reset cancelled")
+// longestWindow returns the longest window, beyond which logged calls can be forgotten
+func (r *RateLimiter) longestWindow() time.Duration {
+	longest := r.window()
+	for _, w := range r.shared {
+		if w.Period > longest {
+			longest = w.Period
 		}
 	}
+	return longest
 }
 
-// ResetWithCountdown shows progress while waiting for reset
-func (r *RateLimiter) ResetWithCountdown(ctx context.Context) error {
-	fmt.Println("\nRate limit reached.")
-	return r.WaitForReset(ctx)
+// prune returns the calls made within period of now
+func (r *RateLimiter) prune(calls []time.Time, period time.Duration) []time.Time {
+	cutoff := r.now().Add(-period)
+	i := sort.Search(len(calls), func(i int) bool { return calls[i].After(cutoff) })
+	return calls[i:]
 }
 
-// SetMaxCalls updates the maximum calls per hour
-func (r *RateLimiter) SetMaxCalls(maxCalls int) error {
-	r.maxCalls = maxCalls
-	return r.SaveState()
+// waitFor returns how long until w has room for another call
+// Once w is full, the next call waits for enough of its oldest calls to age out
+func (r *RateLimiter) waitFor(calls []time.Time, w RateWindow) time.Duration {
+	recent := r.prune(calls, w.Period)
+	if len(recent) < w.Calls {
+		return 0
+	}
+	oldest := recent[len(recent)-w.Calls]
+	if wait := oldest.Add(w.Period).Sub(r.now()); wait > 0 {
+		return wait
+	}
+	return 0
 }
 
-// SetResetHours updates the reset interval in hours
-func (r *RateLimiter) SetResetHours(hours int) error {
-	r.resetHours = hours
-	return r.SaveState()
+// readSharedLog refreshes the calls other processes made (caller must hold mu)
+// A log that cannot be read keeps the calls last seen
+func (r *RateLimiter) readSharedLog() error {
+	if r.sharedPath == "" {
+		return nil
+	}
+	log, err := state.LoadCallLog(r.sharedPath)
+	if err != nil {
+		return err
+	}
+	r.sharedLog = log.Calls
+	return nil
 }
 
-// GetStats returns current rate limiter statistics
-func (r *RateLimiter) GetStats() map[string]interface{} {
-	return map[string]interface{}{
-		"max_calls":        r.maxCalls,
-		"current_calls":    r.currentCalls,
-		"calls_remaining":  r.CallsRemaining(),
-		"last_reset":       r.lastReset.Format(time.RFC3339),
-		"reset_hours":      r.resetHours,
-		"time_until_reset": r.TimeUntilReset().String(),
+// awaitRateLimit waits until the rate limit allows another call, reporting the wait as
+// a "rate_limited" loop update; returns false if ctx ended first
+func (c *Controller) awaitRateLimit(ctx stdcontext.Context) bool {
+	wait := c.rateLimiter.TimeUntilNextCall()
+	if wait == 0 {
+		return true
 	}
+
+	c.emitLog(LogLevelWarn, fmt.Sprintf("Rate limit reached (%s); next call in %s", c.rateLimiter.FullWindows(), wait.Round(time.Second)))
+	for wait > 0 {
+		update := c.loopUpdate("rate_limited")
+		update.RateLimitWait = wait
+		c.emit(update)
+
+		timer := time.NewTimer(min(wait, rateLimitPoll))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+		wait = c.rateLimiter.TimeUntilNextCall()
+	}
+
+	c.emitLog(LogLevelInfo, "Rate limit window has room again")
+	return true
 }
@@ -1,14 +1,30 @@
 package loop
 
 import (
+	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/brainwhocodes/lisa-loop/internal/circuit"
 	"github.com/brainwhocodes/lisa-loop/internal/state"
 )
 
+// fakeClock lets a limiter's windows slide without waiting
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newClockedLimiter(maxCalls, resetHours int) (*RateLimiter, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)}
+	limiter := NewRateLimiter(maxCalls, resetHours)
+	limiter.now = clock.now
+	return limiter, clock
+}
+
 func TestNewRateLimiter(t *testing.T) {
 	limiter := NewRateLimiter(100, 1)
 
@@ -20,8 +36,8 @@ func TestNewRateLimiter(t *testing.T) {
 		t.Errorf("NewRateLimiter() resetHours = %d, want 1", limiter.resetHours)
 	}
 
-	if limiter.currentCalls != 0 {
-		t.Errorf("NewRateLimiter() currentCalls = %d, want 0", limiter.currentCalls)
+	if limiter.CallsMade() != 0 {
+		t.Errorf("NewRateLimiter() CallsMade = %d, want 0", limiter.CallsMade())
 	}
 }
 
@@ -33,7 +49,7 @@ func TestCanMakeCall(t *testing.T) {
 		if !limiter.CanMakeCall() {
 			t.Errorf("CanMakeCall() should return true for call %d", i+1)
 		}
-		limiter.currentCalls++ // Manually increment to simulate calls
+		limiter.RecordCall()
 	}
 
 	// Should not allow beyond max
@@ -42,20 +58,6 @@ func TestCanMakeCall(t *testing.T) {
 	}
 }
 
-func TestRecordCall(t *testing.T) {
-	limiter := NewRateLimiter(100, 1)
-
-	err := limiter.RecordCall()
-
-	if err != nil {
-		t.Errorf("RecordCall() error = %v, want nil", err)
-	}
-
-	if limiter.currentCalls != 1 {
-		t.Errorf("RecordCall() currentCalls = %d, want 1", limiter.currentCalls)
-	}
-}
-
 func TestCallsRemaining(t *testing.T) {
 	limiter := NewRateLimiter(100, 1)
 
@@ -70,50 +72,44 @@ func TestCallsRemaining(t *testing.T) {
 	}
 }
 
-func TestShouldReset(t *testing.T) {
-	limiter := NewRateLimiter(100, 1)
+func TestSlidingWindow(t *testing.T) {
+	limiter, clock := newClockedLimiter(3, 1)
 
-	// Just created, should not need reset
-	if limiter.ShouldReset() {
-		t.Errorf("ShouldReset() should return false for new limiter")
-	}
+	limiter.RecordCall()
+	clock.advance(20 * time.Minute)
+	limiter.RecordCall()
+	clock.advance(20 * time.Minute)
+	limiter.RecordCall()
 
-	// Reset lastReset to 1 hour ago
-	oldTime := limiter.lastReset.Add(-1 * time.Hour)
-	limiter.lastReset = oldTime
+	if limiter.CanMakeCall() {
+		t.Fatal("CanMakeCall() = true with 3 calls in the last hour")
+	}
+	if wait := limiter.TimeUntilNextCall(); wait != 20*time.Minute {
+		t.Errorf("TimeUntilNextCall() = %v, want 20m until the first call ages out", wait)
+	}
 
-	// Now should need reset
-	if !limiter.ShouldReset() {
-		t.Errorf("ShouldReset() should return true after 1 hour")
+	// Only the oldest call leaves the window, not the whole count
+	clock.advance(20 * time.Minute)
+	if !limiter.CanMakeCall() || limiter.CallsMade() != 2 {
+		t.Errorf("after 1h: CanMakeCall() = %v, CallsMade() = %d, want true and 2", limiter.CanMakeCall(), limiter.CallsMade())
 	}
 }
 
 func TestReset(t *testing.T) {
 	limiter := NewRateLimiter(100, 1)
+	limiter.RecordCall()
 
-	err := limiter.Reset()
-
-	if err != nil {
-		t.Errorf("Reset() error = %v, want nil", err)
-	}
-
-	if limiter.currentCalls != 0 {
-		t.Errorf("Reset() currentCalls = %d, want 0", limiter.currentCalls)
-	}
+	limiter.Reset()
 
-	if limiter.lastReset.IsZero() {
-		t.Errorf("Reset() lastReset should be now")
+	if limiter.CallsMade() != 0 {
+		t.Errorf("Reset() CallsMade = %d, want 0", limiter.CallsMade())
 	}
 }
 
 func TestSetMaxCalls(t *testing.T) {
 	limiter := NewRateLimiter(100, 1)
 
-	err := limiter.SetMaxCalls(50)
-
-	if err != nil {
-		t.Errorf("SetMaxCalls() error = %v, want nil", err)
-	}
+	limiter.SetMaxCalls(50)
 
 	if limiter.maxCalls != 50 {
 		t.Errorf("SetMaxCalls() maxCalls = %d, want 50", limiter.maxCalls)
@@ -123,36 +119,18 @@ func TestSetMaxCalls(t *testing.T) {
 func TestSetResetHours(t *testing.T) {
 	limiter := NewRateLimiter(100, 1)
 
-	err := limiter.SetResetHours(2)
-
-	if err != nil {
-		t.Errorf("SetResetHours() error = %v, want nil", err)
-	}
+	limiter.SetResetHours(2)
 
 	if limiter.resetHours != 2 {
 		t.Errorf("SetResetHours() resetHours = %d, want 2", limiter.resetHours)
 	}
 }
 
-func TestTimeUntilReset(t *testing.T) {
-	limiter := NewRateLimiter(100, 1)
-
-	// Set lastReset to 30 minutes ago
-	oldTime := limiter.lastReset.Add(-30 * time.Minute)
-	limiter.lastReset = oldTime
-
-	remaining := limiter.TimeUntilReset()
-
-	// Should be around 30 minutes (1800 seconds) remaining
-	expectedSeconds := 30 * 60.0 // 30 minutes in seconds
-	if remaining.Seconds() < expectedSeconds-60 || remaining.Seconds() > expectedSeconds+60 {
-		t.Errorf("TimeUntilReset() = %v, want ~30m", remaining)
-	}
-}
-
 func TestGetStats(t *testing.T) {
 	limiter := NewRateLimiter(100, 1)
-	limiter.currentCalls = 42
+	for i := 0; i < 42; i++ {
+		limiter.RecordCall()
+	}
 
 	stats := limiter.GetStats()
 
@@ -168,63 +146,115 @@ func TestGetStats(t *testing.T) {
 		t.Errorf("GetStats() calls_remaining = %d, want 58", stats["calls_remaining"])
 	}
 
-	if _, ok := stats["last_reset"]; !ok {
-		t.Error("GetStats() should have last_reset")
-	}
-
 	if _, ok := stats["reset_hours"]; !ok {
 		t.Error("GetStats() should have reset_hours")
 	}
 
-	if _, ok := stats["time_until_reset"]; !ok {
-		t.Error("GetStats() should have time_until_reset")
+	if _, ok := stats["time_until_next_call"]; !ok {
+		t.Error("GetStats() should have time_until_next_call")
 	}
 }
 
-func TestLoadState(t *testing.T) {
-	tmpDir := t.TempDir()
+func TestSharedWindows(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calls.json")
+	windows := []RateWindow{{Calls: 2, Period: time.Hour}, {Calls: 3, Period: 24 * time.Hour}}
 
-	// Create state files
-	callCountPath := filepath.Join(tmpDir, ".call_count")
-	os.WriteFile(callCountPath, []byte("42"), 0644)
+	first, clock := newClockedLimiter(100, 1)
+	second := NewRateLimiter(100, 1)
+	second.now = clock.now
+	for _, limiter := range []*RateLimiter{first, second} {
+		if err := limiter.ShareWindows(path, windows); err != nil {
+			t.Fatalf("ShareWindows() error = %v", err)
+		}
+	}
 
-	// Test: Load state (direct file read, as LoadState() doesn't exist)
-	data, err := os.ReadFile(callCountPath)
+	// Calls of one process count against the other's shared windows
+	first.RecordCall()
+	second.RecordCall()
+	if first.CanMakeCall() {
+		t.Fatal("CanMakeCall() = true with the shared hourly window full")
+	}
+	if first.CallsMade() != 1 || first.CallsRemaining() != 0 {
+		t.Errorf("CallsMade() = %d, CallsRemaining() = %d, want 1 and 0", first.CallsMade(), first.CallsRemaining())
+	}
+	if full := first.FullWindows(); full != "2/1h shared" {
+		t.Errorf("FullWindows() = %q, want the hourly window", full)
+	}
 
-	if err != nil {
-		t.Errorf("ReadFile() error = %v, want nil", err)
+	// The hourly window frees up, then the daily window fills
+	clock.advance(time.Hour)
+	second.RecordCall()
+	if wait := first.TimeUntilNextCall(); wait != 23*time.Hour {
+		t.Errorf("TimeUntilNextCall() = %v, want 23h for the daily window", wait)
 	}
 
-	if string(data) != "42" {
-		t.Errorf("ReadFile() data = %s, want 42", string(data))
+	log, err := state.LoadCallLog(path)
+	if err != nil || len(log.Calls) != 3 {
+		t.Errorf("LoadCallLog() = %d calls, %v, want 3", len(log.Calls), err)
 	}
 }
 
-func TestSaveState(t *testing.T) {
-	tmpDir := t.TempDir()
+func TestShareWindows_CountsCallsWindow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calls.json")
+	first, clock := newClockedLimiter(2, 1)
+	second := NewRateLimiter(2, 1)
+	second.now = clock.now
+	for _, limiter := range []*RateLimiter{first, second} {
+		if err := limiter.ShareWindows(path, nil); err != nil {
+			t.Fatalf("ShareWindows() error = %v", err)
+		}
+	}
+
+	// The --calls window alone is shared once there is a call log
+	first.RecordCall()
+	second.RecordCall()
+	if first.CanMakeCall() {
+		t.Fatal("CanMakeCall() = true with the shared --calls window full")
+	}
+	if full := first.FullWindows(); full != "2/1h shared" {
+		t.Errorf("FullWindows() = %q, want the --calls window", full)
+	}
+	stats := first.GetStats()
+	if stats["current_calls"] != 1 || stats["calls_remaining"] != 0 {
+		t.Errorf("GetStats() current_calls = %v, calls_remaining = %v, want 1 and 0", stats["current_calls"], stats["calls_remaining"])
+	}
+
+	clock.advance(time.Hour)
+	if !first.CanMakeCall() {
+		t.Error("CanMakeCall() = false after the window slid past both calls")
+	}
+}
 
-	// Change to tmpDir for state files
+func TestAwaitRateLimit_ReportsWait(t *testing.T) {
+	tmpDir := t.TempDir()
 	origDir, _ := os.Getwd()
 	os.Chdir(tmpDir)
 	defer os.Chdir(origDir)
 
-	limiter := NewRateLimiter(100, 1)
-
-	// Save state
-	err := limiter.RecordCall()
+	controller := NewController(Config{MaxCalls: 10, Backend: "cli"}, NewRateLimiter(1, 1), circuit.NewBreaker(3, 5))
+	controller.rateLimiter.RecordCall()
+
+	var waits []time.Duration
+	var logs []string
+	controller.SetEventCallback(func(event LoopEvent) {
+		switch {
+		case event.Type == EventTypeLoopUpdate && event.Status == "rate_limited":
+			waits = append(waits, event.RateLimitWait)
+		case event.Type == EventTypeLog:
+			logs = append(logs, event.LogMessage)
+		}
+	})
 
-	if err != nil {
-		t.Errorf("SaveState() error = %v, want nil", err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if controller.awaitRateLimit(ctx) {
+		t.Fatal("awaitRateLimit() = true after cancellation with the window full")
 	}
 
-	// Verify by loading count directly
-	loadedCount, err := state.LoadCallCount()
-
-	if err != nil {
-		t.Errorf("LoadCallCount() error = %v, want nil", err)
+	if len(waits) != 1 || waits[0] <= 59*time.Minute || waits[0] > time.Hour {
+		t.Errorf("rate_limited waits = %v, want one of about 1h", waits)
 	}
-
-	if loadedCount != 1 {
-		t.Errorf("LoadCallCount() = %d, want 1", loadedCount)
+	if len(logs) != 1 || !strings.HasPrefix(logs[0], "Rate limit reached (1/1h); next call in") {
+		t.Errorf("logs = %q", logs)
 	}
 }
@@ -0,0 +1,99 @@
+package loop
+
+import (
+	stdcontext "context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/brainwhocodes/lisa-loop/internal/circuit"
+	"github.com/brainwhocodes/lisa-loop/internal/codex"
+	"github.com/brainwhocodes/lisa-loop/internal/runner"
+)
+
+// emitStateChange sends a circuit breaker transition as a state change event
+func (c *Controller) emitStateChange(from, to circuit.State, reason string) {
+	c.emit(LoopEvent{
+		Type:             EventTypeStateChange,
+		LoopNumber:       c.loopNum,
+		CircuitState:     to.String(),
+		PrevCircuitState: from.String(),
+		LogMessage:       reason,
+	})
+}
+
+// awaitCooldown waits out the cool-down of an OPEN circuit and moves it to HALF_OPEN
+// Returns false if ctx ended first; a circuit that stays open until reset returns at once
+func (c *Controller) awaitCooldown(ctx stdcontext.Context) bool {
+	at, ok := c.breaker.NextProbe()
+	if !ok {
+		return true
+	}
+
+	if wait := time.Until(at); wait > 0 {
+		c.emitLog(LogLevelWarn, fmt.Sprintf("Circuit breaker is OPEN; probing again in %s", wait.Round(time.Second)))
+		c.emitUpdate("circuit_cooldown")
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+	}
+
+	if _, err := c.breaker.CheckCooldown(); err != nil {
+		c.emitLog(LogLevelWarn, fmt.Sprintf("Failed to save circuit breaker state: %v", err))
+	}
+	return true
+}
+
+// iterationPrompt loads the prompt for the next iteration
+// Probes use the configured probe prompt, if any
+func (c *Controller) iterationPrompt(probing bool) (string, error) {
+	if !probing || c.runnerConfig.CircuitProbePrompt == "" {
+		return GetPrompt()
+	}
+	data, err := os.ReadFile(c.runnerConfig.CircuitProbePrompt)
+	if err != nil {
+		return "", fmt.Errorf("failed to read probe prompt: %w", err)
+	}
+	return string(data), nil
+}
+
+// iterationRunner returns the runner for the next iteration
+// Probes use a runner for the configured probe model, if the backend supports one
+func (c *Controller) iterationRunner(probing bool) runner.Runner {
+	model := c.runnerConfig.CircuitProbeModel
+	if !probing || model == "" {
+		return c.runner
+	}
+	if c.probeRunner != nil {
+		return c.probeRunner
+	}
+
+	cfg, ok := probeConfig(c.runnerConfig, model)
+	if !ok {
+		c.emitLog(LogLevelWarn, fmt.Sprintf("The %s backend has no model setting; probing with the usual model", c.backend))
+		c.probeRunner = c.runner
+		return c.runner
+	}
+	c.probeRunner = c.newRunner(cfg)
+	c.probeRunner.SetOutputCallback(func(event runner.Event) {
+		c.handleCodexEvent(codex.Event(event))
+	})
+	return c.probeRunner
+}
+
+// probeConfig returns cfg with its backend's model set to model
+func probeConfig(cfg Config, model string) (Config, bool) {
+	switch cfg.Backend {
+	case "opencode":
+		cfg.OpenCodeModelID = model
+	case "openai":
+		cfg.OpenAIModel = model
+	default:
+		return cfg, false
+	}
+	return cfg, true
+}
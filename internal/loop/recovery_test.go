@@ -0,0 +1,94 @@
+package loop
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brainwhocodes/lisa-loop/internal/circuit"
+)
+
+func TestExecuteLoop_HalfOpenProbe(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	os.WriteFile("@fix_plan.md", []byte("- [ ] First task\n- [ ] Second task\n"), 0644)
+	os.WriteFile("PROMPT.md", []byte("Test prompt"), 0644)
+	os.WriteFile("PROBE.md", []byte("Probe prompt"), 0644)
+
+	breaker := circuit.NewBreaker(3, 1)
+	controller := NewController(Config{MaxCalls: 10, Backend: "cli", CircuitProbePrompt: "PROBE.md"}, NewRateLimiter(10, 1), breaker)
+	breaker.SetRecovery(time.Millisecond, 1)
+	runner := &stuckRunner{touchFile: "main.go", output: `---RALPH_STATUS---
+STATUS: WORKING
+FILES_MODIFIED: 1
+TESTS_STATUS: PASSING
+EXIT_SIGNAL: false
+---END_RALPH_STATUS---`}
+	controller.SetRunner(runner)
+
+	var changes []string
+	controller.SetEventCallback(func(event LoopEvent) {
+		if event.Type == EventTypeStateChange {
+			changes = append(changes, event.PrevCircuitState+">"+event.CircuitState)
+		}
+	})
+
+	breaker.RecordError("agent crashed")
+	breaker.RecordError("agent crashed")
+	if !breaker.IsOpen() {
+		t.Fatalf("breaker state = %s, want OPEN", breaker.GetState())
+	}
+	if controller.ShouldContinue() {
+		t.Error("ShouldContinue() should keep an OPEN circuit with a cool-down running")
+	}
+
+	if !controller.awaitCooldown(context.Background()) || !breaker.IsProbing() {
+		t.Fatalf("awaitCooldown() left the breaker %s, want probing", breaker.GetState())
+	}
+	if err := controller.ExecuteLoop(context.Background()); err != nil {
+		t.Fatalf("ExecuteLoop() error = %v", err)
+	}
+	if !strings.Contains(runner.prompts[0], "Probe prompt") || strings.Contains(runner.prompts[0], "Test prompt") {
+		t.Errorf("probe should use the probe prompt:\n%s", runner.prompts[0])
+	}
+	if !breaker.IsClosed() {
+		t.Errorf("breaker state = %s after a successful probe, want CLOSED", breaker.GetState())
+	}
+
+	want := "CLOSED>HALF_OPEN HALF_OPEN>OPEN OPEN>HALF_OPEN HALF_OPEN>CLOSED"
+	if got := strings.Join(changes, " "); got != want {
+		t.Errorf("state changes = %s, want %s", got, want)
+	}
+
+	// Closed again, iterations use the usual prompt
+	controller.ExecuteLoop(context.Background())
+	if !strings.Contains(runner.prompts[1], "Test prompt") {
+		t.Errorf("iteration after recovery should use the usual prompt:\n%s", runner.prompts[1])
+	}
+}
+
+func TestAwaitCooldown_Cancelled(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	breaker := circuit.NewBreaker(3, 1)
+	controller := NewController(Config{MaxCalls: 10, Backend: "cli", CircuitCooldown: 3600}, NewRateLimiter(10, 1), breaker)
+	breaker.RecordError("agent crashed")
+	breaker.RecordError("agent crashed")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if controller.awaitCooldown(ctx) {
+		t.Error("awaitCooldown() should give up when the context ends")
+	}
+	if !breaker.IsOpen() {
+		t.Errorf("breaker state = %s, want OPEN", breaker.GetState())
+	}
+}
@@ -0,0 +1,79 @@
+package loop
+
+import (
+	"fmt"
+
+	"github.com/brainwhocodes/lisa-loop/internal/analysis"
+	"github.com/brainwhocodes/lisa-loop/internal/plan"
+)
+
+// recordToolReport keeps the arguments of a lisa_report_status tool call made by the
+// agent of a parallel worker (0 for the serial loop) until its output is analyzed
+func (c *Controller) recordToolReport(worker int, report []byte) {
+	c.reportMu.Lock()
+	defer c.reportMu.Unlock()
+	if c.toolReports == nil {
+		c.toolReports = make(map[int][]byte)
+	}
+	c.toolReports[worker] = report
+}
+
+// takeToolReport returns and forgets the status report a worker's agent sent as a tool call
+func (c *Controller) takeToolReport(worker int) []byte {
+	c.reportMu.Lock()
+	defer c.reportMu.Unlock()
+	report := c.toolReports[worker]
+	delete(c.toolReports, worker)
+	return report
+}
+
+// checkStatusReport checks a loop's status report against the plan it was written for
+// Task IDs must name tasks in the plan, and the current task's ID is resolved to its
+// text. Returns the problems to feed back to the agent, including those that got the
+// report rejected.
+func (c *Controller) checkStatusReport(worker int, result *analysis.Analysis, doc *plan.Plan) []string {
+	if result == nil {
+		return nil
+	}
+	problems := append([]string(nil), result.ReportErrors...)
+
+	if report := result.Report; report != nil && doc != nil {
+		for i, task := range report.Tasks {
+			if doc.Find(task.ID) == nil {
+				problems = append(problems, fmt.Sprintf("tasks[%d].id: no task %q in the plan", i, task.ID))
+			}
+		}
+		if task := doc.Find(report.CurrentTask); task != nil {
+			result.Status.CurrentTask = task.Text
+		}
+
+		for _, blocker := range report.Blockers {
+			c.emitWorkerLog(worker, LogLevelWarn, fmt.Sprintf("Blocker: %s", blocker))
+		}
+		for _, followUp := range report.FollowUpTasks {
+			c.emitWorkerLog(worker, LogLevelInfo, fmt.Sprintf("Follow-up task suggested: %s", followUp))
+		}
+	}
+
+	for _, problem := range problems {
+		c.emitWorkerLog(worker, LogLevelWarn, fmt.Sprintf("Status report: %s", problem))
+	}
+	return problems
+}
+
+// emitWorkerLog sends a log event, prefixed with the parallel worker it concerns
+func (c *Controller) emitWorkerLog(worker int, level LogLevel, message string) {
+	if worker > 0 {
+		message = fmt.Sprintf("Worker %d: %s", worker, message)
+	}
+	c.emitLog(level, message)
+}
+
+// contextTaskStrings formats tasks for the loop context with the IDs status reports use
+func contextTaskStrings(tasks []*plan.Task) []string {
+	out := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		out = append(out, fmt.Sprintf("%s (id: %s)", task, task.ID))
+	}
+	return out
+}
@@ -0,0 +1,77 @@
+package loop
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/brainwhocodes/lisa-loop/internal/analysis"
+	"github.com/brainwhocodes/lisa-loop/internal/circuit"
+	"github.com/brainwhocodes/lisa-loop/internal/runner"
+)
+
+// reportingRunner sends each of its reports as a lisa_report_status tool call
+type reportingRunner struct {
+	reports []string
+	prompts []string
+	cb      runner.OutputCallback
+}
+
+func (r *reportingRunner) Run(ctx context.Context, prompt string) (string, string, error) {
+	r.prompts = append(r.prompts, prompt)
+	report := r.reports[len(r.prompts)-1]
+	if r.cb != nil {
+		r.cb(map[string]interface{}{"type": "tool_use", "name": analysis.StatusTool, "arguments": report})
+	}
+	return "Worked on the parser.", "test-session", nil
+}
+
+func (r *reportingRunner) SetOutputCallback(cb runner.OutputCallback) { r.cb = cb }
+
+func (r *reportingRunner) Stop() error { return nil }
+
+func TestExecuteLoop_StatusReportFeedback(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	os.WriteFile("@fix_plan.md", []byte("- [ ] First task <!-- id: T1 -->\n- [ ] Second task <!-- id: T2 -->\n"), 0644)
+	os.WriteFile("PROMPT.md", []byte("Test prompt"), 0644)
+
+	controller := NewController(Config{MaxCalls: 10, Backend: "cli"}, NewRateLimiter(10, 1), circuit.NewBreaker(3, 5))
+	r := &reportingRunner{reports: []string{
+		`{"version": 1, "status": "WORKING", "current_task": "T1", "tasks": [{"id": "T9", "outcome": "in_progress"}], "tests_status": "UNKNOWN", "exit_signal": false}`,
+		`{"version": 1, "status": "WORKING", "current_task": "T1", "tasks": [{"id": "T1", "outcome": "in_progress"}], "tests_status": "UNKNOWN", "exit_signal": false}`,
+	}}
+	controller.SetRunner(r)
+
+	for i := 0; i < 2; i++ {
+		if err := controller.ExecuteLoop(context.Background()); err != nil {
+			t.Fatalf("ExecuteLoop() #%d error = %v", i+1, err)
+		}
+		controller.loopNum++
+	}
+
+	// Tasks are offered with the IDs reports refer to
+	if !strings.Contains(r.prompts[0], "[ ] First task (id: T1)") {
+		t.Errorf("first prompt should list task IDs:\n%s", r.prompts[0])
+	}
+	if strings.Contains(r.prompts[0], "STATUS REPORT REJECTED") {
+		t.Errorf("first prompt should not report problems")
+	}
+
+	// The unknown task ID is fed back in the next loop
+	if !strings.Contains(r.prompts[1], "STATUS REPORT REJECTED") || !strings.Contains(r.prompts[1], `tasks[0].id: no task "T9" in the plan`) {
+		t.Errorf("second prompt should feed back the report's problems:\n%s", r.prompts[1])
+	}
+	if len(controller.statusErrors) != 0 {
+		t.Errorf("statusErrors = %q after a valid report", controller.statusErrors)
+	}
+
+	// The current task ID is resolved to the task's text
+	if controller.currentTask != "First task" {
+		t.Errorf("currentTask = %q, want the text of T1", controller.currentTask)
+	}
+}
@@ -0,0 +1,116 @@
+package loop
+
+import (
+	stdcontext "context"
+	"fmt"
+	"time"
+
+	"github.com/brainwhocodes/lisa-loop/internal/checkpoint"
+	"github.com/brainwhocodes/lisa-loop/internal/state"
+)
+
+// ResumeRun continues a recorded run instead of starting a new one
+// The run ID, loop counter, backend sessions, circuit breaker, rate-limit window and
+// spending are restored. A breaker state saved after the record, as by "reset-circuit"
+// in between, is kept instead; shared rate-limit windows count calls from the user's
+// call log anyway.
+func (c *Controller) ResumeRun(rec state.RunRecord) error {
+	if !rec.Resumable() {
+		if rec.ID == "" {
+			return fmt.Errorf("no run to resume")
+		}
+		return fmt.Errorf("run %s already finished after %d loops; nothing to resume", rec.ID, rec.Loop)
+	}
+
+	if len(rec.Sessions) > 0 {
+		sessions, err := state.LoadSessions()
+		if err != nil {
+			return fmt.Errorf("failed to load sessions: %w", err)
+		}
+		for backend, sess := range rec.Sessions {
+			sessions[backend] = sess
+		}
+		if err := state.SaveSessions(sessions); err != nil {
+			return fmt.Errorf("failed to restore sessions: %w", err)
+		}
+	}
+
+	circuitState, err := state.LoadCircuitBreakerState()
+	if err != nil || !circuitState.LastCheckTime.After(rec.UpdatedAt) {
+		circuitState = rec.Circuit
+	}
+	if err := c.breaker.Restore(circuitState); err != nil {
+		return fmt.Errorf("failed to restore circuit breaker: %w", err)
+	}
+	c.rateLimiter.RestoreCalls(rec.RateCalls)
+
+	c.runID = rec.ID
+	c.runStarted = rec.StartedAt
+	c.loopNum = rec.Loop
+	c.currentTask = rec.CurrentTask
+	c.budget.SetRunUsage(rec.Usage)
+	c.resumed = true
+	if c.checkpoints != nil {
+		c.checkpoints = checkpoint.NewManager("", c.runID)
+	}
+	return nil
+}
+
+// StartFresh discards the previous run's circuit breaker history and backend sessions
+func (c *Controller) StartFresh() error {
+	if err := c.breaker.Reset(); err != nil {
+		return fmt.Errorf("failed to reset circuit breaker: %w", err)
+	}
+	if err := state.SaveSessions(state.Sessions{}); err != nil {
+		return fmt.Errorf("failed to clear sessions: %w", err)
+	}
+	return nil
+}
+
+// beginRunRecord records the run as in progress
+func (c *Controller) beginRunRecord() {
+	if c.resumed {
+		task := ""
+		if c.currentTask != "" {
+			task = fmt.Sprintf(" (last task: %s)", c.currentTask)
+		}
+		c.emitLog(LogLevelInfo, fmt.Sprintf("Resuming run %s after loop %d%s", c.runID, c.loopNum, task))
+	} else if prev, err := state.LoadRunRecord(); err == nil && prev.Resumable() && prev.ID != c.runID {
+		c.emitLog(LogLevelInfo, fmt.Sprintf("Run %s was %s after loop %d; starting a new run (use --resume to continue it)", prev.ID, prev.Status, prev.Loop))
+	}
+
+	if c.runStarted.IsZero() {
+		c.runStarted = time.Now()
+	}
+	c.loadBudget()
+	c.saveRunRecord(state.RunRunning)
+}
+
+// endRunRecord records how Run returned
+func (c *Controller) endRunRecord(ctx stdcontext.Context) {
+	status := state.RunFinished
+	if ctx.Err() != nil {
+		status = state.RunInterrupted
+	}
+	c.saveRunRecord(status)
+}
+
+// saveRunRecord snapshots the run's position, sessions, breaker, rate limit and spending
+func (c *Controller) saveRunRecord(status string) {
+	rec := state.RunRecord{
+		ID:          c.runID,
+		Status:      status,
+		StartedAt:   c.runStarted,
+		UpdatedAt:   time.Now(),
+		Loop:        c.loopNum,
+		CurrentTask: c.currentTask,
+		Usage:       c.budget.RunUsage(),
+	}
+	rec.Sessions, _ = state.LoadSessions()
+	rec.Circuit, _ = state.LoadCircuitBreakerState()
+	rec.RateCalls = c.rateLimiter.Calls()
+
+	if err := state.SaveRunRecord(rec); err != nil {
+		c.emitLog(LogLevelWarn, fmt.Sprintf("Failed to save run record: %v", err))
+	}
+}
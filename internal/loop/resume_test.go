@@ -0,0 +1,154 @@
+package loop
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/brainwhocodes/lisa-loop/internal/circuit"
+	"github.com/brainwhocodes/lisa-loop/internal/state"
+)
+
+// cancelAfterRunner marks a task, then cancels the run as if Ctrl+C arrived mid-loop
+type cancelAfterRunner struct {
+	*planMarkingRunner
+	cancel context.CancelFunc
+}
+
+func (r *cancelAfterRunner) Run(ctx context.Context, prompt string) (string, string, error) {
+	output, sessionID, err := r.planMarkingRunner.Run(ctx, prompt)
+	r.cancel()
+	return output, sessionID, err
+}
+
+func TestResumeRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	os.WriteFile("@fix_plan.md", []byte("- [ ] First task\n- [ ] Second task\n- [ ] Third task\n"), 0644)
+	os.WriteFile("PROMPT.md", []byte("Test prompt"), 0644)
+	state.SaveCodexSession("thread-1")
+
+	statusOutput := `---RALPH_STATUS---
+STATUS: WORKING
+CURRENT_TASK: First task
+TASKS_COMPLETED_THIS_LOOP: 1
+FILES_MODIFIED: 1
+TESTS_STATUS: PASSING
+EXIT_SIGNAL: false
+---END_RALPH_STATUS---`
+	marker := &planMarkingRunner{planFile: "@fix_plan.md", output: statusOutput}
+
+	// First run is interrupted after one iteration
+	ctx, cancel := context.WithCancel(context.Background())
+	first := NewController(Config{MaxCalls: 5, Backend: "cli"}, NewRateLimiter(10, 1), circuit.NewBreaker(3, 5))
+	first.SetRunner(&cancelAfterRunner{planMarkingRunner: marker, cancel: cancel})
+	if err := first.Run(ctx); err == nil {
+		t.Fatal("Run() should report the cancellation")
+	}
+
+	rec, err := state.LoadRunRecord()
+	if err != nil {
+		t.Fatalf("LoadRunRecord() error = %v", err)
+	}
+	if rec.ID != first.RunID() || rec.Status != state.RunInterrupted || rec.Loop != 1 {
+		t.Errorf("run record = %+v, want %s interrupted after loop 1", rec, first.RunID())
+	}
+	if rec.Sessions["codex"].ID != "thread-1" || rec.CurrentTask != "First task" {
+		t.Errorf("run record sessions/task = %+v / %q, want thread-1 / First task", rec.Sessions, rec.CurrentTask)
+	}
+	if len(rec.RateCalls) != 1 {
+		t.Errorf("run record rate calls = %v, want the first run's call", rec.RateCalls)
+	}
+
+	// The session and breaker history are lost in between; resuming restores them and
+	// the rate-limit window, and continues the run
+	state.SaveCodexSession("")
+	rec.Circuit.NoProgressCount = 1
+	state.SaveCircuitBreakerState(state.CircuitState{State: "CLOSED"})
+	limiter := NewRateLimiter(10, 1)
+	breaker := circuit.NewBreaker(3, 5)
+	second := NewController(Config{MaxCalls: 5, Backend: "cli"}, limiter, breaker)
+	second.SetRunner(marker)
+	if err := second.ResumeRun(rec); err != nil {
+		t.Fatalf("ResumeRun() error = %v", err)
+	}
+	if id, _ := state.LoadCodexSession(); id != "thread-1" {
+		t.Errorf("codex session after resume = %q, want thread-1", id)
+	}
+	if breaker.GetNoProgressCount() != 1 {
+		t.Errorf("no-progress count after resume = %d, want the recorded 1", breaker.GetNoProgressCount())
+	}
+	if limiter.CallsMade() != 1 {
+		t.Errorf("rate-limited calls after resume = %d, want the first run's call", limiter.CallsMade())
+	}
+	if err := second.Run(context.Background()); err != nil {
+		t.Fatalf("resumed Run() error = %v", err)
+	}
+
+	rec, _ = state.LoadRunRecord()
+	if second.RunID() != first.RunID() || rec.Status != state.RunFinished || rec.Loop != 3 {
+		t.Errorf("run record = %+v, want %s finished after loop 3", rec, first.RunID())
+	}
+	history, _ := LoadRunHistory("")
+	if len(history) != 1 || history[0].Iterations != 3 {
+		t.Errorf("history = %+v, want one run with 3 iterations", history)
+	}
+
+	// A finished run cannot be resumed
+	if err := NewController(Config{MaxCalls: 5}, NewRateLimiter(10, 1), circuit.NewBreaker(3, 5)).ResumeRun(rec); err == nil {
+		t.Error("ResumeRun() of a finished run should fail")
+	}
+}
+
+func TestGracefulExit_KeepsState(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	state.SaveCodexSession("thread-1")
+	breaker := circuit.NewBreaker(3, 5)
+	for i := 0; i < 3; i++ {
+		breaker.RecordResult(i, 0, false)
+	}
+
+	controller := NewController(Config{MaxCalls: 5, Backend: "cli"}, NewRateLimiter(10, 1), breaker)
+	controller.SetRunner(&planMarkingRunner{})
+
+	// The signal cancels the run; Run records it before GracefulExit cleans up
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := controller.Run(ctx); err == nil {
+		t.Fatal("Run() should report the cancellation")
+	}
+	if !controller.WaitForRun(time.Second) {
+		t.Fatal("WaitForRun() = false after Run returned")
+	}
+	if err := controller.GracefulExit(); err != nil {
+		t.Fatalf("GracefulExit() error = %v", err)
+	}
+
+	if cs, _ := state.LoadCircuitBreakerState(); cs.State == circuit.StateClosed.String() || cs.NoProgressCount != 3 {
+		t.Errorf("circuit state after exit = %+v, want the no-progress history kept", cs)
+	}
+	if id, _ := state.LoadCodexSession(); id != "thread-1" {
+		t.Errorf("codex session after exit = %q, want thread-1 kept", id)
+	}
+	if rec, _ := state.LoadRunRecord(); rec.Status != state.RunInterrupted || !rec.Resumable() {
+		t.Errorf("run record = %+v, want a resumable interrupted run", rec)
+	}
+
+	if err := controller.StartFresh(); err != nil {
+		t.Fatalf("StartFresh() error = %v", err)
+	}
+	if cs, _ := state.LoadCircuitBreakerState(); cs.State != circuit.StateClosed.String() {
+		t.Errorf("circuit state after StartFresh = %s, want CLOSED", cs.State)
+	}
+	if id, _ := state.LoadCodexSession(); id != "" {
+		t.Errorf("codex session after StartFresh = %q, want none", id)
+	}
+}
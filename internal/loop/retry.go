@@ -0,0 +1,166 @@
+package loop
+
+import (
+	stdcontext "context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/brainwhocodes/lisa-loop/internal/runner"
+)
+
+// Backoff after failed iterations: transient failures start at retryBaseDelay and
+// quota errors that don't say when the quota resets at quotaBaseDelay, doubling with
+// every consecutive failure up to the class's maximum
+const (
+	retryBaseDelay = 5 * time.Second
+	retryMaxDelay  = 5 * time.Minute
+	quotaBaseDelay = time.Minute
+	quotaMaxDelay  = time.Hour
+)
+
+// FatalError is a backend failure retrying cannot fix, with what to do about it
+type FatalError struct {
+	Class runner.ErrorClass
+	Hint  string
+	Err   error
+}
+
+func (e *FatalError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Hint, e.Err)
+}
+
+func (e *FatalError) Unwrap() error {
+	return e.Err
+}
+
+// handleLoopError reacts to a failed iteration according to the class of its error
+// Returns a *FatalError to stop the run; otherwise the next iteration may start
+func (c *Controller) handleLoopError(ctx stdcontext.Context, err error) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	cls := runner.Classify(err)
+	c.consecutiveErrors++
+	c.emitLog(LogLevelError, fmt.Sprintf("Loop iteration error (%s): %v", cls.Class, err))
+	c.emitUpdate("error")
+
+	if cls.Class.Fatal() {
+		fatal := &FatalError{Class: cls.Class, Hint: fatalHint(c.backend, cls), Err: err}
+		c.emitLog(LogLevelError, fatal.Hint)
+		c.emitUpdate("fatal_error")
+		return fatal
+	}
+
+	// A new session fixes an overflowing one, unless the last retry already started one
+	if cls.Class == runner.ErrorContextOverflow && !c.sessionRotated {
+		if c.rotateSession() {
+			c.sessionRotated = true
+			c.emitLog(LogLevelInfo, "Starting new loop iteration after error...")
+			return nil
+		}
+	}
+
+	delay := retryDelay(cls, c.consecutiveErrors, rand.Float64)
+	update := c.loopUpdate("retry_wait")
+	if cls.Class == runner.ErrorQuota {
+		c.emitLog(LogLevelWarn, fmt.Sprintf("Backend quota exhausted; waiting %s for it to reset", delay.Round(time.Second)))
+		update.Status = "quota_wait"
+	} else {
+		c.emitLog(LogLevelInfo, fmt.Sprintf("Waiting %s before retrying (attempt %d)...", delay.Round(time.Second), c.consecutiveErrors))
+	}
+	update.RateLimitWait = delay
+	c.emit(update)
+	if !sleepContext(ctx, delay) {
+		return nil
+	}
+	c.emitLog(LogLevelInfo, "Starting new loop iteration after error...")
+	return nil
+}
+
+// resetErrors clears the failure streak after a successful iteration
+func (c *Controller) resetErrors() {
+	c.consecutiveErrors = 0
+	c.sessionRotated = false
+}
+
+// rotateSession starts the runners on new sessions; false if none keeps a session
+func (c *Controller) rotateSession() bool {
+	rotated := false
+	for _, r := range []runner.Runner{c.runner, c.probeRunner} {
+		if r == nil || (r == c.probeRunner && c.probeRunner == c.runner) {
+			continue
+		}
+		ok, err := runner.NewSession(r)
+		if err != nil {
+			c.emitLog(LogLevelWarn, fmt.Sprintf("Failed to start a new session: %v", err))
+			continue
+		}
+		rotated = rotated || ok
+	}
+	if rotated {
+		c.emitLog(LogLevelWarn, "Context window exceeded; starting a new session")
+	} else {
+		c.emitLog(LogLevelWarn, "Context window exceeded and the backend keeps no session; the prompt itself may be too large")
+	}
+	return rotated
+}
+
+// retryDelay returns how long to wait after the attempt-th consecutive failure of class
+// cls; rnd supplies the jitter, which spreads each delay over its upper half
+func retryDelay(cls runner.Classification, attempt int, rnd func() float64) time.Duration {
+	if cls.Class == runner.ErrorQuota && cls.RetryAfter > 0 {
+		return cls.RetryAfter
+	}
+
+	base, limit := retryBaseDelay, retryMaxDelay
+	if cls.Class == runner.ErrorQuota {
+		base, limit = quotaBaseDelay, quotaMaxDelay
+	}
+	delay := base
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		delay = limit
+	}
+	return delay/2 + time.Duration(rnd()*float64(delay/2))
+}
+
+// fatalHint tells the user how to fix a fatal backend error
+func fatalHint(backend string, cls runner.Classification) string {
+	if cls.Class == runner.ErrorMissingBinary {
+		switch backend {
+		case "opencode":
+			return "The opencode CLI is not installed or not on PATH; install it or connect to a running server with --opencode-url"
+		case "command":
+			return fmt.Sprintf("%s is not installed or not on PATH; install it or fix the agent command (--command)", cls.Binary)
+		default:
+			return "The codex CLI is not installed or not on PATH; install it (npm install -g @openai/codex) or choose another --backend"
+		}
+	}
+
+	switch backend {
+	case "opencode":
+		return "OpenCode rejected the credentials; check --opencode-user and --opencode-pass (OPENCODE_SERVER_PASSWORD) and the provider login"
+	case "openai":
+		return "The endpoint rejected the API key; set --openai-key or OPENAI_API_KEY"
+	case "command":
+		return "The agent command is not authenticated; log in with its CLI and run Lisa again"
+	default:
+		return "Codex is not authenticated; run `codex login` and run Lisa again"
+	}
+}
+
+// sleepContext waits for d; false if ctx ended first
+func sleepContext(ctx stdcontext.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,237 @@
+package loop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brainwhocodes/lisa-loop/internal/circuit"
+	"github.com/brainwhocodes/lisa-loop/internal/runner"
+	"github.com/brainwhocodes/lisa-loop/internal/state"
+)
+
+// failingRunner returns each of errs in turn, then completes the plan
+type failingRunner struct {
+	errs     []error
+	calls    int
+	sessions int // Calls to NewSession
+}
+
+func (r *failingRunner) Run(ctx context.Context, prompt string) (string, string, error) {
+	r.calls++
+	if r.calls <= len(r.errs) {
+		return "", "", r.errs[r.calls-1]
+	}
+	os.WriteFile("@fix_plan.md", []byte("- [x] First task\n"), 0644)
+	return `---RALPH_STATUS---
+STATUS: COMPLETE
+TASKS_COMPLETED_THIS_LOOP: 1
+FILES_MODIFIED: 1
+EXIT_SIGNAL: true
+---END_RALPH_STATUS---`, "test-session", nil
+}
+
+func (r *failingRunner) SetOutputCallback(cb runner.OutputCallback) {}
+
+func (r *failingRunner) Stop() error { return nil }
+
+func (r *failingRunner) NewSession() error {
+	r.sessions++
+	return nil
+}
+
+func TestRun_StopsOnFatalError(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	os.WriteFile("@fix_plan.md", []byte("- [ ] First task\n"), 0644)
+	os.WriteFile("PROMPT.md", []byte("Test prompt"), 0644)
+
+	controller := NewController(Config{MaxCalls: 10, Backend: "cli"}, NewRateLimiter(10, 1), circuit.NewBreaker(3, 5))
+	notFound := fmt.Errorf("failed to start codex: %w", &exec.Error{Name: "codex", Err: exec.ErrNotFound})
+	fake := &failingRunner{errs: []error{notFound}}
+	controller.SetRunner(fake)
+
+	start := time.Now()
+	err := controller.Run(context.Background())
+	var fatal *FatalError
+	if !errors.As(err, &fatal) || fatal.Class != runner.ErrorMissingBinary {
+		t.Fatalf("Run() error = %v, want a missing binary *FatalError", err)
+	}
+	if !strings.Contains(fatal.Error(), "codex CLI is not installed") {
+		t.Errorf("FatalError = %q, want an actionable message", fatal.Error())
+	}
+	if fake.calls != 1 {
+		t.Errorf("runner called %d times, want 1", fake.calls)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Run() took %s, want an immediate stop", elapsed)
+	}
+}
+
+func TestRun_RotatesSessionOnContextOverflow(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	os.WriteFile("@fix_plan.md", []byte("- [ ] First task\n"), 0644)
+	os.WriteFile("PROMPT.md", []byte("Test prompt"), 0644)
+
+	controller := NewController(Config{MaxCalls: 10, Backend: "cli"}, NewRateLimiter(10, 1), circuit.NewBreaker(3, 5))
+	fake := &failingRunner{errs: []error{errors.New("session error: prompt is too long: 210000 tokens > 200000 maximum")}}
+	controller.SetRunner(fake)
+
+	start := time.Now()
+	if err := controller.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if fake.sessions != 1 || fake.calls != 2 {
+		t.Errorf("sessions = %d, calls = %d; want a new session and one retry", fake.sessions, fake.calls)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Run() took %s, want the retry without a wait", elapsed)
+	}
+	if controller.consecutiveErrors != 0 || controller.sessionRotated {
+		t.Error("a successful iteration should reset the failure streak")
+	}
+}
+
+func TestExecuteLoop_TimeoutOutcome(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	os.WriteFile("@fix_plan.md", []byte("- [ ] First task\n"), 0644)
+	os.WriteFile("PROMPT.md", []byte("Test prompt"), 0644)
+
+	breaker := circuit.NewBreaker(3, 5)
+	controller := NewController(Config{MaxCalls: 10, Backend: "cli"}, NewRateLimiter(10, 1), breaker)
+	controller.SetRunner(&failingRunner{errs: []error{fmt.Errorf("codex timed out after 10m0s: %w", context.DeadlineExceeded)}})
+
+	var outcome *LoopOutcome
+	var statuses []string
+	controller.SetEventCallback(func(event LoopEvent) {
+		switch event.Type {
+		case EventTypeOutcome:
+			outcome = event.Outcome
+		case EventTypeLoopUpdate:
+			statuses = append(statuses, event.Status)
+		}
+	})
+
+	if err := controller.ExecuteLoop(context.Background()); err == nil {
+		t.Fatal("ExecuteLoop() should return the timeout")
+	}
+	if outcome == nil || outcome.Success || !outcome.TimedOut {
+		t.Errorf("outcome = %+v, want a timed out failure", outcome)
+	}
+	if !strings.Contains(strings.Join(statuses, ","), "execution_timeout") {
+		t.Errorf("statuses = %v, want execution_timeout", statuses)
+	}
+}
+
+// cancellingRunner cancels the run while the call is in flight, as Ctrl+C would
+type cancellingRunner struct {
+	cancel context.CancelFunc
+}
+
+func (r *cancellingRunner) Run(ctx context.Context, prompt string) (string, string, error) {
+	r.cancel()
+	<-ctx.Done()
+	return "", "", fmt.Errorf("codex cancelled: %w", ctx.Err())
+}
+
+func (r *cancellingRunner) SetOutputCallback(cb runner.OutputCallback) {}
+
+func (r *cancellingRunner) Stop() error { return nil }
+
+func TestExecuteLoop_Cancelled(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	os.WriteFile("@fix_plan.md", []byte("- [ ] First task\n"), 0644)
+	os.WriteFile("PROMPT.md", []byte("Test prompt"), 0644)
+
+	breaker := circuit.NewBreaker(3, 5)
+	controller := NewController(Config{MaxCalls: 10, Backend: "cli"}, NewRateLimiter(10, 1), breaker)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	controller.SetRunner(&cancellingRunner{cancel: cancel})
+
+	if err := controller.ExecuteLoop(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ExecuteLoop() error = %v, want context.Canceled", err)
+	}
+	if groups := breaker.GetErrorGroups(); len(groups) != 0 {
+		t.Errorf("breaker recorded %v, want a cancellation not counted as an error", groups)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	transient := runner.Classification{Class: runner.ErrorTransient}
+	low := func() float64 { return 0 }
+	high := func() float64 { return 1 }
+
+	tests := []struct {
+		name    string
+		cls     runner.Classification
+		attempt int
+		rnd     func() float64
+		want    time.Duration
+	}{
+		{"first attempt, least jitter", transient, 1, low, retryBaseDelay / 2},
+		{"first attempt, most jitter", transient, 1, high, retryBaseDelay},
+		{"doubles", transient, 3, high, 4 * retryBaseDelay},
+		{"capped", transient, 20, high, retryMaxDelay},
+		{"quota without reset", runner.Classification{Class: runner.ErrorQuota}, 2, high, 2 * quotaBaseDelay},
+		{"quota capped", runner.Classification{Class: runner.ErrorQuota}, 20, high, quotaMaxDelay},
+		{"quota reset", runner.Classification{Class: runner.ErrorQuota, RetryAfter: 90 * time.Second}, 5, high, 90 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryDelay(tt.cls, tt.attempt, tt.rnd); got != tt.want {
+				t.Errorf("retryDelay() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteLoop_BackendErrorsSpendNoFocusAttempt(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	os.WriteFile("@fix_plan.md", []byte("- [ ] First task\n"), 0644)
+	os.WriteFile("PROMPT.md", []byte("Test prompt"), 0644)
+
+	cfg := Config{MaxCalls: 10, Backend: "cli", FocusMode: true, MaxTaskAttempts: 2}
+	controller := NewController(cfg, NewRateLimiter(10, 1), circuit.NewBreaker(3, 5))
+	blip := errors.New("failed to send message: dial tcp 127.0.0.1:4096: connect: connection refused")
+	controller.SetRunner(&failingRunner{errs: []error{blip, blip, blip}})
+
+	for i := 0; i < 3; i++ {
+		if err := controller.ExecuteLoop(context.Background()); err == nil {
+			t.Fatalf("ExecuteLoop() #%d should return the backend error", i+1)
+		}
+	}
+
+	data, _ := os.ReadFile("@fix_plan.md")
+	if strings.Contains(string(data), "BLOCKED") {
+		t.Errorf("plan = %q, want the task left open after backend errors", string(data))
+	}
+	if attempts, _ := state.LoadTaskAttempts(); len(attempts) != 0 {
+		t.Errorf("task attempts = %+v, want none spent on backend errors", attempts)
+	}
+}
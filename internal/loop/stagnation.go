@@ -0,0 +1,41 @@
+package loop
+
+import (
+	"fmt"
+
+	"github.com/brainwhocodes/lisa-loop/internal/checkpoint"
+	"github.com/brainwhocodes/lisa-loop/internal/git"
+	"github.com/brainwhocodes/lisa-loop/internal/state"
+)
+
+// diffBase returns the commit an iteration's changes are diffed against: the
+// checkpoint when there is one, otherwise a snapshot of the work tree
+// Returns "" outside a git repository or with stagnation detection off
+func (c *Controller) diffBase(cp *checkpoint.Checkpoint) string {
+	if c.runnerConfig.CircuitStagnation <= 0 || !git.IsRepo("") {
+		return ""
+	}
+	if cp != nil {
+		return cp.SHA
+	}
+	sha, err := git.Snapshot("")
+	if err != nil {
+		c.emitLog(LogLevelDebug, fmt.Sprintf("Cannot snapshot work tree for stagnation detection: %v", err))
+		return ""
+	}
+	return sha
+}
+
+// checkStagnation compares an iteration's output and changes since base with recent iterations
+func (c *Controller) checkStagnation(output, base string) (bool, string) {
+	diff, hasDiff := "", false
+	if base != "" {
+		d, err := git.Diff("", base, state.Dir)
+		if err != nil {
+			c.emitLog(LogLevelDebug, fmt.Sprintf("Cannot diff work tree for stagnation detection: %v", err))
+		} else {
+			diff, hasDiff = d, true
+		}
+	}
+	return c.breaker.CheckStagnation(output, diff, hasDiff)
+}
@@ -0,0 +1,42 @@
+package loop
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/brainwhocodes/lisa-loop/internal/circuit"
+)
+
+func TestExecuteLoop_Stagnation(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	os.WriteFile("@fix_plan.md", []byte("- [ ] First task\n- [ ] Second task\n"), 0644)
+	os.WriteFile("PROMPT.md", []byte("Test prompt"), 0644)
+
+	breaker := circuit.NewBreaker(2, 5)
+	controller := NewController(Config{MaxCalls: 10, Backend: "cli", CircuitStagnation: 95}, NewRateLimiter(10, 1), breaker)
+
+	// The agent edits a file every time but keeps sending the same reply
+	controller.SetRunner(&stuckRunner{touchFile: "handler.go", output: `Updated the handler again to use the new session store.
+---RALPH_STATUS---
+STATUS: WORKING
+FILES_MODIFIED: 2
+TESTS_STATUS: FAILING
+EXIT_SIGNAL: false
+---END_RALPH_STATUS---`})
+
+	for i := 0; i < 3; i++ {
+		if err := controller.ExecuteLoop(context.Background()); err != nil {
+			t.Fatalf("ExecuteLoop() #%d error = %v", i+1, err)
+		}
+		controller.loopNum++
+	}
+
+	if breaker.GetNoProgressCount() != 2 || !breaker.IsHalfOpen() {
+		t.Errorf("breaker = %s with %d loops without progress, want HALF_OPEN after 2 repeats", breaker.GetState(), breaker.GetNoProgressCount())
+	}
+}
@@ -2,15 +2,21 @@ package loop
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+
+	"github.com/brainwhocodes/lisa-loop/internal/checkpoint"
+	"github.com/brainwhocodes/lisa-loop/internal/git"
+	"github.com/brainwhocodes/lisa-loop/internal/plan"
 )
 
 // TaskEvidence represents evidence that a task may be completed
 type TaskEvidence struct {
 	TaskIndex   int
+	TaskID      string // Stable plan task ID (set by git-based detection)
 	TaskText    string
 	FilesFound  []string // Files that MUST be created by the task (not pre-existing)
 	Confidence  float64
@@ -26,6 +32,70 @@ type SyncResult struct {
 	UpdatedPlan   string
 }
 
+// SyncOptions controls how task sync gathers evidence
+type SyncOptions struct {
+	Since string // Git ref to diff against (default: start of the last checkpointed run, else HEAD)
+}
+
+// gitMarkThreshold is the minimum confidence for git evidence to auto-mark a task
+const gitMarkThreshold = 0.8
+
+// maxUntrackedScanBytes caps how much of an untracked file is scanned for identifiers
+const maxUntrackedScanBytes = 256 * 1024
+
+var (
+	backtickRegex   = regexp.MustCompile("`([^`]+)`")
+	identifierRegex = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+	taskPathRegex   = regexp.MustCompile(`^[\w@.\-]+(?:/[\w@.\-]+)*/?$`)
+	wordRegex       = regexp.MustCompile(`[a-z0-9]+`)
+	// declarationRegex matches a line declaring a name: "func (r *T) Name(", "type Name",
+	// "const Name =", "def name(", "export class Name", "pub fn name("
+	declarationRegex = regexp.MustCompile(`^\s*(?:(?:export|default|pub(?:\([^)]*\))?|async|static|public|private|protected|abstract)\s+)*` +
+		`(?:func|type|var|const|let|class|def|function|interface|struct|enum|trait|fn)\s+(?:\([^)]*\)\s*)?([A-Za-z_][A-Za-z0-9_]*)`)
+)
+
+// syncStopWords are task words too generic to count as keyword evidence
+var syncStopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "that": true, "this": true,
+	"from": true, "into": true, "when": true, "should": true, "make": true, "using": true,
+	"use": true, "add": true, "create": true, "update": true, "implement": true, "support": true,
+	"ensure": true, "file": true, "files": true, "new": true, "all": true, "each": true,
+}
+
+// SyncTasks detects completed tasks, using git history when the project is a repository
+// and falling back to filesystem checks otherwise
+func SyncTasks(projectDir string, opts SyncOptions) (*SyncResult, error) {
+	if !git.Available() || !git.IsRepo(projectDir) {
+		if opts.Since != "" {
+			return nil, fmt.Errorf("--since requires a git repository")
+		}
+		return SyncTasksWithFilesystem(projectDir)
+	}
+
+	_, planFile, err := LoadPlanWithFile()
+	if err != nil {
+		return nil, err
+	}
+
+	planContent, err := os.ReadFile(planFile)
+	if err != nil {
+		return nil, err
+	}
+
+	evidence, err := DetectCompletedTasksByGit(projectDir, opts.Since)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SyncResult{
+		PlanFile: planFile,
+		Evidence: evidence,
+	}
+	result.UpdatedPlan, result.TasksUpdated = markTasksInPlan(string(planContent), evidence)
+
+	return result, nil
+}
+
 // SyncTasksWithFilesystem checks which tasks appear completed based on NEW file creation
 // It only marks tasks complete if specific NEW files were created (not pre-existing files)
 func SyncTasksWithFilesystem(projectDir string) (*SyncResult, error) {
@@ -47,10 +117,6 @@ func SyncTasksWithFilesystem(projectDir string) (*SyncResult, error) {
 	}
 	planText := string(planContent)
 
-	// Look for tasks that create NEW files (indicated by "Add" or "Create" + filepath)
-	// These are the only ones we can reliably auto-detect
-	newFileRegex := regexp.MustCompile("`((?:src|server|lib|tests?|__tests__)/[^`]+\\.(ts|tsx|js|jsx|go|py|css))`")
-
 	for i, task := range tasks {
 		// Skip already completed tasks
 		if strings.HasPrefix(task, "[x]") {
@@ -79,16 +145,13 @@ func SyncTasksWithFilesystem(projectDir string) (*SyncResult, error) {
 		}
 
 		// Find NEW file paths in task text
-		matches := newFileRegex.FindAllStringSubmatch(task, -1)
+		paths, _ := extractTaskRefs(task)
 		newFilesFound := 0
-		for _, match := range matches {
-			if len(match) >= 2 {
-				filePath := match[1]
-				fullPath := filepath.Join(projectDir, filePath)
-				if _, err := os.Stat(fullPath); err == nil {
-					evidence.FilesFound = append(evidence.FilesFound, filePath)
-					newFilesFound++
-				}
+		for _, filePath := range paths {
+			fullPath := filepath.Join(projectDir, filePath)
+			if _, err := os.Stat(fullPath); err == nil {
+				evidence.FilesFound = append(evidence.FilesFound, filePath)
+				newFilesFound++
 			}
 		}
 
@@ -106,12 +169,12 @@ func SyncTasksWithFilesystem(projectDir string) (*SyncResult, error) {
 					break
 				}
 			}
-		} else if newFilesFound > 0 && len(matches) > 0 {
+		} else if newFilesFound > 0 && len(paths) > 0 {
 			// For creation tasks, only mark complete if the specific NEW file exists
-			evidence.Confidence = float64(newFilesFound) / float64(len(matches))
+			evidence.Confidence = float64(newFilesFound) / float64(len(paths))
 			// Don't auto-mark based on file existence alone - too error-prone
 			// Files may already exist from before the task
-			evidence.Reason = fmt.Sprintf("%d/%d files found", newFilesFound, len(matches))
+			evidence.Reason = fmt.Sprintf("%d/%d files found", newFilesFound, len(paths))
 		}
 
 		if len(evidence.FilesFound) > 0 {
@@ -119,20 +182,7 @@ func SyncTasksWithFilesystem(projectDir string) (*SyncResult, error) {
 		}
 	}
 
-	// Update the plan file if we have evidence
-	updatedPlan := planText
-	for _, ev := range result.Evidence {
-		if ev.ShouldMark {
-			// Find the task line and mark it complete
-			taskPattern := regexp.MustCompile(`(?m)^(\s*)-\s*\[\s*\]\s*` + regexp.QuoteMeta(strings.TrimPrefix(ev.TaskText, "[ ] ")))
-			if taskPattern.MatchString(updatedPlan) {
-				updatedPlan = taskPattern.ReplaceAllString(updatedPlan, "$1- [x] "+strings.TrimPrefix(ev.TaskText, "[ ] "))
-				result.TasksUpdated++
-			}
-		}
-	}
-
-	result.UpdatedPlan = updatedPlan
+	result.UpdatedPlan, result.TasksUpdated = markTasksInPlan(planText, result.Evidence)
 
 	return result, nil
 }
@@ -145,9 +195,317 @@ func ApplySyncResult(result *SyncResult) error {
 	return os.WriteFile(result.PlanFile, []byte(result.UpdatedPlan), 0644)
 }
 
-// DetectCompletedTasksByGit uses git diff to detect which tasks may have been completed
-func DetectCompletedTasksByGit(projectDir string) ([]string, error) {
-	// This could be enhanced to use git diff to see what files changed
-	// and match them against task descriptions
-	return nil, nil
+// DetectCompletedTasksByGit diffs the work tree against since and scores each open task
+// by how many of its backticked paths and identifiers, and plain keywords, the diff touches.
+// An identifier only counts if the diff declares it; merely using a name is no evidence.
+// An empty since diffs against the start of the most recent checkpointed run, or HEAD.
+func DetectCompletedTasksByGit(projectDir string, since string) ([]TaskEvidence, error) {
+	base, err := resolveSyncBase(projectDir, since)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := LoadPlanDocument()
+	if err != nil {
+		return nil, err
+	}
+	planFile := doc.Path
+
+	// The plan itself mentions every task - its own edits are not evidence
+	changes, err := git.ChangedFiles(projectDir, base, planFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		return []TaskEvidence{}, nil
+	}
+
+	added, err := git.AddedLines(projectDir, base, planFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// Untracked files don't appear in the diff - treat their content as added
+	for _, change := range changes {
+		if change.Status == "?" {
+			added = append(added, strings.Split(readHead(filepath.Join(projectDir, change.Path), maxUntrackedScanBytes), "\n")...)
+		}
+	}
+
+	identifiers := make(map[string]bool)
+	words := make(map[string]bool)
+	for _, line := range added {
+		if m := declarationRegex.FindStringSubmatch(line); m != nil {
+			identifiers[m[1]] = true
+		}
+		for _, id := range identifierRegex.FindAllString(line, -1) {
+			words[strings.ToLower(id)] = true
+		}
+	}
+	for _, change := range changes {
+		for _, w := range wordRegex.FindAllString(strings.ToLower(change.Path), -1) {
+			words[w] = true
+		}
+	}
+
+	evidence := []TaskEvidence{}
+	for i, task := range doc.Tasks() {
+		if task.Checked {
+			continue
+		}
+
+		ev := scoreTaskAgainstDiff(task.String(), changes, identifiers, words)
+		if ev.Confidence > 0 {
+			ev.TaskIndex = i
+			ev.TaskID = task.ID
+			evidence = append(evidence, ev)
+		}
+	}
+
+	return evidence, nil
+}
+
+// resolveSyncBase picks the commit that git-based sync diffs against
+func resolveSyncBase(projectDir, since string) (string, error) {
+	if since != "" {
+		sha, err := git.ResolveRef(projectDir, since)
+		if err != nil {
+			return "", fmt.Errorf("unknown ref %q: %w", since, err)
+		}
+		return sha, nil
+	}
+
+	if cp, err := checkpoint.RunStart(""); err == nil {
+		if sha, err := git.ResolveRef(projectDir, cp.SHA); err == nil {
+			return sha, nil
+		}
+	}
+
+	head, err := git.HeadSHA(projectDir)
+	if err != nil {
+		return "", err
+	}
+	if head == "" {
+		return "", fmt.Errorf("repository has no commits to diff against")
+	}
+	return head, nil
+}
+
+// scoreTaskAgainstDiff builds evidence for a single task from the diff
+func scoreTaskAgainstDiff(task string, changes []git.FileChange, identifiers, words map[string]bool) TaskEvidence {
+	ev := TaskEvidence{
+		TaskText:   task,
+		FilesFound: make([]string, 0),
+	}
+
+	taskLower := strings.ToLower(task)
+	removal := strings.Contains(taskLower, "remove") || strings.Contains(taskLower, "delete")
+
+	paths, idents := extractTaskRefs(task)
+	pathsMatched := 0
+	for _, ref := range paths {
+		for _, change := range changes {
+			// A deleted file only counts for tasks that ask for removal
+			if change.Status == "D" && !removal {
+				continue
+			}
+			if pathMatches(change.Path, ref) {
+				ev.FilesFound = append(ev.FilesFound, change.Path)
+				pathsMatched++
+				break
+			}
+		}
+	}
+
+	identsMatched := 0
+	for _, id := range idents {
+		if identifiers[id] {
+			identsMatched++
+		}
+	}
+
+	keywords := taskKeywords(task)
+	keywordsMatched := 0
+	for _, kw := range keywords {
+		if words[kw] {
+			keywordsMatched++
+		}
+	}
+
+	var reasons []string
+	refs := len(paths) + len(idents)
+	if refs > 0 {
+		// Explicit references are strong evidence; keywords only add a small boost
+		ev.Confidence = 0.9 * float64(pathsMatched+identsMatched) / float64(refs)
+		if keywordsMatched > 0 && ev.Confidence > 0 {
+			ev.Confidence += 0.1
+		}
+		if len(paths) > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d/%d paths changed", pathsMatched, len(paths)))
+		}
+		if len(idents) > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d/%d identifiers declared", identsMatched, len(idents)))
+		}
+	} else if len(keywords) > 0 {
+		// Keywords alone are never enough to auto-mark
+		ev.Confidence = 0.6 * float64(keywordsMatched) / float64(len(keywords))
+	}
+	if len(keywords) > 0 && keywordsMatched > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d/%d keywords matched", keywordsMatched, len(keywords)))
+	}
+
+	if ev.Confidence > 1 {
+		ev.Confidence = 1
+	}
+	ev.ShouldMark = ev.Confidence >= gitMarkThreshold
+	ev.Reason = strings.Join(reasons, ", ")
+
+	return ev
+}
+
+// extractTaskRefs splits a task's backticked spans into file paths and code identifiers
+func extractTaskRefs(task string) (paths []string, identifiers []string) {
+	for _, match := range backtickRegex.FindAllStringSubmatch(task, -1) {
+		ref := strings.TrimSpace(match[1])
+		ref = strings.TrimPrefix(ref, "./")
+		if ref == "" {
+			continue
+		}
+
+		if isTaskPath(ref) {
+			paths = append(paths, ref)
+			continue
+		}
+
+		// Take the last identifier of qualified names like `pkg.Func()` or `Type.Method`
+		ids := identifierRegex.FindAllString(ref, -1)
+		if len(ids) > 0 && len(ids) <= 3 {
+			identifiers = append(identifiers, ids[len(ids)-1])
+		}
+	}
+	return paths, identifiers
+}
+
+// isTaskPath reports whether a backticked reference looks like a file or directory path
+func isTaskPath(ref string) bool {
+	if !taskPathRegex.MatchString(ref) {
+		return false
+	}
+	if strings.Contains(ref, "/") {
+		return true
+	}
+	// Bare file name: needs an extension that isn't just a call like `foo.Bar`
+	ext := filepath.Ext(ref)
+	return len(ext) > 1 && len(ext) <= 6 && strings.ToLower(ext) == ext
+}
+
+// pathMatches reports whether a changed path satisfies a task's path reference
+func pathMatches(changed, ref string) bool {
+	if strings.HasSuffix(ref, "/") {
+		return strings.HasPrefix(changed, ref) || strings.Contains(changed, "/"+ref)
+	}
+	return changed == ref || strings.HasSuffix(changed, "/"+ref)
+}
+
+// taskKeywords returns distinctive lowercase words from the task text outside backticks
+func taskKeywords(task string) []string {
+	text := strings.ToLower(backtickRegex.ReplaceAllString(task, " "))
+	text = strings.TrimPrefix(strings.TrimPrefix(text, "[ ] "), "[x] ")
+
+	seen := make(map[string]bool)
+	var keywords []string
+	for _, w := range wordRegex.FindAllString(text, -1) {
+		if len(w) < 4 || syncStopWords[w] || seen[w] {
+			continue
+		}
+		seen[w] = true
+		keywords = append(keywords, w)
+	}
+	return keywords
+}
+
+// readHead reads up to limit bytes from a file, returning "" on error
+func readHead(path string, limit int64) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, limit))
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// markTasksInPlan ticks the tasks whose evidence says they should be marked
+func markTasksInPlan(planText string, evidence []TaskEvidence) (string, int) {
+	doc := plan.Parse(planText)
+	tasks := doc.Tasks()
+
+	marked := 0
+	for _, ev := range evidence {
+		if !ev.ShouldMark {
+			continue
+		}
+
+		// TaskIndex follows document order; fall back to text if the plan moved on
+		var task *plan.Task
+		if ev.TaskIndex >= 0 && ev.TaskIndex < len(tasks) && tasks[ev.TaskIndex].String() == ev.TaskText {
+			task = tasks[ev.TaskIndex]
+		} else {
+			task = doc.FindByText(ev.TaskText)
+		}
+
+		if doc.SetChecked(task, true) {
+			marked++
+		}
+	}
+	return doc.String(), marked
+}
+
+// UncheckTasks reverts the given completed tasks (as returned by LoadPlan, with
+// their "[x] " prefix) back to unchecked in the plan file. Returns the number
+// of lines changed.
+func UncheckTasks(planFile string, tasks []string) (int, error) {
+	if len(tasks) == 0 {
+		return 0, nil
+	}
+
+	doc, err := plan.Load(planFile)
+	if err != nil {
+		return 0, err
+	}
+
+	unchecked := 0
+	for _, text := range tasks {
+		if doc.SetChecked(doc.FindByText(text), false) {
+			unchecked++
+		}
+	}
+
+	if unchecked == 0 {
+		return 0, nil
+	}
+
+	return unchecked, doc.Save()
+}
+
+// newlyCompletedTasks returns tasks that are checked in after but were not checked in before
+func newlyCompletedTasks(before, after []string) []string {
+	wasDone := make(map[string]bool, len(before))
+	for _, task := range before {
+		if strings.HasPrefix(task, "[x]") {
+			wasDone[task] = true
+		}
+	}
+
+	var completed []string
+	for _, task := range after {
+		if strings.HasPrefix(task, "[x]") && !wasDone[task] {
+			completed = append(completed, task)
+		}
+	}
+	return completed
 }
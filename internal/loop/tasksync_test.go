@@ -0,0 +1,179 @@
+package loop
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/brainwhocodes/lisa-loop/internal/git"
+)
+
+func TestExtractTaskRefs(t *testing.T) {
+	tests := []struct {
+		name      string
+		task      string
+		wantPaths []string
+		wantIdent []string
+	}{
+		{
+			name:      "path and identifier",
+			task:      "Add `internal/auth/token.go` with `ParseToken()`",
+			wantPaths: []string{"internal/auth/token.go"},
+			wantIdent: []string{"ParseToken"},
+		},
+		{
+			name:      "bare file name",
+			task:      "Create `README.md`",
+			wantPaths: []string{"README.md"},
+		},
+		{
+			name:      "qualified identifier",
+			task:      "Call `config.Load` from main",
+			wantIdent: []string{"Load"},
+		},
+		{
+			name:      "directory",
+			task:      "Move helpers into `pkg/util/`",
+			wantPaths: []string{"pkg/util/"},
+		},
+		{
+			name: "no refs",
+			task: "Improve error handling",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths, idents := extractTaskRefs(tt.task)
+			if !reflect.DeepEqual(paths, tt.wantPaths) {
+				t.Errorf("paths = %v, want %v", paths, tt.wantPaths)
+			}
+			if !reflect.DeepEqual(idents, tt.wantIdent) {
+				t.Errorf("identifiers = %v, want %v", idents, tt.wantIdent)
+			}
+		})
+	}
+}
+
+func TestDetectCompletedTasksByGit(t *testing.T) {
+	if !git.Available() {
+		t.Skip("git not installed")
+	}
+
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	plan := "# Plan\n" +
+		"- [ ] Add `internal/auth/token.go` with `ParseToken`\n" +
+		"- [ ] Add `internal/billing/invoice.go`\n" +
+		"- [ ] Improve authentication token handling\n" +
+		"- [ ] Validate the `Config` on startup\n" +
+		"- [x] Already done `main.go`\n"
+	os.WriteFile("@fix_plan.md", []byte(plan), 0644)
+	os.WriteFile("main.go", []byte("package main\n"), 0644)
+
+	if _, err := git.Run("", "init", "-q"); err != nil {
+		t.Fatalf("git init: %v", err)
+	}
+	if _, err := git.CommitAll("", "initial", false); err != nil {
+		t.Fatalf("initial commit: %v", err)
+	}
+	base, _ := git.HeadSHA("")
+
+	os.MkdirAll(filepath.Join("internal", "auth"), 0755)
+	os.WriteFile(filepath.Join("internal", "auth", "token.go"), []byte("package auth\n\nfunc ParseToken() {}\n"), 0644)
+	// Using a name the task mentions is not declaring it
+	os.WriteFile("main.go", []byte("package main\n\nfunc main() { _ = Config{} }\n"), 0644)
+
+	evidence, err := DetectCompletedTasksByGit(".", base)
+	if err != nil {
+		t.Fatalf("DetectCompletedTasksByGit() error = %v", err)
+	}
+
+	byTask := make(map[string]TaskEvidence)
+	for _, ev := range evidence {
+		byTask[ev.TaskText] = ev
+	}
+
+	tokenTask := byTask["[ ] Add `internal/auth/token.go` with `ParseToken`"]
+	if !tokenTask.ShouldMark {
+		t.Errorf("token task ShouldMark = false (confidence %.2f, %s), want true", tokenTask.Confidence, tokenTask.Reason)
+	}
+	if len(tokenTask.FilesFound) != 1 || tokenTask.FilesFound[0] != "internal/auth/token.go" {
+		t.Errorf("token task FilesFound = %v", tokenTask.FilesFound)
+	}
+
+	if ev, ok := byTask["[ ] Add `internal/billing/invoice.go`"]; ok {
+		t.Errorf("billing task should have no evidence, got %+v", ev)
+	}
+
+	if ev := byTask["[ ] Validate the `Config` on startup"]; ev.ShouldMark {
+		t.Errorf("a task should not be marked because the diff uses a name it mentions, got %+v", ev)
+	}
+
+	keywordTask, ok := byTask["[ ] Improve authentication token handling"]
+	if !ok {
+		t.Fatal("keyword-only task should have some evidence")
+	}
+	if keywordTask.ShouldMark {
+		t.Error("keyword-only evidence should never auto-mark a task")
+	}
+
+	for task := range byTask {
+		if strings.HasPrefix(task, "[x]") {
+			t.Errorf("completed task %q should be skipped", task)
+		}
+	}
+}
+
+func TestSyncTasks_DryRunLeavesPlan(t *testing.T) {
+	if !git.Available() {
+		t.Skip("git not installed")
+	}
+
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	plan := "- [ ] Create `cmd/tool/main.go`\n"
+	os.WriteFile("@fix_plan.md", []byte(plan), 0644)
+
+	git.Run("", "init", "-q")
+	if _, err := git.CommitAll("", "initial", false); err != nil {
+		t.Fatalf("initial commit: %v", err)
+	}
+
+	os.MkdirAll(filepath.Join("cmd", "tool"), 0755)
+	os.WriteFile(filepath.Join("cmd", "tool", "main.go"), []byte("package main\n"), 0644)
+
+	result, err := SyncTasks(".", SyncOptions{Since: "HEAD"})
+	if err != nil {
+		t.Fatalf("SyncTasks() error = %v", err)
+	}
+
+	if result.TasksUpdated != 1 {
+		t.Fatalf("TasksUpdated = %d, want 1", result.TasksUpdated)
+	}
+	if !strings.Contains(result.UpdatedPlan, "- [x] Create `cmd/tool/main.go`") {
+		t.Errorf("UpdatedPlan = %q, want task marked", result.UpdatedPlan)
+	}
+
+	// Nothing is written until ApplySyncResult is called
+	data, _ := os.ReadFile("@fix_plan.md")
+	if string(data) != plan {
+		t.Errorf("plan file changed before apply: %q", string(data))
+	}
+
+	if err := ApplySyncResult(result); err != nil {
+		t.Fatalf("ApplySyncResult() error = %v", err)
+	}
+	data, _ = os.ReadFile("@fix_plan.md")
+	if !strings.Contains(string(data), "- [x]") {
+		t.Errorf("plan file not updated after apply: %q", string(data))
+	}
+}
@@ -0,0 +1,154 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/brainwhocodes/lisa-loop/internal/analysis"
+	"github.com/brainwhocodes/lisa-loop/internal/budget"
+	"github.com/brainwhocodes/lisa-loop/internal/config"
+)
+
+// maxToolTurns bounds the number of model round trips in one Run
+const maxToolTurns = 50
+
+const systemPrompt = `You are an autonomous software engineer working in a project directory.
+Use the provided tools to read, write and edit files, list directories and run shell commands.
+All paths are relative to the project root. When you are done, reply with your final report and no tool calls.`
+
+// OutputCallback is called for streaming output events
+type OutputCallback func(event map[string]interface{})
+
+// Runner executes prompts against an OpenAI-compatible chat completions endpoint,
+// running the model's tool calls locally
+type Runner struct {
+	cfg            config.Config
+	outputCallback OutputCallback
+}
+
+// NewRunner creates a new chat completions runner
+func NewRunner(cfg config.Config) *Runner {
+	return &Runner{cfg: cfg}
+}
+
+// SetOutputCallback sets the callback for streaming output
+func (r *Runner) SetOutputCallback(cb OutputCallback) {
+	r.outputCallback = cb
+}
+
+// Stop is a no-op; the runner holds no long-lived resources
+func (r *Runner) Stop() error {
+	return nil
+}
+
+// Run sends the prompt and executes tool calls until the model gives a final answer
+// Returns the final answer and the ID of the first completion as the session ID
+func (r *Runner) Run(ctx context.Context, prompt string) (string, string, error) {
+	if r.cfg.OpenAIBaseURL == "" {
+		return "", "", fmt.Errorf("openai backend needs a base URL (--openai-url)")
+	}
+
+	projectDir := r.cfg.ProjectPath
+	if r.cfg.WorkDir != "" {
+		projectDir = r.cfg.WorkDir
+	}
+	if projectDir == "" {
+		projectDir = "."
+	}
+	tools, err := NewToolbox(projectDir, 0)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to resolve project directory: %w", err)
+	}
+
+	timeout := time.Duration(r.cfg.Timeout) * time.Second
+	client := NewClient(r.cfg.OpenAIBaseURL, r.cfg.OpenAIAPIKey, r.cfg.OpenAIModel, timeout)
+
+	messages := []Message{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: prompt},
+	}
+	sessionID := ""
+
+	for turn := 0; turn < maxToolTurns; turn++ {
+		resp, err := client.Complete(ctx, messages, tools.Definitions())
+		if err != nil {
+			return "", sessionID, err
+		}
+		if sessionID == "" {
+			sessionID = resp.ID
+		}
+		if resp.Usage.PromptTokens+resp.Usage.CompletionTokens > 0 {
+			r.emit(budget.UsageEventType, budget.UsageEvent(budget.Usage{
+				PromptTokens:     resp.Usage.PromptTokens,
+				CompletionTokens: resp.Usage.CompletionTokens,
+			}))
+		}
+
+		reply := resp.Choices[0].Message
+		reply.Role = "assistant"
+		messages = append(messages, reply)
+
+		if len(reply.ToolCalls) == 0 {
+			if reply.Content != "" {
+				r.emit("message", map[string]interface{}{"content": reply.Content})
+			}
+			return strings.TrimSpace(reply.Content), sessionID, nil
+		}
+
+		// Text alongside tool calls is the model thinking out loud
+		if reply.Content != "" {
+			r.emit("item.completed", map[string]interface{}{
+				"item": map[string]interface{}{"type": "reasoning", "text": reply.Content},
+			})
+		}
+
+		for _, call := range reply.ToolCalls {
+			messages = append(messages, r.runTool(ctx, tools, call))
+		}
+	}
+
+	return "", sessionID, fmt.Errorf("no final answer after %d tool turns", maxToolTurns)
+}
+
+// runTool executes one tool call, emitting tool_use and tool_result events
+func (r *Runner) runTool(ctx context.Context, tools *Toolbox, call ToolCall) Message {
+	name := call.Function.Name
+	target := tools.Target(name, call.Function.Arguments)
+
+	started := map[string]interface{}{
+		"name":   name,
+		"target": target,
+		"status": "started",
+	}
+	// The loop reads the status report from the call's arguments
+	if name == analysis.StatusTool {
+		started["arguments"] = call.Function.Arguments
+	}
+	r.emit("tool_use", started)
+
+	result, err := tools.Execute(ctx, name, call.Function.Arguments)
+	if err != nil {
+		result = "error: " + err.Error()
+	}
+
+	r.emit("tool_result", map[string]interface{}{
+		"name":   name,
+		"target": target,
+	})
+
+	return Message{Role: "tool", ToolCallID: call.ID, Content: result}
+}
+
+// emit sends an event to the output callback if set
+func (r *Runner) emit(eventType string, data map[string]interface{}) {
+	if r.outputCallback == nil {
+		return
+	}
+	event := map[string]interface{}{"type": eventType}
+	for k, v := range data {
+		event[k] = v
+	}
+	r.outputCallback(event)
+}
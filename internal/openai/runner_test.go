@@ -0,0 +1,172 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brainwhocodes/lisa-loop/internal/codex"
+	"github.com/brainwhocodes/lisa-loop/internal/config"
+)
+
+// stubServer replays canned assistant messages and records the requests it gets
+func stubServer(t *testing.T, replies []Message) (*httptest.Server, *[]ChatRequest) {
+	t.Helper()
+	var requests []ChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("expected path /v1/chat/completions, got %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Errorf("Authorization = %q, want bearer token", got)
+		}
+
+		var req ChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		requests = append(requests, req)
+
+		if len(requests) > len(replies) {
+			http.Error(w, "no more replies", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(ChatResponse{
+			ID:      "chatcmpl-1",
+			Choices: []Choice{{Message: replies[len(requests)-1]}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestRunner_ToolLoop(t *testing.T) {
+	dir := t.TempDir()
+	server, requests := stubServer(t, []Message{
+		{Role: "assistant", Content: "Creating the file", ToolCalls: []ToolCall{{
+			ID: "call_1", Type: "function",
+			Function: FunctionCall{Name: "write_file", Arguments: `{"path":"hello.txt","content":"hi"}`},
+		}}},
+		{Role: "assistant", Content: "Done. EXIT_SIGNAL: true"},
+	})
+
+	r := NewRunner(config.Config{
+		OpenAIBaseURL: server.URL + "/v1/",
+		OpenAIAPIKey:  "test-key",
+		OpenAIModel:   "qwen",
+		ProjectPath:   dir,
+	})
+
+	var parsed []*codex.ParsedEvent
+	r.SetOutputCallback(func(event map[string]interface{}) {
+		parsed = append(parsed, codex.ParseEvent(codex.Event(event)))
+	})
+
+	output, sessionID, err := r.Run(context.Background(), "Write hello.txt")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if output != "Done. EXIT_SIGNAL: true" {
+		t.Errorf("Run() output = %q", output)
+	}
+	if sessionID != "chatcmpl-1" {
+		t.Errorf("Run() sessionID = %q, want chatcmpl-1", sessionID)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "hello.txt"))
+	if err != nil || string(data) != "hi" {
+		t.Errorf("hello.txt = %q, %v", data, err)
+	}
+
+	if len(*requests) != 2 {
+		t.Fatalf("requests = %d, want 2", len(*requests))
+	}
+	first := (*requests)[0]
+	if first.Model != "qwen" || len(first.Tools) != 6 {
+		t.Errorf("first request model = %q tools = %d", first.Model, len(first.Tools))
+	}
+	last := (*requests)[1].Messages
+	toolMsg := last[len(last)-1]
+	if toolMsg.Role != "tool" || toolMsg.ToolCallID != "call_1" {
+		t.Errorf("tool result message = %+v", toolMsg)
+	}
+
+	want := []struct{ typ, tool, status string }{
+		{"reasoning", "", ""},
+		{"tool_call", "write_file", "started"},
+		{"tool_result", "write_file", "completed"},
+		{"message", "", ""},
+	}
+	if len(parsed) != len(want) {
+		t.Fatalf("events = %d, want %d", len(parsed), len(want))
+	}
+	for i, w := range want {
+		if parsed[i].Type != w.typ || parsed[i].ToolName != w.tool || parsed[i].ToolStatus != w.status {
+			t.Errorf("event %d = %+v, want %+v", i, parsed[i], w)
+		}
+	}
+	if parsed[1].ToolTarget != "hello.txt" {
+		t.Errorf("tool target = %q, want hello.txt", parsed[1].ToolTarget)
+	}
+}
+
+func TestRunner_ToolErrorsGoBackToModel(t *testing.T) {
+	dir := t.TempDir()
+	server, requests := stubServer(t, []Message{
+		{Role: "assistant", ToolCalls: []ToolCall{{
+			ID: "call_1", Type: "function",
+			Function: FunctionCall{Name: "read_file", Arguments: `{"path":"../etc/passwd"}`},
+		}}},
+		{Role: "assistant", Content: "Cannot read it"},
+	})
+
+	r := NewRunner(config.Config{OpenAIBaseURL: server.URL + "/v1", OpenAIAPIKey: "test-key", ProjectPath: dir})
+	if _, _, err := r.Run(context.Background(), "Read a file"); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	last := (*requests)[1].Messages
+	if got := last[len(last)-1].Content; got == "" || got[:6] != "error:" {
+		t.Errorf("tool message = %q, want error reported to the model", got)
+	}
+}
+
+func TestRunner_ServerError(t *testing.T) {
+	server, _ := stubServer(t, nil)
+
+	r := NewRunner(config.Config{OpenAIBaseURL: server.URL + "/v1", OpenAIAPIKey: "test-key", ProjectPath: t.TempDir()})
+	if _, _, err := r.Run(context.Background(), "prompt"); err == nil {
+		t.Error("Run() expected error for failing server")
+	}
+}
+
+func TestRunner_StatusError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	r := NewRunner(config.Config{OpenAIBaseURL: server.URL + "/v1", ProjectPath: t.TempDir()})
+	_, _, err := r.Run(context.Background(), "prompt")
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("Run() error = %v, want a *StatusError", err)
+	}
+	if statusErr.StatusCode != http.StatusTooManyRequests || statusErr.RetryAfter != 30*time.Second {
+		t.Errorf("StatusError = %d, retry after %s; want 429, retry after 30s", statusErr.StatusCode, statusErr.RetryAfter)
+	}
+}
+
+func TestRunner_NoBaseURL(t *testing.T) {
+	r := NewRunner(config.Config{})
+	if _, _, err := r.Run(context.Background(), "prompt"); err == nil {
+		t.Error("Run() expected error without a base URL")
+	}
+}
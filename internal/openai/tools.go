@@ -0,0 +1,324 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/brainwhocodes/lisa-loop/internal/analysis"
+	"github.com/brainwhocodes/lisa-loop/internal/proc"
+)
+
+// maxToolOutput caps what a tool returns to the model
+const maxToolOutput = 64 * 1024
+
+// defaultCommandTimeout bounds run_command when no timeout is configured
+const defaultCommandTimeout = 2 * time.Minute
+
+// commandWaitDelay is how long run_command waits for output after the shell exits
+const commandWaitDelay = 2 * time.Second
+
+// ErrOutsideRoot is returned for paths that escape the project directory
+var ErrOutsideRoot = errors.New("path is outside the project directory")
+
+// Toolbox executes the built-in tools confined to a project directory
+type Toolbox struct {
+	root           string
+	commandTimeout time.Duration
+}
+
+// NewToolbox creates a toolbox rooted at dir
+func NewToolbox(dir string, commandTimeout time.Duration) (*Toolbox, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	if commandTimeout == 0 {
+		commandTimeout = defaultCommandTimeout
+	}
+	return &Toolbox{root: root, commandTimeout: commandTimeout}, nil
+}
+
+// Root returns the directory tools are confined to
+func (tb *Toolbox) Root() string {
+	return tb.root
+}
+
+// Definitions returns the tool schemas sent to the model
+func (tb *Toolbox) Definitions() []Tool {
+	return []Tool{
+		tool("read_file", "Read a file in the project.",
+			params(map[string]string{"path": "File path relative to the project root"}, "path")),
+		tool("write_file", "Create or overwrite a file in the project.",
+			params(map[string]string{"path": "File path relative to the project root", "content": "Full file content"}, "path", "content")),
+		tool("edit_file", "Replace one exact occurrence of old_string with new_string in a file.",
+			params(map[string]string{"path": "File path relative to the project root", "old_string": "Text to replace (must occur exactly once)", "new_string": "Replacement text"}, "path", "old_string", "new_string")),
+		tool("list_dir", "List a directory in the project. Directories end with /.",
+			params(map[string]string{"path": "Directory path relative to the project root (default: .)"})),
+		tool("run_command", "Run a shell command in the project root and return its output and exit code.",
+			params(map[string]string{"command": "Shell command to run"}, "command")),
+		tool(analysis.StatusTool, "Report the status of this loop to Lisa. Call it once, at the end of your work.",
+			statusParams()),
+	}
+}
+
+// Target returns the path or command a tool call acts on, for display
+func (tb *Toolbox) Target(name, arguments string) string {
+	var args map[string]string
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return ""
+	}
+	if name == "run_command" {
+		return args["command"]
+	}
+	return args["path"]
+}
+
+// Execute runs a tool call and returns its result for the model
+func (tb *Toolbox) Execute(ctx context.Context, name, arguments string) (string, error) {
+	// The status report is validated here so the model can correct it in the same run
+	if name == analysis.StatusTool {
+		if _, err := analysis.ParseStatusReport([]byte(arguments)); err != nil {
+			return "", err
+		}
+		return "Status report received", nil
+	}
+
+	var args map[string]string
+	if arguments != "" {
+		if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+			return "", fmt.Errorf("invalid arguments for %s: %w", name, err)
+		}
+	}
+
+	switch name {
+	case "read_file":
+		return tb.readFile(args["path"])
+	case "write_file":
+		return tb.writeFile(args["path"], args["content"])
+	case "edit_file":
+		return tb.editFile(args["path"], args["old_string"], args["new_string"])
+	case "list_dir":
+		return tb.listDir(args["path"])
+	case "run_command":
+		return tb.runCommand(ctx, args["command"])
+	default:
+		return "", fmt.Errorf("unknown tool %q", name)
+	}
+}
+
+func (tb *Toolbox) readFile(path string) (string, error) {
+	abs, err := tb.resolve(path)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(abs)
+	if err != nil {
+		return "", err
+	}
+	return truncate(string(data)), nil
+}
+
+func (tb *Toolbox) writeFile(path, content string) (string, error) {
+	abs, err := tb.resolve(path)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(abs, []byte(content), 0644); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Wrote %d bytes to %s", len(content), path), nil
+}
+
+func (tb *Toolbox) editFile(path, oldString, newString string) (string, error) {
+	abs, err := tb.resolve(path)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(abs)
+	if err != nil {
+		return "", err
+	}
+	if oldString == "" {
+		return "", fmt.Errorf("old_string must not be empty")
+	}
+	switch n := strings.Count(string(data), oldString); n {
+	case 0:
+		return "", fmt.Errorf("old_string not found in %s", path)
+	case 1:
+	default:
+		return "", fmt.Errorf("old_string occurs %d times in %s; include more context", n, path)
+	}
+
+	content := strings.Replace(string(data), oldString, newString, 1)
+	info, err := os.Stat(abs)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(abs, []byte(content), info.Mode().Perm()); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Edited %s", path), nil
+}
+
+func (tb *Toolbox) listDir(path string) (string, error) {
+	if path == "" {
+		path = "."
+	}
+	abs, err := tb.resolve(path)
+	if err != nil {
+		return "", err
+	}
+	entries, err := os.ReadDir(abs)
+	if err != nil {
+		return "", err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			name += "/"
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return truncate(strings.Join(names, "\n")), nil
+}
+
+func (tb *Toolbox) runCommand(ctx context.Context, command string) (string, error) {
+	if strings.TrimSpace(command) == "" {
+		return "", fmt.Errorf("command must not be empty")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, tb.commandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = tb.root
+	proc.KillGroup(cmd)
+	// Don't wait forever on background processes that inherited the output pipe
+	cmd.WaitDelay = commandWaitDelay
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	exitCode := 0
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		switch {
+		case errors.Is(err, exec.ErrWaitDelay):
+			// The shell exited but left a background process, such as a server, running
+		case ctx.Err() == context.DeadlineExceeded:
+			return truncate(out.String()), fmt.Errorf("command timed out after %s", tb.commandTimeout)
+		case errors.As(err, &exitErr):
+			exitCode = exitErr.ExitCode()
+		default:
+			return "", err
+		}
+	}
+	return fmt.Sprintf("exit code: %d\n%s", exitCode, truncate(out.String())), nil
+}
+
+// resolve maps a tool path to an absolute path inside the root
+// Symlinks are followed so a link cannot be used to escape the project
+func (tb *Toolbox) resolve(path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("path is required")
+	}
+
+	abs := path
+	if !filepath.IsAbs(abs) {
+		abs = filepath.Join(tb.root, abs)
+	}
+	abs = filepath.Clean(abs)
+	if !tb.contains(abs) {
+		return "", fmt.Errorf("%s: %w", path, ErrOutsideRoot)
+	}
+
+	// Resolve symlinks on the deepest existing ancestor
+	existing := abs
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		existing = parent
+	}
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return "", err
+	}
+	if !tb.contains(resolved) {
+		return "", fmt.Errorf("%s: %w", path, ErrOutsideRoot)
+	}
+	return abs, nil
+}
+
+func (tb *Toolbox) contains(path string) bool {
+	rel, err := filepath.Rel(tb.root, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+func truncate(s string) string {
+	if len(s) <= maxToolOutput {
+		return s
+	}
+	return s[:maxToolOutput] + fmt.Sprintf("\n... [truncated %d bytes]", len(s)-maxToolOutput)
+}
+
+func tool(name, description string, parameters map[string]interface{}) Tool {
+	return Tool{
+		Type: "function",
+		Function: FunctionDef{
+			Name:        name,
+			Description: description,
+			Parameters:  parameters,
+		},
+	}
+}
+
+// statusParams returns the status report schema as tool parameters
+func statusParams() map[string]interface{} {
+	var schema map[string]interface{}
+	if err := json.Unmarshal(analysis.StatusSchema, &schema); err != nil {
+		panic(fmt.Sprintf("invalid status report schema: %v", err))
+	}
+	// Endpoints expect a bare object schema
+	delete(schema, "$schema")
+	delete(schema, "$id")
+	return schema
+}
+
+// params builds a JSON schema for an object of string properties
+func params(properties map[string]string, required ...string) map[string]interface{} {
+	props := make(map[string]interface{}, len(properties))
+	for name, description := range properties {
+		props[name] = map[string]interface{}{"type": "string", "description": description}
+	}
+	schema := map[string]interface{}{"type": "object", "properties": props}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
@@ -0,0 +1,125 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestToolbox(t *testing.T) (*Toolbox, string) {
+	t.Helper()
+	dir := t.TempDir()
+	tb, err := NewToolbox(dir, 0)
+	if err != nil {
+		t.Fatalf("NewToolbox() error = %v", err)
+	}
+	return tb, tb.Root()
+}
+
+func TestToolbox_FileTools(t *testing.T) {
+	tb, root := newTestToolbox(t)
+	ctx := context.Background()
+
+	if _, err := tb.Execute(ctx, "write_file", `{"path":"src/main.go","content":"package main\n\nfunc main() {}\n"}`); err != nil {
+		t.Fatalf("write_file error = %v", err)
+	}
+	got, err := tb.Execute(ctx, "read_file", `{"path":"src/main.go"}`)
+	if err != nil || !strings.Contains(got, "func main()") {
+		t.Fatalf("read_file = %q, %v", got, err)
+	}
+
+	if _, err := tb.Execute(ctx, "edit_file", `{"path":"src/main.go","old_string":"func main() {}","new_string":"func main() { run() }"}`); err != nil {
+		t.Fatalf("edit_file error = %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(root, "src", "main.go"))
+	if !strings.Contains(string(data), "run()") {
+		t.Errorf("edit_file did not apply: %q", data)
+	}
+	if _, err := tb.Execute(ctx, "edit_file", `{"path":"src/main.go","old_string":"missing","new_string":"x"}`); err == nil {
+		t.Error("edit_file with missing old_string expected error")
+	}
+
+	list, err := tb.Execute(ctx, "list_dir", `{}`)
+	if err != nil || list != "src/" {
+		t.Errorf("list_dir = %q, %v, want src/", list, err)
+	}
+}
+
+func TestToolbox_RunCommand(t *testing.T) {
+	tb, _ := newTestToolbox(t)
+	ctx := context.Background()
+
+	got, err := tb.Execute(ctx, "run_command", `{"command":"pwd; exit 3"}`)
+	if err != nil {
+		t.Fatalf("run_command error = %v", err)
+	}
+	if !strings.HasPrefix(got, "exit code: 3\n") || !strings.Contains(got, tb.Root()) {
+		t.Errorf("run_command = %q, want exit code 3 in project root", got)
+	}
+}
+
+func TestToolbox_PathConfinement(t *testing.T) {
+	tb, root := newTestToolbox(t)
+	ctx := context.Background()
+
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644)
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	tests := []struct {
+		tool string
+		args string
+	}{
+		{"read_file", `{"path":"../secret.txt"}`},
+		{"read_file", `{"path":"` + filepath.Join(outside, "secret.txt") + `"}`},
+		{"read_file", `{"path":"link/secret.txt"}`},
+		{"write_file", `{"path":"link/new.txt","content":"x"}`},
+		{"write_file", `{"path":"a/../../escape.txt","content":"x"}`},
+		{"list_dir", `{"path":".."}`},
+	}
+	for _, tt := range tests {
+		_, err := tb.Execute(ctx, tt.tool, tt.args)
+		if !errors.Is(err, ErrOutsideRoot) {
+			t.Errorf("%s %s error = %v, want ErrOutsideRoot", tt.tool, tt.args, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outside, "new.txt")); err == nil {
+		t.Error("write_file escaped through a symlink")
+	}
+
+	// Paths that only look like they escape are fine
+	if _, err := tb.Execute(ctx, "write_file", `{"path":"a/../inside.txt","content":"x"}`); err != nil {
+		t.Errorf("write_file inside root error = %v", err)
+	}
+}
+
+func TestToolbox_StatusReport(t *testing.T) {
+	tb, _ := newTestToolbox(t)
+	ctx := context.Background()
+
+	got, err := tb.Execute(ctx, "lisa_report_status", `{"version":1,"status":"WORKING","tests_status":"PASSING","exit_signal":false,"tasks":[{"id":"T1","outcome":"completed"}]}`)
+	if err != nil || got != "Status report received" {
+		t.Errorf("valid report = %q, %v", got, err)
+	}
+
+	// The model is told what to fix
+	_, err = tb.Execute(ctx, "lisa_report_status", `{"version":1,"status":"DONE","tests_status":"PASSING","exit_signal":false}`)
+	if err == nil || !strings.Contains(err.Error(), `status: got "DONE"`) {
+		t.Errorf("invalid report error = %v", err)
+	}
+
+	var params map[string]interface{}
+	for _, def := range tb.Definitions() {
+		if def.Function.Name == "lisa_report_status" {
+			params = def.Function.Parameters
+		}
+	}
+	if params["type"] != "object" || params["$schema"] != nil {
+		t.Errorf("status tool parameters = %v, want the bare report schema", params)
+	}
+}
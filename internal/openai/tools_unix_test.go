@@ -0,0 +1,46 @@
+//go:build !windows
+
+package openai
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestToolbox_RunCommandTimeoutKillsGroup(t *testing.T) {
+	tb, err := NewToolbox(t.TempDir(), time.Second)
+	if err != nil {
+		t.Fatalf("NewToolbox() error = %v", err)
+	}
+
+	// The background sleep holds the output open; only killing the group ends it at the timeout
+	start := time.Now()
+	_, err = tb.Execute(context.Background(), "run_command", `{"command":"sleep 30 & wait"}`)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("run_command error = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second+commandWaitDelay/2 {
+		t.Errorf("run_command took %s, want it stopped at the timeout", elapsed)
+	}
+}
+
+func TestToolbox_RunCommandBackground(t *testing.T) {
+	tb, err := NewToolbox(t.TempDir(), time.Second)
+	if err != nil {
+		t.Fatalf("NewToolbox() error = %v", err)
+	}
+
+	start := time.Now()
+	got, err := tb.Execute(context.Background(), "run_command", `{"command":"sleep 6 & echo started"}`)
+	if err != nil {
+		t.Fatalf("run_command error = %v", err)
+	}
+	if !strings.HasPrefix(got, "exit code: 0\n") || !strings.Contains(got, "started") {
+		t.Errorf("run_command = %q, want the shell's output", got)
+	}
+	if elapsed := time.Since(start); elapsed > commandWaitDelay+time.Second {
+		t.Errorf("run_command took %s, want it back once the shell exited", elapsed)
+	}
+}
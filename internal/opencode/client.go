@@ -362,10 +362,11 @@ type StreamResult struct {
 	SessionID        string
 	MessageID        string
 	Error            error
-	RetryCount       int  // Number of API retries during streaming
-	WasCompacted     bool // True if session was compacted during this message
-	PromptTokens     int  // Token usage after message
-	CompletionTokens int  // Token usage after message
+	RetryCount       int     // Number of API retries during streaming
+	WasCompacted     bool    // True if session was compacted during this message
+	PromptTokens     int     // Token usage after message
+	CompletionTokens int     // Token usage after message
+	Cost             float64 // Session cost after message
 }
 
 // connectToSSE attempts to connect to the SSE endpoint with fallback
@@ -697,6 +698,7 @@ func (c *Client) sendMessageStreamingInternal(ctx context.Context, sessionID, co
 					if props.Session.ID == sessionID {
 						result.PromptTokens = props.Session.PromptTokens
 						result.CompletionTokens = props.Session.CompletionTokens
+						result.Cost = props.Session.Cost
 					}
 				}
 			}
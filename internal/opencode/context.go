@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"sync"
 	"time"
+
+	"github.com/brainwhocodes/lisa-loop/internal/state"
 )
 
 // Default context window sizes for known models (in tokens)
@@ -163,7 +165,7 @@ type SessionArchiver struct {
 
 // NewSessionArchiver creates a new archiver with the given directory
 func NewSessionArchiver(projectDir string) *SessionArchiver {
-	archiveDir := filepath.Join(projectDir, ".ralph", "sessions")
+	archiveDir := state.Path(projectDir, state.ArchiveDir)
 	return &SessionArchiver{archiveDir: archiveDir}
 }
 
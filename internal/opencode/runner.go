@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/brainwhocodes/lisa-loop/internal/budget"
 	"github.com/brainwhocodes/lisa-loop/internal/config"
 )
 
@@ -28,6 +29,16 @@ type Runner struct {
 	contextTracker *ContextTracker
 	archiver       *SessionArchiver
 	loopNumber     int
+	// Session totals before the current message, to report each message's usage
+	totals sessionTotals
+}
+
+// sessionTotals is a session's cumulative usage as last reported by the server
+type sessionTotals struct {
+	sessionID        string
+	promptTokens     int
+	completionTokens int
+	cost             float64
 }
 
 // NewRunner creates a new OpenCode runner from config
@@ -67,7 +78,8 @@ func (r *Runner) SetOutputCallback(cb OutputCallback) {
 }
 
 // Run executes a prompt and returns the output, session ID, and any error
-func (r *Runner) Run(prompt string) (output string, sessionID string, err error) {
+// Ending ctx aborts the message
+func (r *Runner) Run(ctx context.Context, prompt string) (output string, sessionID string, err error) {
 	// Start managed server if needed
 	if r.client == nil {
 		if err := r.startManagedServer(); err != nil {
@@ -80,7 +92,7 @@ func (r *Runner) Run(prompt string) (output string, sessionID string, err error)
 
 	// Load from file if not cached
 	if sessionID == "" {
-		sessionID, err = LoadSessionID()
+		sessionID, err = LoadSessionIDIn(r.cfg.WorkDir)
 		if err != nil {
 			return "", "", fmt.Errorf("failed to load session: %w", err)
 		}
@@ -97,7 +109,7 @@ func (r *Runner) Run(prompt string) (output string, sessionID string, err error)
 			return "", "", fmt.Errorf("failed to create session: %w", err)
 		}
 
-		if err := SaveSessionID(sessionID); err != nil {
+		if err := SaveSessionIDIn(r.cfg.WorkDir, sessionID); err != nil {
 			return "", sessionID, fmt.Errorf("failed to save session ID: %w", err)
 		}
 
@@ -113,12 +125,22 @@ func (r *Runner) Run(prompt string) (output string, sessionID string, err error)
 		"content": "Sending prompt to OpenCode...",
 	})
 
+	// The server reports usage per session; start from where a session we haven't used stands
+	if r.totals.sessionID != sessionID {
+		r.totals = sessionTotals{sessionID: sessionID}
+		if info, err := r.client.GetSession(sessionID); err == nil {
+			r.totals.promptTokens = info.PromptTokens
+			r.totals.completionTokens = info.CompletionTokens
+			r.totals.cost = info.Cost
+		}
+	}
+
 	// Reset tracking for new message (SSE sends cumulative updates)
 	r.lastReasoning = ""
 	r.lastMessage = ""
 
 	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
 	defer cancel()
 
 	// Send the message with SSE streaming
@@ -147,6 +169,8 @@ func (r *Runner) Run(prompt string) (output string, sessionID string, err error)
 		"text": content,
 	})
 
+	r.emitUsage(sessionID, result)
+
 	// Update context tracking with token usage from result
 	usage := r.contextTracker.Update(result.PromptTokens, result.CompletionTokens, result.WasCompacted)
 
@@ -211,11 +235,31 @@ func (r *Runner) emitEvent(eventType string, data map[string]interface{}) {
 	r.outputCallback(event)
 }
 
+// emitUsage reports the usage of the last message as the growth of its session's totals
+func (r *Runner) emitUsage(sessionID string, result *StreamResult) {
+	if result.PromptTokens == 0 && result.CompletionTokens == 0 && result.Cost == 0 {
+		return // No session update arrived
+	}
+
+	usage := budget.Usage{
+		PromptTokens:     max(0, result.PromptTokens-r.totals.promptTokens),
+		CompletionTokens: max(0, result.CompletionTokens-r.totals.completionTokens),
+		Cost:             max(0, result.Cost-r.totals.cost),
+	}
+	r.totals = sessionTotals{
+		sessionID:        sessionID,
+		promptTokens:     result.PromptTokens,
+		completionTokens: result.CompletionTokens,
+		cost:             result.Cost,
+	}
+	r.emitEvent(budget.UsageEventType, budget.UsageEvent(usage))
+}
+
 // NewSession clears the current session and starts fresh
 func (r *Runner) NewSession() error {
 	r.sessionID = "" // Clear cached session
 	r.contextTracker.Reset()
-	return ClearSession()
+	return SaveSessionIDIn(r.cfg.WorkDir, "")
 }
 
 // SetLoopNumber sets the current loop number for archiving
@@ -258,7 +302,7 @@ func (r *Runner) saveAndRotateSession(sessionID string, usage ContextUsage, reas
 	}
 
 	// Save new session ID
-	if err := SaveSessionID(newSessionID); err != nil {
+	if err := SaveSessionIDIn(r.cfg.WorkDir, newSessionID); err != nil {
 		return archivePath, fmt.Errorf("failed to save new session ID: %w", err)
 	}
 
@@ -275,7 +319,7 @@ func (r *Runner) saveAndRotateSession(sessionID string, usage ContextUsage, reas
 
 // GetSessionID returns the current session ID
 func (r *Runner) GetSessionID() (string, error) {
-	return LoadSessionID()
+	return LoadSessionIDIn(r.cfg.WorkDir)
 }
 
 // Stop shuts down the managed server if running
@@ -387,8 +431,13 @@ func (r *Runner) startManagedServer() error {
 		"content": "Starting OpenCode server...",
 	})
 
+	projectDir := r.cfg.ProjectPath
+	if r.cfg.WorkDir != "" {
+		projectDir = r.cfg.WorkDir
+	}
+
 	r.server = NewServer(ServerConfig{
-		ProjectDir: r.cfg.ProjectPath,
+		ProjectDir: projectDir,
 		Verbose:    r.verbose,
 	})
 
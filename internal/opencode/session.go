@@ -1,43 +1,41 @@
 package opencode
 
 import (
-	"os"
 	"time"
+
+	"github.com/brainwhocodes/lisa-loop/internal/state"
 )
 
-const sessionFile = ".opencode_session_id"
+// sessionBackend is the key the OpenCode session is saved under in the state directory
+const sessionBackend = "opencode"
 
-// LoadSessionID loads the OpenCode session ID from .opencode_session_id
+// LoadSessionID loads the saved OpenCode session ID
 func LoadSessionID() (string, error) {
-	data, err := os.ReadFile(sessionFile)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return "", nil
-		}
-		return "", err
-	}
-	return string(data), nil
+	return LoadSessionIDIn("")
 }
 
-// SaveSessionID saves the OpenCode session ID to .opencode_session_id atomically
+// LoadSessionIDIn loads the OpenCode session ID kept in dir ("" for the current directory)
+func LoadSessionIDIn(dir string) (string, error) {
+	sess, err := state.LoadSessionIn(dir, sessionBackend)
+	return sess.ID, err
+}
+
+// SaveSessionID saves the OpenCode session ID
 func SaveSessionID(id string) error {
-	tmpPath := sessionFile + ".tmp"
-	if err := os.WriteFile(tmpPath, []byte(id), 0644); err != nil {
-		return err
-	}
-	return os.Rename(tmpPath, sessionFile)
+	return SaveSessionIDIn("", id)
 }
 
-// ClearSession removes the session file
+// SaveSessionIDIn saves the OpenCode session ID in dir ("" for the current directory)
+func SaveSessionIDIn(dir, id string) error {
+	return state.SaveSessionIn(dir, sessionBackend, id)
+}
+
+// ClearSession forgets the saved session
 func ClearSession() error {
-	err := os.Remove(sessionFile)
-	if os.IsNotExist(err) {
-		return nil
-	}
-	return err
+	return SaveSessionID("")
 }
 
-// SessionExists checks if a session ID file exists
+// SessionExists checks if a session ID is saved
 func SessionExists() bool {
 	id, err := LoadSessionID()
 	if err != nil {
@@ -48,16 +46,12 @@ func SessionExists() bool {
 
 // SessionAgeHours calculates the age of the session in hours
 func SessionAgeHours() (int, error) {
-	if !SessionExists() {
-		return 0, nil
-	}
-
-	info, err := os.Stat(sessionFile)
-	if err != nil {
+	sess, err := state.LoadSessionIn("", sessionBackend)
+	if err != nil || sess.ID == "" {
 		return 0, err
 	}
 
-	age := time.Since(info.ModTime()).Hours()
+	age := time.Since(sess.UpdatedAt).Hours()
 	return int(age), nil
 }
 
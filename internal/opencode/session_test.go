@@ -5,10 +5,16 @@ import (
 	"testing"
 )
 
+// chdirTemp runs the test in an empty directory so saved sessions don't leak between tests
+func chdirTemp(t *testing.T) {
+	t.Helper()
+	origDir, _ := os.Getwd()
+	os.Chdir(t.TempDir())
+	t.Cleanup(func() { os.Chdir(origDir) })
+}
+
 func TestSessionPersistence(t *testing.T) {
-	// Clean up before and after test
-	os.Remove(sessionFile)
-	defer os.Remove(sessionFile)
+	chdirTemp(t)
 
 	// Initially no session should exist
 	if SessionExists() {
@@ -56,7 +62,7 @@ func TestSessionPersistence(t *testing.T) {
 }
 
 func TestClearSession_NoFile(t *testing.T) {
-	os.Remove(sessionFile)
+	chdirTemp(t)
 
 	// Should not error if file doesn't exist
 	if err := ClearSession(); err != nil {
@@ -65,7 +71,7 @@ func TestClearSession_NoFile(t *testing.T) {
 }
 
 func TestSessionAgeHours_NoSession(t *testing.T) {
-	os.Remove(sessionFile)
+	chdirTemp(t)
 
 	age, err := SessionAgeHours()
 	if err != nil {
@@ -77,8 +83,7 @@ func TestSessionAgeHours_NoSession(t *testing.T) {
 }
 
 func TestIsSessionExpired_NoExpiry(t *testing.T) {
-	os.Remove(sessionFile)
-	defer os.Remove(sessionFile)
+	chdirTemp(t)
 
 	if err := SaveSessionID("test-session"); err != nil {
 		t.Fatalf("SaveSessionID failed: %v", err)
@@ -96,8 +101,7 @@ func TestIsSessionExpired_NoExpiry(t *testing.T) {
 }
 
 func TestIsSessionExpired_NotExpired(t *testing.T) {
-	os.Remove(sessionFile)
-	defer os.Remove(sessionFile)
+	chdirTemp(t)
 
 	if err := SaveSessionID("test-session"); err != nil {
 		t.Fatalf("SaveSessionID failed: %v", err)
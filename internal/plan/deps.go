@@ -0,0 +1,284 @@
+package plan
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// afterRegex matches dependency annotations like "(after: T3, T4)"
+var afterRegex = regexp.MustCompile(`\(\s*(?:after|depends on|needs):\s*([^)]*)\)`)
+
+// blockedRegex matches the marker focus mode leaves on tasks it gave up on
+var blockedRegex = regexp.MustCompile(`\(\s*BLOCKED:\s*([^)]*)\)`)
+
+// frontMatterDependsKeys are the front-matter keys that hold the dependency graph
+var frontMatterDependsKeys = map[string]bool{"depends": true, "dependencies": true}
+
+// ValidationError describes a problem with the plan's dependency graph
+type ValidationError struct {
+	TaskID  string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.TaskID == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("task %s: %s", e.TaskID, e.Message)
+}
+
+// extractAfter strips dependency annotations from task text and returns the referenced IDs
+func extractAfter(text string) (string, []string) {
+	matches := afterRegex.FindAllStringSubmatch(text, -1)
+	if matches == nil {
+		return text, nil
+	}
+
+	var deps []string
+	for _, m := range matches {
+		deps = append(deps, splitIDList(m[1])...)
+	}
+	return strings.TrimSpace(afterRegex.ReplaceAllString(text, "")), deps
+}
+
+// extractBlocked strips a "(BLOCKED: reason)" marker from task text and returns the reason
+func extractBlocked(text string) (string, string) {
+	m := blockedRegex.FindStringSubmatch(text)
+	if m == nil {
+		return text, ""
+	}
+
+	reason := strings.TrimSpace(m[1])
+	if reason == "" {
+		reason = "blocked"
+	}
+	return strings.TrimSpace(blockedRegex.ReplaceAllString(text, "")), reason
+}
+
+// maxReasonLength caps the length of a BLOCKED reason written into the plan
+const maxReasonLength = 160
+
+// sanitizeReason flattens a reason so it fits inside a single-line marker
+// Only the first non-empty line is kept, cut to maxReasonLength
+func sanitizeReason(reason string) string {
+	for _, line := range strings.Split(reason, "\n") {
+		if strings.TrimSpace(line) != "" {
+			reason = line
+			break
+		}
+	}
+	reason = strings.Join(strings.Fields(reason), " ")
+	if r := []rune(reason); len(r) > maxReasonLength {
+		reason = strings.TrimSpace(string(r[:maxReasonLength-3])) + "..."
+	}
+	reason = strings.NewReplacer("(", "[", ")", "]").Replace(reason)
+	if reason == "" {
+		reason = "blocked"
+	}
+	return reason
+}
+
+// parseFrontMatterDepends reads a dependency graph from YAML-style front matter:
+//
+//	---
+//	depends:
+//	  T5: [T3, T4]
+//	  T6: T5
+//	---
+func parseFrontMatterDepends(lines []string) map[string][]string {
+	if len(lines) == 0 || strings.TrimSpace(strings.TrimRight(lines[0], "\r")) != "---" {
+		return nil
+	}
+
+	graph := make(map[string][]string)
+	inDepends := false
+	for _, raw := range lines[1:] {
+		line := strings.TrimRight(raw, "\r")
+		if strings.TrimSpace(line) == "---" {
+			return graph
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		indented := line[0] == ' ' || line[0] == '\t'
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+
+		if !indented {
+			inDepends = frontMatterDependsKeys[key]
+			continue
+		}
+		if inDepends {
+			graph[key] = append(graph[key], splitIDList(value)...)
+		}
+	}
+
+	// Unterminated front matter - don't guess
+	return nil
+}
+
+// splitIDList splits "T3, T4" or "[T3, T4]" into IDs
+func splitIDList(s string) []string {
+	s = strings.Trim(strings.TrimSpace(s), "[]")
+	var ids []string
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if part = strings.Trim(part, `"'`); part != "" {
+			ids = append(ids, part)
+		}
+	}
+	return ids
+}
+
+// Ready returns unchecked, unblocked tasks whose prerequisites are all checked, in document order
+func (p *Plan) Ready() []*Task {
+	var ready []*Task
+	for _, t := range p.tasks {
+		if !t.Checked && p.IsReady(t) {
+			ready = append(ready, t)
+		}
+	}
+	return ready
+}
+
+// Blocked returns unchecked tasks that are marked BLOCKED or still waiting on prerequisites, in document order
+func (p *Plan) Blocked() []*Task {
+	var blocked []*Task
+	for _, t := range p.tasks {
+		if !t.Checked && !p.IsReady(t) {
+			blocked = append(blocked, t)
+		}
+	}
+	return blocked
+}
+
+// IsReady reports whether t is not marked BLOCKED and every prerequisite of t (and of its parents) is checked
+// Unknown prerequisites count as unmet so a typo can't silently unblock a task
+func (p *Plan) IsReady(t *Task) bool {
+	return t.Blocked == "" && len(p.BlockedBy(t)) == 0
+}
+
+// BlockedBy returns the IDs of unmet prerequisites of t and of its parent tasks
+func (p *Plan) BlockedBy(t *Task) []string {
+	var unmet []string
+	for cur := t; cur != nil; cur = cur.Parent {
+		for _, id := range cur.After {
+			dep := p.Find(id)
+			if dep == nil || !dep.Checked {
+				unmet = append(unmet, id)
+			}
+		}
+	}
+	return unmet
+}
+
+// Validate checks for task IDs used more than once and the dependency graph for
+// unknown references and cycles
+func (p *Plan) Validate() []error {
+	var errs []error
+
+	// Find returns the first task with an ID, so a repeated anchor sends dependencies,
+	// focus attempts and checkbox updates to the wrong task
+	lines := make(map[string][]int)
+	for _, t := range p.tasks {
+		lines[t.ID] = append(lines[t.ID], t.Line)
+	}
+	for _, t := range p.tasks {
+		if at := lines[t.ID]; len(at) > 1 && at[0] == t.Line {
+			errs = append(errs, &ValidationError{TaskID: t.ID, Message: fmt.Sprintf("ID is used by more than one task (lines %s)", joinInts(at))})
+		}
+	}
+
+	for _, t := range p.tasks {
+		for _, id := range t.After {
+			if id == t.ID {
+				errs = append(errs, &ValidationError{TaskID: t.ID, Message: "depends on itself"})
+			} else if p.Find(id) == nil {
+				errs = append(errs, &ValidationError{TaskID: t.ID, Message: fmt.Sprintf("depends on unknown task %s", id)})
+			}
+		}
+	}
+
+	unmatched := make([]string, 0, len(p.unmatchedFrontMatter))
+	for id := range p.unmatchedFrontMatter {
+		unmatched = append(unmatched, id)
+	}
+	sort.Strings(unmatched)
+	for _, id := range unmatched {
+		errs = append(errs, &ValidationError{TaskID: id, Message: "front matter lists dependencies for an unknown task"})
+	}
+
+	for _, cycle := range p.cycles() {
+		errs = append(errs, &ValidationError{
+			TaskID:  cycle[0],
+			Message: "dependency cycle: " + strings.Join(cycle, " -> "),
+		})
+	}
+
+	return errs
+}
+
+// cycles finds dependency cycles with a depth-first search
+// Each cycle is reported once, starting and ending with the same ID
+func (p *Plan) cycles() [][]string {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+
+	state := make(map[string]int)
+	var path []string
+	var found [][]string
+
+	var visit func(t *Task)
+	visit = func(t *Task) {
+		state[t.ID] = visiting
+		path = append(path, t.ID)
+
+		for _, id := range t.After {
+			dep := p.Find(id)
+			if dep == nil || dep == t {
+				continue
+			}
+			switch state[dep.ID] {
+			case visiting:
+				// Slice the current path from the first occurrence of dep
+				for i, pid := range path {
+					if pid == dep.ID {
+						cycle := append(append([]string{}, path[i:]...), dep.ID)
+						found = append(found, cycle)
+						break
+					}
+				}
+			case unvisited:
+				visit(dep)
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[t.ID] = done
+	}
+
+	for _, t := range p.tasks {
+		if state[t.ID] == unvisited {
+			visit(t)
+		}
+	}
+	return found
+}
+
+// joinInts formats numbers as a comma-separated list
+func joinInts(nums []int) string {
+	parts := make([]string, len(nums))
+	for i, n := range nums {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,171 @@
+package plan
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const dependentPlan = `## Phase 1
+- [x] Design schema <!-- id: T1 -->
+- [ ] Write migrations (after: T1) <!-- id: T2 -->
+- [ ] Seed data (after: T1, T2) <!-- id: T3 -->
+- [ ] Update docs <!-- id: T4 -->
+`
+
+func TestParse_AfterAnnotation(t *testing.T) {
+	p := Parse(dependentPlan)
+
+	seed := p.Find("T3")
+	if seed == nil {
+		t.Fatal("task T3 missing")
+	}
+	if seed.Text != "Seed data" {
+		t.Errorf("seed.Text = %q, want annotation stripped", seed.Text)
+	}
+	if !reflect.DeepEqual(seed.After, []string{"T1", "T2"}) {
+		t.Errorf("seed.After = %v, want [T1 T2]", seed.After)
+	}
+	if p.String() != dependentPlan {
+		t.Error("annotations should survive a round trip")
+	}
+}
+
+func TestPlan_ReadyAndBlocked(t *testing.T) {
+	p := Parse(dependentPlan)
+
+	if got := ids(p.Ready()); !reflect.DeepEqual(got, []string{"T2", "T4"}) {
+		t.Errorf("Ready() = %v, want [T2 T4]", got)
+	}
+	if got := ids(p.Blocked()); !reflect.DeepEqual(got, []string{"T3"}) {
+		t.Errorf("Blocked() = %v, want [T3]", got)
+	}
+	if got := p.BlockedBy(p.Find("T3")); !reflect.DeepEqual(got, []string{"T2"}) {
+		t.Errorf("BlockedBy(T3) = %v, want [T2]", got)
+	}
+
+	p.SetChecked(p.Find("T2"), true)
+	if got := ids(p.Ready()); !reflect.DeepEqual(got, []string{"T3", "T4"}) {
+		t.Errorf("Ready() after checking T2 = %v, want [T3 T4]", got)
+	}
+}
+
+func TestPlan_SubtasksInheritParentDependencies(t *testing.T) {
+	p := Parse("- [ ] Base <!-- id: A -->\n- [ ] Feature (after: A)\n  - [ ] Part one\n")
+
+	part := p.FindByText("Part one")
+	if p.IsReady(part) {
+		t.Error("subtask should be blocked by its parent's prerequisite")
+	}
+}
+
+func TestParse_FrontMatterDepends(t *testing.T) {
+	content := "---\ntitle: Plan\ndepends:\n  T3: [T1, T2]\n  T2: T1\n---\n" +
+		"- [ ] One <!-- id: T1 -->\n- [ ] Two <!-- id: T2 -->\n- [ ] Three <!-- id: T3 -->\n"
+	p := Parse(content)
+
+	if got := p.Find("T3").After; !reflect.DeepEqual(got, []string{"T1", "T2"}) {
+		t.Errorf("T3.After = %v, want [T1 T2]", got)
+	}
+	if got := ids(p.Ready()); !reflect.DeepEqual(got, []string{"T1"}) {
+		t.Errorf("Ready() = %v, want [T1]", got)
+	}
+	if errs := p.Validate(); len(errs) != 0 {
+		t.Errorf("Validate() = %v, want no errors", errs)
+	}
+}
+
+func TestPlan_Validate(t *testing.T) {
+	content := "---\ndepends:\n  T9: T1\n---\n" +
+		"- [ ] One (after: T2) <!-- id: T1 -->\n" +
+		"- [ ] Two (after: T1) <!-- id: T2 -->\n" +
+		"- [ ] Three (after: T7) <!-- id: T3 -->\n" +
+		"- [ ] Four (after: T4) <!-- id: T4 -->\n"
+	p := Parse(content)
+
+	var msgs []string
+	for _, err := range p.Validate() {
+		msgs = append(msgs, err.Error())
+	}
+	joined := strings.Join(msgs, "\n")
+
+	for _, want := range []string{
+		"task T3: depends on unknown task T7",
+		"task T4: depends on itself",
+		"task T9: front matter lists dependencies for an unknown task",
+		"dependency cycle: T1 -> T2 -> T1",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("Validate() missing %q in:\n%s", want, joined)
+		}
+	}
+
+	if p.IsReady(p.Find("T3")) {
+		t.Error("unknown prerequisites should keep a task blocked")
+	}
+}
+
+func TestPlan_ValidateDuplicateAnchors(t *testing.T) {
+	p := Parse("- [ ] One <!-- id: T1 -->\n- [ ] Two <!-- id: T2 -->\n- [ ] Copy of one <!-- id: T1 -->\n")
+
+	errs := p.Validate()
+	if len(errs) != 1 || errs[0].Error() != "task T1: ID is used by more than one task (lines 1, 3)" {
+		t.Errorf("Validate() = %v, want one duplicate ID error for T1", errs)
+	}
+
+	// Identical task texts get distinct hash IDs and are not reported
+	if errs := Parse("- [ ] Same\n- [ ] Same\n").Validate(); len(errs) != 0 {
+		t.Errorf("Validate() of repeated unanchored tasks = %v, want none", errs)
+	}
+}
+
+func ids(tasks []*Task) []string {
+	var out []string
+	for _, t := range tasks {
+		out = append(out, t.ID)
+	}
+	return out
+}
+
+func TestPlan_SetBlocked(t *testing.T) {
+	content := "- [ ] Call the API\r\n- [ ] Render results (after: %s)\r\n"
+	p := Parse(content)
+	api := p.FindByText("Call the API")
+	id := api.ID
+	p = Parse(strings.Replace(content, "%s", id, 1))
+	api = p.Find(id)
+
+	if !p.SetBlocked(api, "missing (staging) credentials") {
+		t.Fatal("SetBlocked() = false, want true")
+	}
+	if p.SetBlocked(api, "again") {
+		t.Error("SetBlocked() on an already-blocked task should report no change")
+	}
+
+	want := "- [ ] Call the API (BLOCKED: missing [staging] credentials)\r\n"
+	if got := p.String(); !strings.HasPrefix(got, want) {
+		t.Errorf("String() = %q, want prefix %q", got, want)
+	}
+
+	reparsed := Parse(p.String())
+	blocked := reparsed.Find(id)
+	if blocked == nil {
+		t.Fatal("BLOCKED marker changed the task ID")
+	}
+	if blocked.Text != "Call the API" || blocked.Blocked != "missing [staging] credentials" {
+		t.Errorf("reparsed task = %q (blocked %q)", blocked.Text, blocked.Blocked)
+	}
+	if len(reparsed.Ready()) != 0 || len(reparsed.Blocked()) != 2 {
+		t.Errorf("Ready()/Blocked() = %d/%d, want the marked task and its dependent blocked", len(reparsed.Ready()), len(reparsed.Blocked()))
+	}
+}
+
+func TestSanitizeReason(t *testing.T) {
+	long := "codex timed out: " + strings.Repeat("x", 500)
+	if got := sanitizeReason(long); len(got) > maxReasonLength || !strings.HasSuffix(got, "...") {
+		t.Errorf("sanitizeReason() = %q, want it cut to %d characters", got, maxReasonLength)
+	}
+	if got := sanitizeReason("\ncodex execution failed: exit status 1\nOutput: {\"type\": \"message\"}"); got != "codex execution failed: exit status 1" {
+		t.Errorf("sanitizeReason() = %q, want only the first line", got)
+	}
+}
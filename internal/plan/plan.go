@@ -0,0 +1,459 @@
+package plan
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// DefaultPhaseName names the implicit phase holding tasks that precede any header
+const DefaultPhaseName = "Tasks"
+
+// anchorRegex matches explicit task ID anchors like <!-- id: T3 -->
+var anchorRegex = regexp.MustCompile(`<!--\s*id:\s*([A-Za-z0-9_.\-]+)\s*-->`)
+
+// Plan is a parsed plan document
+// It keeps the original lines so edits can be written back without reformatting
+type Plan struct {
+	Path   string
+	Phases []*Phase
+
+	lines []string
+	tasks []*Task // All tasks in document order, including subtasks
+
+	unmatchedFrontMatter map[string]bool // Front-matter dependency keys with no matching task
+}
+
+// Phase is a group of tasks under a section header
+type Phase struct {
+	Name  string
+	Line  int // 1-based line of the header (0 for the implicit default phase)
+	Tasks []*Task
+}
+
+// Task is a checklist item, possibly with nested subtasks
+type Task struct {
+	ID       string // Explicit anchor ID, or a hash of the text and its parents
+	Text     string // Task text without checkbox or ID anchor
+	Checked  bool
+	Anchored bool     // True if ID came from an explicit <!-- id: --> anchor
+	After    []string // IDs of tasks that must be completed first
+	Blocked  string   // Reason from a "(BLOCKED: ...)" marker; the task is skipped until it is removed
+	Line     int      // 1-based line number in the source document
+	Indent   int      // Leading whitespace width
+	Subtasks []*Task
+	Parent   *Task
+	Phase    *Phase
+
+	boxCol int // Byte offset of the checkbox character inside "[ ]"
+}
+
+// Load reads and parses a plan file
+func Load(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan file %s: %w", path, err)
+	}
+
+	p := Parse(string(data))
+	p.Path = path
+	return p, nil
+}
+
+// Parse parses plan markdown into phases, tasks and subtasks
+func Parse(content string) *Plan {
+	p := &Plan{lines: strings.Split(content, "\n")}
+
+	var current *Phase
+	var stack []*Task // Open tasks by nesting level
+	seen := make(map[string]int)
+
+	for i, raw := range p.lines {
+		line := strings.TrimRight(raw, "\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+
+		if IsPhaseHeader(trimmed) {
+			current = &Phase{Name: PhaseName(trimmed), Line: i + 1}
+			p.Phases = append(p.Phases, current)
+			stack = stack[:0]
+			continue
+		}
+
+		item, ok := parseChecklistLine(line)
+		if !ok {
+			continue
+		}
+
+		if current == nil {
+			current = &Phase{Name: DefaultPhaseName}
+			p.Phases = append(p.Phases, current)
+		}
+
+		task := &Task{
+			Text:    item.text,
+			Checked: item.checked,
+			Line:    i + 1,
+			Indent:  item.indent,
+			Phase:   current,
+			boxCol:  item.boxCol,
+		}
+
+		if m := anchorRegex.FindStringSubmatch(task.Text); m != nil {
+			task.ID = m[1]
+			task.Anchored = true
+			task.Text = strings.TrimSpace(anchorRegex.ReplaceAllString(task.Text, ""))
+		}
+		task.Text, task.After = extractAfter(task.Text)
+		task.Text, task.Blocked = extractBlocked(task.Text)
+
+		// Attach to the nearest less-indented open task
+		for len(stack) > 0 && stack[len(stack)-1].Indent >= task.Indent {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) > 0 {
+			task.Parent = stack[len(stack)-1]
+			task.Parent.Subtasks = append(task.Parent.Subtasks, task)
+		} else {
+			current.Tasks = append(current.Tasks, task)
+		}
+		stack = append(stack, task)
+
+		if !task.Anchored {
+			task.ID = hashID(task, seen)
+		}
+
+		p.tasks = append(p.tasks, task)
+	}
+
+	// Merge the front-matter dependency graph into the tasks
+	for id, deps := range parseFrontMatterDepends(p.lines) {
+		if t := p.Find(id); t != nil {
+			t.After = append(t.After, deps...)
+		} else {
+			if p.unmatchedFrontMatter == nil {
+				p.unmatchedFrontMatter = make(map[string]bool)
+			}
+			p.unmatchedFrontMatter[id] = true
+		}
+	}
+
+	return p
+}
+
+// Tasks returns every task in document order, including subtasks
+func (p *Plan) Tasks() []*Task {
+	return p.tasks
+}
+
+// Remaining returns unchecked tasks in document order
+func (p *Plan) Remaining() []*Task {
+	var remaining []*Task
+	for _, t := range p.tasks {
+		if !t.Checked {
+			remaining = append(remaining, t)
+		}
+	}
+	return remaining
+}
+
+// Counts returns the number of checked tasks and the total number of tasks
+func (p *Plan) Counts() (done, total int) {
+	for _, t := range p.tasks {
+		if t.Checked {
+			done++
+		}
+	}
+	return done, len(p.tasks)
+}
+
+// Find returns the task with the given ID, or nil
+func (p *Plan) Find(id string) *Task {
+	for _, t := range p.tasks {
+		if t.ID == id {
+			return t
+		}
+	}
+	return nil
+}
+
+// FindByText returns the first task whose text matches exactly, or nil
+// A leading "[ ] " or "[x] " prefix (as produced by Strings) also constrains the checkbox state
+func (p *Plan) FindByText(text string) *Task {
+	want, state := splitStatePrefix(text)
+	for _, t := range p.tasks {
+		if t.Text == want && (state == "" || (state == "x") == t.Checked) {
+			return t
+		}
+	}
+	return nil
+}
+
+// SetChecked updates a task's checkbox in place, leaving the rest of the line untouched
+func (p *Plan) SetChecked(t *Task, checked bool) bool {
+	if t == nil || t.Checked == checked || t.Line < 1 || t.Line > len(p.lines) {
+		return false
+	}
+
+	line := p.lines[t.Line-1]
+	if t.boxCol >= len(line) {
+		return false
+	}
+
+	mark := byte(' ')
+	if checked {
+		mark = 'x'
+	}
+	p.lines[t.Line-1] = line[:t.boxCol] + string(mark) + line[t.boxCol+1:]
+	t.Checked = checked
+	return true
+}
+
+// SetBlocked appends a "(BLOCKED: reason)" marker to a task's line
+// The marker is stripped before hashing, so the task keeps its ID
+func (p *Plan) SetBlocked(t *Task, reason string) bool {
+	if t == nil || t.Blocked != "" || t.Line < 1 || t.Line > len(p.lines) {
+		return false
+	}
+
+	reason = sanitizeReason(reason)
+	line := p.lines[t.Line-1]
+	cr := ""
+	if strings.HasSuffix(line, "\r") {
+		line, cr = strings.TrimSuffix(line, "\r"), "\r"
+	}
+	p.lines[t.Line-1] = fmt.Sprintf("%s (BLOCKED: %s)%s", strings.TrimRight(line, " \t"), reason, cr)
+	t.Blocked = reason
+	return true
+}
+
+// String returns the document with any edits applied
+func (p *Plan) String() string {
+	return strings.Join(p.lines, "\n")
+}
+
+// Save writes the document back to its source path
+func (p *Plan) Save() error {
+	if p.Path == "" {
+		return fmt.Errorf("plan has no source path")
+	}
+	return os.WriteFile(p.Path, []byte(p.String()), 0644)
+}
+
+// Strings returns tasks in the legacy "[x] text" / "[ ] text" format
+func (p *Plan) Strings() []string {
+	out := make([]string, 0, len(p.tasks))
+	for _, t := range p.tasks {
+		out = append(out, t.String())
+	}
+	return out
+}
+
+// String returns the task in the legacy "[x] text" / "[ ] text" format
+func (t *Task) String() string {
+	if t.Checked {
+		return "[x] " + t.Text
+	}
+	return "[ ] " + t.Text
+}
+
+// Completed reports whether the task and all of its subtasks are checked
+func (t *Task) Completed() bool {
+	if !t.Checked {
+		return false
+	}
+	for _, sub := range t.Subtasks {
+		if !sub.Completed() {
+			return false
+		}
+	}
+	return true
+}
+
+// Completed reports whether every task in the phase is checked
+func (ph *Phase) Completed() bool {
+	for _, t := range ph.Tasks {
+		if !t.Completed() {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseChecklistItem extracts a checklist item from a trimmed line
+// Returns (isChecked, taskText, found)
+func ParseChecklistItem(line string) (bool, string, bool) {
+	item, ok := parseChecklistLine(line)
+	if !ok || item.indent != 0 {
+		return false, "", false
+	}
+	return item.checked, item.text, true
+}
+
+// checklistItem is a checklist line broken into its parts
+type checklistItem struct {
+	checked bool
+	text    string
+	indent  int
+	boxCol  int
+}
+
+// parseChecklistLine recognises the supported checklist formats:
+//   - "- [ ] task" or "- [x] task" (Markdown)
+//   - "* [ ] task" or "* [x] task" (Alternative bullet)
+//   - "1. [ ] task" or "1. [x] task" (Numbered)
+//   - "[ ] task" or "[x] task" (Bare checkbox)
+func parseChecklistLine(line string) (checklistItem, bool) {
+	line = strings.TrimRight(line, "\r")
+	body := strings.TrimLeft(line, " \t")
+	indent := len(line) - len(body)
+
+	// Skip the list marker to find the checkbox
+	rest := body
+	switch {
+	case strings.HasPrefix(body, "- "), strings.HasPrefix(body, "* "):
+		rest = body[2:]
+	default:
+		if idx := strings.Index(body, ". "); idx > 0 && idx < 5 && isNumber(body[:idx]) {
+			rest = body[idx+2:]
+		}
+	}
+
+	if len(rest) < 4 || rest[0] != '[' || rest[2] != ']' {
+		return checklistItem{}, false
+	}
+
+	mark := rest[1]
+	if mark != ' ' && mark != 'x' && mark != 'X' {
+		return checklistItem{}, false
+	}
+
+	boxCol := indent + (len(body) - len(rest)) + 1
+	return checklistItem{
+		checked: mark != ' ',
+		text:    strings.TrimSpace(rest[3:]),
+		indent:  indent,
+		boxCol:  boxCol,
+	}, true
+}
+
+// IsPhaseHeader checks if a line is a phase/section header
+// Supports multiple plan formats:
+// - REFACTOR_PLAN.md: ## Phase N: ... headers
+// - IMPLEMENTATION_PLAN.md: ## Phase N: ... or ### N) atomic commit headers
+// - @fix_plan.md: ## Critical Fixes, ## High Priority, ## Medium Priority, etc.
+func IsPhaseHeader(line string) bool {
+	lower := strings.ToLower(line)
+
+	// ## Phase N: ... (REFACTOR_PLAN.md, IMPLEMENTATION_PLAN.md)
+	if strings.HasPrefix(line, "## ") {
+		header := strings.TrimPrefix(line, "## ")
+		headerLower := strings.ToLower(header)
+
+		// Phase headers
+		if strings.Contains(headerLower, "phase") {
+			return true
+		}
+
+		// Fix plan priority headers
+		if strings.HasPrefix(headerLower, "critical") ||
+			strings.HasPrefix(headerLower, "high priority") ||
+			strings.HasPrefix(headerLower, "medium priority") ||
+			strings.HasPrefix(headerLower, "low priority") ||
+			strings.HasPrefix(headerLower, "testing") ||
+			strings.HasPrefix(headerLower, "nice to have") {
+			return true
+		}
+
+		// Verification/Success criteria sections
+		if strings.Contains(headerLower, "verification") ||
+			strings.Contains(headerLower, "success criteria") {
+			return true
+		}
+	}
+
+	// ### N) Atomic commit headers (IMPLEMENTATION_PLAN.md)
+	if strings.HasPrefix(line, "### ") {
+		header := strings.TrimPrefix(line, "### ")
+		// Check for numbered headers like "1) Config..." or "2) OpenCode..."
+		if len(header) >= 2 && header[0] >= '1' && header[0] <= '9' && header[1] == ')' {
+			return true
+		}
+	}
+
+	// ## Atomic Commits section header
+	if strings.HasPrefix(lower, "## atomic") {
+		return true
+	}
+
+	return false
+}
+
+// PhaseName extracts the display name from a phase header line
+func PhaseName(line string) string {
+	if strings.HasPrefix(line, "### ") {
+		header := strings.TrimPrefix(line, "### ")
+		// For "1) Config..." make it "Step 1: Config..."
+		if len(header) >= 2 && header[0] >= '1' && header[0] <= '9' && header[1] == ')' {
+			return "Step " + string(header[0]) + ":" + header[2:]
+		}
+		return header
+	}
+
+	if strings.HasPrefix(line, "## ") {
+		return strings.TrimPrefix(line, "## ")
+	}
+
+	return line
+}
+
+// hashID derives a stable ID from the task text and its parents' text
+// Line numbers are deliberately excluded so inserting lines doesn't change IDs
+func hashID(t *Task, seen map[string]int) string {
+	var parts []string
+	for cur := t; cur != nil; cur = cur.Parent {
+		parts = append(parts, normalizeText(cur.Text))
+	}
+
+	sum := sha1.Sum([]byte(strings.Join(parts, "\x00")))
+	id := "t" + hex.EncodeToString(sum[:])[:8]
+
+	// Identical tasks get an occurrence suffix
+	seen[id]++
+	if n := seen[id]; n > 1 {
+		id = fmt.Sprintf("%s-%d", id, n)
+	}
+	return id
+}
+
+// normalizeText lowercases and collapses whitespace for hashing
+func normalizeText(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// splitStatePrefix separates a legacy "[ ] " or "[x] " prefix from the task text
+// Returns the text and " " or "x" for the state ("" if there was no prefix)
+func splitStatePrefix(s string) (string, string) {
+	switch {
+	case strings.HasPrefix(s, "[ ] "):
+		return s[4:], " "
+	case strings.HasPrefix(s, "[x] "), strings.HasPrefix(s, "[X] "):
+		return s[4:], "x"
+	}
+	return s, ""
+}
+
+// isNumber checks if a string consists only of digits
+func isNumber(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return len(s) > 0
+}
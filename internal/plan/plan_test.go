@@ -0,0 +1,222 @@
+package plan
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const samplePlan = `# Implementation Plan
+
+Intro text that is not a task.
+
+## Phase 1: Setup
+- [x] Create project skeleton
+- [ ] Add config loader <!-- id: CFG -->
+  - [x] Parse YAML
+  - [ ] Validate fields
+    * [ ] Report unknown keys
+
+## Phase 2: Features
+1. [ ] Implement login
+2. [X] Implement logout
+
+Notes:
+- plain bullet
+`
+
+func TestParse_PhasesAndNesting(t *testing.T) {
+	p := Parse(samplePlan)
+
+	if len(p.Phases) != 2 {
+		t.Fatalf("len(Phases) = %d, want 2", len(p.Phases))
+	}
+	if p.Phases[0].Name != "Phase 1: Setup" || p.Phases[0].Line != 5 {
+		t.Errorf("Phases[0] = %q at line %d", p.Phases[0].Name, p.Phases[0].Line)
+	}
+
+	setup := p.Phases[0]
+	if len(setup.Tasks) != 2 {
+		t.Fatalf("len(setup.Tasks) = %d, want 2 top-level tasks", len(setup.Tasks))
+	}
+
+	cfg := setup.Tasks[1]
+	if cfg.Text != "Add config loader" {
+		t.Errorf("cfg.Text = %q, want anchor stripped", cfg.Text)
+	}
+	if cfg.ID != "CFG" || !cfg.Anchored {
+		t.Errorf("cfg.ID = %q (anchored=%v), want explicit CFG", cfg.ID, cfg.Anchored)
+	}
+	if len(cfg.Subtasks) != 2 {
+		t.Fatalf("len(cfg.Subtasks) = %d, want 2", len(cfg.Subtasks))
+	}
+
+	validate := cfg.Subtasks[1]
+	if validate.Parent != cfg || validate.Line != 9 {
+		t.Errorf("validate parent/line = %v/%d", validate.Parent == cfg, validate.Line)
+	}
+	if len(validate.Subtasks) != 1 || validate.Subtasks[0].Text != "Report unknown keys" {
+		t.Errorf("validate.Subtasks = %+v", validate.Subtasks)
+	}
+
+	features := p.Phases[1]
+	if len(features.Tasks) != 2 || !features.Tasks[1].Checked {
+		t.Errorf("features tasks = %+v, want numbered tasks with uppercase X checked", features.Tasks)
+	}
+
+	done, total := p.Counts()
+	if done != 3 || total != 7 {
+		t.Errorf("Counts() = %d/%d, want 3/7", done, total)
+	}
+
+	if setup.Completed() {
+		t.Error("setup phase should not be complete")
+	}
+}
+
+func TestParse_DefaultPhase(t *testing.T) {
+	p := Parse("- [ ] First\n- [x] Second\n")
+
+	if len(p.Phases) != 1 || p.Phases[0].Name != DefaultPhaseName {
+		t.Fatalf("Phases = %+v, want a single default phase", p.Phases)
+	}
+	if got := p.Strings(); len(got) != 2 || got[0] != "[ ] First" || got[1] != "[x] Second" {
+		t.Errorf("Strings() = %v", got)
+	}
+}
+
+func TestParse_StableIDs(t *testing.T) {
+	before := Parse("## Phase 1\n- [ ] Write docs\n- [ ] Ship it\n")
+	after := Parse("# Title\n\nSome new intro.\n\n## Phase 1\n- [ ] New first task\n- [x] Write docs\n- [ ] Ship it\n")
+
+	for _, text := range []string{"Write docs", "Ship it"} {
+		b := before.FindByText(text)
+		a := after.FindByText(text)
+		if b == nil || a == nil {
+			t.Fatalf("task %q missing", text)
+		}
+		if a.ID != b.ID {
+			t.Errorf("ID for %q changed from %s to %s after inserting lines", text, b.ID, a.ID)
+		}
+	}
+}
+
+func TestParse_DuplicateTasksGetDistinctIDs(t *testing.T) {
+	p := Parse("- [ ] Run tests\n- [ ] Run tests\n")
+	tasks := p.Tasks()
+
+	if tasks[0].ID == tasks[1].ID {
+		t.Errorf("duplicate tasks share ID %s", tasks[0].ID)
+	}
+	if p.Find(tasks[1].ID) != tasks[1] {
+		t.Error("Find() did not return the second duplicate")
+	}
+}
+
+func TestPlan_RoundTrip(t *testing.T) {
+	p := Parse(samplePlan)
+	if p.String() != samplePlan {
+		t.Fatal("String() should reproduce the input exactly when nothing was edited")
+	}
+
+	validate := p.FindByText("Validate fields")
+	if !p.SetChecked(validate, true) {
+		t.Fatal("SetChecked() = false, want true")
+	}
+	if p.SetChecked(validate, true) {
+		t.Error("SetChecked() on an already-checked task should report no change")
+	}
+
+	logout := p.FindByText("[x] Implement logout")
+	if !p.SetChecked(logout, false) {
+		t.Fatal("SetChecked(false) = false, want true")
+	}
+
+	want := strings.Replace(samplePlan, "  - [ ] Validate fields", "  - [x] Validate fields", 1)
+	want = strings.Replace(want, "2. [X] Implement logout", "2. [ ] Implement logout", 1)
+	if got := p.String(); got != want {
+		t.Errorf("String() after edits =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestPlan_RoundTripCRLF(t *testing.T) {
+	content := "## Phase 1\r\n- [ ] Task one\r\n- [ ] Task two\r\n"
+	p := Parse(content)
+
+	if task := p.FindByText("Task one"); task == nil {
+		t.Fatal("CRLF line endings should not leak into task text")
+	} else {
+		p.SetChecked(task, true)
+	}
+
+	want := "## Phase 1\r\n- [x] Task one\r\n- [ ] Task two\r\n"
+	if got := p.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestLoadAndSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.md")
+	os.WriteFile(path, []byte("- [ ] Only task\n"), 0644)
+
+	p, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	p.SetChecked(p.Tasks()[0], true)
+	if err := p.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "- [x] Only task\n" {
+		t.Errorf("saved content = %q", string(data))
+	}
+}
+
+func TestFindByText_StatePrefix(t *testing.T) {
+	p := Parse("- [ ] Same\n- [x] Same\n")
+
+	if got := p.FindByText("[x] Same"); got == nil || !got.Checked {
+		t.Error(`FindByText("[x] Same") should return the checked task`)
+	}
+	if got := p.FindByText("[ ] Same"); got == nil || got.Checked {
+		t.Error(`FindByText("[ ] Same") should return the unchecked task`)
+	}
+	if got := p.FindByText("Same"); got != p.Tasks()[0] {
+		t.Error(`FindByText("Same") should return the first match`)
+	}
+}
+
+func TestIsPhaseHeader(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+		name string
+	}{
+		{"## Phase 1: Setup", true, "Phase 1: Setup"},
+		{"## Critical Fixes", true, "Critical Fixes"},
+		{"## High Priority", true, "High Priority"},
+		{"## Success Criteria", true, "Success Criteria"},
+		{"### 2) OpenCode runner", true, "Step 2: OpenCode runner"},
+		{"## Atomic Commits", true, "Atomic Commits"},
+		{"## Overview", false, ""},
+		{"# Phase 1", false, ""},
+		{"- [ ] Phase task", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if got := IsPhaseHeader(tt.line); got != tt.want {
+				t.Errorf("IsPhaseHeader(%q) = %v, want %v", tt.line, got, tt.want)
+			}
+			if tt.want {
+				if got := PhaseName(tt.line); got != tt.name {
+					t.Errorf("PhaseName(%q) = %q, want %q", tt.line, got, tt.name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+// Package proc holds helpers for the processes Lisa starts
+package proc
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// KillGroup starts cmd in a process group of its own and has cancellation kill the
+// whole group, so the processes cmd started don't outlive it
+func KillGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build windows
+
+// Package proc holds helpers for the processes Lisa starts
+package proc
+
+import "os/exec"
+
+// KillGroup leaves cancellation to kill cmd's process alone
+// Windows has no process group to signal, so processes cmd started may outlive it
+func KillGroup(cmd *exec.Cmd) {}
@@ -242,12 +242,8 @@ func initGitRepo(projectPath string) error {
 
 	// Create .gitignore
 	gitignorePath := filepath.Join(projectPath, ".gitignore")
-	gitignoreContent := `# Lisa Codex
-.ralph_session
-.circuit_breaker_state
-.exit_signals
-.call_count
-.response_analysis
+	gitignoreContent := `# Lisa state
+.lisa/
 
 # Logs
 logs/
@@ -0,0 +1,206 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brainwhocodes/lisa-loop/internal/codex"
+	"github.com/brainwhocodes/lisa-loop/internal/openai"
+)
+
+// ErrorClass says what kind of failure a backend error is, and so how to react to it
+type ErrorClass string
+
+const (
+	ErrorTransient       ErrorClass = "transient"        // Network errors, timeouts and server errors
+	ErrorQuota           ErrorClass = "quota"            // HTTP 429, rate limits and exhausted quotas
+	ErrorAuth            ErrorClass = "auth"             // Missing or rejected credentials
+	ErrorMissingBinary   ErrorClass = "missing_binary"   // The backend's executable is not installed
+	ErrorContextOverflow ErrorClass = "context_overflow" // The session outgrew the model's context window
+	ErrorCrash           ErrorClass = "crash"            // The agent process exited abnormally
+	ErrorUnknown         ErrorClass = "unknown"
+)
+
+// Fatal reports whether retrying cannot help until the user steps in
+func (c ErrorClass) Fatal() bool {
+	return c == ErrorAuth || c == ErrorMissingBinary
+}
+
+// Classification is the class of a backend error and what it says about retrying
+type Classification struct {
+	Class      ErrorClass
+	RetryAfter time.Duration // Time until a quota resets, if the backend said (0 if unknown)
+	Binary     string        // The executable that was not found (ErrorMissingBinary)
+}
+
+// errorTailSize bounds how much of an error message is searched
+// Process errors carry the agent's stderr, whose start may be progress it printed there
+const errorTailSize = 2048
+
+var (
+	// statusCode matches an HTTP status in an error message: "status 429", "HTTP 401"
+	statusCode = regexp.MustCompile(`(?i)\b(?:status|http)(?: code)?[: ]+(\d{3})\b`)
+	// retryPhrase matches the start of a reset time: "retry after 30s", "try again in 2 hours"
+	retryPhrase = regexp.MustCompile(`(?i)(?:retry[- ]after|try again in|resets? in)[:\s]+`)
+	// retryAmount matches one amount of a reset time: "30s", "2 hours", "1.5 minutes"
+	retryAmount = regexp.MustCompile(`(?i)^(?:,?\s*(?:and\s+)?)(\d+(?:\.\d+)?)\s*(ms|milli[a-z]*|[smhd][a-z]*)`)
+	// retrySeconds matches a bare number of seconds: "Retry-After: 30"
+	retrySeconds = regexp.MustCompile(`^(\d+)\b`)
+)
+
+// Message fragments of each class, matched against the lowercased error
+var (
+	contextOverflowHints = []string{
+		"context_length_exceeded", "context length", "context window", "maximum context",
+		"prompt is too long", "input is too long", "too many tokens",
+	}
+	authHints = []string{
+		"unauthorized", "invalid api key", "invalid_api_key", "incorrect api key",
+		"authentication", "not logged in", "please log in", "login required",
+	}
+	quotaHints = []string{
+		"rate limit", "rate_limit", "ratelimit", "too many requests", "quota", "usage limit",
+	}
+	transientHints = []string{
+		"connection refused", "connection reset", "broken pipe", "no such host",
+		"timeout", "timed out", "temporarily unavailable", "service unavailable",
+		"bad gateway", "overloaded", "tls handshake", "unexpected eof", "sse read error",
+	}
+	crashHints = []string{"panic:", "signal: ", "segmentation fault"}
+)
+
+// Classify works out the class of an error returned by a Runner
+func Classify(err error) Classification {
+	if err == nil {
+		return Classification{Class: ErrorUnknown}
+	}
+
+	var execErr *exec.Error
+	if errors.As(err, &execErr) && errors.Is(execErr.Err, exec.ErrNotFound) {
+		return Classification{Class: ErrorMissingBinary, Binary: execErr.Name}
+	}
+
+	// An agent's stdout is its own work, which may well discuss logins or rate limits;
+	// only what it wrote to stderr describes the failure
+	msg := err.Error()
+	var agentErr *codex.ExecError
+	if errors.As(err, &agentErr) {
+		msg = fmt.Sprintf("%s execution failed: %v\n%s", agentErr.Name, agentErr.Err, agentErr.Stderr)
+	}
+	msg = errorTail(msg)
+	lower := strings.ToLower(msg)
+
+	status := 0
+	var statusErr *openai.StatusError
+	if errors.As(err, &statusErr) {
+		status = statusErr.StatusCode
+	} else if m := statusCode.FindStringSubmatch(msg); m != nil {
+		status, _ = strconv.Atoi(m[1])
+	}
+
+	switch {
+	case status == 413 || containsAny(lower, contextOverflowHints):
+		return Classification{Class: ErrorContextOverflow}
+	case status == 401 || status == 403 || containsAny(lower, authHints):
+		return Classification{Class: ErrorAuth}
+	case status == 429 || containsAny(lower, quotaHints):
+		wait := parseRetryAfter(msg)
+		if statusErr != nil && statusErr.RetryAfter > 0 {
+			wait = statusErr.RetryAfter
+		}
+		return Classification{Class: ErrorQuota, RetryAfter: wait}
+	case status == 408 || status >= 500 || isNetworkError(err) || containsAny(lower, transientHints):
+		return Classification{Class: ErrorTransient}
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) || containsAny(lower, crashHints) {
+		return Classification{Class: ErrorCrash}
+	}
+	return Classification{Class: ErrorUnknown}
+}
+
+// isNetworkError reports whether err is a network failure or a timeout
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// errorTail returns the first line of msg and at most errorTailSize bytes of its end
+func errorTail(msg string) string {
+	if len(msg) <= errorTailSize {
+		return msg
+	}
+	first := msg
+	if i := strings.IndexByte(msg, '\n'); i >= 0 {
+		first = msg[:i]
+	}
+	if len(first) > errorTailSize {
+		first = first[:errorTailSize]
+	}
+	return first + "\n" + msg[len(msg)-errorTailSize:]
+}
+
+// parseRetryAfter finds how long an error message says to wait, such as
+// "try again in 4 days 23 hours" or "Retry-After: 30" (0 if it doesn't say)
+func parseRetryAfter(msg string) time.Duration {
+	loc := retryPhrase.FindStringIndex(msg)
+	if loc == nil {
+		return 0
+	}
+	rest := msg[loc[1]:]
+
+	var total time.Duration
+	for {
+		m := retryAmount.FindStringSubmatch(rest)
+		if m == nil {
+			break
+		}
+		n, _ := strconv.ParseFloat(m[1], 64)
+		total += time.Duration(n * float64(retryUnit(strings.ToLower(m[2]))))
+		rest = rest[len(m[0]):]
+	}
+	if total == 0 {
+		if m := retrySeconds.FindStringSubmatch(rest); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			total = time.Duration(n) * time.Second
+		}
+	}
+	return total
+}
+
+// retryUnit returns the duration of a unit such as "s", "min" or "hours"
+func retryUnit(unit string) time.Duration {
+	switch {
+	case unit == "ms" || strings.HasPrefix(unit, "milli"):
+		return time.Millisecond
+	case unit[0] == 's':
+		return time.Second
+	case unit[0] == 'm':
+		return time.Minute
+	case unit[0] == 'h':
+		return time.Hour
+	default:
+		return 24 * time.Hour
+	}
+}
+
+// containsAny reports whether s contains any of the fragments
+func containsAny(s string, fragments []string) bool {
+	for _, f := range fragments {
+		if strings.Contains(s, f) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brainwhocodes/lisa-loop/internal/codex"
+	"github.com/brainwhocodes/lisa-loop/internal/openai"
+)
+
+func TestClassify(t *testing.T) {
+	notFound := &exec.Error{Name: "codex", Err: exec.ErrNotFound}
+	exitErr := &exec.ExitError{}
+
+	tests := []struct {
+		name  string
+		err   error
+		class ErrorClass
+		wait  time.Duration
+	}{
+		{"missing binary", fmt.Errorf("failed to start codex: %w", notFound), ErrorMissingBinary, 0},
+		{"openai 401", &openai.StatusError{StatusCode: 401, Body: `{"error":"bad key"}`}, ErrorAuth, 0},
+		{"openai 429", &openai.StatusError{StatusCode: 429, RetryAfter: 30 * time.Second}, ErrorQuota, 30 * time.Second},
+		{"openai 503", &openai.StatusError{StatusCode: 503, Body: "unavailable"}, ErrorTransient, 0},
+		{"opencode status", errors.New("failed to send message: status 401, body: unauthorized"), ErrorAuth, 0},
+		{"codex not logged in", &codex.ExecError{Name: "codex", Err: exitErr, Stderr: "Error: Not logged in. Run codex login."}, ErrorAuth, 0},
+		{"usage limit", &codex.ExecError{Name: "codex", Err: exitErr, Stderr: "You've hit your usage limit. Try again in 2 hours 30 minutes."}, ErrorQuota, 150 * time.Minute},
+		{"agent output", &codex.ExecError{Name: "claude", Err: exitErr, Output: "Added a 429 rate limit and an unauthorized handler"}, ErrorCrash, 0},
+		{"rate limited", errors.New("API rate limited after 3 retries: Rate limit reached, retry after 20s"), ErrorQuota, 20 * time.Second},
+		{"retry-after header", errors.New("HTTP 429 Too Many Requests (Retry-After: 12)"), ErrorQuota, 12 * time.Second},
+		{"context overflow", errors.New(`chat completion failed with status 400: {"code":"context_length_exceeded"}`), ErrorContextOverflow, 0},
+		{"prompt too long", errors.New("session error: prompt is too long: 210000 tokens > 200000 maximum"), ErrorContextOverflow, 0},
+		{"timeout", fmt.Errorf("request failed: %w", context.DeadlineExceeded), ErrorTransient, 0},
+		{"connection refused", errors.New("failed to send message: dial tcp 127.0.0.1:4096: connect: connection refused"), ErrorTransient, 0},
+		{"crash", errors.New("codex execution failed: signal: killed\nOutput: "), ErrorCrash, 0},
+		{"exit status", fmt.Errorf("codex execution failed: %w\nOutput: ", &exec.ExitError{}), ErrorCrash, 0},
+		{"unknown", errors.New("no final answer after 50 tool turns"), ErrorUnknown, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Classify(tt.err)
+			if got.Class != tt.class || got.RetryAfter != tt.wait {
+				t.Errorf("Classify() = %s (retry after %s), want %s (retry after %s)", got.Class, got.RetryAfter, tt.class, tt.wait)
+			}
+		})
+	}
+
+	if got := Classify(notFound); got.Binary != "codex" {
+		t.Errorf("Classify() Binary = %q, want codex", got.Binary)
+	}
+}
+
+func TestClassify_OnlyTheEndOfLongOutput(t *testing.T) {
+	// The agent's own output talks about authentication; only the failure at the end counts
+	output := "Implemented the unauthorized handler for 401 responses\n" + strings.Repeat("working...\n", 500)
+	err := fmt.Errorf("agent execution failed: %w\nOutput: %s", &exec.ExitError{}, output+"segmentation fault")
+	if got := Classify(err); got.Class != ErrorCrash {
+		t.Errorf("Classify() = %s, want %s", got.Class, ErrorCrash)
+	}
+}
+
+func TestErrorClass_Fatal(t *testing.T) {
+	for _, class := range []ErrorClass{ErrorTransient, ErrorQuota, ErrorAuth, ErrorMissingBinary, ErrorContextOverflow, ErrorCrash, ErrorUnknown} {
+		want := class == ErrorAuth || class == ErrorMissingBinary
+		if class.Fatal() != want {
+			t.Errorf("%s.Fatal() = %v, want %v", class, class.Fatal(), want)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package runner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Recording is one captured Run call
+type Recording struct {
+	Seq        int             `json:"seq"`            // 1-based call number within the recording directory
+	Loop       int             `json:"loop,omitempty"` // Loop iteration the call was made in (0 if unknown)
+	PromptHash string          `json:"prompt_hash"`
+	Prompt     string          `json:"prompt"`
+	Events     []RecordedEvent `json:"events"`
+	Output     string          `json:"output"`
+	SessionID  string          `json:"session_id,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	Duration   Duration        `json:"duration"`
+	Backend    string          `json:"backend,omitempty"`
+	RecordedAt time.Time       `json:"recorded_at"`
+}
+
+// RecordedEvent is an output event with its offset from the start of the call
+type RecordedEvent struct {
+	Offset Duration `json:"offset"`
+	Event  Event    `json:"event"`
+}
+
+// Duration is a time.Duration stored as milliseconds
+type Duration time.Duration
+
+// MarshalJSON encodes the duration in milliseconds
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).Milliseconds())
+}
+
+// UnmarshalJSON decodes a duration in milliseconds
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var ms int64
+	if err := json.Unmarshal(data, &ms); err != nil {
+		return err
+	}
+	*d = Duration(time.Duration(ms) * time.Millisecond)
+	return nil
+}
+
+// PromptHash returns the hash recordings are matched by
+func PromptHash(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+// recordMu serializes sequence allocation across recorders sharing a directory
+var recordMu sync.Mutex
+
+// Recorder wraps a Runner and writes every call to a directory
+type Recorder struct {
+	inner   Runner
+	dir     string
+	backend string
+	cb      OutputCallback
+}
+
+// NewRecorder wraps inner so each Run call is saved in dir as call-NNNN.json
+func NewRecorder(inner Runner, dir, backend string) *Recorder {
+	return &Recorder{inner: inner, dir: dir, backend: backend}
+}
+
+// Run executes the prompt on the wrapped runner and records the call
+// A call cut short by ctx is not recorded, since replaying it would replay the interruption
+func (r *Recorder) Run(ctx context.Context, prompt string) (string, string, error) {
+	rec := &Recording{
+		Loop:       LoopFrom(ctx),
+		PromptHash: PromptHash(prompt),
+		Prompt:     prompt,
+		Backend:    r.backend,
+		RecordedAt: time.Now(),
+	}
+
+	start := time.Now()
+	r.inner.SetOutputCallback(func(event Event) {
+		rec.Events = append(rec.Events, RecordedEvent{Offset: Duration(time.Since(start)), Event: event})
+		if r.cb != nil {
+			r.cb(event)
+		}
+	})
+
+	output, sessionID, runErr := r.inner.Run(ctx, prompt)
+	if ctx.Err() != nil {
+		return output, sessionID, runErr
+	}
+	rec.Duration = Duration(time.Since(start))
+	rec.Output = output
+	rec.SessionID = sessionID
+	if runErr != nil {
+		rec.Error = runErr.Error()
+	}
+
+	if err := r.save(rec); err != nil && runErr == nil {
+		return output, sessionID, fmt.Errorf("failed to save recording: %w", err)
+	}
+	return output, sessionID, runErr
+}
+
+// SetOutputCallback sets the callback events are forwarded to
+func (r *Recorder) SetOutputCallback(cb OutputCallback) {
+	r.cb = cb
+}
+
+// Stop stops the wrapped runner
+func (r *Recorder) Stop() error {
+	return r.inner.Stop()
+}
+
+// NewSession starts the wrapped runner on a new session, if it keeps one
+func (r *Recorder) NewSession() error {
+	_, err := NewSession(r.inner)
+	return err
+}
+
+// save assigns the next sequence number and writes the recording
+func (r *Recorder) save(rec *Recording) error {
+	recordMu.Lock()
+	defer recordMu.Unlock()
+
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return err
+	}
+	rec.Seq = 1
+	for _, entry := range entries {
+		var seq int
+		if _, err := fmt.Sscanf(entry.Name(), "call-%d.json", &seq); err == nil && seq >= rec.Seq {
+			rec.Seq = seq + 1
+		}
+	}
+
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(r.dir, recordingName(rec.Seq)), data, 0644)
+}
+
+// LoadRecordings reads all recordings in dir, ordered by sequence number
+func LoadRecordings(dir string) ([]*Recording, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var recs []*Recording
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, "call-") || filepath.Ext(name) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		var rec Recording
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return nil, fmt.Errorf("invalid recording %s: %w", name, err)
+		}
+		recs = append(recs, &rec)
+	}
+
+	sort.Slice(recs, func(i, j int) bool { return recs[i].Seq < recs[j].Seq })
+	return recs, nil
+}
+
+func recordingName(seq int) string {
+	return fmt.Sprintf("call-%04d.json", seq)
+}
@@ -0,0 +1,169 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/brainwhocodes/lisa-loop/internal/config"
+)
+
+// scriptedRunner emits a fixed event stream and echoes the prompt
+type scriptedRunner struct {
+	cb    OutputCallback
+	delay time.Duration
+	err   error
+}
+
+func (r *scriptedRunner) Run(ctx context.Context, prompt string) (string, string, error) {
+	r.cb(Event{"type": "message", "content": "working on " + prompt})
+	time.Sleep(r.delay)
+	r.cb(Event{"type": "tool_use", "name": "edit", "target": "main.go"})
+	return "done: " + prompt, "sess-" + prompt, r.err
+}
+
+func (r *scriptedRunner) SetOutputCallback(cb OutputCallback) { r.cb = cb }
+
+func (r *scriptedRunner) Stop() error { return nil }
+
+// collect replays a prompt and returns the events, output, session and error
+func collect(t *testing.T, r Runner, prompt string) ([]Event, string, string, error) {
+	t.Helper()
+	var events []Event
+	r.SetOutputCallback(func(event Event) { events = append(events, event) })
+	output, sessionID, err := r.Run(context.Background(), prompt)
+	return events, output, sessionID, err
+}
+
+func TestRecordReplay_Loop(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "rec")
+	recorder := NewRecorder(&scriptedRunner{}, dir, "cli")
+
+	wantEvents, _, _, _ := collect(t, recorder, "one")
+	if _, _, _, err := collect(t, recorder, "two"); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	recs, err := LoadRecordings(dir)
+	if err != nil {
+		t.Fatalf("LoadRecordings() error = %v", err)
+	}
+	if len(recs) != 2 || recs[0].Seq != 1 || recs[1].Seq != 2 || recs[1].Prompt != "two" {
+		t.Fatalf("recordings = %+v", recs)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "call-0001.json")); err != nil {
+		t.Errorf("call-0001.json missing: %v", err)
+	}
+
+	replayer := NewReplayer(dir, ReplayMatchLoop, false)
+	events, output, sessionID, err := collect(t, replayer, "anything")
+	if err != nil {
+		t.Fatalf("replay Run() error = %v", err)
+	}
+	if output != "done: one" || sessionID != "sess-one" {
+		t.Errorf("replay = %q %q, want first recording", output, sessionID)
+	}
+	if !reflect.DeepEqual(events, wantEvents) {
+		t.Errorf("replayed events = %v, want %v", events, wantEvents)
+	}
+
+	if _, output, _, _ = collect(t, replayer, "anything"); output != "done: two" {
+		t.Errorf("second replay output = %q, want done: two", output)
+	}
+	if _, _, _, err := collect(t, replayer, "anything"); err == nil {
+		t.Error("third replay expected error: only two recordings")
+	}
+}
+
+func TestRecordReplay_LoopNumbers(t *testing.T) {
+	dir := t.TempDir()
+	recorder := NewRecorder(&scriptedRunner{}, dir, "cli")
+
+	// A resumed run starting at loop 3, whose loop 3 is retried after an interruption
+	record := func(loop int, prompt string) {
+		if _, _, err := recorder.Run(WithLoop(context.Background(), loop), prompt); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	}
+	record(3, "three")
+	record(3, "three again")
+	record(4, "four")
+
+	replayer := NewReplayer(dir, ReplayMatchLoop, false)
+	replay := func(loop int) string {
+		output, _, err := replayer.Run(WithLoop(context.Background(), loop), "anything")
+		if err != nil {
+			t.Fatalf("replay of loop %d error = %v", loop, err)
+		}
+		return output
+	}
+	if got := replay(3); got != "done: three" {
+		t.Errorf("loop 3 replay = %q, want the first loop 3 call", got)
+	}
+	if got := replay(4); got != "done: four" {
+		t.Errorf("loop 4 replay = %q, want the loop 4 call", got)
+	}
+	if got := replay(3); got != "done: three again" {
+		t.Errorf("second loop 3 replay = %q, want the retry", got)
+	}
+	if _, _, err := replayer.Run(WithLoop(context.Background(), 1), "anything"); err == nil {
+		t.Error("replaying a loop that was never recorded expected error")
+	}
+}
+
+func TestRecordReplay_Hash(t *testing.T) {
+	dir := t.TempDir()
+	recorder := NewRecorder(&scriptedRunner{}, dir, "cli")
+	collect(t, recorder, "alpha")
+	collect(t, recorder, "beta")
+
+	replayer := NewReplayer(dir, ReplayMatchHash, false)
+	if _, output, _, _ := collect(t, replayer, "beta"); output != "done: beta" {
+		t.Errorf("replay(beta) output = %q", output)
+	}
+	if _, output, _, _ := collect(t, replayer, "alpha"); output != "done: alpha" {
+		t.Errorf("replay(alpha) output = %q", output)
+	}
+	if _, _, _, err := collect(t, replayer, "beta"); err == nil {
+		t.Error("replaying a used recording expected error")
+	}
+}
+
+func TestRecordReplay_ErrorAndTiming(t *testing.T) {
+	dir := t.TempDir()
+	recorder := NewRecorder(&scriptedRunner{delay: 50 * time.Millisecond, err: errors.New("agent crashed")}, dir, "cli")
+	if _, _, _, err := collect(t, recorder, "x"); err == nil || err.Error() != "agent crashed" {
+		t.Fatalf("recorded Run() error = %v", err)
+	}
+
+	start := time.Now()
+	_, _, _, err := collect(t, NewReplayer(dir, ReplayMatchLoop, true), "x")
+	if err == nil || err.Error() != "agent crashed" {
+		t.Errorf("replayed error = %v, want agent crashed", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("timed replay took %v, want at least 50ms", elapsed)
+	}
+
+	start = time.Now()
+	collect(t, NewReplayer(dir, ReplayMatchLoop, false), "x")
+	if elapsed := time.Since(start); elapsed >= 50*time.Millisecond {
+		t.Errorf("untimed replay took %v", elapsed)
+	}
+}
+
+func TestNew_Record(t *testing.T) {
+	r := New(config.Config{Backend: "cli", RecordDir: t.TempDir()})
+	if _, ok := r.(*Recorder); !ok {
+		t.Errorf("New() with RecordDir = %T, want *Recorder", r)
+	}
+
+	r = New(config.Config{Backend: "replay", ReplayDir: t.TempDir(), RecordDir: t.TempDir()})
+	if _, ok := r.(*Replayer); !ok {
+		t.Errorf("New() with replay backend = %T, want *Replayer", r)
+	}
+}
@@ -0,0 +1,140 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Replay matching modes
+const (
+	ReplayMatchLoop = "loop" // A call in loop N gets the next unused recording made in loop N
+	ReplayMatchHash = "hash" // A call gets the first unused recording with the same prompt
+)
+
+// Replayer serves recorded calls back instead of running an agent
+type Replayer struct {
+	dir    string
+	match  string
+	timing bool
+	cb     OutputCallback
+
+	mu    sync.Mutex
+	recs  []*Recording
+	used  map[int]bool
+	calls int
+}
+
+// NewReplayer creates a runner that replays the recordings in dir
+// With timing set, events are delivered at their recorded offsets
+func NewReplayer(dir, match string, timing bool) *Replayer {
+	if match == "" {
+		match = ReplayMatchLoop
+	}
+	return &Replayer{dir: dir, match: match, timing: timing, used: make(map[int]bool)}
+}
+
+// Run replays the recording matching this call
+func (r *Replayer) Run(ctx context.Context, prompt string) (string, string, error) {
+	rec, err := r.next(ctx, prompt)
+	if err != nil {
+		return "", "", err
+	}
+
+	start := time.Now()
+	for _, recorded := range rec.Events {
+		if r.timing && !sleepUntil(ctx, start, time.Duration(recorded.Offset)) {
+			return "", rec.SessionID, fmt.Errorf("replay cancelled: %w", ctx.Err())
+		}
+		if r.cb != nil {
+			r.cb(recorded.Event)
+		}
+	}
+	if r.timing && !sleepUntil(ctx, start, time.Duration(rec.Duration)) {
+		return "", rec.SessionID, fmt.Errorf("replay cancelled: %w", ctx.Err())
+	}
+
+	if rec.Error != "" {
+		return rec.Output, rec.SessionID, errors.New(rec.Error)
+	}
+	return rec.Output, rec.SessionID, nil
+}
+
+// SetOutputCallback sets the callback for replayed events
+func (r *Replayer) SetOutputCallback(cb OutputCallback) {
+	r.cb = cb
+}
+
+// Stop is a no-op
+func (r *Replayer) Stop() error {
+	return nil
+}
+
+// next picks the recording for a call, loading the directory on first use
+func (r *Replayer) next(ctx context.Context, prompt string) (*Recording, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.recs == nil {
+		recs, err := LoadRecordings(r.dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load recordings: %w", err)
+		}
+		if len(recs) == 0 {
+			return nil, fmt.Errorf("no recordings in %s", r.dir)
+		}
+		r.recs = recs
+	}
+	r.calls++
+
+	switch r.match {
+	case ReplayMatchLoop:
+		// Without loop numbers, on either side, the Nth call stands for loop N
+		loop := LoopFrom(ctx)
+		if loop == 0 {
+			loop = r.calls
+		}
+		for _, rec := range r.recs {
+			recLoop := rec.Loop
+			if recLoop == 0 {
+				recLoop = rec.Seq
+			}
+			if recLoop == loop && !r.used[rec.Seq] {
+				r.used[rec.Seq] = true
+				return rec, nil
+			}
+		}
+		return nil, fmt.Errorf("no unused recording for loop %d in %s", loop, r.dir)
+
+	case ReplayMatchHash:
+		hash := PromptHash(prompt)
+		for _, rec := range r.recs {
+			if rec.PromptHash == hash && !r.used[rec.Seq] {
+				r.used[rec.Seq] = true
+				return rec, nil
+			}
+		}
+		return nil, fmt.Errorf("no unused recording for prompt %s in %s", hash[:12], r.dir)
+
+	default:
+		return nil, fmt.Errorf("unknown replay match mode %q (want loop or hash)", r.match)
+	}
+}
+
+// sleepUntil waits until offset after start; false if ctx ended first
+func sleepUntil(ctx context.Context, start time.Time, offset time.Duration) bool {
+	wait := offset - time.Since(start)
+	if wait <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -1,8 +1,12 @@
 package runner
 
 import (
+	"context"
+
 	"github.com/brainwhocodes/lisa-loop/internal/codex"
+	"github.com/brainwhocodes/lisa-loop/internal/command"
 	"github.com/brainwhocodes/lisa-loop/internal/config"
+	"github.com/brainwhocodes/lisa-loop/internal/openai"
 	"github.com/brainwhocodes/lisa-loop/internal/opencode"
 )
 
@@ -15,7 +19,8 @@ type OutputCallback func(event Event)
 // Runner is the interface for executing prompts
 type Runner interface {
 	// Run executes a prompt and returns the output, session ID, and any error
-	Run(prompt string) (output string, sessionID string, err error)
+	// Ending ctx stops the call; the error then wraps ctx.Err()
+	Run(ctx context.Context, prompt string) (output string, sessionID string, err error)
 
 	// SetOutputCallback sets the callback for streaming output events
 	SetOutputCallback(cb OutputCallback)
@@ -24,11 +29,56 @@ type Runner interface {
 	Stop() error
 }
 
+// SessionRotator is implemented by runners that resume a session from one call to the next
+type SessionRotator interface {
+	// NewSession forgets the current session so the next call starts a new one
+	NewSession() error
+}
+
+// NewSession starts r on a new session; false if r keeps no session
+func NewSession(r Runner) (bool, error) {
+	rotator, ok := r.(SessionRotator)
+	if !ok {
+		return false, nil
+	}
+	return true, rotator.NewSession()
+}
+
+// loopKey is the context key for the loop iteration a call belongs to
+type loopKey struct{}
+
+// WithLoop returns a copy of ctx recording that calls made with it belong to loop
+func WithLoop(ctx context.Context, loop int) context.Context {
+	return context.WithValue(ctx, loopKey{}, loop)
+}
+
+// LoopFrom returns the loop iteration recorded in ctx by WithLoop (0 if none)
+func LoopFrom(ctx context.Context) int {
+	loop, _ := ctx.Value(loopKey{}).(int)
+	return loop
+}
+
 // New creates a new runner based on the config backend setting
+// With RecordDir set, every call is also recorded there
 func New(cfg config.Config) Runner {
+	r := newBackend(cfg)
+	if cfg.RecordDir != "" && cfg.Backend != "replay" {
+		return NewRecorder(r, cfg.RecordDir, cfg.Backend)
+	}
+	return r
+}
+
+// newBackend creates the runner for the configured backend
+func newBackend(cfg config.Config) Runner {
 	switch cfg.Backend {
+	case "replay":
+		return NewReplayer(cfg.ReplayDir, cfg.ReplayMatch, cfg.ReplayTiming)
 	case "opencode":
 		return &openCodeWrapper{runner: opencode.NewRunner(cfg)}
+	case "openai":
+		return &openAIWrapper{runner: openai.NewRunner(cfg)}
+	case "command":
+		return &commandWrapper{runner: command.NewRunner(cfg)}
 	default:
 		// Default to codex CLI backend
 		return &codexWrapper{runner: codex.NewRunner(codex.Config(cfg))}
@@ -40,8 +90,8 @@ type codexWrapper struct {
 	runner *codex.Runner
 }
 
-func (w *codexWrapper) Run(prompt string) (string, string, error) {
-	return w.runner.Run(prompt)
+func (w *codexWrapper) Run(ctx context.Context, prompt string) (string, string, error) {
+	return w.runner.Run(ctx, prompt)
 }
 
 func (w *codexWrapper) SetOutputCallback(cb OutputCallback) {
@@ -54,13 +104,17 @@ func (w *codexWrapper) Stop() error {
 	return nil // Codex CLI doesn't need cleanup
 }
 
+func (w *codexWrapper) NewSession() error {
+	return w.runner.NewSession()
+}
+
 // openCodeWrapper wraps opencode.Runner to implement the Runner interface
 type openCodeWrapper struct {
 	runner *opencode.Runner
 }
 
-func (w *openCodeWrapper) Run(prompt string) (string, string, error) {
-	return w.runner.Run(prompt)
+func (w *openCodeWrapper) Run(ctx context.Context, prompt string) (string, string, error) {
+	return w.runner.Run(ctx, prompt)
 }
 
 func (w *openCodeWrapper) SetOutputCallback(cb OutputCallback) {
@@ -72,3 +126,49 @@ func (w *openCodeWrapper) SetOutputCallback(cb OutputCallback) {
 func (w *openCodeWrapper) Stop() error {
 	return w.runner.Stop()
 }
+
+func (w *openCodeWrapper) NewSession() error {
+	return w.runner.NewSession()
+}
+
+// commandWrapper wraps command.Runner to implement the Runner interface
+type commandWrapper struct {
+	runner *command.Runner
+}
+
+func (w *commandWrapper) Run(ctx context.Context, prompt string) (string, string, error) {
+	return w.runner.Run(ctx, prompt)
+}
+
+func (w *commandWrapper) SetOutputCallback(cb OutputCallback) {
+	w.runner.SetOutputCallback(func(event map[string]interface{}) {
+		cb(Event(event))
+	})
+}
+
+func (w *commandWrapper) Stop() error {
+	return w.runner.Stop()
+}
+
+func (w *commandWrapper) NewSession() error {
+	return w.runner.NewSession()
+}
+
+// openAIWrapper wraps openai.Runner to implement the Runner interface
+type openAIWrapper struct {
+	runner *openai.Runner
+}
+
+func (w *openAIWrapper) Run(ctx context.Context, prompt string) (string, string, error) {
+	return w.runner.Run(ctx, prompt)
+}
+
+func (w *openAIWrapper) SetOutputCallback(cb OutputCallback) {
+	w.runner.SetOutputCallback(func(event map[string]interface{}) {
+		cb(Event(event))
+	})
+}
+
+func (w *openAIWrapper) Stop() error {
+	return w.runner.Stop()
+}
@@ -89,3 +89,31 @@ func TestRunner_SetOutputCallback_OpenCode(t *testing.T) {
 		t.Error("expected runner to be created")
 	}
 }
+
+func TestNew_CommandBackend(t *testing.T) {
+	cfg := config.Config{
+		Backend:         "command",
+		CommandTemplate: "agent --print",
+	}
+
+	r := New(cfg)
+
+	_, ok := r.(*commandWrapper)
+	if !ok {
+		t.Error("expected commandWrapper for command backend")
+	}
+}
+
+func TestNew_OpenAIBackend(t *testing.T) {
+	cfg := config.Config{
+		Backend:       "openai",
+		OpenAIBaseURL: "http://localhost:8080/v1",
+	}
+
+	r := New(cfg)
+
+	_, ok := r.(*openAIWrapper)
+	if !ok {
+		t.Error("expected openAIWrapper for openai backend")
+	}
+}
@@ -0,0 +1,103 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CallLogSchema is the user-level record of backend calls shared by every Lisa process
+var CallLogSchema = Schema{Name: "calls.json", Version: 1}
+
+// Lock timing for the call log: how long to wait for another process, and how old a
+// lock must be before it is treated as left behind by a crashed process
+const (
+	callLogLockWait  = 5 * time.Second
+	callLogLockStale = 30 * time.Second
+)
+
+// CallLog lists recent backend calls of every Lisa process on the machine
+type CallLog struct {
+	Calls []time.Time `json:"calls"` // Oldest first
+}
+
+// UserCallLogPath returns the call log shared by the user's Lisa processes
+func UserCallLogPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find user config directory: %w", err)
+	}
+	return filepath.Join(dir, "lisa", CallLogSchema.Name), nil
+}
+
+// LoadCallLog reads the call log at path; a missing log is empty
+func LoadCallLog(path string) (CallLog, error) {
+	doc, err := LoadState(path, Document{})
+	if err != nil || doc.Version == 0 {
+		return CallLog{}, err
+	}
+	data, err := upgrade(CallLogSchema, doc)
+	if err != nil {
+		return CallLog{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var log CallLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		return CallLog{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return log, nil
+}
+
+// UpdateCallLog applies update to the call log at path while holding its lock, so
+// concurrent processes do not lose each other's calls
+func UpdateCallLog(path string, update func(log *CallLog)) (CallLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return CallLog{}, fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	unlock, err := lockFileAt(path + ".lock")
+	if err != nil {
+		return CallLog{}, err
+	}
+	defer unlock()
+
+	log, err := LoadCallLog(path)
+	if err != nil {
+		return CallLog{}, err
+	}
+	update(&log)
+
+	data, err := json.Marshal(log)
+	if err != nil {
+		return CallLog{}, err
+	}
+	err = SaveState(path, Document{Version: CallLogSchema.Version, UpdatedAt: time.Now(), Data: data})
+	return log, err
+}
+
+// lockFileAt creates path exclusively, waiting for another holder to remove it
+// A lock older than callLogLockStale is removed as left behind by a crashed process
+func lockFileAt(path string) (unlock func(), err error) {
+	deadline := time.Now().Add(callLogLockWait)
+	for {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+
+		if info, statErr := os.Stat(path); statErr == nil && time.Since(info.ModTime()) > callLogLockStale {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for lock %s", path)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,48 @@
+package state
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUpdateCallLog_Concurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lisa", CallLogSchema.Name)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := UpdateCallLog(path, func(log *CallLog) {
+				log.Calls = append(log.Calls, time.Now())
+			})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("UpdateCallLog() error = %v", err)
+		}
+	}
+
+	// No update is lost to another writer
+	log, err := LoadCallLog(path)
+	if err != nil || len(log.Calls) != 20 {
+		t.Errorf("LoadCallLog() = %d calls, %v, want 20", len(log.Calls), err)
+	}
+	if matches, _ := filepath.Glob(path + ".lock"); len(matches) != 0 {
+		t.Errorf("lock left behind: %v", matches)
+	}
+}
+
+func TestLoadCallLog_Missing(t *testing.T) {
+	log, err := LoadCallLog(filepath.Join(t.TempDir(), CallLogSchema.Name))
+	if err != nil || len(log.Calls) != 0 {
+		t.Errorf("LoadCallLog() = %+v, %v, want an empty log", log, err)
+	}
+}
@@ -44,10 +44,24 @@ func ReadStateFile(path string) ([]byte, error) {
 }
 
 // WriteStateFile writes data to a file atomically (write to temp, then rename)
+// The temp file gets a unique name so concurrent writers never share one
 func WriteStateFile(path string, data []byte) error {
-	// Write to temporary file
-	tmpPath := path + ".tmp"
-	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+	// Write to temporary file next to the target so the rename stays on one filesystem
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file for %s: %w", path, err)
+	}
+	tmpPath := tmp.Name()
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, 0644)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
 		return fmt.Errorf("failed to write temp file %s: %w", tmpPath, err)
 	}
 
@@ -68,97 +82,268 @@ func AtomicWrite(path string, data []byte) error {
 	return WriteStateFile(path, data)
 }
 
-// LoadCallCount loads the call count from .call_count
-func LoadCallCount() (int, error) {
-	return LoadState(".call_count", 0)
+// RateLimit is the fixed rate limiter window kept per project before calls were logged
+// per user (see CallLog); legacy counters are still migrated into it and it is cleaned
+// with the other state
+type RateLimit struct {
+	Calls     int       `json:"calls"`
+	LastReset time.Time `json:"last_reset"`
 }
 
-// SaveCallCount saves the call count to .call_count atomically
-func SaveCallCount(count int) error {
-	return SaveState(".call_count", count)
+// BackendSession is a backend's resumable session
+type BackendSession struct {
+	ID        string    `json:"id"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
-// LoadLastReset loads last reset time from .last_reset
-func LoadLastReset() (time.Time, error) {
-	return LoadState(".last_reset", time.Now())
-}
+// Sessions holds the saved session of each backend, keyed by backend name
+type Sessions map[string]BackendSession
 
-// SaveLastReset saves last reset time to .last_reset atomically
-func SaveLastReset(t time.Time) error {
-	return SaveState(".last_reset", t)
+// LoadSessionIn loads the saved session of backend from the state directory in dir
+// ("" for the current directory); a missing session has an empty ID
+func LoadSessionIn(dir, backend string) (BackendSession, error) {
+	sessions, err := LoadDocument(dir, SessionsSchema, Sessions{})
+	if err != nil {
+		return BackendSession{}, err
+	}
+	return sessions[backend], nil
 }
 
-// LoadCodexSession loads Codex session ID from .codex_session_id
-func LoadCodexSession() (string, error) {
-	data, err := os.ReadFile(".codex_session_id")
+// SaveSessionIn saves the session ID of backend; an empty ID clears it
+func SaveSessionIn(dir, backend, id string) error {
+	sessions, err := LoadDocument(dir, SessionsSchema, Sessions{})
 	if err != nil {
-		if os.IsNotExist(err) {
-			return "", nil
-		}
-		return "", err
+		return err
+	}
+	if sessions == nil {
+		sessions = Sessions{}
+	}
+	if id == "" {
+		delete(sessions, backend)
+	} else {
+		sessions[backend] = BackendSession{ID: id, UpdatedAt: time.Now()}
+	}
+	return SaveDocument(dir, SessionsSchema, sessions)
+}
+
+// LoadSessions loads the saved session of every backend
+func LoadSessions() (Sessions, error) {
+	sessions, err := LoadDocument("", SessionsSchema, Sessions{})
+	if sessions == nil {
+		sessions = Sessions{}
 	}
-	return string(data), nil
+	return sessions, err
 }
 
-// SaveCodexSession saves Codex session ID to .codex_session_id atomically
+// SaveSessions replaces the saved sessions of every backend
+func SaveSessions(sessions Sessions) error {
+	return SaveDocument("", SessionsSchema, sessions)
+}
+
+// LoadCodexSession loads the Codex session ID
+func LoadCodexSession() (string, error) {
+	return LoadCodexSessionIn("")
+}
+
+// LoadCodexSessionIn loads the Codex session ID kept in dir ("" for the current directory)
+func LoadCodexSessionIn(dir string) (string, error) {
+	sess, err := LoadSessionIn(dir, "codex")
+	return sess.ID, err
+}
+
+// SaveCodexSession saves the Codex session ID
 func SaveCodexSession(id string) error {
-	return AtomicWrite(".codex_session_id", []byte(id))
+	return SaveCodexSessionIn("", id)
+}
+
+// SaveCodexSessionIn saves the Codex session ID in dir ("" for the current directory)
+func SaveCodexSessionIn(dir, id string) error {
+	return SaveSessionIn(dir, "codex", id)
 }
 
-// LoadLisaSession loads Lisa session metadata from .ralph_session
-func LoadLisaSession() (map[string]interface{}, error) {
-	return LoadState(".ralph_session", map[string]interface{}{})
+// LisaSession is Lisa's own session metadata
+type LisaSession struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	LastUsed  time.Time `json:"last_used"`
 }
 
-// SaveLisaSession saves Lisa session metadata atomically
-func SaveLisaSession(session map[string]interface{}) error {
-	return SaveState(".ralph_session", session)
+// LoadLisaSession loads Lisa session metadata
+func LoadLisaSession() (LisaSession, error) {
+	return LoadDocument("", LisaSessionSchema, LisaSession{})
 }
 
-// LoadExitSignals loads recent exit signals from .exit_signals
+// SaveLisaSession saves Lisa session metadata
+func SaveLisaSession(session LisaSession) error {
+	return SaveDocument("", LisaSessionSchema, session)
+}
+
+// LoadExitSignals loads recent exit signals
 func LoadExitSignals() ([]string, error) {
-	return LoadState(".exit_signals", []string{})
+	return LoadDocument("", ExitSignalsSchema, []string{})
 }
 
-// SaveExitSignals saves exit signals atomically
+// SaveExitSignals saves exit signals
 func SaveExitSignals(signals []string) error {
-	return SaveState(".exit_signals", signals)
+	return SaveDocument("", ExitSignalsSchema, signals)
+}
+
+// TaskAttempts tracks how often focus mode has tried a single task
+type TaskAttempts struct {
+	Attempts   int       `json:"attempts"`
+	LastReason string    `json:"last_reason,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
 }
 
-// LoadCircuitBreakerState loads circuit breaker state from .circuit_breaker_state
-func LoadCircuitBreakerState() (map[string]interface{}, error) {
-	defaultState := map[string]interface{}{
-		"state":           "CLOSED",
-		"last_check_time": time.Now().Format(time.RFC3339),
+// LoadTaskAttempts loads per-task attempt counts, keyed by task ID
+func LoadTaskAttempts() (map[string]TaskAttempts, error) {
+	attempts, err := LoadDocument("", TaskAttemptsSchema, map[string]TaskAttempts{})
+	if attempts == nil {
+		attempts = map[string]TaskAttempts{}
 	}
-	return LoadState(".circuit_breaker_state", defaultState)
+	return attempts, err
 }
 
-// SaveCircuitBreakerState saves circuit breaker state atomically
-func SaveCircuitBreakerState(state map[string]interface{}) error {
-	return SaveState(".circuit_breaker_state", state)
+// SaveTaskAttempts saves per-task attempt counts
+func SaveTaskAttempts(attempts map[string]TaskAttempts) error {
+	return SaveDocument("", TaskAttemptsSchema, attempts)
 }
 
-// EnsureStateDir ensures the directory for state files exists
-func EnsureStateDir() error {
-	if err := os.MkdirAll(".", 0755); err != nil {
-		return fmt.Errorf("failed to create state directory: %w", err)
+// CircuitState is the persisted circuit breaker state
+type CircuitState struct {
+	State           string       `json:"state"` // CLOSED, HALF_OPEN or OPEN
+	NoProgressCount int          `json:"no_progress_count"`
+	ErrorHistory    []string     `json:"error_history"`
+	ErrorGroups     []ErrorGroup `json:"error_groups,omitempty"` // Errors grouped by fingerprint
+	LastCheckTime   time.Time    `json:"last_check_time"`
+	OpenedAt        time.Time    `json:"opened_at,omitempty"`   // When the circuit last opened
+	Reopens         int          `json:"reopens,omitempty"`     // Failed recoveries since it last closed
+	ProbesLeft      int          `json:"probes_left,omitempty"` // Probe iterations left in HALF_OPEN
+
+	// Simhash fingerprints (hex) of recent iterations, for stagnation detection
+	OutputFingerprints []string `json:"output_fingerprints,omitempty"`
+	DiffFingerprints   []string `json:"diff_fingerprints,omitempty"`
+}
+
+// ErrorGroup counts the occurrences of one normalized error
+type ErrorGroup struct {
+	Fingerprint string    `json:"fingerprint"`
+	Sample      string    `json:"sample"` // Most recent message with this fingerprint
+	Count       int       `json:"count"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// LoadCircuitBreakerState loads circuit breaker state; a missing state is CLOSED
+func LoadCircuitBreakerState() (CircuitState, error) {
+	return LoadDocument("", CircuitSchema, CircuitState{State: "CLOSED", LastCheckTime: time.Now()})
+}
+
+// SaveCircuitBreakerState saves circuit breaker state
+func SaveCircuitBreakerState(cs CircuitState) error {
+	return SaveDocument("", CircuitSchema, cs)
+}
+
+// Run record statuses
+const (
+	RunRunning     = "running"     // In progress, or the process died without recording an exit
+	RunInterrupted = "interrupted" // Stopped by a signal or cancellation
+	RunFinished    = "finished"    // Ended on its own (complete, stopped or skipped)
+)
+
+// RunRecord is the persisted position of the current or most recent run
+// It is rewritten after every iteration so "lisa run --resume" can continue the run
+type RunRecord struct {
+	ID          string       `json:"id"`
+	Status      string       `json:"status"`
+	StartedAt   time.Time    `json:"started_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+	Loop        int          `json:"loop"` // Iterations finished
+	CurrentTask string       `json:"current_task,omitempty"`
+	Sessions    Sessions     `json:"sessions,omitempty"`
+	Circuit     CircuitState `json:"circuit"`
+	Usage       Usage        `json:"usage"`                // Tokens and cost spent by the run so far
+	RateCalls   []time.Time  `json:"rate_calls,omitempty"` // The run's calls within its rate limit window
+}
+
+// Resumable reports whether the run stopped before it finished
+func (r RunRecord) Resumable() bool {
+	return r.ID != "" && r.Status != RunFinished
+}
+
+// LoadRunRecord loads the run record; a missing record has an empty ID
+func LoadRunRecord() (RunRecord, error) {
+	return LoadDocument("", RunSchema, RunRecord{})
+}
+
+// SaveRunRecord saves the run record
+func SaveRunRecord(rec RunRecord) error {
+	return SaveDocument("", RunSchema, rec)
+}
+
+// Usage counts the tokens and cost reported by a backend
+type Usage struct {
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// Tokens returns the prompt and completion tokens together
+func (u Usage) Tokens() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// Add returns the sum of two usages
+func (u Usage) Add(o Usage) Usage {
+	return Usage{
+		PromptTokens:     u.PromptTokens + o.PromptTokens,
+		CompletionTokens: u.CompletionTokens + o.CompletionTokens,
+		Cost:             u.Cost + o.Cost,
 	}
-	return nil
 }
 
-// CleanupOldFiles removes old temporary state files
+// DailyUsage is the usage of every run in the project on one day
+type DailyUsage struct {
+	Date string `json:"date"` // Local date, YYYY-MM-DD
+	Usage
+}
+
+// LoadDailyUsage loads the day's usage record; a missing record has an empty date
+func LoadDailyUsage() (DailyUsage, error) {
+	return LoadDocument("", BudgetSchema, DailyUsage{})
+}
+
+// SaveDailyUsage saves the day's usage record
+func SaveDailyUsage(du DailyUsage) error {
+	return SaveDocument("", BudgetSchema, du)
+}
+
+// EnsureStateDir creates the state directory, importing legacy state files on first use
+func EnsureStateDir() error {
+	return ensureDir("")
+}
+
+// CleanupOldFiles removes old temporary state files from the project and state directories
 func CleanupOldFiles() error {
 	entries, err := os.ReadDir(".")
 	if err != nil {
 		return err
 	}
+	stateEntries, _ := os.ReadDir(Dir)
 
+	var paths []string
 	for _, entry := range entries {
-		if filepath.Ext(entry.Name()) == ".tmp" {
-			if err := os.Remove(filepath.Join(".", entry.Name())); err != nil {
+		paths = append(paths, entry.Name())
+	}
+	for _, entry := range stateEntries {
+		paths = append(paths, filepath.Join(Dir, entry.Name()))
+	}
+
+	for _, path := range paths {
+		if filepath.Ext(path) == ".tmp" {
+			if err := os.Remove(path); err != nil {
 				// Log but continue - best effort cleanup
-				fmt.Printf("Warning: failed to remove temp file %s: %v\n", entry.Name(), err)
+				fmt.Printf("Warning: failed to remove temp file %s: %v\n", path, err)
 			}
 		}
 	}
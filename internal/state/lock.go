@@ -0,0 +1,194 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// lockFile is the advisory lock held by the Lisa process driving a project
+const lockFile = "lock"
+
+// LockInfo identifies the process holding a project lock
+type LockInfo struct {
+	PID       int       `json:"pid"`
+	Host      string    `json:"host"`
+	StartedAt time.Time `json:"started_at"`
+	Command   string    `json:"command"`
+}
+
+// Lock is a held project lock
+type Lock struct {
+	path string
+	info LockInfo
+}
+
+// LockedError reports that another process holds the project lock
+type LockedError struct {
+	Path  string
+	Owner LockInfo
+	Stale bool // The owner is on this host and no longer running
+}
+
+func (e *LockedError) Error() string {
+	owner := fmt.Sprintf("PID %d on %s, started %s", e.Owner.PID, e.Owner.Host, e.Owner.StartedAt.Format(time.RFC3339))
+	if e.Owner.Command != "" {
+		owner += ", running " + e.Owner.Command
+	}
+	if e.Stale {
+		return fmt.Sprintf("stale project lock %s (%s): that process is gone; rerun with --force to take it over", e.Path, owner)
+	}
+	if host, _ := os.Hostname(); e.Owner.Host != host {
+		return fmt.Sprintf("project is locked by another host (%s); if that run has ended, rerun with --force to take over %s", owner, e.Path)
+	}
+	return fmt.Sprintf("another Lisa run is active in this project (%s)", owner)
+}
+
+// AcquireLock takes the advisory lock on the state directory of project dir
+// With force, a lock left by a process that is no longer running (or held from
+// another host, where liveness cannot be checked, or unreadable) is taken over; a
+// lock held by a live process on this host is never taken
+func AcquireLock(dir, command string, force bool) (*Lock, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Join(abs, Dir), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	host, _ := os.Hostname()
+	lock := &Lock{
+		path: Path(abs, lockFile),
+		info: LockInfo{PID: os.Getpid(), Host: host, StartedAt: time.Now(), Command: command},
+	}
+	data, err := json.Marshal(lock.info)
+	if err != nil {
+		return nil, err
+	}
+
+	// Second attempt follows a forced takeover
+	for attempt := 0; attempt < 2; attempt++ {
+		err := createLock(lock.path, data)
+		if err == nil {
+			return lock, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to create lock %s: %w", lock.path, err)
+		}
+
+		held, err := os.ReadFile(lock.path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue // Released between our create and read
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read lock %s: %w", lock.path, err)
+		}
+		var owner LockInfo
+		if err := json.Unmarshal(held, &owner); err != nil && !force {
+			return nil, fmt.Errorf("failed to parse lock %s: %w; rerun with --force to replace it", lock.path, err)
+		}
+		stale := owner.PID > 0 && owner.Host == host && !processAlive(owner.PID)
+		lockedErr := &LockedError{Path: lock.path, Owner: owner, Stale: stale}
+
+		if !force || (!stale && owner.Host == host && owner.PID > 0) {
+			return nil, lockedErr
+		}
+		if err := removeLockIf(lock.path, held); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("failed to acquire lock %s: it was taken concurrently", lock.path)
+}
+
+// createLock writes data to a temporary file and links it into place, so the lock
+// appears complete or not at all; it fails with os.ErrExist if the lock is held
+func createLock(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err != nil {
+		return err
+	}
+	return os.Link(tmp.Name(), path)
+}
+
+// removeLockIf removes the lock at path only if it still holds data
+// The lock is first moved aside, so a lock another process wrote after data was read
+// is never removed; it is linked back into place instead
+func removeLockIf(path string, data []byte) error {
+	aside := fmt.Sprintf("%s.%d.stale", path, os.Getpid())
+	if err := os.Rename(path, aside); err != nil {
+		return err
+	}
+	defer os.Remove(aside)
+
+	current, err := os.ReadFile(aside)
+	if err != nil {
+		return fmt.Errorf("failed to read lock %s: %w", path, err)
+	}
+	if bytes.Equal(current, data) {
+		return nil
+	}
+	if err := os.Link(aside, path); err != nil {
+		return fmt.Errorf("failed to restore lock %s taken over concurrently: %w", path, err)
+	}
+	return fmt.Errorf("failed to acquire lock %s: it was taken concurrently", path)
+}
+
+// Release removes the lock if this process still holds it
+func (l *Lock) Release() error {
+	if l == nil {
+		return nil
+	}
+	owner, err := readLock(l.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if owner.PID != l.info.PID || !owner.StartedAt.Equal(l.info.StartedAt) {
+		return nil // Taken over with --force
+	}
+	if err := os.Remove(l.path); err != nil {
+		return err
+	}
+	os.Remove(filepath.Dir(l.path)) // Only succeeds if "state clean --all" emptied it
+	return nil
+}
+
+// ReadLock returns the current lock owner of project dir, if the project is locked
+func ReadLock(dir string) (LockInfo, bool, error) {
+	owner, err := readLock(Path(dir, lockFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return LockInfo{}, false, nil
+	}
+	return owner, err == nil, err
+}
+
+func readLock(path string) (LockInfo, error) {
+	var info LockInfo
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return info, err
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return info, fmt.Errorf("failed to parse lock %s: %w", path, err)
+	}
+	return info, nil
+}
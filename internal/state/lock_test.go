@@ -0,0 +1,178 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAcquireLock(t *testing.T) {
+	dir := t.TempDir()
+
+	lock, err := AcquireLock(dir, "run", false)
+	if err != nil {
+		t.Fatalf("AcquireLock() error = %v", err)
+	}
+
+	owner, locked, err := ReadLock(dir)
+	if err != nil || !locked || owner.PID != os.Getpid() || owner.Command != "run" {
+		t.Errorf("ReadLock() = %+v, %v, %v, want this process running run", owner, locked, err)
+	}
+
+	// A live owner on this host is never taken over, even with force
+	for _, force := range []bool{false, true} {
+		_, err := AcquireLock(dir, "sync", force)
+		var lockedErr *LockedError
+		if !errors.As(err, &lockedErr) || lockedErr.Stale {
+			t.Errorf("AcquireLock(force=%v) error = %v, want a live LockedError", force, err)
+		}
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if _, locked, _ := ReadLock(dir); locked {
+		t.Error("lock should be removed after Release()")
+	}
+	if lock, err := AcquireLock(dir, "sync", false); err != nil {
+		t.Errorf("AcquireLock() after release error = %v", err)
+	} else {
+		lock.Release()
+	}
+}
+
+func TestAcquireLock_Stale(t *testing.T) {
+	dir := t.TempDir()
+
+	// The PID of a process that has already exited
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("cannot start helper process: %v", err)
+	}
+	host, _ := os.Hostname()
+	os.MkdirAll(filepath.Join(dir, Dir), 0755)
+	stale, _ := json.Marshal(LockInfo{PID: cmd.Process.Pid, Host: host, StartedAt: time.Now(), Command: "run"})
+	os.WriteFile(Path(dir, lockFile), stale, 0644)
+
+	_, err := AcquireLock(dir, "run", false)
+	var lockedErr *LockedError
+	if !errors.As(err, &lockedErr) || !lockedErr.Stale {
+		t.Fatalf("AcquireLock() error = %v, want a stale LockedError", err)
+	}
+
+	lock, err := AcquireLock(dir, "run", true)
+	if err != nil {
+		t.Fatalf("AcquireLock(force) error = %v", err)
+	}
+	defer lock.Release()
+	if owner, _, _ := ReadLock(dir); owner.PID != os.Getpid() {
+		t.Errorf("lock owner = %d after takeover, want %d", owner.PID, os.Getpid())
+	}
+}
+
+func TestAcquireLock_Unparsable(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, Dir), 0755)
+	os.WriteFile(Path(dir, lockFile), []byte("{\"pid\": 12"), 0644)
+
+	// Not knowing the owner is not evidence that it is gone
+	_, err := AcquireLock(dir, "run", false)
+	var lockedErr *LockedError
+	if err == nil || errors.As(err, &lockedErr) {
+		t.Fatalf("AcquireLock() error = %v, want a parse error", err)
+	}
+	if data, _ := os.ReadFile(Path(dir, lockFile)); string(data) != "{\"pid\": 12" {
+		t.Errorf("lock = %q, want it left alone", data)
+	}
+
+	lock, err := AcquireLock(dir, "run", true)
+	if err != nil {
+		t.Fatalf("AcquireLock(force) error = %v", err)
+	}
+	lock.Release()
+}
+
+func TestRemoveLockIf_KeepsNewerLock(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "lock")
+	if err := createLock(path, []byte("new owner")); err != nil {
+		t.Fatalf("createLock() error = %v", err)
+	}
+	if err := createLock(path, []byte("other")); !errors.Is(err, os.ErrExist) {
+		t.Errorf("createLock() over a held lock error = %v, want os.ErrExist", err)
+	}
+
+	// The stale lock that was read has since been replaced
+	if err := removeLockIf(path, []byte("old owner")); err == nil {
+		t.Error("removeLockIf() = nil, want an error for a changed lock")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "new owner" {
+		t.Errorf("lock = %q, want the newer lock kept", data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory holds %d files, want only the lock", len(entries))
+	}
+
+	if err := removeLockIf(path, []byte("new owner")); err != nil {
+		t.Errorf("removeLockIf() error = %v", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Error("removeLockIf() should remove an unchanged lock")
+	}
+}
+
+func TestRelease_AfterTakeover(t *testing.T) {
+	dir := t.TempDir()
+	lock, err := AcquireLock(dir, "run", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Another process took the lock over; releasing must not remove its lock
+	other, _ := json.Marshal(LockInfo{PID: os.Getpid(), Host: "elsewhere", StartedAt: time.Now().Add(time.Minute)})
+	os.WriteFile(Path(dir, lockFile), other, 0644)
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if owner, locked, _ := ReadLock(dir); !locked || owner.Host != "elsewhere" {
+		t.Errorf("ReadLock() = %+v, %v, want the new owner's lock kept", owner, locked)
+	}
+}
+
+func TestWriteStateFile_Concurrent(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- WriteStateFile(path, []byte(fmt.Sprintf(`{"writer":%d}`, i)))
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("WriteStateFile() error = %v", err)
+		}
+	}
+
+	var v map[string]int
+	data, _ := os.ReadFile(path)
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Errorf("file is not one complete write: %q", data)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(matches) != 0 {
+		t.Errorf("temp files left behind: %v", matches)
+	}
+}
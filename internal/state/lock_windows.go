@@ -0,0 +1,19 @@
+//go:build windows
+
+package state
+
+import "os"
+
+// processAlive reports whether pid is a running process on this host
+// FindProcess opens a handle on Windows, so it fails once the process has exited
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
@@ -0,0 +1,168 @@
+package state
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// legacyFile is a pre-.lisa state file at the project root and how to import it
+type legacyFile struct {
+	name    string
+	migrate func(dir string, data []byte, modTime time.Time) error
+}
+
+// legacyFiles are imported in order; .call_count and .last_reset share a document
+var legacyFiles = []legacyFile{
+	{".call_count", migrateCallCount},
+	{".last_reset", migrateLastReset},
+	{".codex_session_id", migrateSession("codex")},
+	{".opencode_session_id", migrateSession("opencode")},
+	{".command_session_id", migrateSession("command")},
+	{".ralph_session", migrateLisaSession},
+	{".exit_signals", migrateRaw[[]string](ExitSignalsSchema)},
+	{".circuit_breaker_state", migrateCircuit},
+	{".task_attempts", migrateRaw[map[string]TaskAttempts](TaskAttemptsSchema)},
+	{".lisa_checkpoints", migrateRaw[json.RawMessage](CheckpointsSchema)},
+}
+
+// legacyArchiveDir held archived OpenCode sessions before the state directory
+var legacyArchiveDir = filepath.Join(".ralph", "sessions")
+
+// LegacyFiles returns the legacy state files still present in dir
+func LegacyFiles(dir string) []string {
+	var found []string
+	for _, lf := range legacyFiles {
+		if _, err := os.Stat(filepath.Join(dir, lf.name)); err == nil {
+			found = append(found, lf.name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, legacyArchiveDir)); err == nil {
+		found = append(found, legacyArchiveDir)
+	}
+	return found
+}
+
+// migrateLegacy imports legacy state files into the state directory and removes them
+// Files that cannot be parsed are left in place and reported as skipped
+func migrateLegacy(dir string) (migrated, skipped []string) {
+	for _, lf := range legacyFiles {
+		path := filepath.Join(dir, lf.name)
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err == nil {
+			err = lf.migrate(dir, data, info.ModTime())
+		}
+		if err != nil {
+			skipped = append(skipped, lf.name)
+			continue
+		}
+		os.Remove(path)
+		migrated = append(migrated, lf.name)
+	}
+
+	oldArchive := filepath.Join(dir, legacyArchiveDir)
+	if _, err := os.Stat(oldArchive); err == nil {
+		newArchive := Path(dir, ArchiveDir)
+		if err := os.Rename(oldArchive, newArchive); err != nil {
+			skipped = append(skipped, legacyArchiveDir)
+		} else {
+			os.Remove(filepath.Join(dir, ".ralph")) // Only succeeds if now empty
+			migrated = append(migrated, legacyArchiveDir)
+		}
+	}
+
+	return migrated, skipped
+}
+
+func migrateCallCount(dir string, data []byte, _ time.Time) error {
+	count, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return err
+	}
+	rl, err := readDocument(dir, RateLimitSchema, RateLimit{})
+	if err != nil {
+		return err
+	}
+	rl.Calls = count
+	return writeDocument(dir, RateLimitSchema, rl)
+}
+
+func migrateLastReset(dir string, data []byte, _ time.Time) error {
+	var reset time.Time
+	if err := json.Unmarshal(data, &reset); err != nil {
+		return err
+	}
+	rl, err := readDocument(dir, RateLimitSchema, RateLimit{})
+	if err != nil {
+		return err
+	}
+	rl.LastReset = reset
+	return writeDocument(dir, RateLimitSchema, rl)
+}
+
+func migrateSession(backend string) func(string, []byte, time.Time) error {
+	return func(dir string, data []byte, modTime time.Time) error {
+		id := strings.TrimSpace(string(data))
+		if id == "" {
+			return nil
+		}
+		sessions, err := readDocument(dir, SessionsSchema, Sessions{})
+		if err != nil {
+			return err
+		}
+		if sessions == nil {
+			sessions = Sessions{}
+		}
+		sessions[backend] = BackendSession{ID: id, UpdatedAt: modTime}
+		return writeDocument(dir, SessionsSchema, sessions)
+	}
+}
+
+func migrateLisaSession(dir string, data []byte, _ time.Time) error {
+	var sess LisaSession
+	if err := json.Unmarshal(data, &sess); err != nil {
+		return err
+	}
+	return writeDocument(dir, LisaSessionSchema, sess)
+}
+
+// migrateCircuit converts the untyped breaker state map
+func migrateCircuit(dir string, data []byte, _ time.Time) error {
+	var legacy struct {
+		State           string   `json:"state"`
+		NoProgressCount int      `json:"no_progress_count"`
+		ErrorHistory    []string `json:"error_history"`
+		LastCheckTime   string   `json:"last_check_time"`
+	}
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return err
+	}
+	cs := CircuitState{
+		State:           legacy.State,
+		NoProgressCount: legacy.NoProgressCount,
+		ErrorHistory:    legacy.ErrorHistory,
+	}
+	if cs.State == "" {
+		cs.State = "CLOSED"
+	}
+	cs.LastCheckTime, _ = time.Parse(time.RFC3339, legacy.LastCheckTime)
+	return writeDocument(dir, CircuitSchema, cs)
+}
+
+// migrateRaw imports a legacy file whose JSON already matches the document's data
+func migrateRaw[T any](schema Schema) func(string, []byte, time.Time) error {
+	return func(dir string, data []byte, _ time.Time) error {
+		var value T
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		return writeDocument(dir, schema, value)
+	}
+}
@@ -0,0 +1,288 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Dir is the project directory holding all of Lisa's state
+const Dir = ".lisa"
+
+// LayoutVersion is the version of the state directory layout
+// It is recorded in meta.json so later releases can migrate older layouts
+const LayoutVersion = 1
+
+// metaFile records the layout version and how the directory was created
+const metaFile = "meta.json"
+
+// Document is the envelope every state file is stored in
+type Document struct {
+	Version   int             `json:"version"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Upgrade converts a document's data from one version to the next
+type Upgrade func(data json.RawMessage) (json.RawMessage, error)
+
+// Schema describes a versioned document in the state directory
+type Schema struct {
+	Name     string    // File name inside the state directory
+	Version  int       // Current version written by this build
+	Upgrades []Upgrade // Upgrades[i] converts version i+1 to version i+2
+}
+
+// Documents kept in the state directory
+var (
+	RateLimitSchema    = Schema{Name: "rate_limit.json", Version: 1}
+	SessionsSchema     = Schema{Name: "sessions.json", Version: 1}
+	LisaSessionSchema  = Schema{Name: "lisa_session.json", Version: 1}
+	CircuitSchema      = Schema{Name: "circuit.json", Version: 1}
+	ExitSignalsSchema  = Schema{Name: "exit_signals.json", Version: 1}
+	TaskAttemptsSchema = Schema{Name: "task_attempts.json", Version: 1}
+	CheckpointsSchema  = Schema{Name: "checkpoints.json", Version: 1}
+	RunSchema          = Schema{Name: "run.json", Version: 1}
+	BudgetSchema       = Schema{Name: "budget.json", Version: 1}
+)
+
+// Schemas lists every document schema, for inspection
+var Schemas = []Schema{
+	RateLimitSchema,
+	SessionsSchema,
+	LisaSessionSchema,
+	CircuitSchema,
+	ExitSignalsSchema,
+	TaskAttemptsSchema,
+	CheckpointsSchema,
+	RunSchema,
+	BudgetSchema,
+}
+
+// ArchiveDir is the subdirectory holding archived backend sessions
+const ArchiveDir = "sessions"
+
+// Path returns the path of name inside the state directory of project dir ("" for the current directory)
+func Path(dir, name string) string {
+	return filepath.Join(dir, Dir, name)
+}
+
+// Meta describes the state directory itself
+type Meta struct {
+	Version      int       `json:"version"`
+	CreatedAt    time.Time `json:"created_at"`
+	MigratedFrom []string  `json:"migrated_from,omitempty"` // Legacy files imported on first run
+	Skipped      []string  `json:"skipped,omitempty"`       // Legacy files that could not be read
+}
+
+// LoadDocument reads a document, upgrading it to the schema's version
+// Missing documents return defaultVal
+func LoadDocument[T any](dir string, schema Schema, defaultVal T) (T, error) {
+	if err := ensureDir(dir); err != nil {
+		return defaultVal, err
+	}
+	return readDocument(dir, schema, defaultVal)
+}
+
+// SaveDocument writes a document at the schema's current version
+func SaveDocument[T any](dir string, schema Schema, value T) error {
+	if err := ensureDir(dir); err != nil {
+		return err
+	}
+	return writeDocument(dir, schema, value)
+}
+
+func readDocument[T any](dir string, schema Schema, defaultVal T) (T, error) {
+	path := Path(dir, schema.Name)
+	doc, err := LoadState(path, Document{})
+	if err != nil {
+		return defaultVal, err
+	}
+	if doc.Version == 0 {
+		return defaultVal, nil
+	}
+
+	data, err := upgrade(schema, doc)
+	if err != nil {
+		return defaultVal, fmt.Errorf("%s: %w", path, err)
+	}
+
+	result := defaultVal
+	if err := json.Unmarshal(data, &result); err != nil {
+		return defaultVal, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return result, nil
+}
+
+func writeDocument[T any](dir string, schema Schema, value T) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", schema.Name, err)
+	}
+	return SaveState(Path(dir, schema.Name), Document{
+		Version:   schema.Version,
+		UpdatedAt: time.Now(),
+		Data:      data,
+	})
+}
+
+// upgrade applies the schema's upgrades to bring doc to the current version
+func upgrade(schema Schema, doc Document) (json.RawMessage, error) {
+	if doc.Version > schema.Version {
+		return nil, fmt.Errorf("version %d is newer than this build supports (%d)", doc.Version, schema.Version)
+	}
+
+	data := doc.Data
+	for v := doc.Version; v < schema.Version; v++ {
+		if v-1 >= len(schema.Upgrades) || schema.Upgrades[v-1] == nil {
+			return nil, fmt.Errorf("no upgrade from version %d", v)
+		}
+		var err error
+		if data, err = schema.Upgrades[v-1](data); err != nil {
+			return nil, fmt.Errorf("upgrade from version %d failed: %w", v, err)
+		}
+	}
+	return data, nil
+}
+
+// ensureDir creates the state directory on first use, importing legacy state files
+func ensureDir(dir string) error {
+	if _, err := os.Stat(Path(dir, metaFile)); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Join(dir, Dir), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	meta := Meta{Version: LayoutVersion, CreatedAt: time.Now()}
+	meta.MigratedFrom, meta.Skipped = migrateLegacy(dir)
+	return SaveState(Path(dir, metaFile), meta)
+}
+
+// LoadMeta reads the state directory metadata, creating the directory if needed
+func LoadMeta(dir string) (Meta, error) {
+	if err := ensureDir(dir); err != nil {
+		return Meta{}, err
+	}
+	return LoadState(Path(dir, metaFile), Meta{})
+}
+
+// DocumentInfo summarizes one state document
+type DocumentInfo struct {
+	Name      string
+	Version   int
+	UpdatedAt time.Time
+	Size      int64
+	Data      json.RawMessage
+	Err       error // Set if the document could not be read
+}
+
+// Inspect returns every document present in the state directory
+func Inspect(dir string) ([]DocumentInfo, error) {
+	if err := ensureDir(dir); err != nil {
+		return nil, err
+	}
+
+	var infos []DocumentInfo
+	for _, schema := range Schemas {
+		path := Path(dir, schema.Name)
+		stat, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		info := DocumentInfo{Name: schema.Name, Size: stat.Size()}
+		doc, err := LoadState(path, Document{})
+		if err != nil {
+			info.Err = err
+		} else {
+			info.Version = doc.Version
+			info.UpdatedAt = doc.UpdatedAt
+			info.Data = doc.Data
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// Archives lists the archived session files
+func Archives(dir string) []string {
+	entries, err := os.ReadDir(Path(dir, ArchiveDir))
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Clean removes run state (rate limit, sessions, circuit breaker, exit signals, task attempts and the run record)
+// With all set, checkpoint history, session archives and the directory itself are removed too
+// Leftover legacy files are always removed
+// Returns the paths that were removed
+func Clean(dir string, all bool) ([]string, error) {
+	var removed []string
+	remove := func(path string) error {
+		if _, err := os.Lstat(path); err != nil {
+			return nil
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+		removed = append(removed, path)
+		return nil
+	}
+
+	if all {
+		// Everything but the lock of the run doing the cleaning
+		entries, _ := os.ReadDir(filepath.Join(dir, Dir))
+		for _, entry := range entries {
+			if entry.Name() == lockFile {
+				continue
+			}
+			if err := remove(Path(dir, entry.Name())); err != nil {
+				return removed, err
+			}
+		}
+		os.Remove(filepath.Join(dir, Dir)) // Only succeeds if no lock is held
+	} else {
+		for _, schema := range Schemas {
+			if schema.Name == CheckpointsSchema.Name {
+				continue
+			}
+			if err := remove(Path(dir, schema.Name)); err != nil {
+				return removed, err
+			}
+		}
+	}
+
+	for _, legacy := range LegacyFiles(dir) {
+		if err := remove(filepath.Join(dir, legacy)); err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+// Describe formats the document's data for display
+func (d DocumentInfo) Describe() string {
+	if d.Err != nil {
+		return "error: " + d.Err.Error()
+	}
+	var v interface{}
+	if err := json.Unmarshal(d.Data, &v); err != nil {
+		return string(d.Data)
+	}
+	pretty, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return string(d.Data)
+	}
+	return string(pretty)
+}
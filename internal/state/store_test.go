@@ -0,0 +1,183 @@
+package state
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDocumentRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := SaveDocument(dir, ExitSignalsSchema, []string{"done"}); err != nil {
+		t.Fatalf("SaveDocument() error = %v", err)
+	}
+
+	var doc Document
+	data, _ := os.ReadFile(Path(dir, ExitSignalsSchema.Name))
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("document is not JSON: %v", err)
+	}
+	if doc.Version != ExitSignalsSchema.Version || doc.UpdatedAt.IsZero() {
+		t.Errorf("envelope = %+v, want version %d and updated_at", doc, ExitSignalsSchema.Version)
+	}
+
+	signals, err := LoadDocument(dir, ExitSignalsSchema, []string{})
+	if err != nil || len(signals) != 1 || signals[0] != "done" {
+		t.Errorf("LoadDocument() = %v, %v, want [done]", signals, err)
+	}
+
+	meta, err := LoadMeta(dir)
+	if err != nil || meta.Version != LayoutVersion {
+		t.Errorf("LoadMeta() = %+v, %v, want layout version %d", meta, err, LayoutVersion)
+	}
+}
+
+func TestDocumentUpgrade(t *testing.T) {
+	dir := t.TempDir()
+	v1 := Schema{Name: "counter.json", Version: 1}
+	if err := SaveDocument(dir, v1, 21); err != nil {
+		t.Fatal(err)
+	}
+
+	v2 := Schema{Name: "counter.json", Version: 2, Upgrades: []Upgrade{
+		func(data json.RawMessage) (json.RawMessage, error) {
+			var n int
+			if err := json.Unmarshal(data, &n); err != nil {
+				return nil, err
+			}
+			return json.Marshal(n * 2)
+		},
+	}}
+	n, err := LoadDocument(dir, v2, 0)
+	if err != nil || n != 42 {
+		t.Errorf("LoadDocument() after upgrade = %d, %v, want 42", n, err)
+	}
+
+	// A document from a newer build is refused rather than misread
+	if err := SaveDocument(dir, v2, 42); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadDocument(dir, v1, 0); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("LoadDocument() of newer version error = %v, want newer-version error", err)
+	}
+}
+
+func TestMigrateLegacy(t *testing.T) {
+	dir := t.TempDir()
+	origDir, _ := os.Getwd()
+	defer os.Chdir(origDir)
+	os.Chdir(dir)
+
+	reset := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	resetJSON, _ := json.Marshal(reset)
+	legacy := map[string]string{
+		".call_count":            "7",
+		".last_reset":            string(resetJSON),
+		".codex_session_id":      "thread-1",
+		".command_session_id":    "cmd-1\n",
+		".ralph_session":         `{"id":"lisa-1","created_at":"2025-06-01T12:00:00Z","last_used":"2025-06-01T13:00:00Z"}`,
+		".exit_signals":          `["done"]`,
+		".circuit_breaker_state": `{"state":"HALF_OPEN","no_progress_count":2,"error_history":["e1"],"last_check_time":"2025-06-01T12:00:00Z"}`,
+		".task_attempts":         `{"T1":{"attempts":2,"last_reason":"tests failing"}}`,
+		".lisa_checkpoints":      `[{"run_id":"r1","loop":1}]`,
+		".opencode_session_id":   "",
+	}
+	for name, content := range legacy {
+		os.WriteFile(name, []byte(content), 0644)
+	}
+	os.MkdirAll(filepath.Join(".ralph", "sessions"), 0755)
+	os.WriteFile(filepath.Join(".ralph", "sessions", "session_1.json"), []byte("{}"), 0644)
+
+	rl, err := LoadDocument("", RateLimitSchema, RateLimit{})
+	if err != nil || rl.Calls != 7 || !rl.LastReset.Equal(reset) {
+		t.Errorf("rate limit = %+v, %v, want 7 calls since %v", rl, err, reset)
+	}
+	if id, _ := LoadCodexSession(); id != "thread-1" {
+		t.Errorf("codex session = %q, want thread-1", id)
+	}
+	if sess, _ := LoadSessionIn("", "command"); sess.ID != "cmd-1" || sess.UpdatedAt.IsZero() {
+		t.Errorf("command session = %+v, want cmd-1 with a timestamp", sess)
+	}
+	if sess, _ := LoadLisaSession(); sess.ID != "lisa-1" {
+		t.Errorf("lisa session = %+v, want lisa-1", sess)
+	}
+	if signals, _ := LoadExitSignals(); len(signals) != 1 {
+		t.Errorf("exit signals = %v, want [done]", signals)
+	}
+	cs, _ := LoadCircuitBreakerState()
+	if cs.State != "HALF_OPEN" || cs.NoProgressCount != 2 || len(cs.ErrorHistory) != 1 || cs.LastCheckTime.IsZero() {
+		t.Errorf("circuit state = %+v, want typed HALF_OPEN state", cs)
+	}
+	if attempts, _ := LoadTaskAttempts(); attempts["T1"].Attempts != 2 {
+		t.Errorf("task attempts = %+v, want T1 with 2 attempts", attempts)
+	}
+	if cps, _ := LoadDocument("", CheckpointsSchema, []map[string]interface{}{}); len(cps) != 1 {
+		t.Errorf("checkpoints = %v, want one", cps)
+	}
+	if archives := Archives(""); len(archives) != 1 {
+		t.Errorf("Archives() = %v, want the moved session archive", archives)
+	}
+
+	if left := LegacyFiles(""); len(left) != 0 {
+		t.Errorf("LegacyFiles() = %v, want all migrated", left)
+	}
+	if _, err := os.Stat(".ralph"); err == nil {
+		t.Error("empty .ralph directory should be removed")
+	}
+	meta, _ := LoadMeta("")
+	if len(meta.MigratedFrom) != len(legacy)+1 || len(meta.Skipped) != 0 {
+		t.Errorf("meta = %+v, want %d migrated files", meta, len(legacy)+1)
+	}
+}
+
+func TestMigrateLegacy_SkipsUnreadable(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, ".call_count"), []byte("many"), 0644)
+	os.WriteFile(filepath.Join(dir, ".exit_signals"), []byte(`["done"]`), 0644)
+
+	meta, err := LoadMeta(dir)
+	if err != nil {
+		t.Fatalf("LoadMeta() error = %v", err)
+	}
+	if len(meta.Skipped) != 1 || meta.Skipped[0] != ".call_count" {
+		t.Errorf("Skipped = %v, want [.call_count]", meta.Skipped)
+	}
+	if left := LegacyFiles(dir); len(left) != 1 || left[0] != ".call_count" {
+		t.Errorf("LegacyFiles() = %v, want the unreadable file left in place", left)
+	}
+}
+
+func TestClean(t *testing.T) {
+	dir := t.TempDir()
+	SaveDocument(dir, RateLimitSchema, RateLimit{Calls: 3})
+	SaveDocument(dir, CircuitSchema, CircuitState{State: "OPEN"})
+	SaveDocument(dir, CheckpointsSchema, []string{"cp"})
+	os.WriteFile(filepath.Join(dir, ".exit_signals"), []byte(`[]`), 0644)
+
+	removed, err := Clean(dir, false)
+	if err != nil {
+		t.Fatalf("Clean() error = %v", err)
+	}
+	if len(removed) != 3 {
+		t.Errorf("Clean() removed %v, want rate limit, circuit and the legacy file", removed)
+	}
+	if _, err := os.Stat(Path(dir, CheckpointsSchema.Name)); err != nil {
+		t.Error("Clean() without all should keep checkpoint history")
+	}
+
+	infos, _ := Inspect(dir)
+	if len(infos) != 1 || infos[0].Name != CheckpointsSchema.Name || infos[0].Version != 1 {
+		t.Errorf("Inspect() = %+v, want only checkpoints", infos)
+	}
+
+	if _, err := Clean(dir, true); err != nil {
+		t.Fatalf("Clean(all) error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, Dir)); err == nil {
+		t.Error("Clean(all) should remove the state directory")
+	}
+}
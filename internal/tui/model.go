@@ -3,10 +3,13 @@ package tui
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/brainwhocodes/lisa-loop/internal/budget"
 	"github.com/brainwhocodes/lisa-loop/internal/loop"
+	"github.com/brainwhocodes/lisa-loop/internal/state"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -62,6 +65,9 @@ type StatusMsg struct {
 	Status string
 }
 
+// StopMsg is sent to stop the loop and quit, as when lisa receives a signal
+type StopMsg struct{}
+
 // TickMsg is sent periodically for animations
 type TickMsg time.Time
 
@@ -72,9 +78,11 @@ type ControllerEventMsg struct {
 
 // Task represents a task from @fix_plan.md
 type Task struct {
+	ID        string // Stable plan task ID
 	Text      string
 	Completed bool
 	Active    bool // Currently being worked on
+	Blocked   bool // Waiting on unfinished dependencies
 }
 
 // Phase represents a group of tasks (matches tui/program.go Phase)
@@ -84,6 +92,15 @@ type Phase struct {
 	Completed bool
 }
 
+// WorkerLane is the status of one parallel worker
+type WorkerLane struct {
+	ID       int
+	Task     string
+	Status   string // running, verifying, merging, merged, failed, idle
+	Detail   string // Failure reason or extra status text
+	LastLine string // Most recent output from the worker's runner
+}
+
 // ViewMode represents the current view mode
 type ViewMode string
 
@@ -104,6 +121,8 @@ type Model struct {
 	maxCalls      int
 	callsUsed     int
 	circuitState  string
+	errorGroups   []state.ErrorGroup // Circuit breaker errors by fingerprint, most repeated first
+	budget        *budget.Status     // Spending against the budget (nil until something is spent)
 	logs          []string
 	activeView    string
 	viewMode      ViewMode // Current view mode for split/full views
@@ -121,6 +140,7 @@ type Model struct {
 	projectMode   loop.ProjectMode // Current project mode (implementation, refactor, fix)
 	activity      string           // Current activity description
 	controller    *loop.Controller
+	parentCtx     context.Context // Cancelled on SIGINT/SIGTERM (nil means never)
 	ctx           context.Context
 	cancel        context.CancelFunc
 	activeTaskIdx int // Index of currently active task (-1 if none)
@@ -145,6 +165,9 @@ type Model struct {
 	exitSignal      bool    // Whether exit was signaled
 	confidenceScore float64 // Confidence in completion (0-1)
 
+	// Claims of the last loop that its observed changes contradict
+	discrepancies []string
+
 	// Context window tracking
 	contextUsagePercent float64 // Current usage (0-1)
 	contextTotalTokens  int     // Total tokens used
@@ -158,12 +181,26 @@ type Model struct {
 	preflightTotalTasks     int
 	preflightRemainingCount int
 	preflightRemainingTasks []string
+	preflightReadyCount     int
+	preflightBlockedCount   int
 	preflightCircuitState   string
 	preflightRateLimitOK    bool
 	preflightCallsRemaining int
 	preflightShouldSkip     bool
 	preflightSkipReason     string
 
+	// Verification gate (from last iteration)
+	verifyRan    bool   // True once a verification result has been received
+	verifyPassed bool   // Whether the last verification passed
+	verifyCmd    string // Command used for verification
+
+	// Parallel worker lanes (empty in the serial loop)
+	workers []WorkerLane
+
+	// When the rate limit allows the next call, or a failed iteration is retried
+	// (zero unless waiting); the status says which
+	rateLimitedUntil time.Time
+
 	// Loop outcome (from last iteration)
 	lastOutcome        *loop.LoopOutcome
 	totalTasksCompleted int // Cumulative tasks completed
@@ -185,20 +222,22 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch msg.Type {
 		case tea.KeyCtrlC, tea.KeyCtrlQ:
-			m.quitting = true
-			return m, tea.Quit
+			return m.quit()
 
 		case tea.KeyRunes:
 			switch msg.String() {
 			case "q":
-				m.quitting = true
-				return m, tea.Quit
+				return m.quit()
 
 			case "r":
 				if m.state != StateRunning && m.controller != nil {
 					m.state = StateRunning
 					m.activeTaskIdx = 0 // Start with first task
-					m.ctx, m.cancel = context.WithCancel(context.Background())
+					parent := m.parentCtx
+					if parent == nil {
+						parent = context.Background()
+					}
+					m.ctx, m.cancel = context.WithCancel(parent)
 					go m.runController()
 					return m, nil
 				}
@@ -275,12 +314,16 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case "R":
 				// Reset circuit breaker - send message to controller
 				m.circuitState = "CLOSED"
+				m.errorGroups = nil
 				m.addLog(string(loop.LogLevelInfo), "Circuit breaker reset")
 				return m, nil
 
 			}
 		}
 
+	case StopMsg:
+		return m.quit()
+
 	case LoopUpdateMsg:
 		m.loopNumber = msg.LoopNumber
 		m.callsUsed = msg.CallsUsed
@@ -323,16 +366,45 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.callsUsed = event.CallsUsed
 			m.status = event.Status
 			m.circuitState = event.CircuitState
+			m.errorGroups = event.ErrorGroups
+			if event.Budget != nil {
+				m.budget = event.Budget
+			}
+			m.rateLimitedUntil = time.Time{}
+			switch event.Status {
+			case "rate_limited", "retry_wait", "quota_wait":
+				m.rateLimitedUntil = time.Now().Add(event.RateLimitWait)
+			}
 			m.updateActiveTask()
 		case loop.EventTypeLog:
 			m.addLog(string(event.LogLevel), event.LogMessage)
 		case loop.EventTypeStateChange:
-			// Handle state changes if needed
+			m.circuitState = event.CircuitState
+			level := loop.LogLevelWarn
+			if event.CircuitState == "CLOSED" {
+				level = loop.LogLevelSuccess
+			}
+			m.addLog(string(level), fmt.Sprintf("Circuit %s → %s: %s", event.PrevCircuitState, event.CircuitState, event.LogMessage))
 		case loop.EventTypeCodexOutput:
+			if event.Worker > 0 {
+				m.setWorkerLine(event.Worker, event.OutputLine)
+				m.addOutputLine(fmt.Sprintf("[W%d] %s", event.Worker, event.OutputLine), string(event.OutputType))
+				return m, nil
+			}
 			m.addOutputLine(event.OutputLine, string(event.OutputType))
 		case loop.EventTypeCodexReasoning:
+			if event.Worker > 0 {
+				m.setWorkerLine(event.Worker, event.ReasoningText)
+				return m, nil
+			}
 			m.addReasoningLine(event.ReasoningText)
+		case loop.EventTypeWorker:
+			m.updateWorkerLane(event)
 		case loop.EventTypeCodexTool:
+			if event.Worker > 0 {
+				m.setWorkerLine(event.Worker, fmt.Sprintf("> %s %s", event.ToolName, event.ToolTarget))
+				return m, nil
+			}
 			// Deduplicate tool calls
 			toolID := fmt.Sprintf("%s:%s:%s", event.ToolName, event.ToolTarget, event.ToolStatus)
 			if toolID == m.lastToolCall {
@@ -353,6 +425,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.testsStatus = event.TestsStatus
 			m.exitSignal = event.ExitSignal
 			m.confidenceScore = event.ConfidenceScore
+			m.discrepancies = event.Discrepancies
 
 			// Find and update task by CurrentTask text
 			if event.CurrentTask != "" {
@@ -363,6 +436,8 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.exitSignal || (m.confidenceScore >= 0.9 && m.analysisStatus == "COMPLETE") {
 				m.state = StateComplete
 			}
+		case loop.EventTypeUsage:
+			m.budget = event.Budget
 		case loop.EventTypeContextUsage:
 			// Update context window usage
 			m.contextUsagePercent = event.ContextUsagePercent
@@ -379,6 +454,8 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.preflightTotalTasks = event.Preflight.TotalTasks
 				m.preflightRemainingCount = event.Preflight.RemainingCount
 				m.preflightRemainingTasks = event.Preflight.RemainingTasks
+				m.preflightReadyCount = event.Preflight.ReadyCount
+				m.preflightBlockedCount = event.Preflight.BlockedCount
 				m.preflightCircuitState = event.Preflight.CircuitState
 				m.preflightRateLimitOK = event.Preflight.RateLimitOK
 				m.preflightCallsRemaining = event.Preflight.CallsRemaining
@@ -387,19 +464,44 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 				// Log preflight info
 				m.addLog(string(loop.LogLevelInfo), fmt.Sprintf("Preflight: %d/%d tasks remaining", event.Preflight.RemainingCount, event.Preflight.TotalTasks))
+				if event.Preflight.BlockedCount > 0 {
+					m.addLog(string(loop.LogLevelInfo), fmt.Sprintf("Preflight: %d ready, %d blocked by dependencies", event.Preflight.ReadyCount, event.Preflight.BlockedCount))
+				}
+				for _, planErr := range event.Preflight.PlanErrors {
+					m.addLog(string(loop.LogLevelWarn), fmt.Sprintf("Plan: %s", planErr))
+				}
 				if event.Preflight.ShouldSkip {
 					m.addLog(string(loop.LogLevelWarn), fmt.Sprintf("Skip reason: %s", event.Preflight.SkipReason))
 				}
 			}
 
+		case loop.EventTypeFocus:
+			m.updateTaskByText(event.CurrentTask, false)
+
+		case loop.EventTypeVerification:
+			if event.Verification != nil {
+				m.verifyRan = true
+				m.verifyPassed = event.Verification.Passed
+				m.verifyCmd = event.Verification.Command
+				if !event.Verification.Passed {
+					m.testsStatus = "FAILING"
+				}
+			}
+
 		case loop.EventTypeOutcome:
 			// Update loop outcome
 			if event.Outcome != nil {
 				m.lastOutcome = event.Outcome
 				if event.Outcome.Success {
 					m.totalTasksCompleted += event.Outcome.TasksCompleted
-					m.addLog(string(loop.LogLevelInfo), fmt.Sprintf("Loop outcome: %d tasks completed, %d files modified",
-						event.Outcome.TasksCompleted, event.Outcome.FilesModified))
+					message := fmt.Sprintf("Loop outcome: %d tasks completed, %d files modified",
+						event.Outcome.TasksCompleted, event.Outcome.FilesModified)
+					if event.Outcome.Tests != nil {
+						message += ", tests " + event.Outcome.Tests.Summary()
+					}
+					m.addLog(string(loop.LogLevelInfo), message)
+				} else if event.Outcome.TimedOut {
+					m.addLog(string(loop.LogLevelError), fmt.Sprintf("Loop timed out: %s", event.Outcome.Error))
 				} else {
 					m.addLog(string(loop.LogLevelError), fmt.Sprintf("Loop failed: %s", event.Outcome.Error))
 				}
@@ -524,6 +626,48 @@ func (m *Model) addReasoningLine(line string) {
 	m.reasoningLines = []string{line}
 }
 
+// workerLane returns the lane for a worker, creating it if needed
+func (m *Model) workerLane(id int) *WorkerLane {
+	for i := range m.workers {
+		if m.workers[i].ID == id {
+			return &m.workers[i]
+		}
+	}
+	m.workers = append(m.workers, WorkerLane{ID: id})
+	sort.Slice(m.workers, func(i, j int) bool { return m.workers[i].ID < m.workers[j].ID })
+	return m.workerLane(id)
+}
+
+// updateWorkerLane applies a worker status event to its lane
+func (m *Model) updateWorkerLane(event loop.LoopEvent) {
+	lane := m.workerLane(event.Worker)
+	if event.CurrentTask != "" && event.CurrentTask != lane.Task {
+		lane.Task = event.CurrentTask
+		lane.LastLine = ""
+	}
+	lane.Status = event.Status
+	lane.Detail = event.LogMessage
+
+	switch event.Status {
+	case loop.WorkerStatusRunning:
+		m.updateTaskByText(lane.Task, false)
+	case loop.WorkerStatusMerged:
+		m.updateTaskByText(lane.Task, true)
+	}
+}
+
+// setWorkerLine records the latest output line for a worker lane
+func (m *Model) setWorkerLine(id int, line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+	if idx := strings.LastIndex(line, "\n"); idx >= 0 {
+		line = line[idx+1:]
+	}
+	m.workerLane(id).LastLine = line
+}
+
 // updateActiveTask updates the active task based on loop progress
 func (m *Model) updateActiveTask() {
 	if m.state != StateRunning {
@@ -679,6 +823,25 @@ func (m *Model) updatePhaseCompletion() {
 	}
 }
 
+// stopTimeout bounds how long quitting waits for the loop to stop
+const stopTimeout = 15 * time.Second
+
+// quit cancels a running loop and exits once its Run has returned, so the backend is
+// stopped and the run recorded as interrupted before lisa exits
+func (m Model) quit() (tea.Model, tea.Cmd) {
+	m.quitting = true
+	if m.cancel == nil || m.controller == nil {
+		return m, tea.Quit
+	}
+
+	m.cancel()
+	controller := m.controller
+	return m, func() tea.Msg {
+		controller.WaitForRun(stopTimeout)
+		return tea.QuitMsg{}
+	}
+}
+
 func (m *Model) runController() {
 	if m.controller == nil {
 		return
@@ -862,12 +1025,12 @@ func (m Model) renderCircuitView() string {
 		stateIcon = IconWarning
 		stateLabel = "half-open"
 		stateStyle = StyleCircuitHalfOpen
-		stateDesc = "Circuit is monitoring. Loop may pause if no progress continues."
+		stateDesc = "Circuit is monitoring. The next iterations decide whether it closes or re-opens."
 	case "open":
 		stateIcon = IconError
 		stateLabel = "open"
 		stateStyle = StyleCircuitOpen
-		stateDesc = "Circuit is open! Loop execution halted due to repeated failures."
+		stateDesc = "Circuit is open! Loop execution halted due to repeated failures until the cool-down passes."
 	default:
 		stateIcon = IconPending
 		stateLabel = circuitState
@@ -890,6 +1053,27 @@ func (m Model) renderCircuitView() string {
 	lines = append(lines, "")
 	lines = append(lines, StyleDividerSubtle.Render(strings.Repeat(DividerCharSubtle, width-4)))
 
+	// Repeated errors, grouped by fingerprint
+	if len(m.errorGroups) > 0 {
+		const maxGroups = 5
+		lines = append(lines, "")
+		lines = append(lines, StyleTextBase.Render(" Errors"))
+		lines = append(lines, "")
+		for i, g := range m.errorGroups {
+			if i == maxGroups {
+				lines = append(lines, StyleTextSubtle.Render(fmt.Sprintf(" +%d more", len(m.errorGroups)-maxGroups)))
+				break
+			}
+			sample := strings.Join(strings.Fields(g.Sample), " ")
+			if len(sample) > width-16 {
+				sample = sample[:width-19] + "..."
+			}
+			lines = append(lines, fmt.Sprintf(" %s %s",
+				StyleCircuitHalfOpen.Render(fmt.Sprintf("%3d×", g.Count)),
+				StyleTextMuted.Render(sample)))
+		}
+	}
+
 	circuitInfo := strings.Join(lines, "\n")
 
 	middleHeight := height - headerHeight - footerHeight - 2
@@ -1,10 +1,17 @@
 package tui
 
 import (
+	"context"
+	"os"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/charmbracelet/bubbletea"
+
+	"github.com/brainwhocodes/lisa-loop/internal/circuit"
+	"github.com/brainwhocodes/lisa-loop/internal/loop"
+	"github.com/brainwhocodes/lisa-loop/internal/runner"
 )
 
 // TestModelTick tests that tick counter increments properly
@@ -85,6 +92,57 @@ func TestModelQuit(t *testing.T) {
 	}
 }
 
+// blockingRunner runs until its call is cancelled, then takes a moment to stop
+type blockingRunner struct {
+	started chan struct{}
+	stopped atomic.Bool
+}
+
+func (r *blockingRunner) Run(ctx context.Context, prompt string) (string, string, error) {
+	close(r.started)
+	<-ctx.Done()
+	time.Sleep(50 * time.Millisecond)
+	r.stopped.Store(true)
+	return "", "", ctx.Err()
+}
+
+func (r *blockingRunner) SetOutputCallback(cb runner.OutputCallback) {}
+
+func (r *blockingRunner) Stop() error { return nil }
+
+// TestModelQuit_StopsRunningLoop tests that quitting cancels the loop and waits for it
+func TestModelQuit_StopsRunningLoop(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+	os.WriteFile("PROMPT.md", []byte("Test prompt"), 0644)
+	os.WriteFile("@fix_plan.md", []byte("- [ ] First task\n"), 0644)
+
+	agent := &blockingRunner{started: make(chan struct{})}
+	controller := loop.NewController(loop.Config{MaxCalls: 5, Backend: "cli"}, loop.NewRateLimiter(10, 1), circuit.NewBreaker(3, 5))
+	controller.SetRunner(agent)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	model := Model{state: StateRunning, controller: controller, ctx: ctx, cancel: cancel}
+	go controller.Run(ctx)
+	<-agent.started
+
+	newModel, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}})
+	if !newModel.(Model).quitting || cmd == nil {
+		t.Fatal("q should start quitting")
+	}
+	if ctx.Err() == nil {
+		t.Error("quitting should cancel the loop")
+	}
+	if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Error("quit command should end with tea.QuitMsg")
+	}
+	if !agent.stopped.Load() {
+		t.Error("the TUI quit before the loop stopped")
+	}
+}
+
 // TestModelTogglePause tests pause/resume toggle
 func TestModelTogglePause(t *testing.T) {
 	model := Model{
@@ -1,16 +1,16 @@
 package tui
 
 import (
-	"bufio"
+	"context"
 	"fmt"
 	"os"
-	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 
 	"github.com/brainwhocodes/lisa-loop/internal/codex"
 	"github.com/brainwhocodes/lisa-loop/internal/loop"
+	"github.com/brainwhocodes/lisa-loop/internal/plan"
 )
 
 // Program wraps the Bubble Tea program
@@ -164,158 +164,54 @@ func parseTasksFromData(data string) []Task {
 }
 
 // parsePhasesFromData extracts tasks grouped by phase from plan file content
-// Supports multiple plan formats:
-// - REFACTOR_PLAN.md: ## Phase N: ... headers
-// - IMPLEMENTATION_PLAN.md: ## Phase N: ... or ### N) atomic commit headers
-// - @fix_plan.md: ## Critical Fixes, ## High Priority, ## Medium Priority, etc.
+// Phase detection lives in the plan package; subtasks are flattened into their phase
 func parsePhasesFromData(data string) []Phase {
-	var phases []Phase
-	var currentPhase *Phase
-	scanner := bufio.NewScanner(strings.NewReader(data))
-
-	for scanner.Scan() {
-		line := scanner.Text()
-		trimmed := strings.TrimSpace(line)
+	doc := plan.Parse(data)
 
-		if trimmed == "" {
-			continue
-		}
-
-		// Detect phase/section headers
-		if isPhaseHeader(trimmed) {
-			header := extractPhaseHeader(trimmed)
-			// Save previous phase if it has tasks
-			if currentPhase != nil && len(currentPhase.Tasks) > 0 {
-				phases = append(phases, *currentPhase)
-			}
-			currentPhase = &Phase{
-				Name:  header,
-				Tasks: []Task{},
-			}
-			continue
+	var phases []Phase
+	for _, ph := range doc.Phases {
+		phase := Phase{
+			Name:      ph.Name,
+			Tasks:     []Task{},
+			Completed: ph.Completed(),
 		}
-
-		// Parse checkbox items: - [ ] or - [x]
-		if strings.HasPrefix(trimmed, "- [") {
-			completed := strings.HasPrefix(trimmed, "- [x]") || strings.HasPrefix(trimmed, "- [X]")
-
-			// Extract task text (skip "- [ ] " or "- [x] ")
-			text := ""
-			if len(trimmed) > 6 {
-				text = strings.TrimSpace(trimmed[6:])
-			}
-
-			if text != "" {
-				task := Task{
-					Text:      text,
-					Completed: completed,
-				}
-
-				if currentPhase != nil {
-					currentPhase.Tasks = append(currentPhase.Tasks, task)
-				} else {
-					// No phase yet, create a default one
-					currentPhase = &Phase{
-						Name:  "Tasks",
-						Tasks: []Task{task},
-					}
-				}
-			}
+		for _, t := range ph.Tasks {
+			phase.Tasks = appendTaskTree(phase.Tasks, doc, t)
 		}
-	}
-
-	// Don't forget the last phase
-	if currentPhase != nil && len(currentPhase.Tasks) > 0 {
-		phases = append(phases, *currentPhase)
-	}
 
-	// Update phase completion status
-	for i := range phases {
-		allComplete := true
-		for _, task := range phases[i].Tasks {
-			if !task.Completed {
-				allComplete = false
-				break
-			}
+		// Skip headers with no tasks under them
+		if len(phase.Tasks) > 0 {
+			phases = append(phases, phase)
 		}
-		phases[i].Completed = allComplete
 	}
-
 	return phases
 }
 
-// isPhaseHeader checks if a line is a phase/section header
-func isPhaseHeader(line string) bool {
-	lower := strings.ToLower(line)
-
-	// ## Phase N: ... (REFACTOR_PLAN.md, IMPLEMENTATION_PLAN.md)
-	if strings.HasPrefix(line, "## ") {
-		header := strings.TrimPrefix(line, "## ")
-		headerLower := strings.ToLower(header)
-
-		// Phase headers
-		if strings.Contains(headerLower, "phase") {
-			return true
-		}
-
-		// Fix plan priority headers
-		if strings.HasPrefix(headerLower, "critical") ||
-			strings.HasPrefix(headerLower, "high priority") ||
-			strings.HasPrefix(headerLower, "medium priority") ||
-			strings.HasPrefix(headerLower, "low priority") ||
-			strings.HasPrefix(headerLower, "testing") ||
-			strings.HasPrefix(headerLower, "nice to have") {
-			return true
-		}
-
-		// Verification/Success criteria sections
-		if strings.Contains(headerLower, "verification") ||
-			strings.Contains(headerLower, "success criteria") {
-			return true
-		}
-	}
-
-	// ### N) Atomic commit headers (IMPLEMENTATION_PLAN.md)
-	if strings.HasPrefix(line, "### ") {
-		header := strings.TrimPrefix(line, "### ")
-		// Check for numbered headers like "1) Config..." or "2) OpenCode..."
-		if len(header) >= 2 && header[0] >= '1' && header[0] <= '9' && header[1] == ')' {
-			return true
-		}
-	}
-
-	// ## Atomic Commits section header
-	if strings.HasPrefix(lower, "## atomic") {
-		return true
-	}
-
-	return false
-}
-
-// extractPhaseHeader extracts the display name from a phase header line
-func extractPhaseHeader(line string) string {
-	if strings.HasPrefix(line, "### ") {
-		header := strings.TrimPrefix(line, "### ")
-		// For "1) Config..." make it "Step 1: Config..."
-		if len(header) >= 2 && header[0] >= '1' && header[0] <= '9' && header[1] == ')' {
-			return "Step " + string(header[0]) + ":" + header[2:]
-		}
-		return header
+// appendTaskTree appends a task and its subtasks in document order
+func appendTaskTree(tasks []Task, doc *plan.Plan, t *plan.Task) []Task {
+	if t.Text != "" {
+		tasks = append(tasks, Task{
+			ID:        t.ID,
+			Text:      t.Text,
+			Completed: t.Checked,
+			Blocked:   !t.Checked && !doc.IsReady(t),
+		})
 	}
-
-	if strings.HasPrefix(line, "## ") {
-		return strings.TrimPrefix(line, "## ")
+	for _, sub := range t.Subtasks {
+		tasks = appendTaskTree(tasks, doc, sub)
 	}
-
-	return line
+	return tasks
 }
 
 // Run starts the TUI program
-func (p *Program) Run() error {
+// Cancelling ctx stops a running loop and quits, as "q" does
+func (p *Program) Run(ctx context.Context) error {
+	p.model.parentCtx = ctx
 	program := tea.NewProgram(
 		p.model,
-		tea.WithAltScreen(),       // Full-screen alternate buffer mode
-		tea.WithMouseCellMotion(), // Enable mouse support
+		tea.WithAltScreen(),        // Full-screen alternate buffer mode
+		tea.WithMouseCellMotion(),  // Enable mouse support
+		tea.WithoutSignalHandler(), // Signals cancel ctx, so the loop stops before the TUI quits
 	)
 
 	// Set up controller event callback to send messages to the TUI
@@ -325,6 +221,16 @@ func (p *Program) Run() error {
 		})
 	}
 
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			program.Send(StopMsg{})
+		case <-done:
+		}
+	}()
+
 	_, err := program.Run()
 	return err
 }
@@ -189,6 +189,10 @@ var (
 	StyleTaskPending = lipgloss.NewStyle().
 				Foreground(Squid)
 
+	// Blocked task: waiting on unfinished dependencies
+	StyleTaskBlocked = lipgloss.NewStyle().
+				Foreground(Coral)
+
 	// Task text styles
 	StyleTaskTextCompleted = lipgloss.NewStyle().
 				Foreground(Smoke)
@@ -198,6 +202,9 @@ var (
 
 	StyleTaskTextPending = lipgloss.NewStyle().
 				Foreground(Squid)
+
+	StyleTaskTextBlocked = lipgloss.NewStyle().
+				Foreground(Oyster)
 )
 
 // Text styles
@@ -38,6 +38,7 @@ const (
 	IconWarning     = "⚠"
 	IconInfo        = "ⓘ"
 	IconPending     = "•"
+	IconBlocked     = "⊘"
 	IconInProgress  = "●"
 	IconArrowRight  = "→"
 	IconBorderThin  = "│"
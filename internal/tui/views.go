@@ -3,6 +3,7 @@ package tui
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 )
@@ -24,9 +25,15 @@ func (m Model) renderSplitView() string {
 	footerHeight := 1
 	contentHeight := height - headerHeight - statusHeight - footerHeight - 2
 
+	// Parallel worker lanes sit between tasks and output
+	lanesHeight := 0
+	if len(m.workers) > 0 {
+		lanesHeight = len(m.workers) + 1 // +1 for divider
+	}
+
 	// Split content vertically: 40% tasks, 60% output
 	topHeight := (contentHeight * 40) / 100
-	bottomHeight := contentHeight - topHeight - 1 // 1 for divider
+	bottomHeight := contentHeight - topHeight - lanesHeight - 1 // 1 for divider
 
 	// Render header
 	header := m.renderHeader(width)
@@ -46,15 +53,14 @@ func (m Model) renderSplitView() string {
 	// Render footer
 	footer := m.renderFooter(width)
 
+	sections := []string{header, statusBar, topPane, divider}
+	if lanesHeight > 0 {
+		sections = append(sections, m.renderWorkerLanes(width), divider)
+	}
+	sections = append(sections, bottomPane, footer)
+
 	// Join everything vertically
-	return lipgloss.JoinVertical(lipgloss.Left,
-		header,
-		statusBar,
-		topPane,
-		divider,
-		bottomPane,
-		footer,
-	)
+	return lipgloss.JoinVertical(lipgloss.Left, sections...)
 }
 
 // renderHeader renders the Crush-style header with gradient text and diagonal separators
@@ -108,6 +114,11 @@ func (m Model) renderHeader(width int) string {
 		metaParts = append(metaParts, fmt.Sprintf("%d/%d", completed, len(m.tasks)))
 	}
 
+	// Spending of the run, against its budget
+	if m.budget != nil {
+		metaParts = append(metaParts, m.budget.RunSummary())
+	}
+
 	metadata := StyleHeaderMeta.Render(strings.Join(metaParts, MetaDotSeparator))
 
 	// Calculate space for diagonal separators (include SAX animation width)
@@ -184,6 +195,30 @@ func (m Model) renderStatusBar(width int) string {
 		if m.exitSignal {
 			midStatus += StyleSuccessMsg.Render(" EXIT")
 		}
+		if n := len(m.discrepancies); n > 0 {
+			midStatus += StyleWarningMsg.Render(fmt.Sprintf(" %s mismatch:%d", IconWarning, n))
+		}
+	}
+
+	// Verification gate result from the last iteration
+	if m.verifyRan {
+		if m.verifyPassed {
+			midStatus += StyleTextMuted.Render(" │ ") + StyleSuccessMsg.Render(IconCheck+" verify")
+		} else {
+			midStatus += StyleTextMuted.Render(" │ ") + StyleErrorMsg.Render(IconError+" verify")
+		}
+	}
+
+	// Countdown to the next call while a rate-limit window is full or a retry is pending
+	if wait := time.Until(m.rateLimitedUntil); wait > 0 {
+		label := "rate limited "
+		switch m.status {
+		case "quota_wait":
+			label = "quota resets in "
+		case "retry_wait":
+			label = "retrying in "
+		}
+		midStatus += StyleTextMuted.Render(" │ ") + StyleWarningMsg.Render(label+wait.Round(time.Second).String())
 	}
 
 	// Context usage indicator (before circuit)
@@ -261,6 +296,51 @@ func (m Model) renderHorizontalDivider(width int) string {
 	return StyleDivider.Render(strings.Repeat(DividerChar, width))
 }
 
+// renderWorkerLanes renders one status line per parallel worker
+// Format: W1 ⠋ Add login form │ running │ > edit src/login.tsx
+func (m Model) renderWorkerLanes(width int) string {
+	lines := make([]string, 0, len(m.workers))
+	for _, lane := range m.workers {
+		var icon string
+		var statusStyle lipgloss.Style
+		switch lane.Status {
+		case "running", "verifying", "merging":
+			icon = BrailleSpinnerFrames[(m.tick+lane.ID)%len(BrailleSpinnerFrames)]
+			statusStyle = StyleTaskInProgress
+		case "merged":
+			icon = IconCheck
+			statusStyle = StyleTaskCompleted
+		case "failed":
+			icon = IconError
+			statusStyle = StyleErrorMsg
+		default:
+			icon = IconPending
+			statusStyle = StyleTaskPending
+		}
+
+		task := lane.Task
+		if task == "" {
+			task = "waiting for a ready task"
+		}
+		detail := lane.LastLine
+		if lane.Detail != "" {
+			detail = lane.Detail
+		}
+
+		line := fmt.Sprintf(" %s %s %s %s %s",
+			StyleTextMuted.Render(fmt.Sprintf("W%d", lane.ID)),
+			statusStyle.Render(icon),
+			StyleTextBase.Render(task),
+			StyleTextMuted.Render("│"),
+			statusStyle.Render(lane.Status))
+		if detail != "" {
+			line += StyleTextMuted.Render(" │ " + detail)
+		}
+		lines = append(lines, lipgloss.NewStyle().MaxWidth(width).Render(line))
+	}
+	return strings.Join(lines, "\n")
+}
+
 // renderTaskPane renders the tasks pane with Crush-style icons
 // Shows only current phase tasks with phase header
 func (m Model) renderTaskPane(width, height int) string {
@@ -418,6 +498,9 @@ func (m Model) renderPhaseTaskLine(task Task, taskIdx, phaseIdx int, maxWidth in
 		spinnerFrame := BrailleSpinnerFrames[m.tick%len(BrailleSpinnerFrames)]
 		icon = StyleTaskInProgress.Render(spinnerFrame)
 		textStyle = StyleTaskTextActive
+	} else if task.Blocked {
+		icon = StyleTaskBlocked.Render(IconBlocked)
+		textStyle = StyleTaskTextBlocked
 	} else {
 		icon = StyleTaskPending.Render(IconPending)
 		textStyle = StyleTaskTextPending
@@ -448,6 +531,9 @@ func (m Model) renderTaskLine(task Task, index int, maxWidth int) string {
 		spinnerFrame := BrailleSpinnerFrames[m.tick%len(BrailleSpinnerFrames)]
 		icon = StyleTaskInProgress.Render(spinnerFrame)
 		textStyle = StyleTaskTextActive
+	} else if task.Blocked {
+		icon = StyleTaskBlocked.Render(IconBlocked)
+		textStyle = StyleTaskTextBlocked
 	} else {
 		icon = StyleTaskPending.Render(IconPending)
 		textStyle = StyleTaskTextPending
@@ -861,6 +947,13 @@ func (m Model) renderPreflightSummary(width int) string {
 			bar))
 	}
 
+	if m.preflightBlockedCount > 0 {
+		lines = append(lines, fmt.Sprintf(" %s %s %s",
+			StyleTextMuted.Render("Tasks:"),
+			StyleTextBase.Render(fmt.Sprintf("%d ready", m.preflightReadyCount)),
+			StyleTaskBlocked.Render(fmt.Sprintf("%s %d blocked", IconBlocked, m.preflightBlockedCount))))
+	}
+
 	// Circuit state
 	circuitState := m.preflightCircuitState
 	if circuitState == "" {
@@ -0,0 +1,176 @@
+package verify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/brainwhocodes/lisa-loop/internal/analysis/testparse"
+	"github.com/brainwhocodes/lisa-loop/internal/proc"
+)
+
+// maxOutputBytes caps the captured verification output kept in memory
+const maxOutputBytes = 64 * 1024
+
+// Result holds the outcome of a single verification run
+type Result struct {
+	Command  string        `json:"command"`             // Command that was executed
+	ExitCode int           `json:"exit_code"`           // Process exit code (-1 if it never started or was killed)
+	Duration time.Duration `json:"duration"`            // Wall-clock duration of the run
+	Output   string        `json:"output,omitempty"`    // Combined stdout/stderr (truncated to the last 64KB)
+	Passed   bool          `json:"passed"`              // True if the command exited with code 0
+	TimedOut bool          `json:"timed_out,omitempty"` // True if the run was killed by the timeout
+
+	// Test results recognized in the output (nil if it holds none)
+	Tests *testparse.Result `json:"tests,omitempty"`
+}
+
+// Gate runs a project-specific verification command (e.g. "go test ./...")
+type Gate struct {
+	command string
+	dir     string
+	timeout time.Duration
+}
+
+// NewGate creates a new verification gate
+// An empty command yields a disabled gate
+func NewGate(command, dir string, timeout time.Duration) *Gate {
+	return &Gate{
+		command: strings.TrimSpace(command),
+		dir:     dir,
+		timeout: timeout,
+	}
+}
+
+// In returns a copy of the gate that runs in dir
+func (g *Gate) In(dir string) *Gate {
+	if g == nil {
+		return nil
+	}
+	clone := *g
+	clone.dir = dir
+	return &clone
+}
+
+// Enabled reports whether the gate has a command to run
+func (g *Gate) Enabled() bool {
+	return g != nil && g.command != ""
+}
+
+// Command returns the configured verification command
+func (g *Gate) Command() string {
+	return g.command
+}
+
+// Run executes the verification command through the shell and captures its result
+// A non-zero exit code is reported in the Result, not as an error; errors are
+// only returned when the gate is disabled or the run was cancelled by ctx
+func (g *Gate) Run(ctx context.Context) (*Result, error) {
+	if !g.Enabled() {
+		return nil, fmt.Errorf("verification gate has no command configured")
+	}
+
+	runCtx := ctx
+	if g.timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, g.timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(runCtx, "sh", "-c", g.command)
+	if g.dir != "" {
+		cmd.Dir = g.dir
+	}
+
+	// Kill the test processes the command started along with the shell, and don't wait
+	// forever on grandchildren that inherited the output pipes
+	proc.KillGroup(cmd)
+	cmd.WaitDelay = 2 * time.Second
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	start := time.Now()
+	err := cmd.Run()
+
+	result := &Result{
+		Command:  g.command,
+		Duration: time.Since(start),
+		Output:   truncateOutput(output.String()),
+	}
+	result.Tests, _ = testparse.Parse(result.Output)
+
+	if err == nil {
+		result.Passed = true
+		return result, nil
+	}
+
+	// Parent context cancelled (Ctrl+C) - the result is meaningless
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("verification cancelled: %w", ctx.Err())
+	}
+
+	if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		result.TimedOut = true
+		result.ExitCode = -1
+		return result, nil
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		result.ExitCode = exitErr.ExitCode()
+		return result, nil
+	}
+
+	// Command could not be started at all
+	result.ExitCode = -1
+	result.Output = err.Error()
+	return result, nil
+}
+
+// Tail returns the last n non-empty lines of the captured output
+func (r *Result) Tail(n int) string {
+	if r == nil || n <= 0 {
+		return ""
+	}
+
+	var lines []string
+	for _, line := range strings.Split(r.Output, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Summary returns a one-line description of the result
+func (r *Result) Summary() string {
+	if r == nil {
+		return ""
+	}
+
+	switch {
+	case r.Passed:
+		return fmt.Sprintf("verification passed: %s (%s)", r.Command, r.Duration.Round(time.Millisecond))
+	case r.TimedOut:
+		return fmt.Sprintf("verification timed out: %s (%s)", r.Command, r.Duration.Round(time.Millisecond))
+	default:
+		return fmt.Sprintf("verification failed: %s (exit %d, %s)", r.Command, r.ExitCode, r.Duration.Round(time.Millisecond))
+	}
+}
+
+// truncateOutput keeps the tail of large outputs, where test failures usually are
+func truncateOutput(s string) string {
+	if len(s) <= maxOutputBytes {
+		return s
+	}
+	return "...(truncated)...\n" + s[len(s)-maxOutputBytes:]
+}
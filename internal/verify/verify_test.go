@@ -0,0 +1,116 @@
+package verify
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGate_Disabled(t *testing.T) {
+	gate := NewGate("  ", "", 0)
+
+	if gate.Enabled() {
+		t.Error("Enabled() = true, want false for empty command")
+	}
+
+	if _, err := gate.Run(context.Background()); err == nil {
+		t.Error("Run() error = nil, want error for disabled gate")
+	}
+}
+
+func TestGate_Passing(t *testing.T) {
+	gate := NewGate("echo ok", t.TempDir(), 0)
+
+	result, err := gate.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if !result.Passed {
+		t.Errorf("Passed = false, want true")
+	}
+
+	if result.ExitCode != 0 {
+		t.Errorf("ExitCode = %d, want 0", result.ExitCode)
+	}
+
+	if !strings.Contains(result.Output, "ok") {
+		t.Errorf("Output = %q, want it to contain 'ok'", result.Output)
+	}
+}
+
+func TestGate_Failing(t *testing.T) {
+	gate := NewGate("echo broken >&2; exit 3", t.TempDir(), 0)
+
+	result, err := gate.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if result.Passed {
+		t.Error("Passed = true, want false")
+	}
+
+	if result.ExitCode != 3 {
+		t.Errorf("ExitCode = %d, want 3", result.ExitCode)
+	}
+
+	if !strings.Contains(result.Output, "broken") {
+		t.Errorf("Output = %q, want stderr captured", result.Output)
+	}
+
+	if !strings.Contains(result.Summary(), "exit 3") {
+		t.Errorf("Summary() = %q, want exit code", result.Summary())
+	}
+}
+
+func TestGate_Timeout(t *testing.T) {
+	gate := NewGate("exec sleep 5", t.TempDir(), 100*time.Millisecond)
+
+	result, err := gate.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if !result.TimedOut {
+		t.Error("TimedOut = false, want true")
+	}
+
+	if result.Passed {
+		t.Error("Passed = true, want false on timeout")
+	}
+}
+
+func TestResult_Tail(t *testing.T) {
+	result := &Result{Output: "one\ntwo\nthree\nfour\n"}
+
+	if got := result.Tail(2); got != "three\nfour" {
+		t.Errorf("Tail(2) = %q, want %q", got, "three\nfour")
+	}
+
+	if got := result.Tail(10); got != "one\ntwo\nthree\nfour" {
+		t.Errorf("Tail(10) = %q, want all lines", got)
+	}
+
+	result = &Result{Output: "one\n\ntwo\n  \n\n"}
+	if got := result.Tail(2); got != "one\ntwo" {
+		t.Errorf("Tail(2) = %q, want blank lines skipped", got)
+	}
+}
+
+func TestGate_ParsesTests(t *testing.T) {
+	gate := NewGate(`printf -- '--- FAIL: TestParse (0.00s)\n    parse_test.go:9: unexpected EOF\nFAIL\texample.com/parse\t0.01s\n'; exit 1`, t.TempDir(), 0)
+
+	result, err := gate.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if result.Tests == nil || result.Tests.Failed != 1 {
+		t.Fatalf("Tests = %+v, want one failing go test", result.Tests)
+	}
+	if got := result.Tests.Failures[0].String(); got != "TestParse: parse_test.go:9: unexpected EOF" {
+		t.Errorf("Failures[0] = %q", got)
+	}
+}
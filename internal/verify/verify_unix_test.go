@@ -0,0 +1,30 @@
+//go:build !windows
+
+package verify
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGate_TimeoutKillsProcessGroup(t *testing.T) {
+	dir := t.TempDir()
+	gate := NewGate("(sleep 1; touch marker) & wait", dir, 200*time.Millisecond)
+
+	result, err := gate.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !result.TimedOut {
+		t.Fatalf("TimedOut = false, want true")
+	}
+
+	// The backgrounded subshell would have created the marker by now
+	time.Sleep(1500 * time.Millisecond)
+	if _, err := os.Stat(filepath.Join(dir, "marker")); err == nil {
+		t.Error("the command's background process outlived the timeout")
+	}
+}
@@ -23,7 +23,7 @@ type fakeRunner struct {
 	maxCalls     int
 }
 
-func (f *fakeRunner) Run(prompt string) (string, string, error) {
+func (f *fakeRunner) Run(ctx context.Context, prompt string) (string, string, error) {
 	f.callCount++
 
 	if f.callCount > f.maxCalls {